@@ -3,9 +3,8 @@ package main
 import (
 	"app/go-sso/internal/config"
 	"app/go-sso/internal/entity"
+	"app/go-sso/utils"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
@@ -32,6 +31,25 @@ func main() {
 		&entity.UserRole{},
 		&entity.UserToken{},
 		&entity.Grade{},
+		&entity.AuthorizationCode{},
+		&entity.SigningKey{},
+		&entity.RevokedToken{},
+		&entity.ApplicationRedirectURI{},
+		&entity.ApplicationClientPermission{},
+		&entity.DeviceAuthorization{},
+		&entity.SAMLServiceProvider{},
+		&entity.ApplicationSession{},
+		&entity.UserSession{},
+		&entity.UserTOTP{},
+		&entity.UserRecoveryCode{},
+		&entity.MFAChallenge{},
+		&entity.WebAuthnCredential{},
+		&entity.LoginThrottle{},
+		&entity.RateLimitBucket{},
+		&entity.UserPasswordHistory{},
+		&entity.UserIdentity{},
+		&entity.UpstreamProvider{},
+		&entity.AuditLog{},
 	)
 
 	if err != nil {
@@ -259,6 +277,9 @@ func main() {
 			Secret:      "secret for authenticator",
 			RedirectURI: "http://localhost:3000",
 			Domain:      "localhost",
+			RedirectURIs: []entity.ApplicationRedirectURI{
+				{URI: "http://localhost:3000", Type: entity.REDIRECT_URI_POST_LOGOUT},
+			},
 		},
 		{
 			Name:        "manpower",
@@ -266,13 +287,20 @@ func main() {
 			Secret:      "secret for web1",
 			RedirectURI: "https://www.google.com",
 			Domain:      "localhost",
+			RedirectURIs: []entity.ApplicationRedirectURI{
+				{URI: "https://www.google.com", Type: entity.REDIRECT_URI_POST_LOGOUT},
+			},
 		},
 		{
-			Name:        "recruitment",
-			Label:       "Julong Recruitment",
-			Secret:      "secret for web2",
-			RedirectURI: "https://www.github.com",
-			Domain:      "localhost",
+			Name:             "recruitment",
+			Label:            "Julong Recruitment",
+			Secret:           "secret for web2",
+			RedirectURI:      "https://www.github.com",
+			Domain:           "localhost",
+			MagicLinkEnabled: true,
+			RedirectURIs: []entity.ApplicationRedirectURI{
+				{URI: "https://www.github.com", Type: entity.REDIRECT_URI_POST_LOGOUT},
+			},
 		},
 	}
 
@@ -368,6 +396,18 @@ func main() {
 				GuardName:     "web",
 				ApplicationID: authApplication.ID,
 			},
+			{
+				Name:          "read-scim",
+				Label:         "Read SCIM",
+				GuardName:     "web",
+				ApplicationID: authApplication.ID,
+			},
+			{
+				Name:          "write-scim",
+				Label:         "Write SCIM",
+				GuardName:     "web",
+				ApplicationID: authApplication.ID,
+			},
 			{
 				Name:          "sync-job",
 				Label:         "Sync Job",
@@ -1735,7 +1775,7 @@ func main() {
 	}
 
 	// Create default user
-	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte("changeme"), bcrypt.DefaultCost)
+	hashedPassword, err := utils.PasswordHasherFromConfig(viper).Hash("changeme")
 	if err != nil {
 		log.Fatalf("failed to hash password: %v", err)
 	}
@@ -1758,7 +1798,7 @@ func main() {
 	user := entity.User{
 		Username:        "admin",
 		Email:           "admin@test.test",
-		Password:        string(hashedPasswordBytes),
+		Password:        hashedPassword,
 		Name:            "Admin",
 		EmailVerifiedAt: time.Now(),
 		Status:          entity.UserStatus("ACTIVE"),
@@ -1817,7 +1857,7 @@ func main() {
 	googleAccount := entity.User{
 		Username:        "ilham",
 		Email:           "ilham.ahmadz18@gmail.com",
-		Password:        string(hashedPasswordBytes),
+		Password:        hashedPassword,
 		Name:            "Ilham Setiaji",
 		EmailVerifiedAt: time.Now(),
 		Status:          entity.UserStatus("ACTIVE"),
@@ -2351,7 +2391,7 @@ func main() {
 	user1 := entity.User{
 		Username:        "timrekrutmen",
 		Email:           "tr@test.test",
-		Password:        string(hashedPasswordBytes),
+		Password:        hashedPassword,
 		Name:            "Tim Rekrutmen",
 		EmailVerifiedAt: time.Now(),
 		Status:          entity.UserStatus("ACTIVE"),
@@ -2368,7 +2408,7 @@ func main() {
 	user2 := entity.User{
 		Username:        "hrdsite",
 		Email:           "hrd@test.test",
-		Password:        string(hashedPasswordBytes),
+		Password:        hashedPassword,
 		Name:            "HRD Site",
 		EmailVerifiedAt: time.Now(),
 		Status:          entity.UserStatus("ACTIVE"),
@@ -54,10 +54,10 @@ func NewDatabase() *gorm.DB {
 
 		switch driver {
 		case "mysql":
-			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, host, port, database)
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=UTC", username, password, host, port, database)
 			db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
 		case "postgres":
-			dsn = fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=disable", host, port, username, database, password)
+			dsn = fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=disable TimeZone=UTC", host, port, username, database, password)
 			db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 		default:
 			log.Fatalf("unsupported database driver: %s", driver)
@@ -14,25 +14,66 @@ type Application struct {
 	Secret      string    `json:"secret" gorm:"unique;not null"`
 	RedirectURI string    `json:"redirect_uri" gorm:"not null"`
 	Domain      string    `json:"domain" gorm:"not null"`
-	CreatedAt   time.Time `gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
-	DeletedAt   gorm.DeletedAt
-	Roles       []Role       `json:"roles" gorm:"foreignKey:ApplicationID;references:ID"`
-	Permissions []Permission `json:"permissions" gorm:"foreignKey:ApplicationID;references:ID"`
+	// Public applications run in the browser or on devices and cannot keep
+	// Secret. They identify with client_id alone and have to use PKCE.
+	Public bool `json:"public" gorm:"not null;default:false"`
+	// MagicLinkEnabled lets users of the application sign in with a link
+	// mailed to them instead of their password.
+	MagicLinkEnabled bool      `json:"magic_link_enabled" gorm:"not null;default:false"`
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt
+	Roles            []Role                   `json:"roles" gorm:"foreignKey:ApplicationID;references:ID"`
+	Permissions      []Permission             `json:"permissions" gorm:"foreignKey:ApplicationID;references:ID"`
+	RedirectURIs     []ApplicationRedirectURI `json:"redirect_uris" gorm:"foreignKey:ApplicationID;references:ID"`
+	// ClientPermissions are granted to the application itself and end up in
+	// its client credentials tokens.
+	ClientPermissions   []Permission         `json:"client_permissions" gorm:"many2many:application_client_permissions;"`
+	SAMLServiceProvider *SAMLServiceProvider `json:"saml_service_provider" gorm:"foreignKey:ApplicationID;references:ID"`
 }
 
 func (application *Application) BeforeCreate(tx *gorm.DB) (err error) {
 	application.ID = uuid.New()
-	application.CreatedAt = time.Now().Add(time.Hour * 7)
-	application.UpdatedAt = time.Now().Add(time.Hour * 7)
+	application.CreatedAt = time.Now()
+	application.UpdatedAt = time.Now()
 	return nil
 }
 
 func (application *Application) BeforeUpdate(tx *gorm.DB) (err error) {
-	application.UpdatedAt = time.Now().Add(time.Hour * 7)
+	application.UpdatedAt = time.Now()
 	return nil
 }
 
 func (Application) TableName() string {
 	return "applications"
 }
+
+// HasRedirectURI reports whether uri exactly matches one of the registered
+// redirect URIs of the given type. The legacy RedirectURI column still counts
+// as a login redirect URI.
+func (application *Application) HasRedirectURI(uri string, uriType RedirectURIType) bool {
+	if uri == "" {
+		return false
+	}
+	if uriType == REDIRECT_URI_LOGIN && uri == application.RedirectURI {
+		return true
+	}
+	for _, redirectURI := range application.RedirectURIs {
+		if redirectURI.Type == uriType && redirectURI.URI == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectURIsOfType returns the registered URIs of the given type, used to
+// find where logouts have to be propagated.
+func (application *Application) RedirectURIsOfType(uriType RedirectURIType) []string {
+	uris := []string{}
+	for _, redirectURI := range application.RedirectURIs {
+		if redirectURI.Type == uriType {
+			uris = append(uris, redirectURI.URI)
+		}
+	}
+	return uris
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApplicationClientPermission grants a permission to an application acting on
+// its own behalf through the client credentials grant.
+type ApplicationClientPermission struct {
+	ApplicationID uuid.UUID `json:"application_id" gorm:"type:char(36);primaryKey"`
+	PermissionID  uuid.UUID `json:"permission_id" gorm:"type:char(36);primaryKey"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Application Application `json:"application" gorm:"foreignKey:ApplicationID;references:ID"`
+	Permission  Permission  `json:"permission" gorm:"foreignKey:PermissionID;references:ID"`
+}
+
+func (acp *ApplicationClientPermission) BeforeCreate() (err error) {
+	acp.CreatedAt = time.Now()
+	acp.UpdatedAt = time.Now()
+	return nil
+}
+
+func (acp *ApplicationClientPermission) BeforeUpdate() (err error) {
+	acp.UpdatedAt = time.Now()
+	return nil
+}
+
+func (ApplicationClientPermission) TableName() string {
+	return "application_client_permissions"
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RedirectURIType string
+
+const (
+	REDIRECT_URI_LOGIN               RedirectURIType = "LOGIN"
+	REDIRECT_URI_POST_LOGOUT         RedirectURIType = "POST_LOGOUT"
+	REDIRECT_URI_FRONTCHANNEL_LOGOUT RedirectURIType = "FRONTCHANNEL_LOGOUT"
+	REDIRECT_URI_BACKCHANNEL_LOGOUT  RedirectURIType = "BACKCHANNEL_LOGOUT"
+)
+
+// ApplicationRedirectURI is one of the URIs an application may be sent back
+// to. Requested URIs are compared byte for byte, so every environment of an
+// application (staging, production, ...) registers its own row.
+type ApplicationRedirectURI struct {
+	ID            uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
+	ApplicationID uuid.UUID       `json:"application_id" gorm:"type:char(36);uniqueIndex:idx_application_redirect_uri"`
+	URI           string          `json:"uri" gorm:"type:varchar(255);not null;uniqueIndex:idx_application_redirect_uri"`
+	Type          RedirectURIType `json:"type" gorm:"type:varchar(20);default:LOGIN;uniqueIndex:idx_application_redirect_uri"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time       `gorm:"autoUpdateTime"`
+	Application   *Application    `json:"application" gorm:"foreignKey:ApplicationID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (redirectURI *ApplicationRedirectURI) BeforeCreate(tx *gorm.DB) (err error) {
+	redirectURI.ID = uuid.New()
+	redirectURI.CreatedAt = time.Now()
+	redirectURI.UpdatedAt = time.Now()
+	return nil
+}
+
+func (redirectURI *ApplicationRedirectURI) BeforeUpdate(tx *gorm.DB) (err error) {
+	redirectURI.UpdatedAt = time.Now()
+	return nil
+}
+
+func (ApplicationRedirectURI) TableName() string {
+	return "application_redirect_uris"
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApplicationSession records that a go-sso login session (the sid claim of ID
+// tokens) issued tokens to an application, so the application can be told when
+// that session ends.
+type ApplicationSession struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:char(36);primaryKey"`
+	SessionID     string       `json:"session_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_application_session"`
+	ApplicationID uuid.UUID    `json:"application_id" gorm:"type:char(36);not null;uniqueIndex:idx_application_session"`
+	Application   *Application `json:"application" gorm:"foreignKey:ApplicationID;references:ID;constraint:OnDelete:CASCADE"`
+	UserID        uuid.UUID    `json:"user_id" gorm:"type:char(36);not null;index"`
+	User          *User        `json:"user" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (applicationSession *ApplicationSession) BeforeCreate(tx *gorm.DB) (err error) {
+	applicationSession.ID = uuid.New()
+	applicationSession.CreatedAt = time.Now()
+	applicationSession.UpdatedAt = time.Now()
+	return nil
+}
+
+func (applicationSession *ApplicationSession) BeforeUpdate(tx *gorm.DB) (err error) {
+	applicationSession.UpdatedAt = time.Now()
+	return nil
+}
+
+func (ApplicationSession) TableName() string {
+	return "application_sessions"
+}
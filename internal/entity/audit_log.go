@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditAction string
+
+const (
+	AUDIT_USER_PROVISIONED AuditAction = "USER_PROVISIONED"
+)
+
+// AuditLog records an action taken on a user without an administrator, with
+// where it came from. UserID is not a foreign key so the entry outlives the
+// user; Details holds the facts of the action as JSON.
+type AuditLog struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
+	Action    AuditAction `json:"action" gorm:"type:varchar(64);not null;index"`
+	UserID    *uuid.UUID  `json:"user_id" gorm:"type:char(36);default:null;index"`
+	Source    string      `json:"source" gorm:"type:varchar(64);default:null"`
+	IPAddress string      `json:"ip_address" gorm:"type:varchar(45);default:null"`
+	Details   string      `json:"details" gorm:"type:text;default:null"`
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (auditLog *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	auditLog.ID = uuid.New()
+	auditLog.CreatedAt = time.Now()
+	return nil
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -7,31 +7,33 @@ import (
 	"gorm.io/gorm"
 )
 
+// AuthToken is a refresh token. Only the hash of the token is stored; every
+// rotation creates a new row in the same family and marks the old one used.
 type AuthToken struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null"`
-	User      User      `json:"user" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
-	Token     string    `json:"token" gorm:"type:text;not null"`
-	ExpiredAt time.Time `json:"expired_at" gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID            uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:char(36);not null"`
+	User          User       `json:"user" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	FamilyID      uuid.UUID  `json:"family_id" gorm:"type:char(36);index;default:null"`
+	ApplicationID *uuid.UUID `json:"application_id" gorm:"type:char(36);default:null"`
+	RoleID        *uuid.UUID `json:"role_id" gorm:"type:char(36);default:null"`
+	Scope         string     `json:"scope" gorm:"type:varchar(255);default:null"`
+	Token         string     `json:"-" gorm:"type:varchar(255);index;not null"`
+	ExpiredAt     time.Time  `json:"expired_at" gorm:"not null"`
+	UsedAt        *time.Time `json:"used_at" gorm:"default:null"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"default:null"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
 }
 
 func (authToken *AuthToken) BeforeCreate(tx *gorm.DB) (err error) {
 	authToken.ID = uuid.New()
-	authToken.CreatedAt = time.Now().Add(time.Hour * 7)
-	authToken.UpdatedAt = time.Now().Add(time.Hour * 7)
-	if !authToken.ExpiredAt.IsZero() {
-		authToken.ExpiredAt = authToken.ExpiredAt.Add(time.Hour * 7)
-	}
+	authToken.CreatedAt = time.Now()
+	authToken.UpdatedAt = time.Now()
 	return nil
 }
 
 func (authToken *AuthToken) BeforeUpdate(tx *gorm.DB) (err error) {
-	authToken.UpdatedAt = time.Now().Add(time.Hour * 7)
-	if !authToken.ExpiredAt.IsZero() {
-		authToken.ExpiredAt = authToken.ExpiredAt.Add(time.Hour * 7)
-	}
+	authToken.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuthorizationCode is a pending /oauth2/authorize grant. RedirectURIExplicit
+// records whether the request named RedirectURI or fell back to the default
+// one, the token request only has to repeat it in the first case.
+type AuthorizationCode struct {
+	ID                  uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
+	Code                string      `json:"-" gorm:"type:varchar(255);unique;not null"`
+	ApplicationID       uuid.UUID   `json:"application_id" gorm:"type:char(36);not null"`
+	Application         Application `json:"application" gorm:"foreignKey:ApplicationID;references:ID;constraint:OnDelete:CASCADE"`
+	UserID              uuid.UUID   `json:"user_id" gorm:"type:char(36);not null"`
+	User                User        `json:"user" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	RoleID              uuid.UUID   `json:"role_id" gorm:"type:char(36);not null"`
+	RedirectURI         string      `json:"redirect_uri" gorm:"type:text;not null"`
+	RedirectURIExplicit bool        `json:"redirect_uri_explicit" gorm:"not null;default:false"`
+	Scope               string      `json:"scope" gorm:"type:varchar(255);default:null"`
+	Nonce               string      `json:"nonce" gorm:"type:varchar(255);default:null"`
+	CodeChallenge       string      `json:"code_challenge" gorm:"type:varchar(255);default:null"`
+	CodeChallengeMethod string      `json:"code_challenge_method" gorm:"type:varchar(10);default:null"`
+	AuthTime            time.Time   `json:"auth_time"`
+	SessionID           string      `json:"session_id" gorm:"type:varchar(64);default:null"`
+	ExpiredAt           time.Time   `json:"expired_at" gorm:"not null"`
+	UsedAt              *time.Time  `json:"used_at" gorm:"default:null"`
+	CreatedAt           time.Time   `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time   `gorm:"autoUpdateTime"`
+}
+
+func (authorizationCode *AuthorizationCode) BeforeCreate(tx *gorm.DB) (err error) {
+	authorizationCode.ID = uuid.New()
+	authorizationCode.CreatedAt = time.Now()
+	authorizationCode.UpdatedAt = time.Now()
+	return nil
+}
+
+func (authorizationCode *AuthorizationCode) BeforeUpdate(tx *gorm.DB) (err error) {
+	authorizationCode.UpdatedAt = time.Now()
+	return nil
+}
+
+func (AuthorizationCode) TableName() string {
+	return "authorization_codes"
+}
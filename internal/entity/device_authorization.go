@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DeviceAuthorizationStatus string
+
+const (
+	DEVICE_AUTHORIZATION_PENDING  DeviceAuthorizationStatus = "PENDING"
+	DEVICE_AUTHORIZATION_APPROVED DeviceAuthorizationStatus = "APPROVED"
+	DEVICE_AUTHORIZATION_DENIED   DeviceAuthorizationStatus = "DENIED"
+	DEVICE_AUTHORIZATION_CONSUMED DeviceAuthorizationStatus = "CONSUMED"
+)
+
+// DeviceAuthorization is an RFC 8628 device authorization request. The device
+// polls with DeviceCode (stored hashed) while the user approves UserCode on
+// the /device page.
+type DeviceAuthorization struct {
+	ID            uuid.UUID                 `json:"id" gorm:"type:char(36);primaryKey"`
+	DeviceCode    string                    `json:"-" gorm:"type:varchar(255);unique;not null"`
+	UserCode      string                    `json:"user_code" gorm:"type:varchar(20);unique;not null"`
+	ApplicationID uuid.UUID                 `json:"application_id" gorm:"type:char(36);not null"`
+	Application   Application               `json:"application" gorm:"foreignKey:ApplicationID;references:ID;constraint:OnDelete:CASCADE"`
+	Scope         string                    `json:"scope" gorm:"type:varchar(255);default:null"`
+	Status        DeviceAuthorizationStatus `json:"status" gorm:"type:varchar(10);default:PENDING"`
+	UserID        *uuid.UUID                `json:"user_id" gorm:"type:char(36);default:null"`
+	RoleID        *uuid.UUID                `json:"role_id" gorm:"type:char(36);default:null"`
+	AuthTime      *time.Time                `json:"auth_time" gorm:"default:null"`
+	Interval      int                       `json:"interval" gorm:"not null"`
+	LastPolledAt  *time.Time                `json:"last_polled_at" gorm:"default:null"`
+	ExpiredAt     time.Time                 `json:"expired_at" gorm:"not null"`
+	CreatedAt     time.Time                 `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time                 `gorm:"autoUpdateTime"`
+}
+
+func (deviceAuthorization *DeviceAuthorization) BeforeCreate(tx *gorm.DB) (err error) {
+	deviceAuthorization.ID = uuid.New()
+	deviceAuthorization.CreatedAt = time.Now()
+	deviceAuthorization.UpdatedAt = time.Now()
+	return nil
+}
+
+func (deviceAuthorization *DeviceAuthorization) BeforeUpdate(tx *gorm.DB) (err error) {
+	deviceAuthorization.UpdatedAt = time.Now()
+	return nil
+}
+
+func (DeviceAuthorization) TableName() string {
+	return "device_authorizations"
+}
@@ -31,13 +31,13 @@ type Employee struct {
 
 func (employee *Employee) BeforeCreate(tx *gorm.DB) (err error) {
 	employee.ID = uuid.New()
-	employee.CreatedAt = time.Now().Add(time.Hour * 7)
-	employee.UpdatedAt = time.Now().Add(time.Hour * 7)
+	employee.CreatedAt = time.Now()
+	employee.UpdatedAt = time.Now()
 	return nil
 }
 
 func (employee *Employee) BeforeUpdate(tx *gorm.DB) (err error) {
-	employee.UpdatedAt = time.Now().Add(time.Hour * 7)
+	employee.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -31,13 +31,13 @@ type EmployeeJob struct {
 
 func (e *EmployeeJob) BeforeCreate(tx *gorm.DB) (err error) {
 	e.ID = uuid.New()
-	e.CreatedAt = time.Now().Add(time.Hour * 7)
-	e.UpdatedAt = time.Now().Add(time.Hour * 7)
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = time.Now()
 	return nil
 }
 
 func (e *EmployeeJob) BeforeUpdate(tx *gorm.DB) (err error) {
-	e.UpdatedAt = time.Now().Add(time.Hour * 7)
+	e.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -20,13 +20,13 @@ type Grade struct {
 
 func (g *Grade) BeforeCreate(tx *gorm.DB) (err error) {
 	g.ID = uuid.New()
-	g.CreatedAt = time.Now().Add(time.Hour * 7)
-	g.UpdatedAt = time.Now().Add(time.Hour * 7)
+	g.CreatedAt = time.Now()
+	g.UpdatedAt = time.Now()
 	return nil
 }
 
 func (g *Grade) BeforeUpdate(tx *gorm.DB) (err error) {
-	g.UpdatedAt = time.Now().Add(time.Hour * 7)
+	g.UpdatedAt = time.Now()
 	return nil
 }
 
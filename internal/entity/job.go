@@ -36,8 +36,8 @@ type Job struct {
 
 func (job *Job) BeforeCreate(tx *gorm.DB) (err error) {
 	job.ID = uuid.New()
-	job.CreatedAt = time.Now().Add(time.Hour * 7)
-	job.UpdatedAt = time.Now().Add(time.Hour * 7)
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
 
 	// Set level and path based on parent
 	if job.ParentID != nil {
@@ -55,7 +55,7 @@ func (job *Job) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 func (job *Job) BeforeUpdate(tx *gorm.DB) (err error) {
-	job.UpdatedAt = time.Now().Add(time.Hour * 7)
+	job.UpdatedAt = time.Now()
 	return nil
 }
 
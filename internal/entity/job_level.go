@@ -21,13 +21,13 @@ type JobLevel struct {
 
 func (jobLevel *JobLevel) BeforeCreate(tx *gorm.DB) (err error) {
 	jobLevel.ID = uuid.New()
-	jobLevel.CreatedAt = time.Now().Add(time.Hour * 7)
-	jobLevel.UpdatedAt = time.Now().Add(time.Hour * 7)
+	jobLevel.CreatedAt = time.Now()
+	jobLevel.UpdatedAt = time.Now()
 	return nil
 }
 
 func (jobLevel *JobLevel) BeforeUpdate(tx *gorm.DB) (err error) {
-	jobLevel.UpdatedAt = time.Now().Add(time.Hour * 7)
+	jobLevel.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LoginThrottleAction string
+
+const (
+	LOGIN_THROTTLE_LOGIN               LoginThrottleAction = "LOGIN"
+	LOGIN_THROTTLE_VERIFY_EMAIL        LoginThrottleAction = "VERIFY_EMAIL"
+	LOGIN_THROTTLE_RESEND_VERIFY_EMAIL LoginThrottleAction = "RESEND_VERIFY_EMAIL"
+	LOGIN_THROTTLE_MAGIC_LINK          LoginThrottleAction = "MAGIC_LINK"
+)
+
+type LoginThrottleScope string
+
+const (
+	LOGIN_THROTTLE_ACCOUNT LoginThrottleScope = "ACCOUNT"
+	LOGIN_THROTTLE_IP      LoginThrottleScope = "IP"
+)
+
+// LoginThrottle counts the recent failed attempts of an action for one
+// account (identified by email) or one IP address. Once Failures reaches the
+// limit of the scope the identifier is locked until LockedUntil.
+type LoginThrottle struct {
+	ID           uuid.UUID           `json:"id" gorm:"type:char(36);primaryKey"`
+	Action       LoginThrottleAction `json:"action" gorm:"type:varchar(32);not null;uniqueIndex:idx_login_throttles_identifier"`
+	Scope        LoginThrottleScope  `json:"scope" gorm:"type:varchar(16);not null;uniqueIndex:idx_login_throttles_identifier"`
+	Identifier   string              `json:"identifier" gorm:"type:varchar(255);not null;uniqueIndex:idx_login_throttles_identifier"`
+	Failures     int                 `json:"failures" gorm:"not null;default:0"`
+	LastFailedAt time.Time           `json:"last_failed_at"`
+	LockedUntil  *time.Time          `json:"locked_until" gorm:"default:null;index"`
+	CreatedAt    time.Time           `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time           `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (loginThrottle *LoginThrottle) BeforeCreate(tx *gorm.DB) (err error) {
+	loginThrottle.ID = uuid.New()
+	loginThrottle.CreatedAt = time.Now()
+	loginThrottle.UpdatedAt = time.Now()
+	return nil
+}
+
+func (loginThrottle *LoginThrottle) BeforeUpdate(tx *gorm.DB) (err error) {
+	loginThrottle.UpdatedAt = time.Now()
+	return nil
+}
+
+// IsLocked reports whether the identifier is locked at the given time.
+func (loginThrottle *LoginThrottle) IsLocked(now time.Time) bool {
+	return loginThrottle.LockedUntil != nil && loginThrottle.LockedUntil.After(now)
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFAChallenge is handed out by /api/login when the password is correct but
+// the user still has to enter a second factor. The client answers it on
+// /api/login/mfa with the token, whose hash is stored.
+type MFAChallenge struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);unique;not null"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Attempts  int        `json:"attempts" gorm:"not null"`
+	ExpiredAt time.Time  `json:"expired_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"default:null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (mfaChallenge *MFAChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	mfaChallenge.ID = uuid.New()
+	mfaChallenge.CreatedAt = time.Now()
+	mfaChallenge.UpdatedAt = time.Now()
+	return nil
+}
+
+func (mfaChallenge *MFAChallenge) BeforeUpdate(tx *gorm.DB) (err error) {
+	mfaChallenge.UpdatedAt = time.Now()
+	return nil
+}
+
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
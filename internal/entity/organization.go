@@ -27,13 +27,13 @@ type Organization struct {
 
 func (organization *Organization) BeforeCreate(tx *gorm.DB) (err error) {
 	organization.ID = uuid.New()
-	organization.CreatedAt = time.Now().Add(time.Hour * 7)
-	organization.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organization.CreatedAt = time.Now()
+	organization.UpdatedAt = time.Now()
 	return nil
 }
 
 func (organization *Organization) BeforeUpdate(tx *gorm.DB) (err error) {
-	organization.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organization.UpdatedAt = time.Now()
 	return nil
 }
 
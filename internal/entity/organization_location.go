@@ -20,13 +20,13 @@ type OrganizationLocation struct {
 
 func (organizationLocation *OrganizationLocation) BeforeCreate(tx *gorm.DB) (err error) {
 	organizationLocation.ID = uuid.New()
-	organizationLocation.CreatedAt = time.Now().Add(time.Hour * 7)
-	organizationLocation.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationLocation.CreatedAt = time.Now()
+	organizationLocation.UpdatedAt = time.Now()
 	return nil
 }
 
 func (organizationLocation *OrganizationLocation) BeforeUpdate(tx *gorm.DB) (err error) {
-	organizationLocation.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationLocation.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -30,8 +30,8 @@ type OrganizationStructure struct {
 
 func (organizationStructure *OrganizationStructure) BeforeCreate(tx *gorm.DB) (err error) {
 	organizationStructure.ID = uuid.New()
-	organizationStructure.CreatedAt = time.Now().Add(time.Hour * 7)
-	organizationStructure.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationStructure.CreatedAt = time.Now()
+	organizationStructure.UpdatedAt = time.Now()
 
 	// Set level and path based on parent
 	if organizationStructure.ParentID != nil {
@@ -49,7 +49,7 @@ func (organizationStructure *OrganizationStructure) BeforeCreate(tx *gorm.DB) (e
 }
 
 func (organizationStructure *OrganizationStructure) BeforeUpdate(tx *gorm.DB) (err error) {
-	organizationStructure.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationStructure.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -19,13 +19,13 @@ type OrganizationType struct {
 
 func (organizationType *OrganizationType) BeforeCreate(tx *gorm.DB) (err error) {
 	organizationType.ID = uuid.New()
-	organizationType.CreatedAt = time.Now().Add(time.Hour * 7)
-	organizationType.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationType.CreatedAt = time.Now()
+	organizationType.UpdatedAt = time.Now()
 	return nil
 }
 
 func (organizationType *OrganizationType) BeforeUpdate(tx *gorm.DB) (err error) {
-	organizationType.UpdatedAt = time.Now().Add(time.Hour * 7)
+	organizationType.UpdatedAt = time.Now()
 	return nil
 }
 
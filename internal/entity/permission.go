@@ -21,13 +21,13 @@ type Permission struct {
 
 func (permission *Permission) BeforeCreate(tx *gorm.DB) (err error) {
 	permission.ID = uuid.New()
-	permission.CreatedAt = time.Now().Add(time.Hour * 7)
-	permission.UpdatedAt = time.Now().Add(time.Hour * 7)
+	permission.CreatedAt = time.Now()
+	permission.UpdatedAt = time.Now()
 	return
 }
 
 func (permission *Permission) BeforeUpdate(tx *gorm.DB) (err error) {
-	permission.UpdatedAt = time.Now().Add(time.Hour * 7)
+	permission.UpdatedAt = time.Now()
 	return
 }
 
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+)
+
+// RateLimitBucket is a token bucket of the database rate limit backend,
+// shared by every instance of the application. ID is the group and the key
+// of the client, e.g. "auth:ip:10.0.0.1".
+type RateLimitBucket struct {
+	ID        string    `json:"id" gorm:"type:varchar(255);primaryKey"`
+	Tokens    float64   `json:"tokens" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:false"`
+	ExpiredAt time.Time `json:"expired_at" gorm:"not null;index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
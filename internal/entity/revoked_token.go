@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RevokedToken blacklists an access token by its jti. A row without a JTI
+// revokes every token of the user issued at or before RevokedAt. Client
+// credentials tokens have no user, their rows carry a nil UserID and are
+// matched by JTI only. Rows are only relevant until ExpiredAt, after which
+// the tokens they cover have expired.
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	JTI       string    `json:"jti" gorm:"type:varchar(64);index;default:null"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index;not null"`
+	RevokedAt time.Time `json:"revoked_at" gorm:"not null"`
+	ExpiredAt time.Time `json:"expired_at" gorm:"index;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (revokedToken *RevokedToken) BeforeCreate(tx *gorm.DB) (err error) {
+	revokedToken.ID = uuid.New()
+	revokedToken.CreatedAt = time.Now()
+	revokedToken.UpdatedAt = time.Now()
+	return nil
+}
+
+func (revokedToken *RevokedToken) BeforeUpdate(tx *gorm.DB) (err error) {
+	revokedToken.UpdatedAt = time.Now()
+	return nil
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -31,13 +31,13 @@ type Role struct {
 
 func (role *Role) BeforeCreate(tx *gorm.DB) (err error) {
 	role.ID = uuid.New()
-	role.CreatedAt = time.Now().Add(time.Hour * 7)
-	role.UpdatedAt = time.Now().Add(time.Hour * 7)
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
 	return nil
 }
 
 func (role *Role) BeforeUpdate(tx *gorm.DB) (err error) {
-	role.UpdatedAt = time.Now().Add(time.Hour * 7)
+	role.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -17,13 +17,13 @@ type RolePermission struct {
 }
 
 func (rp *RolePermission) BeforeCreate() (err error) {
-	rp.CreatedAt = time.Now().Add(time.Hour * 7)
-	rp.UpdatedAt = time.Now().Add(time.Hour * 7)
+	rp.CreatedAt = time.Now()
+	rp.UpdatedAt = time.Now()
 	return nil
 }
 
 func (rp *RolePermission) BeforeUpdate() (err error) {
-	rp.UpdatedAt = time.Now().Add(time.Hour * 7)
+	rp.UpdatedAt = time.Now()
 	return nil
 }
 
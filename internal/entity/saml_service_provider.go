@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SAMLNameIDFormat string
+
+const (
+	SAML_NAME_ID_EMAIL      SAMLNameIDFormat = "EMAIL"
+	SAML_NAME_ID_PERSISTENT SAMLNameIDFormat = "PERSISTENT"
+)
+
+// SAMLServiceProvider is the imported SAML 2.0 metadata of an application that
+// signs in through go-sso as its identity provider. AttributeMapping is a JSON
+// object from SAML attribute names to the user fields they are filled from.
+// The portal starts IdP-initiated logins when IDPInitiated is set, otherwise
+// it opens the application's redirect URI so the application sends an
+// AuthnRequest itself.
+type SAMLServiceProvider struct {
+	ID               uuid.UUID        `json:"id" gorm:"type:char(36);primaryKey"`
+	ApplicationID    uuid.UUID        `json:"application_id" gorm:"type:char(36);unique;not null"`
+	Application      *Application     `json:"application" gorm:"foreignKey:ApplicationID;references:ID;constraint:OnDelete:CASCADE"`
+	EntityID         string           `json:"entity_id" gorm:"type:varchar(255);unique;not null"`
+	Metadata         string           `json:"-" gorm:"type:text;not null"`
+	NameIDFormat     SAMLNameIDFormat `json:"name_id_format" gorm:"type:varchar(20);default:EMAIL"`
+	AttributeMapping string           `json:"attribute_mapping" gorm:"type:text;default:null"`
+	IDPInitiated     bool             `json:"idp_initiated" gorm:"not null"`
+	CreatedAt        time.Time        `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time        `gorm:"autoUpdateTime"`
+}
+
+func (serviceProvider *SAMLServiceProvider) BeforeCreate(tx *gorm.DB) (err error) {
+	serviceProvider.ID = uuid.New()
+	serviceProvider.CreatedAt = time.Now()
+	serviceProvider.UpdatedAt = time.Now()
+	return nil
+}
+
+func (serviceProvider *SAMLServiceProvider) BeforeUpdate(tx *gorm.DB) (err error) {
+	serviceProvider.UpdatedAt = time.Now()
+	return nil
+}
+
+func (SAMLServiceProvider) TableName() string {
+	return "saml_service_providers"
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SigningKeyStatus string
+type SigningKeyAlgorithm string
+
+const (
+	SIGNING_KEY_ACTIVE  SigningKeyStatus = "ACTIVE"
+	SIGNING_KEY_RETIRED SigningKeyStatus = "RETIRED"
+)
+
+const (
+	SIGNING_KEY_RS256 SigningKeyAlgorithm = "RS256"
+	SIGNING_KEY_ES256 SigningKeyAlgorithm = "ES256"
+)
+
+// SigningKey is an asymmetric key pair used to sign tokens. Only the ACTIVE
+// key signs; RETIRED keys stay published in the JWKS until ExpiredAt so tokens
+// signed before a rotation keep verifying.
+type SigningKey struct {
+	ID          uuid.UUID           `json:"id" gorm:"type:char(36);primaryKey"`
+	KeyID       string              `json:"kid" gorm:"type:varchar(64);unique;not null"`
+	Algorithm   SigningKeyAlgorithm `json:"algorithm" gorm:"type:varchar(10);not null"`
+	PrivateKey  string              `json:"-" gorm:"type:text;not null"`
+	PublicKey   string              `json:"public_key" gorm:"type:text;not null"`
+	Status      SigningKeyStatus    `json:"status" gorm:"type:varchar(10);default:ACTIVE"`
+	ActivatedAt time.Time           `json:"activated_at"`
+	RetiredAt   *time.Time          `json:"retired_at" gorm:"default:null"`
+	ExpiredAt   *time.Time          `json:"expired_at" gorm:"default:null"`
+	CreatedAt   time.Time           `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time           `gorm:"autoUpdateTime"`
+}
+
+func (signingKey *SigningKey) BeforeCreate(tx *gorm.DB) (err error) {
+	signingKey.ID = uuid.New()
+	signingKey.CreatedAt = time.Now()
+	signingKey.UpdatedAt = time.Now()
+	return nil
+}
+
+func (signingKey *SigningKey) BeforeUpdate(tx *gorm.DB) (err error) {
+	signingKey.UpdatedAt = time.Now()
+	return nil
+}
+
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UpstreamProvider is an OpenID Connect or OAuth 2.0 provider users can sign
+// in with. Providers with an Issuer are discovered from it; AuthURL, TokenURL
+// and UserInfoURL override the discovered endpoints and are required for
+// plain OAuth 2.0 providers. The *Claim columns map the claims of the ID token
+// and the userinfo response to the user, they default to the standard claims.
+// TrustEmail treats the email of the provider as verified when it does not
+// send an email_verified claim.
+type UpstreamProvider struct {
+	ID                 uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name               string    `json:"name" gorm:"type:varchar(32);unique;not null"`
+	Label              string    `json:"label" gorm:"type:varchar(255);not null"`
+	Issuer             string    `json:"issuer" gorm:"type:varchar(255);default:null"`
+	ClientID           string    `json:"client_id" gorm:"type:varchar(255);not null"`
+	ClientSecret       string    `json:"-" gorm:"type:varchar(255);default:null"`
+	RedirectURL        string    `json:"redirect_url" gorm:"type:varchar(255);default:null"`
+	Scopes             string    `json:"scopes" gorm:"type:varchar(255);default:null"`
+	AuthURL            string    `json:"auth_url" gorm:"type:varchar(255);default:null"`
+	TokenURL           string    `json:"token_url" gorm:"type:varchar(255);default:null"`
+	UserInfoURL        string    `json:"userinfo_url" gorm:"type:varchar(255);default:null"`
+	PKCE               bool      `json:"pkce" gorm:"not null;default:true"`
+	TrustEmail         bool      `json:"trust_email" gorm:"not null;default:false"`
+	SubjectClaim       string    `json:"subject_claim" gorm:"type:varchar(64);default:null"`
+	EmailClaim         string    `json:"email_claim" gorm:"type:varchar(64);default:null"`
+	EmailVerifiedClaim string    `json:"email_verified_claim" gorm:"type:varchar(64);default:null"`
+	NameClaim          string    `json:"name_claim" gorm:"type:varchar(64);default:null"`
+	Enabled            bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt          time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (upstreamProvider *UpstreamProvider) BeforeCreate(tx *gorm.DB) (err error) {
+	upstreamProvider.ID = uuid.New()
+	upstreamProvider.CreatedAt = time.Now()
+	upstreamProvider.UpdatedAt = time.Now()
+	return nil
+}
+
+func (upstreamProvider *UpstreamProvider) BeforeUpdate(tx *gorm.DB) (err error) {
+	upstreamProvider.UpdatedAt = time.Now()
+	return nil
+}
+
+func (UpstreamProvider) TableName() string {
+	return "upstream_providers"
+}
+
+// ScopeList returns the space separated Scopes, openid, profile and email
+// when none are set.
+func (upstreamProvider *UpstreamProvider) ScopeList() []string {
+	if scopes := strings.Fields(upstreamProvider.Scopes); len(scopes) > 0 {
+		return scopes
+	}
+	return []string{"openid", "profile", "email"}
+}
@@ -14,6 +14,9 @@ const (
 	USER_ACTIVE   UserStatus = "ACTIVE"
 	USER_INACTIVE UserStatus = "INACTIVE"
 	USER_PENDING  UserStatus = "PENDING"
+	// USER_AWAITING_APPROVAL accounts were provisioned on a first sign-in
+	// and cannot sign in until an administrator activates them
+	USER_AWAITING_APPROVAL UserStatus = "AWAITING_APPROVAL"
 )
 
 const (
@@ -26,7 +29,7 @@ type User struct {
 	ID              uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
 	EmployeeID      *uuid.UUID  `json:"employee_id" gorm:"type:char(36);default:null"`
 	Employee        *Employee   `json:"employee" gorm:"foreignKey:EmployeeID;references:ID;constraint:OnDelete:CASCADE"`
-	OauthID         string      `json:"oauth_id" gorm:"unique; default:null"`
+	OauthID         string      `json:"oauth_id" gorm:"unique; default:null"` // no longer used, see UserIdentity
 	Username        string      `json:"username" gorm:"unique;not null"`
 	Email           string      `json:"email" gorm:"unique;not null"`
 	Name            string      `json:"name"`
@@ -47,6 +50,9 @@ type User struct {
 	NoKTP           string      `json:"no_ktp" gorm:"default:null"`
 	KTP             string      `json:"ktp" gorm:"type:text;default:null"`
 	Address         string      `json:"address" gorm:"type:text;default:null"`
+	// PasswordChangedAt is when the current password was set, nil for
+	// accounts created before it was tracked
+	PasswordChangedAt *time.Time `json:"password_changed_at" gorm:"default:null"`
 	// DeletedAt       time.Time  `json:"deleted_at" gorm:"index"`
 
 	ChoosedRole         string                 `json:"choosed_role" gorm:"-"`
@@ -56,13 +62,13 @@ type User struct {
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	user.ID = uuid.New()
-	user.CreatedAt = time.Now().Add(time.Hour * 7)
-	user.UpdatedAt = time.Now().Add(time.Hour * 7)
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
 	return nil
 }
 
 func (user *User) BeforeUpdate(tx *gorm.DB) (err error) {
-	user.UpdatedAt = time.Now().Add(time.Hour * 7)
+	user.UpdatedAt = time.Now()
 	return nil
 }
 
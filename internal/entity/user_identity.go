@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentityProvider is the Name of an UpstreamProvider.
+type UserIdentityProvider string
+
+// UserIdentity links an account at an external identity provider to a user.
+// Sign-ins through the provider are matched on Provider and Subject, the
+// stable id of the account there; Email is only the address the provider
+// last reported. A user has at most one identity per provider.
+type UserIdentity struct {
+	ID        uuid.UUID            `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID            `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_user_identities_user_provider"`
+	User      User                 `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Provider  UserIdentityProvider `json:"provider" gorm:"type:varchar(32);not null;uniqueIndex:idx_user_identities_user_provider;uniqueIndex:idx_user_identities_subject"`
+	Subject   string               `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_subject"`
+	Email     string               `json:"email" gorm:"type:varchar(255);default:null"`
+	LinkedAt  time.Time            `json:"linked_at" gorm:"not null"`
+	CreatedAt time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (userIdentity *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	userIdentity.ID = uuid.New()
+	if userIdentity.LinkedAt.IsZero() {
+		userIdentity.LinkedAt = time.Now()
+	}
+	userIdentity.CreatedAt = time.Now()
+	userIdentity.UpdatedAt = time.Now()
+	return nil
+}
+
+func (userIdentity *UserIdentity) BeforeUpdate(tx *gorm.DB) (err error) {
+	userIdentity.UpdatedAt = time.Now()
+	return nil
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserPasswordHistory keeps the hashes of the passwords a user has set, newest
+// first, so the password policy can refuse reusing one of them.
+type UserPasswordHistory struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:char(36);index;not null"`
+	User         *User     `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	PasswordHash string    `json:"-" gorm:"type:varchar(255);not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
+
+func (userPasswordHistory *UserPasswordHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	userPasswordHistory.ID = uuid.New()
+	if userPasswordHistory.CreatedAt.IsZero() {
+		userPasswordHistory.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+func (UserPasswordHistory) TableName() string {
+	return "user_password_histories"
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserRecoveryCode is a one-time code that replaces the authenticator app
+// when it is lost. Only the hash of the code is stored.
+type UserRecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);index;not null"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);unique;not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"default:null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (userRecoveryCode *UserRecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	userRecoveryCode.ID = uuid.New()
+	userRecoveryCode.CreatedAt = time.Now()
+	userRecoveryCode.UpdatedAt = time.Now()
+	return nil
+}
+
+func (userRecoveryCode *UserRecoveryCode) BeforeUpdate(tx *gorm.DB) (err error) {
+	userRecoveryCode.UpdatedAt = time.Now()
+	return nil
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
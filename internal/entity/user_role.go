@@ -17,13 +17,13 @@ type UserRole struct {
 }
 
 func (userRole *UserRole) BeforeCreate() (err error) {
-	userRole.CreatedAt = time.Now().Add(time.Hour * 7)
-	userRole.UpdatedAt = time.Now().Add(time.Hour * 7)
+	userRole.CreatedAt = time.Now()
+	userRole.UpdatedAt = time.Now()
 	return nil
 }
 
 func (userRole *UserRole) BeforeUpdate() (err error) {
-	userRole.UpdatedAt = time.Now().Add(time.Hour * 7)
+	userRole.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSession is a browser session of the web login. The cookie only holds a
+// random token, its hash and the encoded session values live here so sessions
+// can be listed and terminated. Sessions of visitors who have not signed in
+// yet have no UserID.
+type UserSession struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(255);unique;not null"`
+	UserID     *uuid.UUID `json:"user_id" gorm:"type:char(36);default:null;index"`
+	User       *User      `json:"user" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	LoginID    string     `json:"-" gorm:"type:varchar(64);default:null"`
+	Data       string     `json:"-" gorm:"type:text"`
+	Device     string     `json:"device" gorm:"type:varchar(255);default:null"`
+	IPAddress  string     `json:"ip_address" gorm:"type:varchar(45);default:null"`
+	UserAgent  string     `json:"user_agent" gorm:"type:text;default:null"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiredAt  time.Time  `json:"expired_at" gorm:"not null;index"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (userSession *UserSession) BeforeCreate(tx *gorm.DB) (err error) {
+	userSession.ID = uuid.New()
+	userSession.CreatedAt = time.Now()
+	userSession.UpdatedAt = time.Now()
+	return nil
+}
+
+func (userSession *UserSession) BeforeUpdate(tx *gorm.DB) (err error) {
+	userSession.UpdatedAt = time.Now()
+	return nil
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
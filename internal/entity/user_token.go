@@ -11,19 +11,29 @@ type UserTokenType string
 const (
 	UserTokenVerification  UserTokenType = "VERIFICATION"
 	UserTokenResetPassword UserTokenType = "RESET_PASSWORD"
+	UserTokenMagicLink     UserTokenType = "MAGIC_LINK"
 )
 
+// UserToken is a code or link token mailed to a user. Only TokenHash is
+// stored; Token held the plain verification code before codes were hashed and
+// is left at 0. Attempts counts the wrong codes entered for it. Application
+// is the name of the application a magic link signs in to.
 type UserToken struct {
-	Email     string        `json:"email" gorm:"type:varchar(255);not null"`
-	Token     int           `json:"token" gorm:"type:int;not null"`
-	TokenType UserTokenType `json:"token_type" gorm:"not null"`
-	ExpiredAt time.Time     `json:"expired_at"`
-	CreatedAt time.Time     `gorm:"autoCreateTime"`
-	UpdatedAt time.Time     `gorm:"autoUpdateTime"`
+	Email       string        `json:"email" gorm:"type:varchar(255);not null"`
+	Token       int           `json:"-" gorm:"type:int;not null"`
+	TokenHash   string        `json:"-" gorm:"type:varchar(64);index"`
+	TokenType   UserTokenType `json:"token_type" gorm:"not null"`
+	Attempts    int           `json:"attempts" gorm:"not null;default:0"`
+	Application string        `json:"application" gorm:"type:varchar(255)"`
+	ExpiredAt   time.Time     `json:"expired_at"`
+	CreatedAt   time.Time     `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time     `gorm:"autoUpdateTime"`
 }
 
 func (userToken *UserToken) BeforeCreate(tx *gorm.DB) (err error) {
-	userToken.ExpiredAt = time.Now().Add(time.Hour * 3)
+	if userToken.ExpiredAt.IsZero() {
+		userToken.ExpiredAt = time.Now().Add(time.Hour * 3)
+	}
 	userToken.CreatedAt = time.Now()
 	userToken.UpdatedAt = time.Now()
 	return nil
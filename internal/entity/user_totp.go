@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserTOTP is the authenticator app enrolled by a user. It only counts as a
+// second factor once ConfirmedAt is set. LastUsedStep is the last accepted
+// time step, so a code cannot be used twice.
+type UserTOTP struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:char(36);unique;not null"`
+	User         User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Secret       string     `json:"-" gorm:"type:varchar(64);not null"`
+	ConfirmedAt  *time.Time `json:"confirmed_at" gorm:"default:null"`
+	LastUsedStep int64      `json:"-" gorm:"not null"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (userTOTP *UserTOTP) BeforeCreate(tx *gorm.DB) (err error) {
+	userTOTP.ID = uuid.New()
+	userTOTP.CreatedAt = time.Now()
+	userTOTP.UpdatedAt = time.Now()
+	return nil
+}
+
+func (userTOTP *UserTOTP) BeforeUpdate(tx *gorm.DB) (err error) {
+	userTOTP.UpdatedAt = time.Now()
+	return nil
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totps"
+}
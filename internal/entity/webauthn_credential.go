@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredential is a passkey or security key registered by a user.
+// CredentialID is base64url encoded and PublicKey is PKIX DER, base64
+// encoded.
+type WebAuthnCredential struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:char(36);index;not null"`
+	User         User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Name         string     `json:"name" gorm:"type:varchar(100);not null"`
+	CredentialID string     `json:"credential_id" gorm:"type:varchar(512);unique;not null"`
+	PublicKey    string     `json:"-" gorm:"type:text;not null"`
+	Algorithm    int        `json:"algorithm" gorm:"not null"`
+	SignCount    uint32     `json:"sign_count" gorm:"not null"`
+	AAGUID       string     `json:"aaguid" gorm:"type:varchar(36);default:null"`
+	Transports   string     `json:"transports" gorm:"type:varchar(255);default:null"`
+	LastUsedAt   *time.Time `json:"last_used_at" gorm:"default:null"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (webAuthnCredential *WebAuthnCredential) BeforeCreate(tx *gorm.DB) (err error) {
+	webAuthnCredential.ID = uuid.New()
+	webAuthnCredential.CreatedAt = time.Now()
+	webAuthnCredential.UpdatedAt = time.Now()
+	return nil
+}
+
+func (webAuthnCredential *WebAuthnCredential) BeforeUpdate(tx *gorm.DB) (err error) {
+	webAuthnCredential.UpdatedAt = time.Now()
+	return nil
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
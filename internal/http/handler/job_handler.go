@@ -60,18 +60,6 @@ func (h *JobHandler) FindAllPaginated(ctx *gin.Context) {
 		return
 	}
 
-	userFactory := userUsecase.MeUseCaseFactory(h.Log)
-	me, err := userFactory.Execute(&userUsecase.IMeUseCaseRequest{
-		ID:          uuid.MustParse(user["id"].(string)),
-		ChoosedRole: user["choosed_role"].(string),
-	})
-
-	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when finding user by ID: %v", err)
-		return
-	}
-
 	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	if err != nil || page < 1 {
 		page = 1
@@ -104,8 +92,23 @@ func (h *JobHandler) FindAllPaginated(ctx *gin.Context) {
 		organizationId = ""
 	}
 
-	if me.User.Employee.OrganizationID != uuid.Nil {
-		organizationId = me.User.Employee.OrganizationID.String()
+	// client credentials tokens have no employee to scope the jobs to
+	if !middleware.IsClientToken(user) {
+		userFactory := userUsecase.MeUseCaseFactory(h.Log)
+		me, err := userFactory.Execute(&userUsecase.IMeUseCaseRequest{
+			ID:          uuid.MustParse(user["id"].(string)),
+			ChoosedRole: user["choosed_role"].(string),
+		})
+
+		if err != nil {
+			utils.ErrorResponse(ctx, 500, "error", err.Error())
+			h.Log.Errorf("Error when finding user by ID: %v", err)
+			return
+		}
+
+		if me.User.Employee.OrganizationID != uuid.Nil {
+			organizationId = me.User.Employee.OrganizationID.String()
+		}
 	}
 
 	factory := usecase.FindAllPaginatedUseCaseFactory(h.Log)
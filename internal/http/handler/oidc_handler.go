@@ -0,0 +1,619 @@
+package handler
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/middleware"
+	authUsecase "app/go-sso/internal/usecase/auth_token"
+	usecase "app/go-sso/internal/usecase/oidc"
+	userUsecase "app/go-sso/internal/usecase/user"
+	"app/go-sso/utils"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+type IOIDCHandler interface {
+	Discovery(ctx *gin.Context)
+	Authorize(ctx *gin.Context)
+	Token(ctx *gin.Context)
+	UserInfo(ctx *gin.Context)
+	JWKS(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+	Introspect(ctx *gin.Context)
+	DeviceAuthorization(ctx *gin.Context)
+}
+
+type OIDCHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+}
+
+func NewOIDCHandler(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) IOIDCHandler {
+	return &OIDCHandler{
+		Config:   viper,
+		Log:      log,
+		Validate: validate,
+	}
+}
+
+func OIDCHandlerFactory(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) IOIDCHandler {
+	return NewOIDCHandler(viper, log, validate)
+}
+
+func (h *OIDCHandler) issuer() string {
+	return utils.OIDCIssuer(h.Config)
+}
+
+func (h *OIDCHandler) Discovery(ctx *gin.Context) {
+	issuer := h.issuer()
+	ctx.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth2/authorize",
+		"token_endpoint":                        issuer + "/oauth2/token",
+		"userinfo_endpoint":                     issuer + "/oauth2/userinfo",
+		"jwks_uri":                              issuer + "/oauth2/jwks",
+		"revocation_endpoint":                   issuer + "/oauth2/revoke",
+		"introspection_endpoint":                issuer + "/oauth2/introspect",
+		"end_session_endpoint":                  issuer + "/logout",
+		"frontchannel_logout_supported":         true,
+		"frontchannel_logout_session_supported": true,
+		"backchannel_logout_supported":          true,
+		"backchannel_logout_session_supported":  true,
+		"device_authorization_endpoint":         issuer + "/oauth2/device_authorization",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256", "ES256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "sid",
+			"name", "preferred_username", "email", "email_verified", "choosed_role",
+		},
+	})
+}
+
+func (h *OIDCHandler) Authorize(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	if err := ctx.Request.ParseForm(); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
+		return
+	}
+	params := ctx.Request.Form
+
+	validateFactory := usecase.ValidateAuthorizationRequestUseCaseFactory(h.Log)
+	validated, err := validateFactory.Execute(&usecase.IValidateAuthorizationRequestUseCaseRequest{
+		ClientID:            params.Get("client_id"),
+		RedirectURI:         params.Get("redirect_uri"),
+		ResponseType:        params.Get("response_type"),
+		Scope:               params.Get("scope"),
+		CodeChallenge:       params.Get("code_challenge"),
+		CodeChallengeMethod: params.Get("code_challenge_method"),
+	})
+	if err != nil {
+		h.authorizeError(ctx, err, params.Get("state"))
+		return
+	}
+
+	// the user has to sign in (and pick a role) first, the login flow sends
+	// them back here once the pending request is found in the session
+	profile, ok := session.Get("profile").(entity.Profile)
+	roleIDValue, _ := session.Get("choosed_role_id").(string)
+	roleID, roleErr := uuid.Parse(roleIDValue)
+	if !ok || roleErr != nil || profile.EmailVerifiedAt.IsZero() {
+		if params.Get("prompt") == "none" {
+			h.authorizeError(ctx, &usecase.OAuthError{
+				Code:        "login_required",
+				Description: "the user is not signed in",
+				StatusCode:  http.StatusBadRequest,
+				RedirectURI: validated.RedirectURI,
+			}, params.Get("state"))
+			return
+		}
+
+		session.Set("authorize_request", params.Encode())
+		session.Save()
+
+		switch {
+		case !ok:
+			ctx.Redirect(http.StatusFound, "/login")
+		case profile.EmailVerifiedAt.IsZero():
+			ctx.Redirect(http.StatusFound, "/otp")
+		default:
+			ctx.Redirect(http.StatusFound, "/choose-roles")
+		}
+		return
+	}
+
+	session.Delete("authorize_request")
+	sessionID := utils.LoginSessionID(session)
+	session.Save()
+
+	authTime := time.Now()
+	if loggedInAt, ok := session.Get("auth_time").(int64); ok {
+		authTime = time.Unix(loggedInAt, 0)
+	}
+
+	ttl := time.Duration(h.Config.GetInt("oidc.authorization_code_ttl")) * time.Second
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	codeFactory := usecase.CreateAuthorizationCodeUseCaseFactory(h.Log)
+	codeResp, err := codeFactory.Execute(&usecase.ICreateAuthorizationCodeUseCaseRequest{
+		ApplicationID:       validated.Application.ID,
+		UserID:              profile.ID,
+		RoleID:              roleID,
+		RedirectURI:         validated.RedirectURI,
+		RedirectURIExplicit: params.Get("redirect_uri") != "",
+		Scope:               params.Get("scope"),
+		Nonce:               params.Get("nonce"),
+		CodeChallenge:       params.Get("code_challenge"),
+		CodeChallengeMethod: params.Get("code_challenge_method"),
+		AuthTime:            authTime,
+		SessionID:           sessionID,
+		TTL:                 ttl,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when creating authorization code: %v", err)
+		h.authorizeError(ctx, &usecase.OAuthError{
+			Code:        "server_error",
+			Description: "failed to create authorization code",
+			StatusCode:  http.StatusInternalServerError,
+			RedirectURI: validated.RedirectURI,
+		}, params.Get("state"))
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, buildRedirectURL(validated.RedirectURI, map[string]string{
+		"code":  codeResp.Code,
+		"state": params.Get("state"),
+	}))
+}
+
+func (h *OIDCHandler) Token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	clientID, clientSecret := clientCredentials(ctx)
+
+	switch ctx.PostForm("grant_type") {
+	case "authorization_code":
+		factory := usecase.ExchangeAuthorizationCodeUseCaseFactory(h.Log)
+		resp, err := factory.Execute(&usecase.IExchangeAuthorizationCodeUseCaseRequest{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Code:         ctx.PostForm("code"),
+			RedirectURI:  ctx.PostForm("redirect_uri"),
+			CodeVerifier: ctx.PostForm("code_verifier"),
+		})
+		if err != nil {
+			h.tokenError(ctx, err)
+			return
+		}
+
+		h.issueTokens(ctx, resp.User, resp.Application, resp.Scope, resp.Nonce, resp.AuthTime, resp.SessionID, resp.GrantID)
+	case "refresh_token":
+		// public clients have no secret, the refresh token has to belong to
+		// the family issued to them instead
+		client, ok := h.identifyClient(ctx, true)
+		if !ok {
+			return
+		}
+
+		factory := authUsecase.RotateRefreshTokenUseCaseFactory(h.Log)
+		resp, err := factory.Execute(&authUsecase.IRotateRefreshTokenUseCaseRequest{
+			RefreshToken:  ctx.PostForm("refresh_token"),
+			ApplicationID: &client.ID,
+			TTL:           h.refreshTokenTTL(),
+		})
+		if err != nil {
+			if errors.Is(err, authUsecase.ErrInvalidRefreshToken) {
+				err = usecase.NewOAuthError("invalid_grant", err.Error(), http.StatusBadRequest)
+			}
+			h.tokenError(ctx, err)
+			return
+		}
+
+		accessToken, err := utils.GenerateApplicationToken(resp.User, client, "")
+		if err != nil {
+			h.Log.Errorf("Error when generating token: %v", err)
+			h.tokenError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token":  accessToken,
+			"token_type":    "Bearer",
+			"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+			"refresh_token": resp.RefreshToken,
+			"scope":         resp.AuthToken.Scope,
+		})
+	case "client_credentials":
+		factory := usecase.IssueClientCredentialsTokenUseCaseFactory(h.Log)
+		resp, err := factory.Execute(&usecase.IIssueClientCredentialsTokenUseCaseRequest{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scope:        ctx.PostForm("scope"),
+			TTL:          utils.AccessTokenTTL(),
+		})
+		if err != nil {
+			h.tokenError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token": resp.AccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   resp.ExpiresIn,
+			"scope":        resp.Scope,
+		})
+	case deviceCodeGrantType:
+		factory := usecase.ExchangeDeviceCodeUseCaseFactory(h.Log)
+		resp, err := factory.Execute(&usecase.IExchangeDeviceCodeUseCaseRequest{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			DeviceCode:   ctx.PostForm("device_code"),
+		})
+		if err != nil {
+			h.tokenError(ctx, err)
+			return
+		}
+
+		h.issueTokens(ctx, resp.User, resp.Application, resp.Scope, "", resp.AuthTime, "", uuid.Nil)
+	case "":
+		h.tokenError(ctx, usecase.NewOAuthError("invalid_request", "grant_type is required", http.StatusBadRequest))
+	default:
+		h.tokenError(ctx, usecase.NewOAuthError("unsupported_grant_type", "grant_type is not supported", http.StatusBadRequest))
+	}
+}
+
+// DeviceAuthorization starts an RFC 8628 device authorization. The device
+// shows the user code and polls the token endpoint with the device code until
+// the user approves it on the /device page.
+func (h *OIDCHandler) DeviceAuthorization(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+
+	clientID, clientSecret := clientCredentials(ctx)
+
+	ttl := time.Duration(h.Config.GetInt("oidc.device_code_ttl")) * time.Second
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	interval := time.Duration(h.Config.GetInt("oidc.device_poll_interval")) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	factory := usecase.CreateDeviceAuthorizationUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.ICreateDeviceAuthorizationUseCaseRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        ctx.PostForm("scope"),
+		TTL:          ttl,
+		Interval:     interval,
+	})
+	if err != nil {
+		h.tokenError(ctx, err)
+		return
+	}
+
+	verificationURI := h.issuer() + "/device"
+	ctx.JSON(http.StatusOK, gin.H{
+		"device_code":               resp.DeviceCode,
+		"user_code":                 resp.UserCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?user_code=" + url.QueryEscape(resp.UserCode),
+		"expires_in":                resp.ExpiresIn,
+		"interval":                  resp.Interval,
+	})
+}
+
+func (h *OIDCHandler) UserInfo(ctx *gin.Context) {
+	claims, err := middleware.GetUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": err.Error()})
+		return
+	}
+
+	idClaim, _ := claims["id"].(string)
+	userID, err := uuid.Parse(idClaim)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": "token has no subject"})
+		return
+	}
+
+	factory := userUsecase.FindByIdOnlyUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&userUsecase.IFindByIdOnlyUseCaseRequest{
+		ID: userID,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when finding user by ID: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	if resp.User == nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": "user not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sub":                resp.User.ID.String(),
+		"name":               resp.User.Name,
+		"preferred_username": resp.User.Username,
+		"email":              resp.User.Email,
+		"email_verified":     !resp.User.EmailVerifiedAt.IsZero(),
+		"choosed_role":       claims["choosed_role"],
+	})
+}
+
+func (h *OIDCHandler) JWKS(ctx *gin.Context) {
+	jwks, err := utils.GetJSONWebKeySet()
+	if err != nil {
+		h.Log.Errorf("Error when building JWKS: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwks)
+}
+
+// Revoke implements RFC 7009 token revocation for refresh and access tokens.
+func (h *OIDCHandler) Revoke(ctx *gin.Context) {
+	client, ok := h.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	if ctx.PostForm("token") == "" {
+		h.tokenError(ctx, usecase.NewOAuthError("invalid_request", "token is required", http.StatusBadRequest))
+		return
+	}
+
+	factory := authUsecase.RevokeTokenUseCaseFactory(h.Log)
+	_, err := factory.Execute(&authUsecase.IRevokeTokenUseCaseRequest{
+		Token:         ctx.PostForm("token"),
+		TokenTypeHint: ctx.PostForm("token_type_hint"),
+		ApplicationID: &client.ID,
+		ClientID:      client.Name,
+	})
+	if errors.Is(err, authUsecase.ErrTokenNotRevocable) {
+		err = usecase.NewOAuthError("unsupported_token_type", err.Error(), http.StatusBadRequest)
+	}
+	if err != nil {
+		h.tokenError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// Introspect implements RFC 7662 token introspection.
+func (h *OIDCHandler) Introspect(ctx *gin.Context) {
+	client, ok := h.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	if ctx.PostForm("token") == "" {
+		h.tokenError(ctx, usecase.NewOAuthError("invalid_request", "token is required", http.StatusBadRequest))
+		return
+	}
+
+	factory := authUsecase.IntrospectTokenUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&authUsecase.IIntrospectTokenUseCaseRequest{
+		Token:         ctx.PostForm("token"),
+		TokenTypeHint: ctx.PostForm("token_type_hint"),
+		ApplicationID: &client.ID,
+	})
+	if err != nil {
+		h.tokenError(ctx, err)
+		return
+	}
+
+	data := gin.H{"active": resp.Active}
+	if resp.Active {
+		for key, value := range resp.Claims {
+			if value != nil && value != "" {
+				data[key] = value
+			}
+		}
+		if resp.Claims["token_type"] == "refresh_token" {
+			data["client_id"] = client.Name
+		}
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (h *OIDCHandler) authenticateClient(ctx *gin.Context) (*entity.Application, bool) {
+	return h.identifyClient(ctx, false)
+}
+
+// identifyClient authenticates the calling client, public applications
+// included when allowPublic is set.
+func (h *OIDCHandler) identifyClient(ctx *gin.Context, allowPublic bool) (*entity.Application, bool) {
+	clientID, clientSecret := clientCredentials(ctx)
+	factory := usecase.AuthenticateClientUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IAuthenticateClientUseCaseRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AllowPublic:  allowPublic,
+	})
+	if err != nil {
+		h.tokenError(ctx, err)
+		return nil, false
+	}
+	return resp.Application, true
+}
+
+// issueTokens answers a token request with a new access, ID and refresh token.
+// A non-nil grantID names the refresh family and the jti of the access token,
+// so replaying the grant revokes both.
+func (h *OIDCHandler) issueTokens(ctx *gin.Context, user *entity.User, application *entity.Application, scope string, nonce string, authTime time.Time, sessionID string, grantID uuid.UUID) {
+	grantJTI := ""
+	if grantID != uuid.Nil {
+		grantJTI = grantID.String()
+	}
+	accessToken, err := utils.GenerateApplicationToken(user, application, grantJTI)
+	if err != nil {
+		h.Log.Errorf("Error when generating token: %v", err)
+		h.tokenError(ctx, err)
+		return
+	}
+
+	ttl := time.Duration(h.Config.GetInt("oidc.id_token_ttl")) * time.Second
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":          h.issuer(),
+		"sub":          user.ID.String(),
+		"aud":          application.Name,
+		"exp":          now.Add(ttl).Unix(),
+		"iat":          now.Unix(),
+		"auth_time":    authTime.Unix(),
+		"choosed_role": user.Roles[0].Name,
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+	if usecase.HasScope(scope, "profile") {
+		claims["name"] = user.Name
+		claims["preferred_username"] = user.Username
+	}
+	if usecase.HasScope(scope, "email") {
+		claims["email"] = user.Email
+		claims["email_verified"] = !user.EmailVerifiedAt.IsZero()
+	}
+
+	idToken, err := utils.GenerateIDToken(claims)
+	if err != nil {
+		h.Log.Errorf("Error when generating id token: %v", err)
+		h.tokenError(ctx, err)
+		return
+	}
+
+	refreshFactory := authUsecase.IssueRefreshTokenUseCaseFactory(h.Log)
+	refreshResp, err := refreshFactory.Execute(&authUsecase.IIssueRefreshTokenUseCaseRequest{
+		UserID:        user.ID,
+		RoleID:        user.Roles[0].ID,
+		ApplicationID: &application.ID,
+		Scope:         scope,
+		TTL:           h.refreshTokenTTL(),
+		FamilyID:      grantID,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when storing refresh token: %v", err)
+		h.tokenError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token": refreshResp.RefreshToken,
+		"id_token":      idToken,
+		"scope":         scope,
+	})
+}
+
+func (h *OIDCHandler) refreshTokenTTL() time.Duration {
+	ttl := time.Duration(h.Config.GetInt("jwt.refresh_token_ttl")) * time.Second
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return ttl
+}
+
+func (h *OIDCHandler) authorizeError(ctx *gin.Context, err error, state string) {
+	var oauthErr *usecase.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.Log.Errorf("Error when authorizing: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		return
+	}
+
+	if oauthErr.RedirectURI == "" {
+		h.Log.Warnf("Rejected authorization request: %v", oauthErr)
+		utils.ErrorResponse(ctx, oauthErr.StatusCode, oauthErr.Code, oauthErr.Description)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, buildRedirectURL(oauthErr.RedirectURI, map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"state":             state,
+	}))
+}
+
+func (h *OIDCHandler) tokenError(ctx *gin.Context, err error) {
+	var oauthErr *usecase.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.Log.Errorf("Error when issuing token: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	if oauthErr.StatusCode == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Basic realm="go-sso"`)
+	}
+	ctx.JSON(oauthErr.StatusCode, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+// clientCredentials reads client_secret_basic credentials and falls back to
+// client_secret_post form fields.
+func clientCredentials(ctx *gin.Context) (string, string) {
+	if clientID, clientSecret, ok := ctx.Request.BasicAuth(); ok {
+		if decoded, err := url.QueryUnescape(clientID); err == nil {
+			clientID = decoded
+		}
+		if decoded, err := url.QueryUnescape(clientSecret); err == nil {
+			clientSecret = decoded
+		}
+		return clientID, clientSecret
+	}
+	return ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+}
+
+func buildRedirectURL(redirectURI string, params map[string]string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -217,16 +217,27 @@ func (h *OrganizationHandler) FindOrganizationStructurePaginated(ctx *gin.Contex
 		return
 	}
 
-	userFactory := userUsecase.MeUseCaseFactory(h.log)
-	resp, err := userFactory.Execute(&userUsecase.IMeUseCaseRequest{
-		ID:          uuid.MustParse(user["id"].(string)),
-		ChoosedRole: user["choosed_role"].(string),
-	})
-
-	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.log.Errorf("Error when finding user by ID: %v", err)
-		return
+	// client credentials tokens have no employee, so they name the organization
+	var organizationID uuid.UUID
+	if middleware.IsClientToken(user) {
+		organizationID, err = uuid.Parse(ctx.Query("organization_id"))
+		if err != nil {
+			utils.ErrorResponse(ctx, 400, "error", "organization_id is required")
+			return
+		}
+	} else {
+		userFactory := userUsecase.MeUseCaseFactory(h.log)
+		resp, err := userFactory.Execute(&userUsecase.IMeUseCaseRequest{
+			ID:          uuid.MustParse(user["id"].(string)),
+			ChoosedRole: user["choosed_role"].(string),
+		})
+
+		if err != nil {
+			utils.ErrorResponse(ctx, 500, "error", err.Error())
+			h.log.Errorf("Error when finding user by ID: %v", err)
+			return
+		}
+		organizationID = resp.User.Employee.OrganizationID
 	}
 
 	factory := structureUsecase.FindAllPaginatedUseCaseFactory(h.log)
@@ -234,7 +245,7 @@ func (h *OrganizationHandler) FindOrganizationStructurePaginated(ctx *gin.Contex
 		Page:           page,
 		PageSize:       pageSize,
 		Search:         search,
-		OrganizationID: resp.User.Employee.OrganizationID,
+		OrganizationID: organizationID,
 		Filter:         filter,
 	})
 	if err != nil {
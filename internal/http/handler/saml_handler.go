@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/middleware"
+	"app/go-sso/internal/http/request"
+	usecase "app/go-sso/internal/usecase/saml"
+	"app/go-sso/utils"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// pendingSAMLRequestTTL is how long a SAML login may wait for the user to sign
+// in and pick a role.
+const pendingSAMLRequestTTL = 15 * time.Minute
+
+type ISAMLHandler interface {
+	Metadata(ctx *gin.Context)
+	SSO(ctx *gin.Context)
+	ResumeSSO(ctx *gin.Context)
+	IDPInitiated(ctx *gin.Context)
+	ImportServiceProvider(ctx *gin.Context)
+}
+
+type SAMLHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+
+	idpOnce sync.Once
+	idp     *saml.IdentityProvider
+	idpErr  error
+}
+
+// pendingSAMLRequest is kept in the session while the user signs in. It holds
+// either the service provider's AuthnRequest or, for logins started from the
+// portal, the application name.
+type pendingSAMLRequest struct {
+	SAMLRequest string `json:"saml_request,omitempty"`
+	Application string `json:"application,omitempty"`
+	RelayState  string `json:"relay_state,omitempty"`
+	ReceivedAt  int64  `json:"received_at"`
+}
+
+type samlContextKey struct{}
+
+func NewSAMLHandler(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) ISAMLHandler {
+	return &SAMLHandler{
+		Config:   viper,
+		Log:      log,
+		Validate: validate,
+	}
+}
+
+func SAMLHandlerFactory(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) ISAMLHandler {
+	return NewSAMLHandler(viper, log, validate)
+}
+
+// identityProvider builds the SAML identity provider on first use, so a missing
+// signing certificate only breaks the SAML endpoints.
+func (h *SAMLHandler) identityProvider() (*saml.IdentityProvider, error) {
+	h.idpOnce.Do(func() {
+		key, certificate, err := utils.LoadSAMLCertificate(h.Config)
+		if err != nil {
+			h.idpErr = err
+			return
+		}
+
+		issuer := utils.OIDCIssuer(h.Config)
+		metadataURL, err := url.Parse(issuer + "/saml/metadata")
+		if err != nil {
+			h.idpErr = err
+			return
+		}
+		ssoURL, err := url.Parse(issuer + "/saml/sso")
+		if err != nil {
+			h.idpErr = err
+			return
+		}
+
+		validDuration := time.Duration(h.Config.GetInt("saml.assertion_ttl")) * time.Second
+		if validDuration <= 0 {
+			validDuration = saml.DefaultValidDuration
+		}
+
+		h.idp = &saml.IdentityProvider{
+			Key:                     key,
+			Logger:                  h.Log,
+			Certificate:             certificate,
+			MetadataURL:             *metadataURL,
+			SSOURL:                  *ssoURL,
+			ServiceProviderProvider: h,
+			SessionProvider:         h,
+			AssertionMaker:          h,
+			ValidDuration:           &validDuration,
+		}
+	})
+	return h.idp, h.idpErr
+}
+
+func (h *SAMLHandler) withIdentityProvider(ctx *gin.Context) (*saml.IdentityProvider, bool) {
+	idp, err := h.identityProvider()
+	if err != nil {
+		h.Log.Errorf("Error when loading the SAML identity provider: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", "SAML is not available")
+		return nil, false
+	}
+	// the crewjam callbacks only get the *http.Request, carry the gin context along
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), samlContextKey{}, ctx))
+	return idp, true
+}
+
+func (h *SAMLHandler) Metadata(ctx *gin.Context) {
+	idp, ok := h.withIdentityProvider(ctx)
+	if !ok {
+		return
+	}
+	idp.ServeMetadata(ctx.Writer, ctx.Request)
+}
+
+// SSO receives SP-initiated AuthnRequests over the HTTP-Redirect and HTTP-POST
+// bindings.
+func (h *SAMLHandler) SSO(ctx *gin.Context) {
+	idp, ok := h.withIdentityProvider(ctx)
+	if !ok {
+		return
+	}
+	idp.ServeSSO(ctx.Writer, ctx.Request)
+}
+
+// ResumeSSO answers the SAML login that was waiting in the session once the
+// user has signed in and picked a role.
+func (h *SAMLHandler) ResumeSSO(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	raw, ok := session.Get("saml_request").(string)
+	session.Delete("saml_request")
+	session.Save()
+	if !ok {
+		ctx.Redirect(http.StatusFound, "/portal")
+		return
+	}
+
+	pending := new(pendingSAMLRequest)
+	if err := json.Unmarshal([]byte(raw), pending); err != nil || time.Unix(pending.ReceivedAt, 0).Add(pendingSAMLRequestTTL).Before(time.Now()) {
+		session.Set("error", "The sign in request has expired, please try again from the application")
+		session.Save()
+		ctx.Redirect(http.StatusFound, "/portal")
+		return
+	}
+
+	idp, ok := h.withIdentityProvider(ctx)
+	if !ok {
+		return
+	}
+
+	if pending.Application != "" {
+		h.serveIDPInitiated(ctx, idp, pending.Application, pending.RelayState)
+		return
+	}
+
+	requestBuffer, err := base64.StdEncoding.DecodeString(pending.SAMLRequest)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
+		return
+	}
+
+	// the request was checked against the time it arrived, the assertion is
+	// issued for now
+	req := &saml.IdpAuthnRequest{
+		IDP:           idp,
+		HTTPRequest:   ctx.Request,
+		RelayState:    pending.RelayState,
+		RequestBuffer: requestBuffer,
+		Now:           time.Unix(pending.ReceivedAt, 0),
+	}
+	if err := req.Validate(); err != nil {
+		h.Log.Errorf("Error when validating the SAML request: %v", err)
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
+		return
+	}
+	req.Now = saml.TimeNow()
+
+	samlSession := h.GetSession(ctx.Writer, ctx.Request, req)
+	if samlSession == nil {
+		return
+	}
+	if err := h.MakeAssertion(req, samlSession); err != nil {
+		h.Log.Errorf("Error when making the SAML assertion: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		return
+	}
+	if err := req.WriteResponse(ctx.Writer); err != nil {
+		h.Log.Errorf("Error when writing the SAML response: %v", err)
+	}
+}
+
+// IDPInitiated signs the user in to a SAML application from the portal.
+func (h *SAMLHandler) IDPInitiated(ctx *gin.Context) {
+	idp, ok := h.withIdentityProvider(ctx)
+	if !ok {
+		return
+	}
+	h.serveIDPInitiated(ctx, idp, ctx.Param("application"), ctx.Query("RelayState"))
+}
+
+func (h *SAMLHandler) serveIDPInitiated(ctx *gin.Context, idp *saml.IdentityProvider, application string, relayState string) {
+	factory := usecase.FindServiceProviderUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IFindServiceProviderUseCaseRequest{
+		ApplicationName: application,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		return
+	}
+	if resp.ServiceProvider == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "error", "The application does not use SAML")
+		return
+	}
+
+	idp.ServeIDPInitiated(ctx.Writer, ctx.Request, resp.ServiceProvider.EntityID, relayState)
+}
+
+func (h *SAMLHandler) ImportServiceProvider(ctx *gin.Context) {
+	middleware.PermissionApiMiddleware("update-client")(ctx)
+	if denied, exists := ctx.Get("permission_denied"); exists && denied.(bool) {
+		h.Log.Errorf("Permission denied")
+		return
+	}
+
+	payload := new(request.ImportSAMLServiceProviderRequest)
+	if err := ctx.ShouldBindJSON(payload); err != nil {
+		utils.BadRequestResponse(ctx, err.Error(), nil)
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		utils.BadRequestResponse(ctx, err.Error(), nil)
+		return
+	}
+
+	idpInitiated := true
+	if payload.IDPInitiated != nil {
+		idpInitiated = *payload.IDPInitiated
+	}
+
+	factory := usecase.ImportServiceProviderUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IImportServiceProviderUseCaseRequest{
+		ApplicationName:  ctx.Param("name"),
+		Metadata:         payload.Metadata,
+		MetadataURL:      payload.MetadataURL,
+		NameIDFormat:     payload.NameIDFormat,
+		AttributeMapping: payload.AttributeMapping,
+		IDPInitiated:     idpInitiated,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when importing the SAML metadata: %v", err)
+		utils.BadRequestResponse(ctx, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "SAML service provider saved", resp.ServiceProvider)
+}
+
+// GetServiceProvider implements saml.ServiceProviderProvider.
+func (h *SAMLHandler) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	factory := usecase.FindServiceProviderUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IFindServiceProviderUseCaseRequest{
+		EntityID: serviceProviderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.ServiceProvider == nil {
+		return nil, os.ErrNotExist
+	}
+	return resp.Metadata, nil
+}
+
+// GetSession implements saml.SessionProvider. Users without a session (or
+// without a chosen role) are sent through the web login, which comes back to
+// /saml/sso/resume.
+func (h *SAMLHandler) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	ctx := r.Context().Value(samlContextKey{}).(*gin.Context)
+	session := sessions.Default(ctx)
+
+	profile, ok := session.Get("profile").(entity.Profile)
+	roleIDValue, _ := session.Get("choosed_role_id").(string)
+	if _, roleErr := uuid.Parse(roleIDValue); ok && roleErr == nil && !profile.EmailVerifiedAt.IsZero() {
+		return &saml.Session{
+			ID:         uuid.New().String(),
+			CreateTime: req.Now,
+			ExpireTime: req.Now.Add(*req.IDP.ValidDuration),
+			Index:      uuid.New().String(),
+			SubjectID:  profile.ID.String(),
+		}
+	}
+
+	pending := pendingSAMLRequest{
+		RelayState: req.RelayState,
+		ReceivedAt: req.Now.Unix(),
+	}
+	if len(req.RequestBuffer) > 0 {
+		pending.SAMLRequest = base64.StdEncoding.EncodeToString(req.RequestBuffer)
+	} else {
+		pending.Application = ctx.Param("application")
+	}
+	encoded, err := json.Marshal(pending)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	session.Set("saml_request", string(encoded))
+	session.Save()
+
+	switch {
+	case !ok:
+		ctx.Redirect(http.StatusFound, "/login")
+	case profile.EmailVerifiedAt.IsZero():
+		ctx.Redirect(http.StatusFound, "/otp")
+	default:
+		ctx.Redirect(http.StatusFound, "/choose-roles")
+	}
+	return nil
+}
+
+// MakeAssertion implements saml.AssertionMaker. The subject and attributes come
+// from the attribute mapping of the service provider, the signing is left to
+// the default assertion maker.
+func (h *SAMLHandler) MakeAssertion(req *saml.IdpAuthnRequest, samlSession *saml.Session) error {
+	ctx := req.HTTPRequest.Context().Value(samlContextKey{}).(*gin.Context)
+	session := sessions.Default(ctx)
+	roleIDValue, _ := session.Get("choosed_role_id").(string)
+	roleID, err := uuid.Parse(roleIDValue)
+	if err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(samlSession.SubjectID)
+	if err != nil {
+		return err
+	}
+
+	findFactory := usecase.FindServiceProviderUseCaseFactory(h.Log)
+	found, err := findFactory.Execute(&usecase.IFindServiceProviderUseCaseRequest{
+		EntityID: req.ServiceProviderMetadata.EntityID,
+	})
+	if err != nil {
+		return err
+	}
+	if found.ServiceProvider == nil {
+		return os.ErrNotExist
+	}
+
+	buildFactory := usecase.BuildAssertionAttributesUseCaseFactory(h.Log)
+	resp, err := buildFactory.Execute(&usecase.IBuildAssertionAttributesUseCaseRequest{
+		UserID:          userID,
+		RoleID:          roleID,
+		ServiceProvider: found.ServiceProvider,
+	})
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(resp.Attributes))
+	for name := range resp.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	samlSession.NameID = resp.NameID
+	samlSession.NameIDFormat = resp.NameIDFormat
+	samlSession.CustomAttributes = nil
+	for _, name := range names {
+		samlSession.CustomAttributes = append(samlSession.CustomAttributes, saml.Attribute{
+			Name:       name,
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+			Values: []saml.AttributeValue{{
+				Type:  "xs:string",
+				Value: resp.Attributes[name],
+			}},
+		})
+	}
+
+	return saml.DefaultAssertionMaker{}.MakeAssertion(req, samlSession)
+}
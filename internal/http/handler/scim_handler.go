@@ -0,0 +1,493 @@
+package handler
+
+import (
+	"app/go-sso/internal/http/middleware"
+	"app/go-sso/internal/http/request"
+	usecase "app/go-sso/internal/usecase/scim"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// SCIM_CONTENT_TYPE is the media type of SCIM requests and responses.
+const SCIM_CONTENT_TYPE = "application/scim+json"
+
+type ISCIMHandler interface {
+	ServiceProviderConfig(ctx *gin.Context)
+	Schemas(ctx *gin.Context)
+	Schema(ctx *gin.Context)
+	ResourceTypes(ctx *gin.Context)
+	ResourceType(ctx *gin.Context)
+	ListUsers(ctx *gin.Context)
+	GetUser(ctx *gin.Context)
+	CreateUser(ctx *gin.Context)
+	ReplaceUser(ctx *gin.Context)
+	PatchUser(ctx *gin.Context)
+	DeleteUser(ctx *gin.Context)
+	ListGroups(ctx *gin.Context)
+	GetGroup(ctx *gin.Context)
+	CreateGroup(ctx *gin.Context)
+	ReplaceGroup(ctx *gin.Context)
+	PatchGroup(ctx *gin.Context)
+	DeleteGroup(ctx *gin.Context)
+}
+
+type SCIMHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+}
+
+func NewSCIMHandler(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) ISCIMHandler {
+	return &SCIMHandler{
+		Config:   viper,
+		Log:      log,
+		Validate: validate,
+	}
+}
+
+func SCIMHandlerFactory(viper *viper.Viper, log *logrus.Logger, validate *validator.Validate) ISCIMHandler {
+	return NewSCIMHandler(viper, log, validate)
+}
+
+func (h *SCIMHandler) baseURL() string {
+	return strings.TrimRight(h.Config.GetString("app.url"), "/") + "/scim/v2"
+}
+
+// respond writes body as SCIM JSON.
+func (h *SCIMHandler) respond(ctx *gin.Context, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	ctx.Data(status, SCIM_CONTENT_TYPE, data)
+}
+
+// scimError writes err as a SCIM error message, RFC 7644 section 3.12. Errors
+// that are not a SCIMError are internal ones.
+func (h *SCIMHandler) scimError(ctx *gin.Context, err error) {
+	var scimErr *usecase.SCIMError
+	if !errors.As(err, &scimErr) {
+		h.Log.Errorf("Error in SCIM request: %v", err)
+		scimErr = usecase.NewSCIMError(http.StatusInternalServerError, "", "internal server error")
+	}
+	body := map[string]interface{}{
+		"schemas": []string{usecase.SCHEMA_ERROR},
+		"status":  strconv.Itoa(scimErr.Status),
+		"detail":  scimErr.Detail,
+	}
+	if scimErr.ScimType != "" {
+		body["scimType"] = scimErr.ScimType
+	}
+	data, _ := json.Marshal(body)
+	ctx.Data(scimErr.Status, SCIM_CONTENT_TYPE, data)
+}
+
+// authorize checks the permission of the token, read-scim for reads and
+// write-scim for changes, and writes a SCIM error when it is missing.
+func (h *SCIMHandler) authorize(ctx *gin.Context, permission string) bool {
+	if middleware.HasApiPermission(ctx, permission) {
+		return true
+	}
+	h.Log.Errorf("Permission denied")
+	h.scimError(ctx, usecase.NewSCIMError(http.StatusForbidden, "", "You don't have permission to access this resource"))
+	return false
+}
+
+// bind decodes the SCIM JSON body into payload.
+func (h *SCIMHandler) bind(ctx *gin.Context, payload interface{}) bool {
+	if err := json.NewDecoder(ctx.Request.Body).Decode(payload); err != nil {
+		h.Log.Errorf("Error when binding request: %v", err)
+		h.scimError(ctx, usecase.NewSCIMError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return false
+	}
+	return true
+}
+
+func (h *SCIMHandler) bindPatch(ctx *gin.Context) ([]usecase.PatchOperation, bool) {
+	payload := new(request.SCIMPatchRequest)
+	if !h.bind(ctx, payload) {
+		return nil, false
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		h.Log.Errorf("Error when validating request: %v", err)
+		h.scimError(ctx, usecase.NewSCIMError(http.StatusBadRequest, "invalidValue", err.Error()))
+		return nil, false
+	}
+	operations := make([]usecase.PatchOperation, 0, len(payload.Operations))
+	for _, operation := range payload.Operations {
+		operations = append(operations, usecase.PatchOperation{
+			Op:    operation.Op,
+			Path:  operation.Path,
+			Value: operation.Value,
+		})
+	}
+	return operations, true
+}
+
+// listQuery reads the filter, sort and page parameters of RFC 7644 section
+// 3.4.2. A missing count asks for the default page.
+func (h *SCIMHandler) listQuery(ctx *gin.Context) (usecase.ListQuery, error) {
+	query := usecase.ListQuery{
+		Filter:     ctx.Query("filter"),
+		SortBy:     ctx.Query("sortBy"),
+		Descending: strings.EqualFold(ctx.Query("sortOrder"), "descending"),
+		StartIndex: 1,
+		Count:      -1,
+	}
+	if startIndex := ctx.Query("startIndex"); startIndex != "" {
+		value, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return query, usecase.NewSCIMError(http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+		}
+		query.StartIndex = value
+	}
+	if count := ctx.Query("count"); count != "" {
+		value, err := strconv.Atoi(count)
+		if err != nil {
+			return query, usecase.NewSCIMError(http.StatusBadRequest, "invalidValue", "count must be an integer")
+		}
+		if value < 0 {
+			value = 0
+		}
+		query.Count = value
+	}
+	return query, nil
+}
+
+// respondResource writes a resource with the attributes the query asks for.
+func (h *SCIMHandler) respondResource(ctx *gin.Context, status int, resource interface{}, coreSchema string) {
+	projected, err := usecase.Project(resource, ctx.Query("attributes"), ctx.Query("excludedAttributes"), coreSchema)
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respond(ctx, status, projected)
+}
+
+func (h *SCIMHandler) respondList(ctx *gin.Context, totalResults int64, startIndex int, resources []interface{}, coreSchema string) {
+	projected := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		document, err := usecase.Project(resource, ctx.Query("attributes"), ctx.Query("excludedAttributes"), coreSchema)
+		if err != nil {
+			h.scimError(ctx, err)
+			return
+		}
+		projected = append(projected, document)
+	}
+	h.respond(ctx, http.StatusOK, usecase.ListResponse{
+		Schemas:      []string{usecase.SCHEMA_LIST_RESPONSE},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(projected),
+		Resources:    projected,
+	})
+}
+
+func (h *SCIMHandler) ServiceProviderConfig(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	h.respond(ctx, http.StatusOK, usecase.ServiceProviderConfig(h.baseURL()))
+}
+
+func (h *SCIMHandler) Schemas(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	schemas := usecase.Schemas(h.baseURL())
+	resources := make([]interface{}, 0, len(schemas))
+	for _, schema := range schemas {
+		resources = append(resources, schema)
+	}
+	h.respond(ctx, http.StatusOK, usecase.ListResponse{
+		Schemas:      []string{usecase.SCHEMA_LIST_RESPONSE},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (h *SCIMHandler) Schema(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	schema := usecase.FindSchema(h.baseURL(), ctx.Param("id"))
+	if schema == nil {
+		h.scimError(ctx, usecase.NewSCIMError(http.StatusNotFound, "", "Schema "+ctx.Param("id")+" not found"))
+		return
+	}
+	h.respond(ctx, http.StatusOK, schema)
+}
+
+func (h *SCIMHandler) ResourceTypes(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	resourceTypes := usecase.ResourceTypes(h.baseURL())
+	resources := make([]interface{}, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		resources = append(resources, resourceType)
+	}
+	h.respond(ctx, http.StatusOK, usecase.ListResponse{
+		Schemas:      []string{usecase.SCHEMA_LIST_RESPONSE},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (h *SCIMHandler) ResourceType(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	resourceType := usecase.FindResourceType(h.baseURL(), ctx.Param("id"))
+	if resourceType == nil {
+		h.scimError(ctx, usecase.NewSCIMError(http.StatusNotFound, "", "ResourceType "+ctx.Param("id")+" not found"))
+		return
+	}
+	h.respond(ctx, http.StatusOK, resourceType)
+}
+
+func (h *SCIMHandler) ListUsers(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	query, err := h.listQuery(ctx)
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	factory := usecase.ListUsersUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IListUsersUseCaseRequest{
+		Query:   query,
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	resources := make([]interface{}, 0, len(response.Users))
+	for _, user := range response.Users {
+		resources = append(resources, user)
+	}
+	h.respondList(ctx, response.TotalResults, response.StartIndex, resources, usecase.SCHEMA_USER)
+}
+
+func (h *SCIMHandler) GetUser(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	factory := usecase.FindUserUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IFindUserUseCaseRequest{
+		ID:      ctx.Param("id"),
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.User, usecase.SCHEMA_USER)
+}
+
+func (h *SCIMHandler) CreateUser(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	payload := new(usecase.UserResource)
+	if !h.bind(ctx, payload) {
+		return
+	}
+	factory := usecase.CreateUserUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.ICreateUserUseCaseRequest{
+		User:    payload,
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	ctx.Header("Location", response.User.Meta.Location)
+	h.respondResource(ctx, http.StatusCreated, response.User, usecase.SCHEMA_USER)
+}
+
+func (h *SCIMHandler) ReplaceUser(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	payload := new(usecase.UserResource)
+	if !h.bind(ctx, payload) {
+		return
+	}
+	factory := usecase.ReplaceUserUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IReplaceUserUseCaseRequest{
+		ID:      ctx.Param("id"),
+		User:    payload,
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.User, usecase.SCHEMA_USER)
+}
+
+func (h *SCIMHandler) PatchUser(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	operations, ok := h.bindPatch(ctx)
+	if !ok {
+		return
+	}
+	factory := usecase.PatchUserUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IPatchUserUseCaseRequest{
+		ID:         ctx.Param("id"),
+		Operations: operations,
+		BaseURL:    h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.User, usecase.SCHEMA_USER)
+}
+
+func (h *SCIMHandler) DeleteUser(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	factory := usecase.DeleteUserUseCaseFactory(h.Log)
+	if err := factory.Execute(&usecase.IDeleteUserUseCaseRequest{ID: ctx.Param("id")}); err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) ListGroups(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	query, err := h.listQuery(ctx)
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	factory := usecase.ListGroupsUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IListGroupsUseCaseRequest{
+		Query:   query,
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	resources := make([]interface{}, 0, len(response.Groups))
+	for _, group := range response.Groups {
+		resources = append(resources, group)
+	}
+	h.respondList(ctx, response.TotalResults, response.StartIndex, resources, usecase.SCHEMA_GROUP)
+}
+
+func (h *SCIMHandler) GetGroup(ctx *gin.Context) {
+	if !h.authorize(ctx, "read-scim") {
+		return
+	}
+	factory := usecase.FindGroupUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IFindGroupUseCaseRequest{
+		ID:      ctx.Param("id"),
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.Group, usecase.SCHEMA_GROUP)
+}
+
+func (h *SCIMHandler) CreateGroup(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	payload := new(usecase.GroupResource)
+	if !h.bind(ctx, payload) {
+		return
+	}
+	factory := usecase.CreateGroupUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.ICreateGroupUseCaseRequest{
+		Group:              payload,
+		DefaultApplication: h.Config.GetString("scim.default_application"),
+		BaseURL:            h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	ctx.Header("Location", response.Group.Meta.Location)
+	h.respondResource(ctx, http.StatusCreated, response.Group, usecase.SCHEMA_GROUP)
+}
+
+func (h *SCIMHandler) ReplaceGroup(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	payload := new(usecase.GroupResource)
+	if !h.bind(ctx, payload) {
+		return
+	}
+	factory := usecase.ReplaceGroupUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IReplaceGroupUseCaseRequest{
+		ID:      ctx.Param("id"),
+		Group:   payload,
+		BaseURL: h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.Group, usecase.SCHEMA_GROUP)
+}
+
+func (h *SCIMHandler) PatchGroup(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	operations, ok := h.bindPatch(ctx)
+	if !ok {
+		return
+	}
+	factory := usecase.PatchGroupUseCaseFactory(h.Log)
+	response, err := factory.Execute(&usecase.IPatchGroupUseCaseRequest{
+		ID:         ctx.Param("id"),
+		Operations: operations,
+		BaseURL:    h.baseURL(),
+	})
+	if err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	h.respondResource(ctx, http.StatusOK, response.Group, usecase.SCHEMA_GROUP)
+}
+
+func (h *SCIMHandler) DeleteGroup(ctx *gin.Context) {
+	if !h.authorize(ctx, "write-scim") {
+		return
+	}
+	factory := usecase.DeleteGroupUseCaseFactory(h.Log)
+	if err := factory.Execute(&usecase.IDeleteGroupUseCaseRequest{ID: ctx.Param("id")}); err != nil {
+		h.scimError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
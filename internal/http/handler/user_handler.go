@@ -1,42 +1,48 @@
 package handler
 
 import (
-	"app/go-sso/internal/config"
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/http/middleware"
 	request "app/go-sso/internal/http/request/user"
+	"app/go-sso/internal/service"
 	authUsecase "app/go-sso/internal/usecase/auth_token"
+	mfaUsecase "app/go-sso/internal/usecase/mfa"
+	oidcUsecase "app/go-sso/internal/usecase/oidc"
 	usecase "app/go-sso/internal/usecase/user"
+	identityUsecase "app/go-sso/internal/usecase/user_identity"
 	"app/go-sso/utils"
-	"context"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"golang.org/x/exp/rand"
 	"golang.org/x/oauth2"
 )
 
 type UserHandler struct {
-	Config             *viper.Viper
-	Log                *logrus.Logger
-	Validate           *validator.Validate
-	OAuthConfig        *config.Authenticator
-	GoogleOAuthConfig  *config.GoogleAuthenticator
-	ZitadelOAuthConfig *config.ZitadelAuthenticator
+	Config                  *viper.Viper
+	Log                     *logrus.Logger
+	Validate                *validator.Validate
+	UpstreamProviderService service.IUpstreamProviderService
 }
 
 type UserHandlerInterface interface {
 	Login(ctx *gin.Context)
+	LoginMFA(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	RefreshToken(ctx *gin.Context)
 	Logout(ctx *gin.Context)
 	LogoutCookie(ctx *gin.Context)
 	CheckAuthToken(ctx *gin.Context)
@@ -44,15 +50,12 @@ type UserHandlerInterface interface {
 	Me(ctx *gin.Context)
 	LoginOAuth(ctx *gin.Context)
 	CallbackOAuth(ctx *gin.Context)
-	GoogleLoginOAuth(ctx *gin.Context)
-	GoogleCallbackOAuth(ctx *gin.Context)
-	ZitadelLoginOAuth(ctx *gin.Context)
-	ZitadelCallbackOAuth(ctx *gin.Context)
+	LinkOAuth(ctx *gin.Context)
 	FindById(ctx *gin.Context)
 	FindAllPaginated(ctx *gin.Context)
 }
 
-func UserHandlerFactory(log *logrus.Logger, validator *validator.Validate, oAuthConfig *config.Authenticator, googleOAuthConfig *config.GoogleAuthenticator, zitadelOAuthConfig *config.ZitadelAuthenticator) UserHandlerInterface {
+func UserHandlerFactory(log *logrus.Logger, validator *validator.Validate, upstreamProviderService service.IUpstreamProviderService) UserHandlerInterface {
 	config := viper.New()
 	config.SetConfigName("config")
 	config.SetConfigType("json")
@@ -63,35 +66,122 @@ func UserHandlerFactory(log *logrus.Logger, validator *validator.Validate, oAuth
 		panic(fmt.Errorf("Fatal error config file: %w \n", err))
 	}
 	return &UserHandler{
-		Config:             config,
-		Log:                log,
-		Validate:           validator,
-		OAuthConfig:        oAuthConfig,
-		GoogleOAuthConfig:  googleOAuthConfig,
-		ZitadelOAuthConfig: zitadelOAuthConfig,
+		Config:                  config,
+		Log:                     log,
+		Validate:                validator,
+		UpstreamProviderService: upstreamProviderService,
 	}
 }
 
-var codeVerifierStore = make(map[string]string)
+func (h *UserHandler) Login(ctx *gin.Context) {
+	payload := new(request.LoginRequest)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when binding request: %v", err)
+		return
+	}
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when validating request: %v", err)
+		return
+	}
+	factory := usecase.LoginUseCaseFactory(h.Log)
+	response, err := factory.Execute(usecase.ILoginUseCaseRequest{
+		Email:          payload.Email,
+		Password:       payload.Password,
+		IPAddress:      ctx.ClientIP(),
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
+	})
+	if err != nil {
+		h.Log.Errorf("Error when login: %v", err)
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			utils.ErrorResponse(ctx, http.StatusTooManyRequests, "error", err.Error())
+			return
+		}
+		if errors.Is(err, usecase.ErrPasswordExpired) || errors.Is(err, usecase.ErrAwaitingApproval) {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "error", err.Error())
+			return
+		}
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
 
-func generateCodeVerifier() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~"
-	var seededRand = rand.New(rand.NewSource(rand.Uint64()))
+	// the password is right but a second factor is needed, the client
+	// answers the challenge on /api/login/mfa
+	if response.MFARequired {
+		challengeFactory := mfaUsecase.CreateMFAChallengeUseCaseFactory(h.Log)
+		challengeResp, err := challengeFactory.Execute(&mfaUsecase.ICreateMFAChallengeUseCaseRequest{
+			UserID: response.User.ID,
+			TTL:    h.mfaChallengeTTL(),
+		})
+		if err != nil {
+			utils.ErrorResponse(ctx, 500, "error", err.Error())
+			h.Log.Errorf("Error when creating mfa challenge: %v", err)
+			return
+		}
 
-	b := make([]byte, 64)
-	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		utils.SuccessResponse(ctx, 200, "success", map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    challengeResp.Token,
+			"expires_in":   int(time.Until(challengeResp.ExpiredAt).Seconds()),
+		})
+		return
 	}
-	return string(b)
+
+	h.issueLoginTokens(ctx, &response.User, payload.ChoosedRole)
 }
 
-func generateCodeChallenge(verifier string) string {
-	hash := sha256.Sum256([]byte(verifier))
-	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(hash[:])
+// LoginMFA completes a login that /api/login answered with mfa_required, given
+// a code from the authenticator app or a recovery code.
+func (h *UserHandler) LoginMFA(ctx *gin.Context) {
+	payload := new(request.LoginMFARequest)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when binding request: %v", err)
+		return
+	}
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when validating request: %v", err)
+		return
+	}
+
+	factory := mfaUsecase.AnswerMFAChallengeUseCaseFactory(h.Log)
+	challengeResp, err := factory.Execute(&mfaUsecase.IAnswerMFAChallengeUseCaseRequest{
+		Token: payload.MFAToken,
+		Code:  payload.Code,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when verifying mfa code: %v", err)
+		utils.ErrorResponse(ctx, 401, "error", err.Error())
+		return
+	}
+
+	userFactory := usecase.FindByIdUseCaseFactory(h.Log)
+	userResp, err := userFactory.Execute(&usecase.IFindByIdUseCaseRequest{
+		ID: challengeResp.UserID,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		h.Log.Errorf("Error when finding user: %v", err)
+		return
+	}
+	if userResp.User == nil {
+		utils.ErrorResponse(ctx, 404, "error", "User not found")
+		return
+	}
+
+	h.issueLoginTokens(ctx, userResp.User, payload.ChoosedRole)
 }
 
-func (h *UserHandler) Login(ctx *gin.Context) {
-	payload := new(request.LoginRequest)
+// ForgotPassword mails a password reset link. The response is the same
+// whether the email has an account or not.
+func (h *UserHandler) ForgotPassword(ctx *gin.Context) {
+	payload := new(request.ForgotPasswordRequest)
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		utils.ErrorResponse(ctx, 400, "error", err.Error())
 		h.Log.Errorf("Error when binding request: %v", err)
@@ -103,20 +193,84 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 		h.Log.Errorf("Error when validating request: %v", err)
 		return
 	}
-	factory := usecase.LoginUseCaseFactory(h.Log)
-	response, err := factory.Execute(usecase.ILoginUseCaseRequest{
+
+	factory := usecase.ForgotPasswordUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IForgotPasswordUseCaseRequest{
 		Email:    payload.Email,
-		Password: payload.Password,
+		ResetURL: utils.PasswordResetURL(h.Config),
+		From:     h.Config.GetString("mail.from"),
+		TTL:      utils.PasswordResetTTL(h.Config),
 	})
 	if err != nil {
-		h.Log.Errorf("Error when login: %v", err)
+		h.Log.Errorf("Error when requesting password reset: %v", err)
+		utils.ErrorResponse(ctx, 500, "error", "Something went wrong, please try again later")
+		return
+	}
+
+	utils.SuccessResponse(ctx, 200, resp.Message, nil)
+}
+
+// ResetPassword sets a new password with a token from a reset link and
+// revokes every session and token of the user.
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	payload := new(request.ResetPasswordRequest)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when binding request: %v", err)
+		return
+	}
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when validating request: %v", err)
+		return
+	}
+
+	factory := usecase.ResetPasswordUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IResetPasswordUseCaseRequest{
+		Token:          payload.Token,
+		Password:       payload.Password,
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
+	})
+	if err != nil {
+		h.Log.Errorf("Error when resetting password: %v", err)
+		if errors.Is(err, usecase.ErrInvalidResetToken) {
+			utils.ErrorResponse(ctx, 400, "error", err.Error())
+			return
+		}
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			utils.FormatResponse(ctx, http.StatusUnprocessableEntity, "error", "The password does not meet the password policy", policyErr)
+			return
+		}
 		utils.ErrorResponse(ctx, 500, "error", err.Error())
 		return
 	}
 
+	logoutFactory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+	if _, err := logoutFactory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+		UserID: resp.User.ID,
+		Issuer: utils.OIDCIssuer(h.Config),
+	}); err != nil {
+		h.Log.Errorf("Error when logging out applications: %v", err)
+	}
+
+	utils.SuccessResponse(ctx, 200, "Password has been reset", nil)
+}
+
+func (h *UserHandler) mfaChallengeTTL() time.Duration {
+	if ttl := h.Config.GetInt("mfa.challenge_ttl"); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// issueLoginTokens answers a successful login with an access token and a
+// refresh token for the chosen role.
+func (h *UserHandler) issueLoginTokens(ctx *gin.Context, user *entity.User, choosedRole string) {
 	filteredRoles := []entity.Role{}
-	for _, role := range response.User.Roles {
-		if role.Name == payload.ChoosedRole {
+	for _, role := range user.Roles {
+		if role.Name == choosedRole {
 			filteredRoles = append(filteredRoles, role)
 			break
 		}
@@ -126,44 +280,84 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 		utils.ErrorResponse(ctx, 404, "error", "Role not found")
 		return
 	}
-	response.User.Roles = filteredRoles
+	user.Roles = filteredRoles
 
-	token, err := utils.GenerateToken(&response.User)
+	token, err := utils.GenerateToken(user)
 	if err != nil {
 		h.Log.Errorf("Error when generating token: %v", err)
 		utils.ErrorResponse(ctx, 500, "error", err.Error())
 		return
 	}
 
-	// authFactory := authUsecase.StoreTokenUseCaseFactory(h.Log)
-	// authToken, err := authFactory.Execute(authUsecase.IStoreTokenUseCaseRequest{
-	// 	UserID:    response.User.ID,
-	// 	Token:     token,
-	// 	ExpiredAt: time.Now().Add((6) * time.Hour),
-	// })
+	refreshFactory := authUsecase.IssueRefreshTokenUseCaseFactory(h.Log)
+	refreshResp, err := refreshFactory.Execute(&authUsecase.IIssueRefreshTokenUseCaseRequest{
+		UserID: user.ID,
+		RoleID: filteredRoles[0].ID,
+		TTL:    h.refreshTokenTTL(),
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		h.Log.Errorf("Error when storing refresh token: %v", err)
+		return
+	}
+
+	var data = map[string]interface{}{
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token": refreshResp.RefreshToken,
+		"user":          user,
+	}
+
+	jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
+	jwtCookie.Domain = h.Config.GetString("app.domain")
+	utils.SetTokenCookie(ctx, token, jwtCookie)
+
+	utils.SuccessResponse(ctx, 200, "success", data)
+}
 
-	// if err != nil {
-	// 	utils.ErrorResponse(ctx, 500, "error", err.Error())
-	// 	h.Log.Errorf("Error when storing token: %v", err)
-	// 	return
-	// }
+func (h *UserHandler) RefreshToken(ctx *gin.Context) {
+	payload := new(request.RefreshTokenRequest)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when binding request: %v", err)
+		return
+	}
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		h.Log.Errorf("Error when validating request: %v", err)
+		return
+	}
 
-	// if authToken == nil {
-	// 	utils.ErrorResponse(ctx, 500, "error", "Failed to store auth token")
-	// 	h.Log.Errorf("Failed to store auth token")
-	// 	return
-	// }
+	factory := authUsecase.RotateRefreshTokenUseCaseFactory(h.Log)
+	response, err := factory.Execute(&authUsecase.IRotateRefreshTokenUseCaseRequest{
+		RefreshToken: payload.RefreshToken,
+		TTL:          h.refreshTokenTTL(),
+	})
+	if err != nil {
+		if errors.Is(err, authUsecase.ErrInvalidRefreshToken) {
+			utils.ErrorResponse(ctx, 401, "error", err.Error())
+			return
+		}
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		h.Log.Errorf("Error when refreshing token: %v", err)
+		return
+	}
 
-	// var data = map[string]interface{}{
-	// 	"token":      authToken.AuthToken.Token,
-	// 	"token_type": "Bearer",
-	// 	"user":       response.User,
-	// }
+	token, err := utils.GenerateToken(response.User)
+	if err != nil {
+		h.Log.Errorf("Error when generating token: %v", err)
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
 
 	var data = map[string]interface{}{
-		"token":      token,
-		"token_type": "Bearer",
-		"user":       response.User,
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token": response.RefreshToken,
+		"user":          response.User,
 	}
 
 	jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
@@ -173,6 +367,14 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 	utils.SuccessResponse(ctx, 200, "success", data)
 }
 
+func (h *UserHandler) refreshTokenTTL() time.Duration {
+	ttl := time.Duration(h.Config.GetInt("jwt.refresh_token_ttl")) * time.Second
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return ttl
+}
+
 func (h *UserHandler) CheckStoredCookie(ctx *gin.Context) {
 	cookie, err := ctx.Cookie("jwt_token")
 	if err != nil {
@@ -219,20 +421,56 @@ func (h *UserHandler) Logout(ctx *gin.Context) {
 		h.Log.Errorf("Invalid Authorization header format")
 		return
 	}
-	factory := authUsecase.DeleteTokenUseCaseFactory(h.Log)
-	message, err := factory.Execute(authUsecase.IDeleteTokenUseCaseRequest{
-		UserID: user["userId"].(string),
-		Token:  bearerToken[1],
+	factory := authUsecase.RevokeTokenUseCaseFactory(h.Log)
+	message, err := factory.Execute(&authUsecase.IRevokeTokenUseCaseRequest{
+		Token:         bearerToken[1],
+		TokenTypeHint: "access_token",
 	})
+	if errors.Is(err, authUsecase.ErrTokenNotRevocable) {
+		utils.ErrorResponse(ctx, 400, "error", err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when deleting token: %v", err)
+		h.Log.Errorf("Error when revoking token: %v", err)
 		return
 	}
+
+	// the refresh token from /api/login may be sent along to end the login for good
+	payload := new(request.RefreshTokenRequest)
+	if err := ctx.ShouldBindJSON(&payload); err == nil && payload.RefreshToken != "" {
+		_, err := factory.Execute(&authUsecase.IRevokeTokenUseCaseRequest{
+			Token:         payload.RefreshToken,
+			TokenTypeHint: "refresh_token",
+		})
+		if err != nil {
+			utils.ErrorResponse(ctx, 500, "error", err.Error())
+			h.Log.Errorf("Error when revoking refresh token: %v", err)
+			return
+		}
+	}
+
 	utils.SuccessResponse(ctx, 200, "success", message)
 }
 
 func (h *UserHandler) LogoutCookie(ctx *gin.Context) {
+	// the cookie belongs to a web login, end it and the applications it signed in to
+	session := sessions.Default(ctx)
+	if sessionID, ok := session.Get("sid").(string); ok && sessionID != "" {
+		factory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+		if _, err := factory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+			SessionID: sessionID,
+			Issuer:    utils.OIDCIssuer(h.Config),
+		}); err != nil {
+			h.Log.Errorf("Error when logging out applications: %v", err)
+		}
+	}
+	session.Delete("sid")
+	session.Delete("profile")
+	session.Delete("choosed_role_id")
+	session.Delete("auth_time")
+	session.Save()
+
 	utils.ClearTokenCookie(ctx, "access_token", h.Config.GetString("app.domain"))
 	utils.ClearTokenCookie(ctx, "jwt_token", h.Config.GetString("app.domain"))
 	utils.SuccessResponse(ctx, 200, "success", "Logged out successfully")
@@ -284,10 +522,19 @@ func (h *UserHandler) Me(ctx *gin.Context) {
 		return
 	}
 
+	// client credentials tokens have no user behind them
+	userID, _ := user["id"].(string)
+	id, err := uuid.Parse(userID)
+	if middleware.IsClientToken(user) || err != nil {
+		utils.ErrorResponse(ctx, 403, "error", "Token does not belong to a user")
+		return
+	}
+	choosedRole, _ := user["choosed_role"].(string)
+
 	factory := usecase.MeUseCaseFactory(h.Log)
 	res, err := factory.Execute(&usecase.IMeUseCaseRequest{
-		ID:          uuid.MustParse(user["id"].(string)),
-		ChoosedRole: user["choosed_role"].(string),
+		ID:          id,
+		ChoosedRole: choosedRole,
 	})
 
 	if err != nil {
@@ -299,230 +546,329 @@ func (h *UserHandler) Me(ctx *gin.Context) {
 	utils.SuccessResponse(ctx, 200, "success", res.User)
 }
 
-func (h *UserHandler) LoginOAuth(ctx *gin.Context) {
-	state := ctx.Query("state")
-	url := h.OAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	ctx.Redirect(http.StatusTemporaryRedirect, url)
+// rememberAuthorizationRequest checks the login for application like an
+// /oauth2/authorize request, PKCE included, and keeps it in the session. Once
+// the upstream sign-in is done, the application receives an authorization
+// code for it, never a token. The returned state is random, the application
+// name is predictable and stays in the session.
+func (h *UserHandler) rememberAuthorizationRequest(ctx *gin.Context, application string) (string, error) {
+	codeChallengeMethod := ctx.DefaultQuery("code_challenge_method", "S256")
+	factory := oidcUsecase.ValidateAuthorizationRequestUseCaseFactory(h.Log)
+	if _, err := factory.Execute(&oidcUsecase.IValidateAuthorizationRequestUseCaseRequest{
+		ClientID:            application,
+		RedirectURI:         ctx.Query("redirect_uri"),
+		ResponseType:        "code",
+		Scope:               "openid profile email",
+		CodeChallenge:       ctx.Query("code_challenge"),
+		CodeChallengeMethod: codeChallengeMethod,
+	}); err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("client_id", application)
+	params.Set("response_type", "code")
+	params.Set("scope", "openid profile email")
+	if redirectURI := ctx.Query("redirect_uri"); redirectURI != "" {
+		params.Set("redirect_uri", redirectURI)
+	}
+	params.Set("code_challenge", ctx.Query("code_challenge"))
+	params.Set("code_challenge_method", codeChallengeMethod)
+	params.Set("state", application)
+
+	state := utils.GenerateRandomStringToken(32)
+	session := sessions.Default(ctx)
+	session.Set("oauth_state", state)
+	session.Set("oauth_application", application)
+	session.Set("authorize_request", params.Encode())
+	return state, session.Save()
 }
 
-func (h *UserHandler) CallbackOAuth(ctx *gin.Context) {
-	code := ctx.Query("code")
-	state := ctx.Query("state")
-	appConfig, appExists := config.AppConfigs[state]
-	if !appExists {
-		utils.ErrorResponse(ctx, 400, "error", "Invalid state")
-		h.Log.Errorf("Invalid state")
-		return
-	}
-	token, err := h.OAuthConfig.Exchange(ctx, code)
-	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when exchanging token: %v", err)
-		return
+// checkApplicationState checks state against the one kept when the upstream
+// login started, so a forged callback cannot sign the browser in, and returns
+// the application the login is for.
+func (h *UserHandler) checkApplicationState(ctx *gin.Context, state string) (string, error) {
+	session := sessions.Default(ctx)
+	expectedState, _ := session.Get("oauth_state").(string)
+	application, _ := session.Get("oauth_application").(string)
+	_, authorizing := session.Get("authorize_request").(string)
+	session.Delete("oauth_state")
+	session.Delete("oauth_application")
+	session.Save()
+
+	if state == "" || expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 || !authorizing {
+		return "", errors.New("Invalid state")
+	}
+	return application, nil
+}
+
+// upstreamProvider finds the provider named by the route. /oauth/login and
+// /api/oauth/callback predate the provider parameter and stay Auth0.
+func (h *UserHandler) upstreamProvider(ctx *gin.Context) (*entity.UpstreamProvider, error) {
+	name := ctx.Param("provider")
+	if name == "" {
+		name = "auth0"
 	}
-	idToken, err := h.OAuthConfig.VerifyIDToken(ctx, token)
+	return h.UpstreamProviderService.Find(name)
+}
+
+// startUpstreamLogin sends the user to the provider. The nonce and the PKCE
+// verifier of the login are kept in the session for the callback.
+func (h *UserHandler) startUpstreamLogin(ctx *gin.Context, upstreamProvider *entity.UpstreamProvider, state string) {
+	nonce := utils.GenerateRandomStringToken(32)
+	codeVerifier := oauth2.GenerateVerifier()
+	url, err := h.UpstreamProviderService.AuthCodeURL(upstreamProvider, state, nonce, codeVerifier)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when verifying id token: %v", err)
-		return
-	}
-	var profile map[string]interface{}
-	if err := idToken.Claims(&profile); err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when getting profile: %v", err)
+		h.Log.Errorf("Error when starting the login at %s: %v", upstreamProvider.Name, err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 		return
 	}
-	factory := usecase.FindByEmailUseCaseFactory(h.Log)
-	response, err := factory.Execute(usecase.IFindByEmailUseCaseRequest{
-		Email: profile["email"].(string),
-	})
 
+	session := sessions.Default(ctx)
+	session.Set("oauth_provider", upstreamProvider.Name)
+	session.Set("oauth_nonce", nonce)
+	session.Set("oauth_code_verifier", codeVerifier)
+	session.Save()
+	ctx.Redirect(http.StatusTemporaryRedirect, url)
+}
+
+// upstreamLogin returns the nonce and the code verifier startUpstreamLogin
+// kept for upstreamProvider. They are used once.
+func (h *UserHandler) upstreamLogin(ctx *gin.Context, upstreamProvider *entity.UpstreamProvider) (string, string, error) {
+	session := sessions.Default(ctx)
+	name, _ := session.Get("oauth_provider").(string)
+	nonce, _ := session.Get("oauth_nonce").(string)
+	codeVerifier, _ := session.Get("oauth_code_verifier").(string)
+	session.Delete("oauth_provider")
+	session.Delete("oauth_nonce")
+	session.Delete("oauth_code_verifier")
+	session.Save()
+
+	if name != upstreamProvider.Name || nonce == "" {
+		return "", "", errors.New("Invalid or expired state")
+	}
+	return nonce, codeVerifier, nil
+}
+
+func (h *UserHandler) LoginOAuth(ctx *gin.Context) {
+	upstreamProvider, err := h.upstreamProvider(ctx)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when finding user by email: %v", err)
+		utils.ErrorResponse(ctx, http.StatusNotFound, "error", err.Error())
 		return
 	}
-	jwtToken, err := utils.GenerateToken(response.User)
+	// ?state= names the application, as on /login
+	state, err := h.rememberAuthorizationRequest(ctx, ctx.Query("state"))
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when generating token: %v", err)
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
 		return
 	}
-	redirectURL := fmt.Sprintf("%s?token=%s", appConfig.RedirectURI, jwtToken)
-	ctx.Redirect(http.StatusTemporaryRedirect, redirectURL)
+	h.startUpstreamLogin(ctx, upstreamProvider, state)
 }
 
-func (h *UserHandler) GoogleLoginOAuth(ctx *gin.Context) {
-	state := ctx.Query("state")
-	url := h.GoogleOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	ctx.Redirect(http.StatusTemporaryRedirect, url)
-}
-
-func (h *UserHandler) GoogleCallbackOAuth(ctx *gin.Context) {
-	// Get the code and state from the query
+func (h *UserHandler) CallbackOAuth(ctx *gin.Context) {
 	code := ctx.Query("code")
 	state := ctx.Query("state")
 
 	if code == "" {
-		utils.ErrorResponse(ctx, 400, "error", "code is required")
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", "code is required")
 		return
 	}
 
-	appConfig, appExists := config.GoogleAppConfigs[state]
-	if !appExists {
-		utils.ErrorResponse(ctx, 400, "error", "Invalid state")
-		h.Log.Errorf("Invalid state")
+	upstreamProvider, err := h.upstreamProvider(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "error", err.Error())
 		return
 	}
 
-	token, err := h.GoogleOAuthConfig.Exchange(context.Background(), code)
+	application := ""
+	linkUserID, linking := h.linkingUserID(ctx, state)
+	if !linking {
+		application, err = h.checkApplicationState(ctx, state)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			h.Log.Errorf("Invalid state")
+			return
+		}
+	}
+
+	nonce, codeVerifier, err := h.upstreamLogin(ctx, upstreamProvider)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", "failed to exchange token")
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", err.Error())
 		return
 	}
 
-	client := h.GoogleOAuthConfig.Client(context.Background(), token)
-	userInfoResp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	identity, err := h.UpstreamProviderService.Exchange(ctx, upstreamProvider, code, nonce, codeVerifier)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", "failed to get user info")
+		h.Log.Errorf("Error when signing in at %s: %v", upstreamProvider.Name, err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 		return
 	}
-	defer userInfoResp.Body.Close()
 
-	var userInfo struct {
-		Email string `json:"email"`
-	}
-	if err := json.NewDecoder(userInfoResp.Body).Decode(&userInfo); err != nil {
-		utils.ErrorResponse(ctx, 500, "error", "failed to decode user info")
+	if linking {
+		h.linkIdentity(ctx, linkUserID, identity)
 		return
 	}
 
-	email := userInfo.Email
-	if email == "" {
-		utils.ErrorResponse(ctx, 400, "error", "email is required")
+	response, err := h.findUserByIdentity(ctx, identity, application)
+	if err != nil {
 		return
 	}
 
-	factory := usecase.FindByEmailUseCaseFactory(h.Log)
-	response, err := factory.Execute(usecase.IFindByEmailUseCaseRequest{
-		Email: email,
+	// the provider only stands in for the password: the sign-in continues in
+	// the web session like a password login, on /login/mfa for users with
+	// two-factor authentication, and ends on /oauth2/authorize, which sends
+	// the application an authorization code
+	statusFactory := mfaUsecase.FindMFAStatusUseCaseFactory(h.Log)
+	status, err := statusFactory.Execute(&mfaUsecase.IFindMFAStatusUseCaseRequest{
+		UserID: response.User.ID,
 	})
-
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when finding user by email: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		h.Log.Errorf("Error when finding mfa status: %v", err)
 		return
 	}
-	jwtToken, err := utils.GenerateToken(response.User)
-	if err != nil {
-		utils.ErrorResponse(ctx, 500, "error", err.Error())
-		h.Log.Errorf("Error when generating token: %v", err)
+
+	session := sessions.Default(ctx)
+	session.Delete("profile")
+	session.Delete("choosed_role_id")
+	if status.Enabled {
+		session.Set("mfa_user_id", response.User.ID.String())
+		session.Set("mfa_started_at", time.Now().Unix())
+		session.Set("mfa_attempts", 0)
+		session.Save()
+		ctx.Redirect(http.StatusFound, "/login/mfa")
 		return
 	}
 
-	redirectURL := fmt.Sprintf("%s?token=%s", appConfig.RedirectURI, jwtToken)
-	ctx.Redirect(http.StatusTemporaryRedirect, redirectURL)
-}
-
-func (h *UserHandler) ZitadelLoginOAuth(ctx *gin.Context) {
-	state := ctx.Query("state")
-	codeVerifier := generateCodeVerifier()
-	codeChallenge := generateCodeChallenge(codeVerifier)
-
-	codeVerifierStore[state] = codeVerifier
-
-	authURL := h.ZitadelOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline) +
-		"&code_challenge=" + codeChallenge +
-		"&code_challenge_method=S256"
-
-	ctx.Redirect(http.StatusTemporaryRedirect, authURL)
+	session.Set("profile", entity.Profile{
+		ID:              response.User.ID,
+		Name:            response.User.Name,
+		Email:           response.User.Email,
+		Username:        response.User.Username,
+		IsEmployee:      response.User.EmployeeID != nil,
+		EmailVerifiedAt: response.User.EmailVerifiedAt,
+	})
+	session.Set("auth_time", time.Now().Unix())
+	session.Save()
+	ctx.Redirect(http.StatusFound, "/choose-roles")
 }
 
-func (h *UserHandler) ZitadelCallbackOAuth(ctx *gin.Context) {
-	code := ctx.Query("code")
-	state := ctx.Query("state")
-
-	if code == "" {
-		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", "code is required")
-		return
-	}
-
-	appConfig, appExists := config.ZitadelAppConfigs[state]
-	if !appExists {
-		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", "Invalid state")
+// LinkOAuth starts a login at the provider to link the account there to the
+// signed in user. The callback tells the link apart from a login by the state
+// kept in the session.
+func (h *UserHandler) LinkOAuth(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile, ok := session.Get("profile").(entity.Profile)
+	if !ok {
+		ctx.Redirect(302, "/login")
 		return
 	}
 
-	codeVerifier, ok := codeVerifierStore[state]
-	if !ok {
-		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", "Invalid or expired state")
+	payload := new(request.LinkUserIdentityRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
 		return
 	}
-	delete(codeVerifierStore, state)
-
-	token, err := h.ZitadelOAuthConfig.Exchange(
-		context.Background(),
-		code,
-		oauth2.SetAuthURLParam("code_verifier", codeVerifier),
-		oauth2.SetAuthURLParam("grant_type", "authorization_code"),
-	)
-	if err != nil {
-		h.Log.Errorf("Token exchange failed: %v", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", "Failed to exchange token")
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
 		return
 	}
 
-	client := h.ZitadelOAuthConfig.Client(context.Background(), token)
-	userInfoResp, err := client.Get("https://signals99-kjlnde.us1.zitadel.cloud/oidc/v1/userinfo")
+	upstreamProvider, err := h.UpstreamProviderService.Find(payload.Provider)
 	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", "Failed to get user info: "+err.Error())
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
 		return
 	}
-	defer userInfoResp.Body.Close()
 
-	accessToken := token.AccessToken
+	state := "link-" + utils.GenerateRandomStringToken(32)
+	session.Set("oauth_link_state", state)
+	session.Set("oauth_link_user_id", profile.ID.String())
+	session.Save()
 
-	accessTokenCookie := utils.NewDefaultCookieOptions("access_token")
-	accessTokenCookie.Domain = h.Config.GetString("app.domain")
-	utils.SetTokenCookie(ctx, accessToken, accessTokenCookie)
+	h.startUpstreamLogin(ctx, upstreamProvider, state)
+}
 
-	var userInfo struct {
-		Email string `json:"email"`
-	}
-	if err := json.NewDecoder(userInfoResp.Body).Decode(&userInfo); err != nil {
-		h.Log.Errorf("Failed to decode user info: %v", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", "Failed to decode user info: "+err.Error())
-		return
+// linkingUserID returns the user that started LinkOAuth when state is the one
+// it kept in the session. The state is used once.
+func (h *UserHandler) linkingUserID(ctx *gin.Context, state string) (uuid.UUID, bool) {
+	session := sessions.Default(ctx)
+	expectedState, _ := session.Get("oauth_link_state").(string)
+	if state == "" || expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		return uuid.Nil, false
 	}
+	rawUserID, _ := session.Get("oauth_link_user_id").(string)
+	session.Delete("oauth_link_state")
+	session.Delete("oauth_link_user_id")
+	session.Save()
 
-	if userInfo.Email == "" {
-		utils.ErrorResponse(ctx, http.StatusBadRequest, "error", "Email is required")
-		return
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil {
+		return uuid.Nil, false
 	}
+	return userID, true
+}
 
-	factory := usecase.FindByEmailUseCaseFactory(h.Log)
-	response, err := factory.Execute(usecase.IFindByEmailUseCaseRequest{
-		Email: userInfo.Email,
+// linkIdentity finishes LinkOAuth and sends the user back to their linked
+// accounts.
+func (h *UserHandler) linkIdentity(ctx *gin.Context, userID uuid.UUID, identity *service.UpstreamIdentity) {
+	session := sessions.Default(ctx)
+	factory := identityUsecase.LinkUserIdentityUseCaseFactory(h.Log)
+	_, err := factory.Execute(&identityUsecase.ILinkUserIdentityUseCaseRequest{
+		UserID:   userID,
+		Provider: entity.UserIdentityProvider(identity.Provider),
+		Subject:  identity.Subject,
+		Email:    identity.Email,
 	})
 	if err != nil {
-		h.Log.Errorf("Error finding user by email: %v", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
 		return
 	}
 
-	jwtToken, err := utils.GenerateToken(response.User)
+	session.Set("success", "The account has been linked")
+	session.Save()
+	ctx.Redirect(302, "/linked-accounts")
+}
+
+// findUserByIdentity finds, or provisions, the user signing in through the
+// provider to application, and answers the request itself when there is none.
+func (h *UserHandler) findUserByIdentity(ctx *gin.Context, identity *service.UpstreamIdentity, application string) (*identityUsecase.IFindUserByIdentityUseCaseResponse, error) {
+	factory := identityUsecase.FindUserByIdentityUseCaseFactory(h.Log)
+	response, err := factory.Execute(&identityUsecase.IFindUserByIdentityUseCaseRequest{
+		Provider:              entity.UserIdentityProvider(identity.Provider),
+		Subject:               identity.Subject,
+		Email:                 identity.Email,
+		EmailVerified:         identity.EmailVerified,
+		Name:                  identity.Name,
+		Application:           application,
+		IPAddress:             ctx.ClientIP(),
+		JITProvisioningPolicy: utils.JITProvisioningPolicyFromConfig(h.Config),
+	})
+	if errors.Is(err, identityUsecase.ErrIdentityNotLinked) {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "error", err.Error())
+		return nil, err
+	}
+	if errors.Is(err, identityUsecase.ErrAwaitingApproval) || errors.Is(err, identityUsecase.ErrNoDefaultRoles) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "error", err.Error())
+		return nil, err
+	}
 	if err != nil {
-		h.Log.Errorf("Error generating token: %v", err)
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", "Failed to generate token")
-		return
+		h.Log.Errorf("Error when finding user by identity: %v", err)
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+		return nil, err
 	}
-
-	jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
-	jwtCookie.Domain = h.Config.GetString("app.domain")
-	utils.SetTokenCookie(ctx, jwtToken, jwtCookie)
-
-	redirectURL := fmt.Sprintf("%s?token=%s", appConfig.RedirectURI, jwtToken)
-	ctx.Redirect(http.StatusTemporaryRedirect, redirectURL)
+	return response, nil
 }
 
 func (h *UserHandler) FindById(ctx *gin.Context) {
@@ -6,10 +6,16 @@ import (
 	"app/go-sso/internal/http/response"
 	messaging "app/go-sso/internal/messaging/user"
 	appUsecase "app/go-sso/internal/usecase/application"
+	authUsecase "app/go-sso/internal/usecase/auth_token"
+	mfaUsecase "app/go-sso/internal/usecase/mfa"
+	oidcUsecase "app/go-sso/internal/usecase/oidc"
 	usecase "app/go-sso/internal/usecase/user"
+	webauthnUsecase "app/go-sso/internal/usecase/webauthn"
 	"app/go-sso/utils"
 	"app/go-sso/views"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -32,13 +38,31 @@ type AuthHandlerInterface interface {
 	ChooseRoles(ctx *gin.Context)
 	ContinueLogin(ctx *gin.Context)
 	Login(ctx *gin.Context)
+	MFAView(ctx *gin.Context)
+	VerifyMFA(ctx *gin.Context)
+	PasskeyMFAOptions(ctx *gin.Context)
+	PasskeyMFA(ctx *gin.Context)
+	PasskeyLoginOptions(ctx *gin.Context)
+	PasskeyLogin(ctx *gin.Context)
+	MagicLinkView(ctx *gin.Context)
+	RequestMagicLink(ctx *gin.Context)
+	MagicLinkLoginView(ctx *gin.Context)
+	MagicLinkLogin(ctx *gin.Context)
 	Logout(ctx *gin.Context)
 	CheckCookieTest(ctx *gin.Context)
 	RegisterView(ctx *gin.Context)
 	Register(ctx *gin.Context)
 	OtpView(ctx *gin.Context)
 	VerifyEmail(ctx *gin.Context)
+	VerifyEmailLink(ctx *gin.Context)
 	ResendVerifyEmail(ctx *gin.Context)
+	ForgotPasswordView(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPasswordView(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	DeviceView(ctx *gin.Context)
+	DeviceApproveView(ctx *gin.Context)
+	DeviceApprove(ctx *gin.Context)
 }
 
 func AuthHandlerFactory(log *logrus.Logger, validator *validator.Validate) AuthHandlerInterface {
@@ -59,21 +83,31 @@ func AuthHandlerFactory(log *logrus.Logger, validator *validator.Validate) AuthH
 }
 
 func (h *AuthHandler) LoginView(ctx *gin.Context) {
-	state := ctx.Query("state")
 	login := views.NewView("auth_base", "views/auth/login.html")
 	data := map[string]interface{}{
 		"Title": "Go SSO | Login",
 	}
 
-	if state != "" {
-		data["State"] = state
+	if state := ctx.Query("state"); state != "" {
+		h.startApplicationLogin(ctx, state)
+
+		// offer the magic link to applications that enabled it
+		factory := appUsecase.FindApplicationByNameUsecaseFactory(h.Log)
+		if resp, err := factory.Execute(&appUsecase.IFindApplicationByNameUsecaseRequest{
+			Name: state,
+		}); err == nil && resp.Application.MagicLinkEnabled {
+			data["MagicLinkApplication"] = resp.Application.Name
+		}
 	}
 
 	login.Render(ctx, data)
 }
 
 func (h *AuthHandler) ChooseRoles(ctx *gin.Context) {
-	state := ctx.Query("state")
+	if state := ctx.Query("state"); state != "" {
+		h.startApplicationLogin(ctx, state)
+	}
+
 	session := sessions.Default(ctx)
 	profile := session.Get("profile")
 	if profile == nil {
@@ -117,10 +151,6 @@ func (h *AuthHandler) ChooseRoles(ctx *gin.Context) {
 		"Roles": response.User.Roles,
 	}
 
-	if state != "" {
-		data["State"] = state
-	}
-
 	viewRoles.Render(ctx, data)
 }
 
@@ -147,11 +177,36 @@ func (h *AuthHandler) ContinueLogin(ctx *gin.Context) {
 		return
 	}
 
+	// only a user who finished the sign-in, second factor included, has a
+	// profile in the session
+	if _, pending := h.pendingMFAUserID(session); pending {
+		ctx.Redirect(302, "/login/mfa")
+		return
+	}
+	userProfile, ok := session.Get("profile").(entity.Profile)
+	if !ok {
+		session.Set("error", "Profile not found")
+		session.Save()
+		h.Log.Printf("Profile not found")
+		ctx.Redirect(302, "/login")
+		return
+	}
+	roleID, err := uuid.Parse(payload.RoleID)
+	if err != nil {
+		session.Set("error", "Invalid role")
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/choose-roles")
+		return
+	}
+
 	factory := usecase.FindByIdOnlyUseCaseFactory(h.Log)
 	response, err := factory.Execute(&usecase.IFindByIdOnlyUseCaseRequest{
-		ID: uuid.MustParse(payload.ID),
+		ID: userProfile.ID,
 	})
-
+	if err == nil && response.User == nil {
+		err = errors.New("User not found")
+	}
 	if err != nil {
 		session.Set("error", err.Error())
 		session.Save()
@@ -162,11 +217,18 @@ func (h *AuthHandler) ContinueLogin(ctx *gin.Context) {
 
 	filteredRoles := []entity.Role{}
 	for _, role := range response.User.Roles {
-		if role.ID.String() == payload.RoleID {
+		if role.ID == roleID {
 			filteredRoles = append(filteredRoles, role)
 			break
 		}
 	}
+	if len(filteredRoles) == 0 {
+		session.Set("error", "Role not found")
+		session.Save()
+		h.Log.Printf("Role not found")
+		ctx.Redirect(302, "/choose-roles")
+		return
+	}
 	response.User.Roles = filteredRoles
 
 	token, err := utils.GenerateToken(response.User)
@@ -182,49 +244,31 @@ func (h *AuthHandler) ContinueLogin(ctx *gin.Context) {
 	jwtCookie.Domain = h.Config.GetString("app.domain")
 	utils.SetTokenCookie(ctx, token, jwtCookie)
 
-	if payload.State != "" {
-		data, err := h.loginAsApplication(token, payload.State, response.User)
-		if err != nil {
-			session.Set("error", err.Error())
-			session.Save()
-			h.Log.Printf(err.Error())
-			ctx.Redirect(302, ctx.Request.Referer())
-			return
-		}
-
-		application := data["application"].(*entity.Application)
+	session.Set("choosed_role_id", filteredRoles[0].ID.String())
+	session.Save()
 
-		redirectURL := fmt.Sprintf("%s?token=%s", application.RedirectURI, data["token"])
-		h.Log.Printf("Redirecting to URL: %s", redirectURL)
+	if _, ok := session.Get("device_user_code").(string); ok {
+		ctx.Redirect(302, "/device/approve")
+		return
+	}
 
-		if !strings.HasPrefix(redirectURL, "http") {
-			redirectURL = "http://" + redirectURL
-		}
+	if _, ok := session.Get("saml_request").(string); ok {
+		ctx.Redirect(302, "/saml/sso/resume")
+		return
+	}
 
-		ctx.Redirect(302, redirectURL)
+	if authorizeRequest, ok := session.Get("authorize_request").(string); ok {
+		ctx.Redirect(302, "/oauth2/authorize?"+authorizeRequest)
 		return
 	}
 
 	if filteredRoles[0].Name == "Applicant" {
-		data, err := h.loginAsApplication(token, "recruitment", response.User)
-		if err != nil {
-			session.Set("error", err.Error())
-			session.Save()
-			h.Log.Printf(err.Error())
-			ctx.Redirect(302, ctx.Request.Referer())
-			return
-		}
-
-		application := data["application"].(*entity.Application)
-
-		redirectURL := fmt.Sprintf("%s?token=%s", application.RedirectURI, data["token"])
-		h.Log.Printf("Redirecting to URL: %s", redirectURL)
-
-		if !strings.HasPrefix(redirectURL, "http") {
-			redirectURL = "http://" + redirectURL
-		}
+		h.redirectToApplication(ctx, h.loginApplication(session, "recruitment"))
+		return
+	}
 
-		ctx.Redirect(302, redirectURL)
+	if name := h.loginApplication(session, ""); name != "" {
+		h.redirectToApplication(ctx, name)
 		return
 	}
 
@@ -253,9 +297,17 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 
 	factory := usecase.LoginUseCaseFactory(h.Log)
 	response, err := factory.Execute(usecase.ILoginUseCaseRequest{
-		Email:    payload.Email,
-		Password: payload.Password,
+		Email:          payload.Email,
+		Password:       payload.Password,
+		IPAddress:      ctx.ClientIP(),
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
 	})
+	if errors.Is(err, usecase.ErrPasswordExpired) {
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
 	if err != nil {
 		session.Set("error", err.Error())
 		session.Save()
@@ -264,171 +316,184 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 		return
 	}
 
-	var profile = entity.Profile{
-		ID:              response.User.ID,
-		Name:            response.User.Name,
-		Email:           response.User.Email,
-		Username:        response.User.Username,
-		IsEmployee:      h.hasEmployeeData(&response.User),
-		EmailVerifiedAt: response.User.EmailVerifiedAt,
-	}
-
-	session.Set("profile", profile)
-	session.Delete("error")
-	if err := session.Save(); err != nil {
-		h.Log.Printf("[Auth handler] Session save error: %v", err)
-		ctx.Redirect(302, ctx.Request.Referer())
+	// the password is right, the second factor is asked on /login/mfa
+	// before the user is signed in
+	if response.MFARequired {
+		h.requireMFA(ctx, session, response.User.ID)
 		return
 	}
 
-	filteredRoles := []entity.Role{}
-	for _, role := range response.User.Roles {
-		filteredRoles = append(filteredRoles, role)
-		break
-	}
-	response.User.Roles = filteredRoles
-	if filteredRoles[0].Name == "Applicant" {
-		token, err := utils.GenerateToken(&response.User)
-		if err != nil {
-			h.Log.Errorf("Error when generating token: %v", err)
-			session.Set("error", err.Error())
-			session.Save()
-			ctx.Redirect(302, ctx.Request.Referer())
-			return
-		}
-
-		jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
-		jwtCookie.Domain = h.Config.GetString("app.domain")
-		utils.SetTokenCookie(ctx, token, jwtCookie)
-		data, err := h.loginAsApplication(token, "recruitment", &response.User)
-		if err != nil {
-			session.Set("error", err.Error())
-			session.Save()
-			h.Log.Printf(err.Error())
-			ctx.Redirect(302, ctx.Request.Referer())
-			return
-		}
-
-		application := data["application"].(*entity.Application)
-
-		redirectURL := fmt.Sprintf("%s?token=%s", application.RedirectURI, data["token"])
-		h.Log.Printf("Redirecting to URL: %s", redirectURL)
-
-		if !strings.HasPrefix(redirectURL, "http") {
-			redirectURL = "http://" + redirectURL
-		}
+	h.signIn(ctx, session, &response.User)
+}
 
-		if response.User.EmailVerifiedAt.IsZero() {
-			session.Set("error", "Email not verified")
-			session.Save()
-			ctx.Redirect(302, "/otp")
-			return
-		}
+// requireMFA holds the login of a user whose first factor was checked until
+// the second one is verified on /login/mfa.
+func (h *AuthHandler) requireMFA(ctx *gin.Context, session sessions.Session, userID uuid.UUID) {
+	session.Delete("profile")
+	session.Delete("choosed_role_id")
+	session.Set("mfa_user_id", userID.String())
+	session.Set("mfa_started_at", time.Now().Unix())
+	session.Set("mfa_attempts", 0)
+	session.Save()
+	ctx.Redirect(302, "/login/mfa")
+}
 
-		ctx.Redirect(302, redirectURL)
+func (h *AuthHandler) MFAView(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userID, ok := h.pendingMFAUserID(session)
+	if !ok {
+		ctx.Redirect(302, "/login")
 		return
-
 	}
 
-	ctx.Redirect(302, "/choose-roles")
-	return
-}
+	// a registered passkey can stand in for the authenticator app
+	hasPasskeys := false
+	factory := webauthnUsecase.FindWebAuthnCredentialsUseCaseFactory(h.Log)
+	if resp, err := factory.Execute(&webauthnUsecase.IFindWebAuthnCredentialsUseCaseRequest{
+		UserID: userID,
+	}); err == nil {
+		hasPasskeys = len(resp.WebAuthnCredentials) > 0
+	}
 
-func (h *AuthHandler) RegisterView(ctx *gin.Context) {
-	register := views.NewView("auth_base", "views/auth/register.html")
+	mfa := views.NewView("auth_base", "views/auth/mfa.html")
 	data := map[string]interface{}{
-		"Title": "Go SSO | Register",
+		"Title":       "Go SSO | Two-Factor Authentication",
+		"HasPasskeys": hasPasskeys,
 	}
 
-	register.Render(ctx, data)
+	mfa.Render(ctx, data)
 }
 
-func (h *AuthHandler) Register(ctx *gin.Context) {
+// VerifyMFA checks the second factor of a login that passed the password step
+// and signs the user in.
+func (h *AuthHandler) VerifyMFA(ctx *gin.Context) {
 	session := sessions.Default(ctx)
-	payload := new(webRequest.UserRegisterRequest)
+	userID, ok := h.pendingMFAUserID(session)
+	if !ok {
+		session.Set("error", "The login has expired, please sign in again")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+
+	payload := new(webRequest.MFACodeWebRequest)
 	if err := ctx.ShouldBind(payload); err != nil {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login/mfa")
 		return
 	}
-
-	err := h.Validate.Struct(payload)
-	if err != nil {
+	if err := h.Validate.Struct(payload); err != nil {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login/mfa")
 		return
 	}
 
-	factory := usecase.RegisterUserUseCaseFactory(h.Log)
-	resp, err := factory.Execute(usecase.IRegisterUserUseCaseRequest{
-		// Username:    payload.Username,
-		Email:    payload.Email,
-		Name:     payload.Name,
-		Password: payload.Password,
-		// Gender:      payload.Gender,
-		// MobilePhone: payload.MobilePhone,
-		// BirthDate:   payload.BirthDate,
-		// BirthPlace:  payload.BirthPlace,
-		// NoKTP:       payload.NoKTP,
+	factory := mfaUsecase.VerifyMFAUseCaseFactory(h.Log)
+	_, err := factory.Execute(&mfaUsecase.IVerifyMFAUseCaseRequest{
+		UserID: userID,
+		Code:   payload.Code,
 	})
+	if err != nil {
+		h.failMFA(ctx, session, err)
+		return
+	}
+
+	h.signInUserID(ctx, session, userID)
+}
+
+// PasskeyMFAOptions starts a passkey ceremony for the user waiting on the
+// second factor, limited to the passkeys of that user.
+func (h *AuthHandler) PasskeyMFAOptions(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userID, ok := h.pendingMFAUserID(session)
+	if !ok {
+		utils.ErrorResponse(ctx, 401, "error", "The login has expired, please sign in again")
+		return
+	}
 
+	factory := webauthnUsecase.FindWebAuthnCredentialsUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&webauthnUsecase.IFindWebAuthnCredentialsUseCaseRequest{
+		UserID: userID,
+	})
 	if err != nil {
-		session.Set("error", err.Error())
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
+	if len(resp.WebAuthnCredentials) == 0 {
+		utils.ErrorResponse(ctx, 404, "error", "No passkey is registered")
+		return
+	}
+
+	h.webAuthnAssertionOptions(ctx, session, webAuthnCeremonyMFA, webAuthnDescriptors(resp.WebAuthnCredentials), "preferred")
+}
+
+func (h *AuthHandler) PasskeyMFA(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userID, ok := h.pendingMFAUserID(session)
+	if !ok {
+		session.Set("error", "The login has expired, please sign in again")
 		session.Save()
-		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login")
 		return
 	}
 
-	var profile = entity.Profile{
-		ID:    resp.User.ID,
-		Name:  resp.User.Name,
-		Email: resp.User.Email,
-		// Username:        resp.User.Username,
-		IsEmployee:      h.hasEmployeeDataResp(resp.User),
-		EmailVerifiedAt: resp.User.EmailVerifiedAt,
+	_, err := h.verifyWebAuthnAssertion(ctx, session, webAuthnCeremonyMFA, &userID)
+	if err != nil {
+		h.failMFA(ctx, session, err)
+		return
 	}
 
-	session.Set("profile", profile)
-	session.Set("success", "User has been registered")
-	session.Save()
+	h.signInUserID(ctx, session, userID)
+}
 
-	userProfileMessageFactory := messaging.SendFindCreateUserProfileMessageFactory(h.Log)
-	_, err = userProfileMessageFactory.Execute(&messaging.ISendFindCreateUserProfileMessageRequest{
-		UserID: resp.User.ID,
-		Name:   resp.User.Name,
-	})
+// PasskeyLoginOptions starts a passkey ceremony for signing in without a
+// password. No credentials are listed, the authenticator offers the passkeys
+// it holds for this site.
+func (h *AuthHandler) PasskeyLoginOptions(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	h.webAuthnAssertionOptions(ctx, session, webAuthnCeremonyLogin, []map[string]interface{}{}, "required")
+}
+
+// PasskeyLogin signs the user in with a passkey. The authenticator verified
+// the user with a PIN or biometrics, so no second factor is asked.
+func (h *AuthHandler) PasskeyLogin(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userID, err := h.verifyWebAuthnAssertion(ctx, session, webAuthnCeremonyLogin, nil)
 	if err != nil {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login")
 		return
 	}
-	ctx.Redirect(302, "/otp")
+
+	h.signInUserID(ctx, session, userID)
 }
 
-func (h *AuthHandler) OtpView(ctx *gin.Context) {
-	otp := views.NewView("otp_base", "views/auth/otp.html")
+func (h *AuthHandler) MagicLinkView(ctx *gin.Context) {
+	magicLink := views.NewView("auth_base", "views/auth/magic_link.html")
 	data := map[string]interface{}{
-		"Title": "Go SSO | OTP",
+		"Title":       "Go SSO | Sign in with an email link",
+		"Application": ctx.Query("application"),
 	}
 
-	otp.Render(ctx, data)
+	magicLink.Render(ctx, data)
 }
 
-func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+// RequestMagicLink mails a sign-in link for the application. The flash
+// message is the same whether the email has an account or not.
+func (h *AuthHandler) RequestMagicLink(ctx *gin.Context) {
 	session := sessions.Default(ctx)
-	payload := new(webRequest.VerifyEmailRequest)
+	payload := new(webRequest.MagicLinkWebRequest)
 	if err := ctx.ShouldBind(payload); err != nil {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login/magic-link?application="+url.QueryEscape(ctx.PostForm("application")))
 		return
 	}
 
@@ -437,95 +502,554 @@ func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login/magic-link?application="+url.QueryEscape(payload.Application))
 		return
 	}
 
-	factory := usecase.VerifyEmailUseCaseFactory(h.Log)
-	resp, err := factory.Execute(usecase.IVerifyEmailUseCaseRequest{
-		Email: payload.Email,
-		Token: payload.Token,
+	factory := usecase.RequestMagicLinkUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IRequestMagicLinkUseCaseRequest{
+		Email:       payload.Email,
+		Application: payload.Application,
+		LinkURL:     utils.MagicLinkURL(h.Config),
+		From:        h.Config.GetString("mail.from"),
+		TTL:         utils.MagicLinkTTL(h.Config),
+		IPAddress:   ctx.ClientIP(),
 	})
-
 	if err != nil {
 		session.Set("error", err.Error())
 		session.Save()
 		h.Log.Printf(err.Error())
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login/magic-link?application="+url.QueryEscape(payload.Application))
 		return
 	}
 
-	token, err := utils.GenerateToken(resp.User)
-	if err != nil {
-		h.Log.Errorf("Error when generating token: %v", err)
-		session.Set("error", err.Error())
+	session.Set("success", resp.Message)
+	session.Save()
+	ctx.Redirect(302, "/login/magic-link?application="+url.QueryEscape(payload.Application))
+}
+
+// MagicLinkLoginView asks for a click before the link is used, so mail
+// scanners that open links do not use it up.
+func (h *AuthHandler) MagicLinkLoginView(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		session := sessions.Default(ctx)
+		session.Set("error", usecase.ErrInvalidMagicLink.Error())
 		session.Save()
-		ctx.Redirect(302, ctx.Request.Referer())
+		ctx.Redirect(302, "/login")
 		return
 	}
 
-	jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
-	jwtCookie.Domain = h.Config.GetString("app.domain")
-	utils.SetTokenCookie(ctx, token, jwtCookie)
+	magicLinkLogin := views.NewView("auth_base", "views/auth/magic_link_login.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | Sign in",
+		"Token": token,
+	}
 
-	session.Delete("profile")
+	magicLinkLogin.Render(ctx, data)
+}
 
-	profile := entity.Profile{
-		ID:              resp.User.ID,
-		Name:            resp.User.Name,
-		Email:           resp.User.Email,
-		Username:        resp.User.Username,
-		IsEmployee:      h.hasEmployeeData(resp.User),
-		EmailVerifiedAt: time.Now(),
+// MagicLinkLogin signs the user in with a magic link and sends them on like a
+// password login. When the link is opened in a browser that did not start the
+// login, the user is sent to the application the link was asked for.
+func (h *AuthHandler) MagicLinkLogin(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.MagicLinkLoginWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/login")
+		return
 	}
 
-	session.Set("profile", profile)
-	session.Save()
-	session.Delete("error")
-	session.Set("success", "Email has been verified")
-	if err := session.Save(); err != nil {
-		h.Log.Printf("[Auth handler] Session save error: %v", err)
-		ctx.Redirect(302, ctx.Request.Referer())
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/login")
 		return
 	}
 
-	filteredRoles := []entity.Role{}
-	for _, role := range resp.User.Roles {
-		if role.Name == "Applicant" {
-			filteredRoles = append(filteredRoles, role)
-			break
-		}
+	factory := usecase.MagicLinkLoginUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IMagicLinkLoginUseCaseRequest{
+		Token:     payload.Token,
+		IPAddress: ctx.ClientIP(),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/login")
+		return
 	}
-	resp.User.Roles = filteredRoles
-
-	if filteredRoles[0].Name == "Applicant" {
-		data, err := h.loginAsApplication(token, "recruitment", resp.User)
-		if err != nil {
-			session.Set("error", err.Error())
-			session.Save()
-			h.Log.Printf(err.Error())
-			ctx.Redirect(302, ctx.Request.Referer())
-			return
-		}
-
-		application := data["application"].(*entity.Application)
 
-		redirectURL := fmt.Sprintf("%s?token=%s", application.RedirectURI, data["token"])
-		h.Log.Printf("Redirecting to URL: %s", redirectURL)
-
-		if !strings.HasPrefix(redirectURL, "http") {
-			redirectURL = "http://" + redirectURL
-		}
+	_, authorizing := session.Get("authorize_request").(string)
+	_, approvingDevice := session.Get("device_user_code").(string)
+	_, samlLogin := session.Get("saml_request").(string)
+	if !authorizing && !approvingDevice && !samlLogin {
+		session.Set("login_application", resp.Application.Name)
+	}
 
-		ctx.Redirect(302, redirectURL)
+	if resp.MFARequired {
+		h.requireMFA(ctx, session, resp.User.ID)
 		return
 	}
 
-	ctx.Redirect(302, "/portal")
+	h.signIn(ctx, session, resp.User)
 }
 
-func (h *AuthHandler) ResendVerifyEmail(ctx *gin.Context) {
-	session := sessions.Default(ctx)
+// loginApplication returns the application the user asked to sign in to
+// without it starting the login, or fallback. It is only used once.
+func (h *AuthHandler) loginApplication(session sessions.Session, fallback string) string {
+	name, ok := session.Get("login_application").(string)
+	if !ok || name == "" {
+		return fallback
+	}
+	session.Delete("login_application")
+	session.Save()
+	return name
+}
+
+func (h *AuthHandler) webAuthnAssertionOptions(ctx *gin.Context, session sessions.Session, purpose string, allowCredentials []map[string]interface{}, userVerification string) {
+	challenge, err := startWebAuthnCeremony(session, purpose)
+	if err != nil {
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
+	session.Save()
+
+	rp := utils.WebAuthnRelyingPartyFromConfig(h.Config)
+	utils.SuccessResponse(ctx, 200, "success", map[string]interface{}{
+		"challenge":        challenge,
+		"rpId":             rp.ID,
+		"timeout":          webAuthnCeremonyTTL.Milliseconds(),
+		"allowCredentials": allowCredentials,
+		"userVerification": userVerification,
+	})
+}
+
+// verifyWebAuthnAssertion checks the passkey posted by the browser and returns
+// the user it belongs to. User verification is required unless the passkey
+// is a second factor for userID.
+func (h *AuthHandler) verifyWebAuthnAssertion(ctx *gin.Context, session sessions.Session, purpose string, userID *uuid.UUID) (uuid.UUID, error) {
+	challenge, err := finishWebAuthnCeremony(session, purpose)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	payload := new(webRequest.WebAuthnLoginWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		return uuid.Nil, err
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		return uuid.Nil, err
+	}
+	credential, err := parseWebAuthnCredential(payload.Credential)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	clientDataJSON, errClientData := utils.DecodeWebAuthnBytes(credential.Response.ClientDataJSON)
+	authenticatorData, errAuthData := utils.DecodeWebAuthnBytes(credential.Response.AuthenticatorData)
+	signature, errSignature := utils.DecodeWebAuthnBytes(credential.Response.Signature)
+	userHandle, errUserHandle := utils.DecodeWebAuthnBytes(credential.Response.UserHandle)
+	if errClientData != nil || errAuthData != nil || errSignature != nil || errUserHandle != nil {
+		return uuid.Nil, errors.New("Invalid passkey response")
+	}
+
+	factory := webauthnUsecase.AuthenticateWebAuthnUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&webauthnUsecase.IAuthenticateWebAuthnUseCaseRequest{
+		RelyingParty:            utils.WebAuthnRelyingPartyFromConfig(h.Config),
+		Challenge:               challenge,
+		CredentialID:            credential.RawID,
+		ClientDataJSON:          clientDataJSON,
+		AuthenticatorData:       authenticatorData,
+		Signature:               signature,
+		UserHandle:              userHandle,
+		UserID:                  userID,
+		RequireUserVerification: userID == nil,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return resp.UserID, nil
+}
+
+// failMFA counts a wrong second factor; after mfaUsecase.MaxMFAAttempts the
+// login starts over from the password.
+func (h *AuthHandler) failMFA(ctx *gin.Context, session sessions.Session, err error) {
+	h.Log.Printf(err.Error())
+	attempts, _ := session.Get("mfa_attempts").(int)
+	attempts++
+	if attempts >= mfaUsecase.MaxMFAAttempts {
+		h.clearPendingMFA(session)
+		session.Set("error", "Too many invalid codes, please sign in again")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+	session.Set("mfa_attempts", attempts)
+	session.Set("error", err.Error())
+	session.Save()
+	ctx.Redirect(302, "/login/mfa")
+}
+
+func (h *AuthHandler) signInUserID(ctx *gin.Context, session sessions.Session, userID uuid.UUID) {
+	userFactory := usecase.FindByIdUseCaseFactory(h.Log)
+	userResp, err := userFactory.Execute(&usecase.IFindByIdUseCaseRequest{
+		ID: userID,
+	})
+	if err != nil || userResp.User == nil {
+		h.clearPendingMFA(session)
+		session.Set("error", "User not found")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+
+	h.signIn(ctx, session, userResp.User)
+}
+
+// pendingMFAUserID returns the user waiting on the second factor, as long as
+// the password was entered less than mfa.challenge_ttl seconds ago.
+func (h *AuthHandler) pendingMFAUserID(session sessions.Session) (uuid.UUID, bool) {
+	rawUserID, ok := session.Get("mfa_user_id").(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	startedAt, _ := session.Get("mfa_started_at").(int64)
+	ttl := h.Config.GetInt64("mfa.challenge_ttl")
+	if ttl <= 0 {
+		ttl = 300
+	}
+	if time.Now().Unix()-startedAt > ttl {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *AuthHandler) clearPendingMFA(session sessions.Session) {
+	session.Delete("mfa_user_id")
+	session.Delete("mfa_started_at")
+	session.Delete("mfa_attempts")
+}
+
+// signIn starts the web session of a user whose credentials were checked, and
+// sends them on to the role selection or the application waiting for them.
+func (h *AuthHandler) signIn(ctx *gin.Context, session sessions.Session, user *entity.User) {
+	var profile = entity.Profile{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Username:        user.Username,
+		IsEmployee:      h.hasEmployeeData(user),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+
+	h.clearPendingMFA(session)
+	session.Set("profile", profile)
+	session.Set("auth_time", time.Now().Unix())
+	session.Delete("choosed_role_id")
+	session.Delete("error")
+	if err := session.Save(); err != nil {
+		h.Log.Printf("[Auth handler] Session save error: %v", err)
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	// an OpenID Connect client, a SAML application or a device is waiting, let
+	// the user pick the role for it
+	_, authorizing := session.Get("authorize_request").(string)
+	_, approvingDevice := session.Get("device_user_code").(string)
+	_, samlLogin := session.Get("saml_request").(string)
+	if authorizing || approvingDevice || samlLogin {
+		ctx.Redirect(302, "/choose-roles")
+		return
+	}
+
+	if len(user.Roles) == 0 {
+		session.Delete("profile")
+		session.Set("error", "No role is assigned to this account")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+
+	filteredRoles := []entity.Role{}
+	for _, role := range user.Roles {
+		filteredRoles = append(filteredRoles, role)
+		break
+	}
+	user.Roles = filteredRoles
+	if filteredRoles[0].Name == "Applicant" {
+		token, err := utils.GenerateToken(user)
+		if err != nil {
+			h.Log.Errorf("Error when generating token: %v", err)
+			session.Set("error", err.Error())
+			session.Save()
+			ctx.Redirect(302, ctx.Request.Referer())
+			return
+		}
+
+		jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
+		jwtCookie.Domain = h.Config.GetString("app.domain")
+		utils.SetTokenCookie(ctx, token, jwtCookie)
+
+		session.Set("choosed_role_id", filteredRoles[0].ID.String())
+		session.Save()
+
+		if user.EmailVerifiedAt.IsZero() {
+			session.Set("error", "Email not verified")
+			session.Save()
+			ctx.Redirect(302, "/otp")
+			return
+		}
+
+		h.redirectToApplication(ctx, h.loginApplication(session, "recruitment"))
+		return
+	}
+
+	ctx.Redirect(302, "/choose-roles")
+	return
+}
+
+func (h *AuthHandler) RegisterView(ctx *gin.Context) {
+	register := views.NewView("auth_base", "views/auth/register.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | Register",
+	}
+
+	register.Render(ctx, data)
+}
+
+func (h *AuthHandler) Register(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.UserRegisterRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	factory := usecase.RegisterUserUseCaseFactory(h.Log)
+	resp, err := factory.Execute(usecase.IRegisterUserUseCaseRequest{
+		// Username:    payload.Username,
+		Email:    payload.Email,
+		Name:     payload.Name,
+		Password: payload.Password,
+		// Gender:      payload.Gender,
+		// MobilePhone: payload.MobilePhone,
+		// BirthDate:   payload.BirthDate,
+		// BirthPlace:  payload.BirthPlace,
+		// NoKTP:       payload.NoKTP,
+		PasswordPolicy:          utils.PasswordPolicyFromConfig(h.Config),
+		EmailVerificationPolicy: utils.EmailVerificationPolicyFromConfig(h.Config),
+		From:                    h.Config.GetString("mail.from"),
+	})
+
+	if err != nil {
+		flashError(session, err)
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	var profile = entity.Profile{
+		ID:    resp.User.ID,
+		Name:  resp.User.Name,
+		Email: resp.User.Email,
+		// Username:        resp.User.Username,
+		IsEmployee:      h.hasEmployeeDataResp(resp.User),
+		EmailVerifiedAt: resp.User.EmailVerifiedAt,
+	}
+
+	session.Set("profile", profile)
+	session.Set("success", "User has been registered")
+	session.Save()
+
+	userProfileMessageFactory := messaging.SendFindCreateUserProfileMessageFactory(h.Log)
+	_, err = userProfileMessageFactory.Execute(&messaging.ISendFindCreateUserProfileMessageRequest{
+		UserID: resp.User.ID,
+		Name:   resp.User.Name,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+	ctx.Redirect(302, "/otp")
+}
+
+func (h *AuthHandler) OtpView(ctx *gin.Context) {
+	otp := views.NewView("otp_base", "views/auth/otp.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | OTP",
+	}
+
+	otp.Render(ctx, data)
+}
+
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.VerifyEmailRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	factory := usecase.VerifyEmailUseCaseFactory(h.Log)
+	resp, err := factory.Execute(usecase.IVerifyEmailUseCaseRequest{
+		Email:                   payload.Email,
+		Token:                   payload.Token,
+		IPAddress:               ctx.ClientIP(),
+		EmailVerificationPolicy: utils.EmailVerificationPolicyFromConfig(h.Config),
+	})
+
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	h.emailVerified(ctx, session, resp.User)
+}
+
+// VerifyEmailLink verifies the email with the signed link mailed along with
+// the code. The link may be opened in another browser than the one that
+// registered, that browser is only signed in if it already was as the user.
+func (h *AuthHandler) VerifyEmailLink(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+
+	factory := usecase.VerifyEmailUseCaseFactory(h.Log)
+	resp, err := factory.Execute(usecase.IVerifyEmailUseCaseRequest{
+		Email:                   ctx.Query("email"),
+		Expires:                 ctx.Query("expires"),
+		Signature:               ctx.Query("signature"),
+		IPAddress:               ctx.ClientIP(),
+		EmailVerificationPolicy: utils.EmailVerificationPolicyFromConfig(h.Config),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		if profile, ok := session.Get("profile").(entity.Profile); ok && profile.Email != "" {
+			ctx.Redirect(302, "/otp")
+			return
+		}
+		ctx.Redirect(302, "/login")
+		return
+	}
+
+	if profile, ok := session.Get("profile").(entity.Profile); ok && profile.ID == resp.User.ID {
+		h.emailVerified(ctx, session, resp.User)
+		return
+	}
+
+	session.Set("success", "Email has been verified, please sign in")
+	session.Save()
+	ctx.Redirect(302, "/login")
+}
+
+// emailVerified signs in the user whose email was just verified and sends
+// them on to where they were going.
+func (h *AuthHandler) emailVerified(ctx *gin.Context, session sessions.Session, user *entity.User) {
+	token, err := utils.GenerateToken(user)
+	if err != nil {
+		h.Log.Errorf("Error when generating token: %v", err)
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	jwtCookie := utils.NewDefaultCookieOptions("jwt_token")
+	jwtCookie.Domain = h.Config.GetString("app.domain")
+	utils.SetTokenCookie(ctx, token, jwtCookie)
+
+	session.Delete("profile")
+
+	profile := entity.Profile{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Username:        user.Username,
+		IsEmployee:      h.hasEmployeeData(user),
+		EmailVerifiedAt: time.Now(),
+	}
+
+	session.Set("profile", profile)
+	session.Save()
+	session.Delete("error")
+	session.Set("success", "Email has been verified")
+	if err := session.Save(); err != nil {
+		h.Log.Printf("[Auth handler] Session save error: %v", err)
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	_, authorizing := session.Get("authorize_request").(string)
+	_, samlLogin := session.Get("saml_request").(string)
+	if authorizing || samlLogin {
+		ctx.Redirect(302, "/choose-roles")
+		return
+	}
+
+	filteredRoles := []entity.Role{}
+	for _, role := range user.Roles {
+		if role.Name == "Applicant" {
+			filteredRoles = append(filteredRoles, role)
+			break
+		}
+	}
+	user.Roles = filteredRoles
+
+	if len(filteredRoles) > 0 {
+		session.Set("choosed_role_id", filteredRoles[0].ID.String())
+		session.Save()
+
+		h.redirectToApplication(ctx, "recruitment")
+		return
+	}
+
+	ctx.Redirect(302, "/portal")
+}
+
+func (h *AuthHandler) ResendVerifyEmail(ctx *gin.Context) {
+	session := sessions.Default(ctx)
 	email := ctx.Param("email")
 	if email == "" {
 		session.Set("error", "Email not found")
@@ -537,7 +1061,10 @@ func (h *AuthHandler) ResendVerifyEmail(ctx *gin.Context) {
 
 	factory := usecase.ResendVerfiyEmailUseCaseFactory(h.Log)
 	_, err := factory.Execute(usecase.IResendVerfiyEmailUseCaseRequest{
-		Email: email,
+		Email:                   email,
+		IPAddress:               ctx.ClientIP(),
+		EmailVerificationPolicy: utils.EmailVerificationPolicyFromConfig(h.Config),
+		From:                    h.Config.GetString("mail.from"),
 	})
 
 	if err != nil {
@@ -554,6 +1081,146 @@ func (h *AuthHandler) ResendVerifyEmail(ctx *gin.Context) {
 	return
 }
 
+func (h *AuthHandler) ForgotPasswordView(ctx *gin.Context) {
+	forgotPassword := views.NewView("auth_base", "views/auth/forgot_password.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | Forgot Password",
+	}
+
+	forgotPassword.Render(ctx, data)
+}
+
+// ForgotPassword mails a password reset link. The flash message is the same
+// whether the email has an account or not.
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.ForgotPasswordWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
+
+	factory := usecase.ForgotPasswordUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IForgotPasswordUseCaseRequest{
+		Email:    payload.Email,
+		ResetURL: utils.PasswordResetURL(h.Config),
+		From:     h.Config.GetString("mail.from"),
+		TTL:      utils.PasswordResetTTL(h.Config),
+	})
+	if err != nil {
+		session.Set("error", "Something went wrong, please try again later")
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
+
+	session.Set("success", resp.Message)
+	session.Save()
+	ctx.Redirect(302, "/forgot-password")
+}
+
+func (h *AuthHandler) ResetPasswordView(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		session := sessions.Default(ctx)
+		session.Set("error", usecase.ErrInvalidResetToken.Error())
+		session.Save()
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
+
+	resetPassword := views.NewView("auth_base", "views/auth/reset_password.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | Reset Password",
+		"Token": token,
+	}
+
+	resetPassword.Render(ctx, data)
+}
+
+// ResetPassword sets the new password and signs the user out of every
+// browser, token and application.
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.ResetPasswordWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/reset-password?token="+url.QueryEscape(ctx.PostForm("token")))
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/reset-password?token="+url.QueryEscape(payload.Token))
+		return
+	}
+
+	factory := usecase.ResetPasswordUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IResetPasswordUseCaseRequest{
+		Token:          payload.Token,
+		Password:       payload.Password,
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
+	})
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		// the link is still valid, let the user pick another password
+		flashError(session, err)
+		session.Save()
+		ctx.Redirect(302, "/reset-password?token="+url.QueryEscape(payload.Token))
+		return
+	}
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, "/forgot-password")
+		return
+	}
+
+	logoutFactory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+	if _, err := logoutFactory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+		UserID: resp.User.ID,
+		Issuer: utils.OIDCIssuer(h.Config),
+	}); err != nil {
+		h.Log.Printf(err.Error())
+	}
+
+	session.Set("success", "Your password has been reset, please sign in with the new password")
+	session.Save()
+	ctx.Redirect(302, "/login")
+}
+
+// flashError flashes every violation of a password policy error under
+// "errors" so the views list them, any other error under "error".
+func flashError(session sessions.Session, err error) {
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		for _, violation := range policyErr.Violations {
+			session.AddFlash(violation.Message, "errors")
+		}
+		return
+	}
+	session.Set("error", err.Error())
+}
+
 func (h *AuthHandler) CheckCookieTest(ctx *gin.Context) {
 	cookie, err := utils.GetTokenFromCookie(ctx, "test_haha")
 	if err != nil {
@@ -577,24 +1244,238 @@ func (h *AuthHandler) checkUserRole(user *entity.User, role string) bool {
 	return false
 }
 
-func (h *AuthHandler) loginAsApplication(token string, state string, user *entity.User) (map[string]interface{}, error) {
-	factory := appUsecase.FindApplicationByNameUsecaseFactory(h.Log)
-	application, err := factory.Execute(&appUsecase.IFindApplicationByNameUsecaseRequest{
-		Name: state,
+// startApplicationLogin turns a ?state=<application name> login link into a
+// pending authorization request. Once the user has signed in and picked a
+// role, /oauth2/authorize sends the application a one-time code bound to its
+// redirect URI and the PKCE challenge from the link.
+func (h *AuthHandler) startApplicationLogin(ctx *gin.Context, state string) {
+	session := sessions.Default(ctx)
+	codeChallengeMethod := ctx.DefaultQuery("code_challenge_method", "S256")
+
+	factory := oidcUsecase.ValidateAuthorizationRequestUseCaseFactory(h.Log)
+	validated, err := factory.Execute(&oidcUsecase.IValidateAuthorizationRequestUseCaseRequest{
+		ClientID:            state,
+		RedirectURI:         ctx.Query("redirect_uri"),
+		ResponseType:        "code",
+		Scope:               "openid profile email",
+		CodeChallenge:       ctx.Query("code_challenge"),
+		CodeChallengeMethod: codeChallengeMethod,
 	})
+	if err != nil {
+		h.Log.Warnf("Rejected login for application %s: %v", state, err)
+		session.Set("error", err.Error())
+		session.Save()
+		return
+	}
 
+	params := url.Values{}
+	params.Set("client_id", state)
+	params.Set("response_type", "code")
+	params.Set("scope", "openid profile email")
+	// an omitted redirect_uri stays omitted, the token request then need not
+	// repeat the default one
+	if ctx.Query("redirect_uri") != "" {
+		params.Set("redirect_uri", validated.RedirectURI)
+	}
+	params.Set("code_challenge", ctx.Query("code_challenge"))
+	params.Set("code_challenge_method", codeChallengeMethod)
+	params.Set("state", state)
+	session.Set("authorize_request", params.Encode())
+	session.Save()
+}
+
+// redirectToApplication sends the user to an application that did not start
+// the login itself. Only the issuer is passed along (OpenID Connect
+// third-party initiated login): the application starts its own authorization
+// request, which the existing session completes without asking again.
+func (h *AuthHandler) redirectToApplication(ctx *gin.Context, name string) {
+	session := sessions.Default(ctx)
+	factory := appUsecase.FindApplicationByNameUsecaseFactory(h.Log)
+	resp, err := factory.Execute(&appUsecase.IFindApplicationByNameUsecaseRequest{
+		Name: name,
+	})
 	if err != nil {
 		h.Log.Errorf("Error when finding application: %v", err)
-		return nil, err
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
 	}
 
-	data := map[string]interface{}{
-		"token":       token,
-		"application": application.Application,
-		"user":        user,
+	redirectURL := resp.Application.RedirectURI
+	if !strings.HasPrefix(redirectURL, "http") {
+		redirectURL = "http://" + redirectURL
+	}
+
+	if u, err := url.Parse(redirectURL); err == nil {
+		query := u.Query()
+		query.Set("iss", utils.OIDCIssuer(h.Config))
+		u.RawQuery = query.Encode()
+		redirectURL = u.String()
+	}
+
+	h.Log.Printf("Redirecting to URL: %s", redirectURL)
+	ctx.Redirect(302, redirectURL)
+}
+
+// DeviceView is the verification page of the device authorization grant. A
+// valid user code is kept in the session and the user goes through the usual
+// login and role choice before being asked to approve the device.
+func (h *AuthHandler) DeviceView(ctx *gin.Context) {
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		device := views.NewView("auth_base", "views/auth/device.html")
+		device.Render(ctx, map[string]interface{}{
+			"Title": "Go SSO | Connect a Device",
+		})
+		return
+	}
+
+	session := sessions.Default(ctx)
+	factory := oidcUsecase.FindDeviceAuthorizationUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&oidcUsecase.IFindDeviceAuthorizationUseCaseRequest{
+		UserCode: userCode,
+	})
+	if err != nil {
+		h.Log.Warnf("Rejected device code: %v", err)
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	session.Set("device_user_code", resp.DeviceAuthorization.UserCode)
+	session.Save()
+
+	if _, ok := session.Get("profile").(entity.Profile); !ok {
+		ctx.Redirect(302, "/login")
+		return
+	}
+	ctx.Redirect(302, "/choose-roles")
+}
+
+func (h *AuthHandler) DeviceApproveView(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userCode, _ := session.Get("device_user_code").(string)
+	roleID, _ := session.Get("choosed_role_id").(string)
+	if userCode == "" || roleID == "" {
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	factory := oidcUsecase.FindDeviceAuthorizationUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&oidcUsecase.IFindDeviceAuthorizationUseCaseRequest{
+		UserCode: userCode,
+	})
+	if err != nil {
+		session.Delete("device_user_code")
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	profile, ok := session.Get("profile").(entity.Profile)
+	if !ok {
+		session.Set("error", "Profile not found")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+	userFactory := usecase.FindByIdUseCaseFactory(h.Log)
+	userResp, err := userFactory.Execute(&usecase.IFindByIdUseCaseRequest{
+		ID: profile.ID,
+	})
+	if err != nil || userResp.User == nil {
+		session.Set("error", "User not found")
+		session.Save()
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	var role *entity.Role
+	for i := range userResp.User.Roles {
+		if userResp.User.Roles[i].ID.String() == roleID {
+			role = &userResp.User.Roles[i]
+			break
+		}
+	}
+	if role == nil {
+		ctx.Redirect(302, "/choose-roles")
+		return
 	}
 
-	return data, nil
+	approve := views.NewView("auth_base", "views/auth/device_approve.html")
+	approve.Render(ctx, map[string]interface{}{
+		"Title":       "Go SSO | Connect a Device",
+		"Application": resp.DeviceAuthorization.Application,
+		"Role":        role,
+		"UserCode":    resp.DeviceAuthorization.UserCode,
+	})
+}
+
+func (h *AuthHandler) DeviceApprove(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.DeviceApproveWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	// the code on the form must be the one this session was asked about
+	userCode, _ := session.Get("device_user_code").(string)
+	roleID, err := uuid.Parse(fmt.Sprint(session.Get("choosed_role_id")))
+	if userCode == "" || userCode != payload.UserCode || err != nil {
+		session.Set("error", "The code is invalid or has expired")
+		session.Save()
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	authTime := time.Now()
+	if loggedInAt, ok := session.Get("auth_time").(int64); ok {
+		authTime = time.Unix(loggedInAt, 0)
+	}
+
+	profile, ok := session.Get("profile").(entity.Profile)
+	if !ok {
+		session.Set("error", "Profile not found")
+		session.Save()
+		ctx.Redirect(302, "/login")
+		return
+	}
+	factory := oidcUsecase.DecideDeviceAuthorizationUseCaseFactory(h.Log)
+	_, err = factory.Execute(&oidcUsecase.IDecideDeviceAuthorizationUseCaseRequest{
+		UserCode: userCode,
+		UserID:   profile.ID,
+		RoleID:   roleID,
+		AuthTime: authTime,
+		Approve:  payload.Action == "approve",
+	})
+	session.Delete("device_user_code")
+	if err != nil {
+		h.Log.Warnf("Error when deciding device authorization: %v", err)
+		session.Set("error", err.Error())
+		session.Save()
+		ctx.Redirect(302, "/device")
+		return
+	}
+
+	if payload.Action == "approve" {
+		session.Set("success", "Your device is connected, you can return to it now")
+	} else {
+		session.Set("success", "The device was not connected")
+	}
+	session.Save()
+	ctx.Redirect(302, "/device")
 }
 
 func (h *AuthHandler) hasEmployeeData(user *entity.User) bool {
@@ -606,10 +1487,100 @@ func (h *AuthHandler) hasEmployeeDataResp(user *response.UserResponse) bool {
 }
 
 func (h *AuthHandler) Logout(ctx *gin.Context) {
+	// the cookie token may have been handed to other services, make sure it stops working
+	if token, err := utils.GetTokenFromCookie(ctx, "jwt_token"); err == nil && token != "" {
+		factory := authUsecase.RevokeTokenUseCaseFactory(h.Log)
+		if _, err := factory.Execute(&authUsecase.IRevokeTokenUseCaseRequest{
+			Token:         token,
+			TokenTypeHint: "access_token",
+		}); err != nil {
+			h.Log.Errorf("Error when revoking token: %v", err)
+		}
+	}
+
 	session := utils.NewSession(ctx)
+	frontChannelLogoutURIs := h.logoutApplications(session)
+	session.Delete("sid")
 	session.Delete("profile")
+	session.Delete("choosed_role_id")
+	session.Delete("auth_time")
+	session.Delete("authorize_request")
+	session.Delete("device_user_code")
+	session.Delete("saml_request")
+	h.clearPendingMFA(session)
 	session.Set("success", "You have been logged out")
 	session.Save()
 	utils.ClearTokenCookie(ctx, "jwt_token", h.Config.GetString("app.domain"))
-	ctx.Redirect(302, "/login")
+
+	redirectURL := h.postLogoutRedirectURL(ctx)
+	if redirectURL == "" {
+		redirectURL = "/login"
+	}
+
+	// front-channel logout needs the browser to visit every application first
+	if len(frontChannelLogoutURIs) > 0 {
+		logoutView := views.NewView("auth_base", "views/auth/logout.html")
+		logoutView.Render(ctx, map[string]interface{}{
+			"Title":                  "Go SSO | Logout",
+			"RedirectURL":            redirectURL,
+			"FrontChannelLogoutURIs": frontChannelLogoutURIs,
+		})
+		return
+	}
+	ctx.Redirect(302, redirectURL)
+}
+
+// logoutApplications ends the applications that got tokens from the current
+// login and returns their front-channel logout URIs.
+func (h *AuthHandler) logoutApplications(session sessions.Session) []string {
+	sessionID, ok := session.Get("sid").(string)
+	if !ok || sessionID == "" {
+		return nil
+	}
+
+	factory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+		SessionID: sessionID,
+		Issuer:    utils.OIDCIssuer(h.Config),
+	})
+	if err != nil {
+		h.Log.Errorf("Error when logging out applications: %v", err)
+		return nil
+	}
+	return resp.FrontChannelLogoutURIs
+}
+
+// postLogoutRedirectURL returns where an application asked to be sent after
+// logging out (OpenID Connect RP-initiated logout). The URI must be one of the
+// post logout redirect URIs registered for client_id, otherwise it is ignored.
+func (h *AuthHandler) postLogoutRedirectURL(ctx *gin.Context) string {
+	clientID := ctx.Query("client_id")
+	redirectURI := ctx.Query("post_logout_redirect_uri")
+	if clientID == "" || redirectURI == "" {
+		return ""
+	}
+
+	factory := appUsecase.FindApplicationByNameUsecaseFactory(h.Log)
+	resp, err := factory.Execute(&appUsecase.IFindApplicationByNameUsecaseRequest{
+		Name: clientID,
+	})
+	if err != nil {
+		h.Log.Errorf("Error when finding application: %v", err)
+		return ""
+	}
+	if !resp.Application.HasRedirectURI(redirectURI, entity.REDIRECT_URI_POST_LOGOUT) {
+		h.Log.Warnf("Post logout redirect URI %s is not registered for %s", redirectURI, clientID)
+		return ""
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return ""
+	}
+	if state := ctx.Query("state"); state != "" {
+		query := u.Query()
+		query.Set("state", state)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
 }
@@ -7,10 +7,8 @@ import (
 	"app/go-sso/views"
 	"fmt"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -62,38 +60,6 @@ func (h *DashboardHandler) Portal(ctx *gin.Context) {
 		ctx.Redirect(302, "/logout")
 		return
 	}
-	session := sessions.Default(ctx)
-	// token := ctx.Query("token")
-	token, err := utils.GetTokenFromCookie(ctx, "jwt_token")
-	if err != nil {
-		ctx.JSON(200, gin.H{
-			"message": "Cookie not found",
-		})
-		return
-	}
-
-	if token == "" {
-		session.Set("error", "There are no token")
-		session.Save()
-		ctx.Redirect(302, "/logout")
-		return
-	}
-
-	isJWT, err := h.checkIfTokenIsJWT(token)
-	if err != nil {
-		h.Log.Error(err)
-		session.Set("error", err.Error())
-		session.Save()
-		ctx.Redirect(302, "/login")
-		return
-	}
-
-	if !isJWT {
-		session.Set("error", "Invalid token")
-		session.Save()
-		ctx.Redirect(302, "/logout")
-		return
-	}
 
 	factory := usecase.GetAllApplicationsUseCaseFactory(h.Log)
 
@@ -106,27 +72,7 @@ func (h *DashboardHandler) Portal(ctx *gin.Context) {
 	data := map[string]interface{}{
 		"Title":        "Go SSO | Portal",
 		"Applications": resp.Applications,
-		"Token":        token,
+		"Issuer":       utils.OIDCIssuer(h.Config),
 	}
 	index.Render(ctx, data)
 }
-
-func (h *DashboardHandler) checkIfTokenIsJWT(stateToken string) (bool, error) {
-	jwtToken, err := jwt.Parse(stateToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(h.Config.GetString("jwt.secret")), nil
-	})
-
-	if err != nil {
-		return false, err
-	}
-
-	if _, ok := jwtToken.Claims.(jwt.MapClaims); ok && jwtToken.Valid {
-		return true, nil
-	} else {
-		return false, nil
-	}
-
-}
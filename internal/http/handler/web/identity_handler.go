@@ -0,0 +1,147 @@
+package web
+
+import (
+	"app/go-sso/internal/entity"
+	webRequest "app/go-sso/internal/http/request/web/user"
+	"app/go-sso/internal/service"
+	identityUsecase "app/go-sso/internal/usecase/user_identity"
+	"app/go-sso/views"
+	"fmt"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type IdentityHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+	// UpstreamProviderService lists the providers accounts can be linked at.
+	UpstreamProviderService service.IUpstreamProviderService
+}
+
+type IdentityHandlerInterface interface {
+	Index(ctx *gin.Context)
+	Unlink(ctx *gin.Context)
+}
+
+func IdentityHandlerFactory(log *logrus.Logger, validator *validator.Validate, upstreamProviderService service.IUpstreamProviderService) IdentityHandlerInterface {
+	config := viper.New()
+	config.SetConfigName("config")
+	config.SetConfigType("json")
+	config.AddConfigPath("./")
+	err := config.ReadInConfig()
+
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+	return &IdentityHandler{
+		Config:   config,
+		Log:      log,
+		Validate: validator,
+
+		UpstreamProviderService: upstreamProviderService,
+	}
+}
+
+// linkedAccount is a row of the linked accounts page, Identity is nil while
+// the provider is not linked. Identities of providers removed from the
+// configuration are listed too, so they can still be unlinked.
+type linkedAccount struct {
+	Provider entity.UserIdentityProvider
+	Label    string
+	Identity *entity.UserIdentity
+}
+
+func (h *IdentityHandler) Index(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := identityUsecase.FindUserIdentitiesUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&identityUsecase.IFindUserIdentitiesUseCaseRequest{
+		UserID: profile.ID,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		session.Set("error", err.Error())
+		session.Save()
+	}
+
+	upstreamProviders, err := h.UpstreamProviderService.FindAll()
+	if err != nil {
+		h.Log.Error(err)
+		session.Set("error", err.Error())
+		session.Save()
+	}
+
+	linkedAccounts := []linkedAccount{}
+	for _, upstreamProvider := range upstreamProviders {
+		linkedAccounts = append(linkedAccounts, linkedAccount{
+			Provider: entity.UserIdentityProvider(upstreamProvider.Name),
+			Label:    upstreamProvider.Label,
+		})
+	}
+	if resp != nil {
+	identities:
+		for i := range resp.UserIdentities {
+			for j := range linkedAccounts {
+				if linkedAccounts[j].Provider == resp.UserIdentities[i].Provider {
+					linkedAccounts[j].Identity = &resp.UserIdentities[i]
+					continue identities
+				}
+			}
+			linkedAccounts = append(linkedAccounts, linkedAccount{
+				Provider: resp.UserIdentities[i].Provider,
+				Label:    string(resp.UserIdentities[i].Provider),
+				Identity: &resp.UserIdentities[i],
+			})
+		}
+	}
+
+	index := views.NewView("base", "views/identities/index.html")
+	data := map[string]interface{}{
+		"Title":          "Go SSO | Linked Accounts",
+		"LinkedAccounts": linkedAccounts,
+	}
+	index.Render(ctx, data)
+}
+
+func (h *IdentityHandler) Unlink(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload := new(webRequest.UnlinkUserIdentityWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
+		return
+	}
+
+	factory := identityUsecase.UnlinkUserIdentityUseCaseFactory(h.Log)
+	err := factory.Execute(&identityUsecase.IUnlinkUserIdentityUseCaseRequest{
+		UserID:   profile.ID,
+		Provider: entity.UserIdentityProvider(payload.Provider),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/linked-accounts")
+		return
+	}
+
+	session.Set("success", "The account has been unlinked")
+	session.Save()
+	ctx.Redirect(302, "/linked-accounts")
+}
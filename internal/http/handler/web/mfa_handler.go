@@ -0,0 +1,195 @@
+package web
+
+import (
+	"app/go-sso/internal/entity"
+	webRequest "app/go-sso/internal/http/request/web/user"
+	mfaUsecase "app/go-sso/internal/usecase/mfa"
+	"app/go-sso/views"
+	"fmt"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type MFAHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+}
+
+type MFAHandlerInterface interface {
+	Index(ctx *gin.Context)
+	Enroll(ctx *gin.Context)
+	Confirm(ctx *gin.Context)
+	Disable(ctx *gin.Context)
+	RegenerateRecoveryCodes(ctx *gin.Context)
+}
+
+func MFAHandlerFactory(log *logrus.Logger, validator *validator.Validate) MFAHandlerInterface {
+	config := viper.New()
+	config.SetConfigName("config")
+	config.SetConfigType("json")
+	config.AddConfigPath("./")
+	err := config.ReadInConfig()
+
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+	return &MFAHandler{
+		Config:   config,
+		Log:      log,
+		Validate: validator,
+	}
+}
+
+func (h *MFAHandler) Index(ctx *gin.Context) {
+	h.render(ctx, nil)
+}
+
+// Enroll creates the secret for the authenticator app; two-factor
+// authentication is only enabled once Confirm receives a first code.
+func (h *MFAHandler) Enroll(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := mfaUsecase.EnrollTOTPUseCaseFactory(h.Log)
+	_, err := factory.Execute(&mfaUsecase.IEnrollTOTPUseCaseRequest{
+		UserID: profile.ID,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+	}
+
+	ctx.Redirect(302, "/two-factor")
+}
+
+func (h *MFAHandler) Confirm(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload, ok := h.bindCode(ctx, session)
+	if !ok {
+		return
+	}
+
+	factory := mfaUsecase.ConfirmTOTPUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&mfaUsecase.IConfirmTOTPUseCaseRequest{
+		UserID: profile.ID,
+		Code:   payload.Code,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/two-factor")
+		return
+	}
+
+	session.Set("success", "Two-factor authentication has been enabled")
+	session.Save()
+	h.render(ctx, resp.RecoveryCodes)
+}
+
+func (h *MFAHandler) Disable(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload, ok := h.bindCode(ctx, session)
+	if !ok {
+		return
+	}
+
+	factory := mfaUsecase.DisableTOTPUseCaseFactory(h.Log)
+	err := factory.Execute(&mfaUsecase.IDisableTOTPUseCaseRequest{
+		UserID: profile.ID,
+		Code:   payload.Code,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/two-factor")
+		return
+	}
+
+	session.Set("success", "Two-factor authentication has been disabled")
+	session.Save()
+	ctx.Redirect(302, "/two-factor")
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload, ok := h.bindCode(ctx, session)
+	if !ok {
+		return
+	}
+
+	factory := mfaUsecase.RegenerateRecoveryCodesUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&mfaUsecase.IRegenerateRecoveryCodesUseCaseRequest{
+		UserID: profile.ID,
+		Code:   payload.Code,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/two-factor")
+		return
+	}
+
+	session.Set("success", "New recovery codes have been generated")
+	session.Save()
+	h.render(ctx, resp.RecoveryCodes)
+}
+
+func (h *MFAHandler) bindCode(ctx *gin.Context, session sessions.Session) (*webRequest.MFACodeWebRequest, bool) {
+	payload := new(webRequest.MFACodeWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/two-factor")
+		return nil, false
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/two-factor")
+		return nil, false
+	}
+	return payload, true
+}
+
+// render shows the two-factor settings. Recovery codes are only passed right
+// after they are generated, they cannot be shown again.
+func (h *MFAHandler) render(ctx *gin.Context, recoveryCodes []string) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := mfaUsecase.FindMFAStatusUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&mfaUsecase.IFindMFAStatusUseCaseRequest{
+		UserID:      profile.ID,
+		Issuer:      h.Config.GetString("app.name"),
+		AccountName: profile.Email,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		session.Set("error", err.Error())
+		session.Save()
+	}
+
+	index := views.NewView("base", "views/mfa/index.html")
+	data := map[string]interface{}{
+		"Title":         "Go SSO | Two-Factor Authentication",
+		"RecoveryCodes": recoveryCodes,
+	}
+	if resp != nil {
+		data["MFA"] = resp
+	}
+	index.Render(ctx, data)
+}
@@ -0,0 +1,361 @@
+package web
+
+import (
+	"app/go-sso/internal/entity"
+	webRequest "app/go-sso/internal/http/request/web/user"
+	webauthnUsecase "app/go-sso/internal/usecase/webauthn"
+	"app/go-sso/utils"
+	"app/go-sso/views"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type PasskeyHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+}
+
+type PasskeyHandlerInterface interface {
+	Index(ctx *gin.Context)
+	RegistrationOptions(ctx *gin.Context)
+	Register(ctx *gin.Context)
+	Rename(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+func PasskeyHandlerFactory(log *logrus.Logger, validator *validator.Validate) PasskeyHandlerInterface {
+	config := viper.New()
+	config.SetConfigName("config")
+	config.SetConfigType("json")
+	config.AddConfigPath("./")
+	err := config.ReadInConfig()
+
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+	return &PasskeyHandler{
+		Config:   config,
+		Log:      log,
+		Validate: validator,
+	}
+}
+
+// Passkey ceremonies waiting for the browser, stored in the session under
+// "webauthn_ceremony".
+const (
+	webAuthnCeremonyRegister = "register"
+	webAuthnCeremonyLogin    = "login"
+	webAuthnCeremonyMFA      = "mfa"
+	webAuthnCeremonyTTL      = 5 * time.Minute
+)
+
+type pendingWebAuthnCeremony struct {
+	Challenge string `json:"challenge"`
+	Purpose   string `json:"purpose"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// webAuthnCredentialPayload is a PublicKeyCredential as posted by
+// public/js/webauthn.js.
+type webAuthnCredentialPayload struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		AuthenticatorData string   `json:"authenticatorData"`
+		Signature         string   `json:"signature"`
+		UserHandle        string   `json:"userHandle"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// startWebAuthnCeremony stores a new challenge in the session; the caller
+// saves it.
+func startWebAuthnCeremony(session sessions.Session, purpose string) (string, error) {
+	challenge, err := utils.NewWebAuthnChallenge()
+	if err != nil {
+		return "", err
+	}
+	pending, err := json.Marshal(pendingWebAuthnCeremony{
+		Challenge: challenge,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(webAuthnCeremonyTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	session.Set("webauthn_ceremony", string(pending))
+	return challenge, nil
+}
+
+// finishWebAuthnCeremony returns the challenge of the ceremony and removes it
+// from the session, so every challenge is answered at most once.
+func finishWebAuthnCeremony(session sessions.Session, purpose string) (string, error) {
+	raw, _ := session.Get("webauthn_ceremony").(string)
+	session.Delete("webauthn_ceremony")
+
+	var pending pendingWebAuthnCeremony
+	if raw == "" || json.Unmarshal([]byte(raw), &pending) != nil || pending.Purpose != purpose {
+		return "", errors.New("No passkey request is pending, please try again")
+	}
+	if time.Now().Unix() > pending.ExpiresAt {
+		return "", errors.New("The passkey request has expired, please try again")
+	}
+	return pending.Challenge, nil
+}
+
+func parseWebAuthnCredential(credential string) (*webAuthnCredentialPayload, error) {
+	payload := new(webAuthnCredentialPayload)
+	if err := json.Unmarshal([]byte(credential), payload); err != nil {
+		return nil, errors.New("Invalid passkey response")
+	}
+	if payload.Type != "public-key" || payload.RawID == "" {
+		return nil, errors.New("Invalid passkey response")
+	}
+	return payload, nil
+}
+
+// webAuthnDescriptors lists credentials for allowCredentials and
+// excludeCredentials.
+func webAuthnDescriptors(webAuthnCredentials []entity.WebAuthnCredential) []map[string]interface{} {
+	descriptors := []map[string]interface{}{}
+	for _, webAuthnCredential := range webAuthnCredentials {
+		descriptor := map[string]interface{}{
+			"type": "public-key",
+			"id":   webAuthnCredential.CredentialID,
+		}
+		if webAuthnCredential.Transports != "" {
+			descriptor["transports"] = strings.Split(webAuthnCredential.Transports, ",")
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
+
+func (h *PasskeyHandler) Index(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := webauthnUsecase.FindWebAuthnCredentialsUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&webauthnUsecase.IFindWebAuthnCredentialsUseCaseRequest{
+		UserID: profile.ID,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		session.Set("error", err.Error())
+		session.Save()
+	}
+
+	index := views.NewView("base", "views/passkeys/index.html")
+	data := map[string]interface{}{
+		"Title": "Go SSO | Passkeys",
+	}
+	if resp != nil {
+		data["WebAuthnCredentials"] = resp.WebAuthnCredentials
+	}
+	index.Render(ctx, data)
+}
+
+// RegistrationOptions starts a registration ceremony and returns the options
+// for navigator.credentials.create().
+func (h *PasskeyHandler) RegistrationOptions(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := webauthnUsecase.FindWebAuthnCredentialsUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&webauthnUsecase.IFindWebAuthnCredentialsUseCaseRequest{
+		UserID: profile.ID,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
+
+	challenge, err := startWebAuthnCeremony(session, webAuthnCeremonyRegister)
+	if err != nil {
+		utils.ErrorResponse(ctx, 500, "error", err.Error())
+		return
+	}
+	session.Save()
+
+	rp := utils.WebAuthnRelyingPartyFromConfig(h.Config)
+	pubKeyCredParams := []map[string]interface{}{}
+	for _, algorithm := range utils.WebAuthnAlgorithms {
+		pubKeyCredParams = append(pubKeyCredParams, map[string]interface{}{
+			"type": "public-key",
+			"alg":  algorithm,
+		})
+	}
+
+	utils.SuccessResponse(ctx, 200, "success", map[string]interface{}{
+		"challenge": challenge,
+		"rp": map[string]interface{}{
+			"id":   rp.ID,
+			"name": rp.Name,
+		},
+		"user": map[string]interface{}{
+			"id":          utils.EncodeWebAuthnBytes(profile.ID[:]),
+			"name":        profile.Email,
+			"displayName": profile.Name,
+		},
+		"pubKeyCredParams":   pubKeyCredParams,
+		"timeout":            webAuthnCeremonyTTL.Milliseconds(),
+		"excludeCredentials": webAuthnDescriptors(resp.WebAuthnCredentials),
+		"authenticatorSelection": map[string]interface{}{
+			"residentKey":      "required",
+			"userVerification": "preferred",
+		},
+		"attestation": "none",
+	})
+}
+
+func (h *PasskeyHandler) Register(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload := new(webRequest.RegisterWebAuthnCredentialWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	challenge, err := finishWebAuthnCeremony(session, webAuthnCeremonyRegister)
+	if err == nil {
+		err = h.register(profile, payload, challenge)
+	}
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	session.Set("success", "The passkey has been added")
+	session.Save()
+	ctx.Redirect(302, "/passkeys")
+}
+
+func (h *PasskeyHandler) register(profile entity.Profile, payload *webRequest.RegisterWebAuthnCredentialWebRequest, challenge string) error {
+	credential, err := parseWebAuthnCredential(payload.Credential)
+	if err != nil {
+		return err
+	}
+	clientDataJSON, err := utils.DecodeWebAuthnBytes(credential.Response.ClientDataJSON)
+	if err != nil {
+		return errors.New("Invalid passkey response")
+	}
+	attestationObject, err := utils.DecodeWebAuthnBytes(credential.Response.AttestationObject)
+	if err != nil {
+		return errors.New("Invalid passkey response")
+	}
+
+	factory := webauthnUsecase.RegisterWebAuthnCredentialUseCaseFactory(h.Log)
+	_, err = factory.Execute(&webauthnUsecase.IRegisterWebAuthnCredentialUseCaseRequest{
+		UserID:            profile.ID,
+		Name:              payload.Name,
+		RelyingParty:      utils.WebAuthnRelyingPartyFromConfig(h.Config),
+		Challenge:         challenge,
+		ClientDataJSON:    clientDataJSON,
+		AttestationObject: attestationObject,
+		Transports:        credential.Response.Transports,
+	})
+	return err
+}
+
+func (h *PasskeyHandler) Rename(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload := new(webRequest.RenameWebAuthnCredentialWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	factory := webauthnUsecase.RenameWebAuthnCredentialUseCaseFactory(h.Log)
+	err := factory.Execute(&webauthnUsecase.IRenameWebAuthnCredentialUseCaseRequest{
+		UserID: profile.ID,
+		ID:     uuid.MustParse(payload.ID),
+		Name:   payload.Name,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	session.Set("success", "The passkey has been renamed")
+	session.Save()
+	ctx.Redirect(302, "/passkeys")
+}
+
+func (h *PasskeyHandler) Delete(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+	payload := new(webRequest.DeleteWebAuthnCredentialWebRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	factory := webauthnUsecase.DeleteWebAuthnCredentialUseCaseFactory(h.Log)
+	err := factory.Execute(&webauthnUsecase.IDeleteWebAuthnCredentialUseCaseRequest{
+		UserID: profile.ID,
+		ID:     uuid.MustParse(payload.ID),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, "/passkeys")
+		return
+	}
+
+	session.Set("success", "The passkey has been removed")
+	session.Save()
+	ctx.Redirect(302, "/passkeys")
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"app/go-sso/internal/entity"
+	webRequest "app/go-sso/internal/http/request/web/user"
+	oidcUsecase "app/go-sso/internal/usecase/oidc"
+	usecase "app/go-sso/internal/usecase/user_session"
+	"app/go-sso/utils"
+	"app/go-sso/views"
+	"fmt"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type SessionHandler struct {
+	Config   *viper.Viper
+	Log      *logrus.Logger
+	Validate *validator.Validate
+}
+
+type SessionHandlerInterface interface {
+	Index(ctx *gin.Context)
+	Terminate(ctx *gin.Context)
+}
+
+func SessionHandlerFactory(log *logrus.Logger, validator *validator.Validate) SessionHandlerInterface {
+	config := viper.New()
+	config.SetConfigName("config")
+	config.SetConfigType("json")
+	config.AddConfigPath("./")
+	err := config.ReadInConfig()
+
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+	return &SessionHandler{
+		Config:   config,
+		Log:      log,
+		Validate: validator,
+	}
+}
+
+// Index lists the browsers the user is signed in with.
+func (h *SessionHandler) Index(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	profile := session.Get("profile").(entity.Profile)
+
+	factory := usecase.FindUserSessionsUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IFindUserSessionsUseCaseRequest{
+		UserID: profile.ID,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		session.Set("error", err.Error())
+		session.Save()
+	}
+
+	index := views.NewView("base", "views/sessions/index.html")
+	data := map[string]interface{}{
+		"Title":            "Go SSO | Sessions",
+		"CurrentSessionID": session.ID(),
+	}
+	if resp != nil {
+		data["UserSessions"] = resp.UserSessions
+	}
+	index.Render(ctx, data)
+}
+
+// Terminate signs the user out of one of their other browsers. Signing out of
+// the current one is a regular logout.
+func (h *SessionHandler) Terminate(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	payload := new(webRequest.TerminateSessionRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+	if err := h.Validate.Struct(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	if payload.ID == session.ID() {
+		ctx.Redirect(302, "/logout")
+		return
+	}
+
+	profile := session.Get("profile").(entity.Profile)
+	sessionID := uuid.MustParse(payload.ID)
+	factory := usecase.TerminateUserSessionsUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.ITerminateUserSessionsUseCaseRequest{
+		UserID:    profile.ID,
+		SessionID: &sessionID,
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	for _, userSession := range resp.UserSessions {
+		if userSession.LoginID == "" {
+			continue
+		}
+		logoutFactory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+		if _, err := logoutFactory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+			SessionID: userSession.LoginID,
+			Issuer:    utils.OIDCIssuer(h.Config),
+		}); err != nil {
+			h.Log.Errorf("Error when logging out applications: %v", err)
+		}
+	}
+
+	session.Set("success", "The session has been signed out")
+	session.Save()
+	ctx.Redirect(302, "/sessions")
+}
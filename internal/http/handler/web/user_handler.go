@@ -4,22 +4,28 @@ import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/http/middleware"
 	userRequest "app/go-sso/internal/http/request/web/user"
+	authUsecase "app/go-sso/internal/usecase/auth_token"
 	empUsecase "app/go-sso/internal/usecase/employee"
+	oidcUsecase "app/go-sso/internal/usecase/oidc"
 	roleUsecase "app/go-sso/internal/usecase/role"
 	usecase "app/go-sso/internal/usecase/user"
+	sessionUsecase "app/go-sso/internal/usecase/user_session"
+	"app/go-sso/utils"
 	"app/go-sso/views"
-	"log"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/spf13/viper"
 )
 
 type UserHandler struct {
+	Config   *viper.Viper
 	Log      *logrus.Logger
 	Validate *validator.Validate
 }
@@ -29,11 +35,24 @@ type UserHandlerInterface interface {
 	StoreUser(ctx *gin.Context)
 	UpdateUser(ctx *gin.Context)
 	DeleteUser(ctx *gin.Context)
+	RevokeTokens(ctx *gin.Context)
+	TerminateSessions(ctx *gin.Context)
+	Unlock(ctx *gin.Context)
 	// UserRoles(ctx *gin.Context)
 }
 
 func UserHandlerFactory(log *logrus.Logger, validator *validator.Validate) UserHandlerInterface {
+	config := viper.New()
+	config.SetConfigName("config")
+	config.SetConfigType("json")
+	config.AddConfigPath("./")
+	err := config.ReadInConfig()
+
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
 	return &UserHandler{
+		Config:   config,
 		Log:      log,
 		Validate: validator,
 	}
@@ -82,12 +101,33 @@ func (h *UserHandler) Index(ctx *gin.Context) {
 		return
 	}
 
+	lockedFactory := usecase.FindLockedAccountsUseCaseFactory(h.Log)
+	lockedResp, err := lockedFactory.Execute()
+	if err != nil {
+		h.Log.Println(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	lockedEmails := map[string]bool{}
+	for _, loginThrottle := range lockedResp.LoginThrottles {
+		lockedEmails[loginThrottle.Identifier] = true
+	}
+	lockedUsers := map[string]bool{}
+	if resp.Users != nil {
+		for _, user := range *resp.Users {
+			if lockedEmails[strings.ToLower(strings.TrimSpace(user.Email))] {
+				lockedUsers[user.ID.String()] = true
+			}
+		}
+	}
+
 	index := views.NewView("base", "views/users/index.html")
 	data := map[string]interface{}{
-		"Title":     "Julong Portal | Users",
-		"Users":     resp.Users,
-		"Roles":     role.Roles,
-		"Employees": empResp.Employees,
+		"Title":       "Julong Portal | Users",
+		"Users":       resp.Users,
+		"Roles":       role.Roles,
+		"Employees":   empResp.Employees,
+		"LockedUsers": lockedUsers,
 	}
 
 	index.Render(ctx, data)
@@ -120,11 +160,6 @@ func (h *UserHandler) StoreUser(ctx *gin.Context) {
 		return
 	}
 
-	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte("changeme"), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatalf("failed to hash password: %v", err)
-	}
-
 	var employeeID *uuid.UUID
 
 	if payload.EmployeeID != "" {
@@ -140,19 +175,20 @@ func (h *UserHandler) StoreUser(ctx *gin.Context) {
 		Email:       payload.Email,
 		Gender:      payload.Gender,
 		MobilePhone: payload.MobilePhone,
-		Password:    string(hashedPasswordBytes),
 		Status:      payload.Status,
 		EmployeeID:  employeeID,
 	}
 
 	factory := usecase.CreateUserUseCaseFactory(h.Log)
 	response, err := factory.Execute(usecase.ICreateUserUseCaseRequest{
-		User:    user,
-		RoleIDs: payload.RoleIDs,
+		User:           user,
+		RoleIDs:        payload.RoleIDs,
+		Password:       payload.Password,
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
 	})
 
 	if err != nil {
-		session.Set("error", err.Error())
+		flashError(session, err)
 		session.Save()
 		h.Log.Printf(err.Error())
 		ctx.Redirect(302, ctx.Request.Referer())
@@ -208,12 +244,14 @@ func (h *UserHandler) UpdateUser(ctx *gin.Context) {
 	factory := usecase.UpdateUserUseCaseFactory(h.Log)
 
 	response, err := factory.Execute(usecase.IUpdateUserUseCaseRequest{
-		User:    user,
-		RoleIDs: payload.RoleIDs,
+		User:           user,
+		RoleIDs:        payload.RoleIDs,
+		Password:       payload.Password,
+		PasswordPolicy: utils.PasswordPolicyFromConfig(h.Config),
 	})
 
 	if err != nil {
-		session.Set("error", err.Error())
+		flashError(session, err)
 		session.Save()
 		h.Log.Printf(err.Error())
 		ctx.Redirect(302, ctx.Request.Referer())
@@ -266,3 +304,159 @@ func (h *UserHandler) DeleteUser(ctx *gin.Context) {
 	session.Save()
 	ctx.Redirect(302, ctx.Request.Referer())
 }
+
+func (h *UserHandler) RevokeTokens(ctx *gin.Context) {
+	middleware.PermissionMiddleware("update-user")(ctx)
+	if ctx.IsAborted() {
+		ctx.Abort()
+		return
+	}
+	session := sessions.Default(ctx)
+	payload := new(userRequest.RevokeUserTokensRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	factory := authUsecase.RevokeUserTokensUseCaseFactory(h.Log)
+	_, err = factory.Execute(&authUsecase.IRevokeUserTokensUseCaseRequest{
+		UserID: uuid.MustParse(payload.ID),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	// the applications the user signed in to drop their own sessions too
+	logoutFactory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+	_, err = logoutFactory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+		UserID: uuid.MustParse(payload.ID),
+		Issuer: utils.OIDCIssuer(h.Config),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	session.Set("success", "All tokens of the user have been revoked")
+	session.Save()
+	ctx.Redirect(302, ctx.Request.Referer())
+}
+
+// TerminateSessions signs a user out of every browser and of the applications
+// those logins signed in to.
+func (h *UserHandler) TerminateSessions(ctx *gin.Context) {
+	middleware.PermissionMiddleware("update-user")(ctx)
+	if ctx.IsAborted() {
+		ctx.Abort()
+		return
+	}
+	session := sessions.Default(ctx)
+	payload := new(userRequest.TerminateUserSessionsRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	factory := sessionUsecase.TerminateUserSessionsUseCaseFactory(h.Log)
+	_, err = factory.Execute(&sessionUsecase.ITerminateUserSessionsUseCaseRequest{
+		UserID: uuid.MustParse(payload.ID),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	logoutFactory := oidcUsecase.LogoutSessionUseCaseFactory(h.Log)
+	_, err = logoutFactory.Execute(&oidcUsecase.ILogoutSessionUseCaseRequest{
+		UserID: uuid.MustParse(payload.ID),
+		Issuer: utils.OIDCIssuer(h.Config),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	session.Set("success", "All sessions of the user have been terminated")
+	session.Save()
+	ctx.Redirect(302, ctx.Request.Referer())
+}
+
+// Unlock lifts the lockout of a user locked after too many failed attempts.
+func (h *UserHandler) Unlock(ctx *gin.Context) {
+	middleware.PermissionMiddleware("update-user")(ctx)
+	if ctx.IsAborted() {
+		ctx.Abort()
+		return
+	}
+	session := sessions.Default(ctx)
+	payload := new(userRequest.UnlockUserRequest)
+	if err := ctx.ShouldBind(payload); err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Error(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	err := h.Validate.Struct(payload)
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	factory := usecase.UnlockUserUseCaseFactory(h.Log)
+	resp, err := factory.Execute(&usecase.IUnlockUserUseCaseRequest{
+		ID: uuid.MustParse(payload.ID),
+	})
+	if err != nil {
+		session.Set("error", err.Error())
+		session.Save()
+		h.Log.Printf(err.Error())
+		ctx.Redirect(302, ctx.Request.Referer())
+		return
+	}
+
+	session.Set("success", resp.Message)
+	session.Save()
+	ctx.Redirect(302, ctx.Request.Referer())
+}
@@ -1,8 +1,8 @@
 package middleware
 
 import (
+	"app/go-sso/utils"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -27,26 +27,33 @@ func NewAuth(viper *viper.Viper) gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(viper.GetString("jwt.secret")), nil
-		})
-
+		claims, err := utils.ParseToken(bearerToken[1])
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("auth", claims)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// ID tokens handed to applications are signed with the same keys
+		if !utils.IsAccessToken(claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not an access token"})
+			c.Abort()
+			return
+		}
+
+		revoked, err := utils.IsTokenRevoked(claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unable to check token revocation"})
 			c.Abort()
 			return
 		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		c.Set("auth", claims)
 
 		c.Next()
 	}
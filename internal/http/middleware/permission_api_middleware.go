@@ -6,10 +6,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// IsClientToken reports whether the claims belong to a client credentials
+// token, which is issued to an application and has no user behind it.
+func IsClientToken(claims jwt.MapClaims) bool {
+	_, hasUser := claims["id"]
+	clientID, _ := claims["client_id"].(string)
+	return !hasUser && clientID != ""
+}
+
 func GetApiLoggedInUser(ctx *gin.Context) (*entity.User, error) {
 	db := config.NewDatabase()
 	user, err := GetUser(ctx)
@@ -41,7 +51,36 @@ func getApiUserRoles(ctx *gin.Context) ([]entity.Role, error) {
 	return user.Roles, nil
 }
 
+// getApiClientPermissions returns the permissions of a client credentials
+// token: those still granted to the application and named in the token scope.
+func getApiClientPermissions(ctx *gin.Context, claims jwt.MapClaims) ([]entity.Permission, error) {
+	db := config.NewDatabase()
+	clientID, _ := claims["client_id"].(string)
+	scope, _ := claims["scope"].(string)
+
+	var application entity.Application
+	if err := db.Preload("ClientPermissions").First(&application, "name = ?", clientID).Error; err != nil {
+		return nil, err
+	}
+
+	scoped := make(map[string]bool)
+	for _, name := range strings.Fields(scope) {
+		scoped[name] = true
+	}
+	permissions := make([]entity.Permission, 0)
+	for _, permission := range application.ClientPermissions {
+		if scoped[permission.Name] {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
 func getApiUserPermissions(ctx *gin.Context) ([]entity.Permission, error) {
+	if claims, err := GetUser(ctx); err == nil && IsClientToken(claims) {
+		return getApiClientPermissions(ctx, claims)
+	}
+
 	roles, err := getApiUserRoles(ctx)
 	if err != nil {
 		return nil, err
@@ -72,20 +111,27 @@ func getApiUserPermissions(ctx *gin.Context) ([]entity.Permission, error) {
 // 	}
 // }
 
+// HasApiPermission reports whether the user or client of the token has the
+// permission, for handlers that answer a denial in their own format.
+func HasApiPermission(c *gin.Context, requiredPermission string) bool {
+	permissions, err := getApiUserPermissions(c)
+	if err != nil {
+		return false
+	}
+	for _, permission := range permissions {
+		if permission.Name == requiredPermission {
+			return true
+		}
+	}
+	return false
+}
+
 func PermissionApiMiddleware(requiredPermission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		permissions, err := getApiUserPermissions(c)
-		if err != nil || permissions == nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this resource"})
-			c.Set("permission_denied", true)
+		if HasApiPermission(c, requiredPermission) {
+			c.Next()
 			return
 		}
-		for _, permission := range permissions {
-			if permission.Name == requiredPermission {
-				c.Next()
-				return
-			}
-		}
 		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this resource"})
 		c.Set("permission_denied", true)
 	}
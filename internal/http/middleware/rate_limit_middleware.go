@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// rate limit keys, set per group by rate_limit.groups.<group>.key
+const (
+	RATE_LIMIT_KEY_IP          = "ip"
+	RATE_LIMIT_KEY_USER        = "user"
+	RATE_LIMIT_KEY_APPLICATION = "application"
+)
+
+type rateLimitGroup struct {
+	limit utils.RateLimit
+	key   string
+}
+
+// RateLimiter throttles route groups with the limits of the rate_limit section
+// of config.json. Groups without a limit are not throttled.
+type RateLimiter struct {
+	Log    *logrus.Logger
+	Store  utils.RateLimitStore
+	groups map[string]rateLimitGroup
+}
+
+// NewRateLimiter reads the limits from config and keeps the buckets in the
+// memory of the process, or in the database when rate_limit.backend is
+// "database".
+func NewRateLimiter(config *viper.Viper, log *logrus.Logger) *RateLimiter {
+	var store utils.RateLimitStore
+	switch backend := config.GetString("rate_limit.backend"); backend {
+	case "database":
+		store = utils.NewDatabaseRateLimitStore(log, repository.RateLimitBucketRepositoryFactory(log))
+	case "", "memory":
+		store = utils.NewMemoryRateLimitStore()
+	default:
+		panic(fmt.Errorf("Unknown rate limit backend %q", backend))
+	}
+	return NewRateLimiterWithStore(config, log, store)
+}
+
+// NewRateLimiterWithStore is NewRateLimiter with another backend, for stores
+// shared through something else than the database.
+func NewRateLimiterWithStore(config *viper.Viper, log *logrus.Logger, store utils.RateLimitStore) *RateLimiter {
+	limiter := &RateLimiter{
+		Log:    log,
+		Store:  store,
+		groups: make(map[string]rateLimitGroup),
+	}
+	if !config.GetBool("rate_limit.enabled") {
+		return limiter
+	}
+
+	for name := range config.GetStringMap("rate_limit.groups") {
+		prefix := "rate_limit.groups." + name + "."
+		group := rateLimitGroup{
+			limit: utils.RateLimit{
+				Rate:   config.GetInt(prefix + "rate"),
+				Burst:  config.GetInt(prefix + "burst"),
+				Period: time.Duration(config.GetInt(prefix+"period")) * time.Second,
+			},
+			key: config.GetString(prefix + "key"),
+		}
+		if group.limit.Period <= 0 {
+			group.limit.Period = time.Minute
+		}
+		if group.limit.Burst <= 0 {
+			group.limit.Burst = group.limit.Rate
+		}
+		if group.key == "" {
+			group.key = RATE_LIMIT_KEY_IP
+		}
+		if group.limit.Rate <= 0 {
+			continue
+		}
+		limiter.groups[name] = group
+	}
+	return limiter
+}
+
+// Limit throttles the requests of a route group. Requests over the limit get
+// 429 Too Many Requests with a Retry-After header.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group, ok := l.groups[name]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		result, err := l.Store.Take(name+":"+group.key+":"+rateLimitKey(c, group.key), group.limit)
+		if err != nil {
+			// an unavailable backend must not take the whole application down
+			l.Log.Warn("[RateLimiter.Limit] " + err.Error())
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(group.limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			message := fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter)
+			if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+				c.String(http.StatusTooManyRequests, message)
+			} else {
+				utils.ErrorResponse(c, http.StatusTooManyRequests, "error", message)
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client. Only authenticated users and
+// applications are trusted, a client_id sent by anyone could be changed on
+// every request; the others are keyed by IP address.
+func rateLimitKey(c *gin.Context, key string) string {
+	switch key {
+	case RATE_LIMIT_KEY_USER:
+		if auth, ok := c.Get("auth"); ok {
+			if claims, ok := auth.(jwt.MapClaims); ok {
+				if id, ok := claims["id"].(string); ok && id != "" {
+					return id
+				}
+			}
+		}
+		if profile, ok := sessions.Default(c).Get("profile").(entity.Profile); ok {
+			return profile.ID.String()
+		}
+	case RATE_LIMIT_KEY_APPLICATION:
+		if auth, ok := c.Get("auth"); ok {
+			if claims, ok := auth.(jwt.MapClaims); ok {
+				if clientID, ok := claims["client_id"].(string); ok && clientID != "" {
+					return clientID
+				}
+			}
+		}
+	}
+	return c.ClientIP()
+}
@@ -12,7 +12,7 @@ func UserStatusValidation(fl validator.FieldLevel) bool {
 		return true
 	}
 	switch entity.UserStatus(status) {
-	case entity.USER_ACTIVE, entity.USER_INACTIVE, entity.USER_PENDING:
+	case entity.USER_ACTIVE, entity.USER_INACTIVE, entity.USER_PENDING, entity.USER_AWAITING_APPROVAL:
 		return true
 	default:
 		return false
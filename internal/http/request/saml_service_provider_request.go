@@ -0,0 +1,9 @@
+package request
+
+type ImportSAMLServiceProviderRequest struct {
+	Metadata         string            `json:"metadata" validate:"required_without=MetadataURL"`
+	MetadataURL      string            `json:"metadata_url" validate:"omitempty,url"`
+	NameIDFormat     string            `json:"name_id_format" validate:"omitempty,oneof=EMAIL PERSISTENT"`
+	AttributeMapping map[string]string `json:"attribute_mapping"`
+	IDPInitiated     *bool             `json:"idp_initiated"`
+}
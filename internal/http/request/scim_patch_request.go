@@ -0,0 +1,13 @@
+package request
+
+// SCIMPatchRequest is a PatchOp message of RFC 7644 section 3.5.2.
+type SCIMPatchRequest struct {
+	Schemas    []string                 `json:"schemas"`
+	Operations []SCIMPatchOperationItem `json:"Operations" validate:"required,min=1,dive"`
+}
+
+type SCIMPatchOperationItem struct {
+	Op    string      `json:"op" validate:"required"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
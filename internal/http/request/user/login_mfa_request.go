@@ -0,0 +1,7 @@
+package request
+
+type LoginMFARequest struct {
+	MFAToken    string `json:"mfa_token" validate:"required"`
+	Code        string `json:"code" validate:"required"`
+	ChoosedRole string `json:"choosed_role"`
+}
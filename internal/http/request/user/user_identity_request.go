@@ -0,0 +1,5 @@
+package request
+
+type LinkUserIdentityRequest struct {
+	Provider string `form:"provider" validate:"required,max=32"`
+}
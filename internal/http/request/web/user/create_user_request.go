@@ -13,4 +13,7 @@ type CreateUserRequest struct {
 	MobilePhone string            `form:"mobile_phone" validate:"omitempty,numeric,min=10,max=13,startswith=62"`
 	RoleIDs     []string          `form:"role_ids[]" validate:"required,dive"`
 	Status      entity.UserStatus `form:"status" validate:"required,userStatus"`
+
+	Password             string `form:"password" validate:"required"`
+	PasswordConfirmation string `form:"password_confirmation" validate:"required,eqfield=Password"`
 }
@@ -0,0 +1,6 @@
+package request
+
+type DeviceApproveWebRequest struct {
+	UserCode string `form:"user_code" validate:"required"`
+	Action   string `form:"action" validate:"required,oneof=approve deny"`
+}
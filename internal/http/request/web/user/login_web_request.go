@@ -3,11 +3,10 @@ package request
 type LoginWebRequest struct {
 	Email    string `form:"email" validate:"required"`
 	Password string `form:"password" validate:"required"`
-	State    string `form:"state" validate:"omitempty"`
 }
 
+// ChooseRolesWebRequest picks the role of the signed-in user; the user comes
+// from the session, never from the form.
 type ChooseRolesWebRequest struct {
-	ID     string `form:"id" validate:"required"`
 	RoleID string `form:"role_id" validate:"required"`
-	State  string `form:"state" validate:"omitempty"`
 }
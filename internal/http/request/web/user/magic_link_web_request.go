@@ -0,0 +1,10 @@
+package request
+
+type MagicLinkWebRequest struct {
+	Email       string `form:"email" validate:"required,email"`
+	Application string `form:"application" validate:"required"`
+}
+
+type MagicLinkLoginWebRequest struct {
+	Token string `form:"token" validate:"required"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type MFACodeWebRequest struct {
+	Code string `form:"code" validate:"required"`
+}
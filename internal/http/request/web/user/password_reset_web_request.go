@@ -0,0 +1,11 @@
+package request
+
+type ForgotPasswordWebRequest struct {
+	Email string `form:"email" validate:"required,email"`
+}
+
+type ResetPasswordWebRequest struct {
+	Token                string `form:"token" validate:"required"`
+	Password             string `form:"password" validate:"required"`
+	PasswordConfirmation string `form:"password_confirmation" validate:"required,eqfield=Password"`
+}
@@ -0,0 +1,9 @@
+package request
+
+type TerminateSessionRequest struct {
+	ID string `form:"id" validate:"required,uuid"`
+}
+
+type TerminateUserSessionsRequest struct {
+	ID string `form:"id" validate:"required,uuid"`
+}
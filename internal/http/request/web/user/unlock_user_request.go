@@ -0,0 +1,5 @@
+package request
+
+type UnlockUserRequest struct {
+	ID string `form:"id" validate:"required,uuid"`
+}
@@ -12,4 +12,8 @@ type UpdateUserRequest struct {
 	MobilePhone string            `form:"mobile_phone" validate:"omitempty,numeric,min=10,max=13,startswith=62"`
 	Status      entity.UserStatus `form:"status" validate:"required,userStatus"`
 	RoleIDs     []string          `form:"role_ids[]" validate:"omitempty,dive"`
+
+	// Password is left empty to keep the current password
+	Password             string `form:"password" validate:"omitempty"`
+	PasswordConfirmation string `form:"password_confirmation" validate:"eqfield=Password"`
 }
@@ -0,0 +1,5 @@
+package request
+
+type UnlinkUserIdentityWebRequest struct {
+	Provider string `form:"provider" validate:"required,max=32"`
+}
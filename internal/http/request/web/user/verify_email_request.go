@@ -2,5 +2,5 @@ package request
 
 type VerifyEmailRequest struct {
 	Email string `form:"email" validate:"required,email"`
-	Token int    `form:"token" validate:"required"`
+	Token string `form:"token" validate:"required,len=6,numeric"`
 }
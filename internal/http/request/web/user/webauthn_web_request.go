@@ -0,0 +1,19 @@
+package request
+
+type RegisterWebAuthnCredentialWebRequest struct {
+	Name       string `form:"name" validate:"max=100"`
+	Credential string `form:"credential" validate:"required"`
+}
+
+type WebAuthnLoginWebRequest struct {
+	Credential string `form:"credential" validate:"required"`
+}
+
+type RenameWebAuthnCredentialWebRequest struct {
+	ID   string `form:"id" validate:"required,uuid"`
+	Name string `form:"name" validate:"required,max=100"`
+}
+
+type DeleteWebAuthnCredentialWebRequest struct {
+	ID string `form:"id" validate:"required,uuid"`
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"app/go-sso/internal/http/handler"
 	"app/go-sso/internal/http/handler/web"
+	"app/go-sso/internal/http/middleware"
 
 	"github.com/spf13/viper"
 
@@ -26,12 +27,23 @@ type RouteConfig struct {
 	EmployeeHandler         handler.IEmployeeHandler
 	EmployeeWebHandler      web.EmployeeHandlerInterface
 	GradeHandler            handler.IGradeHandler
+	OIDCHandler             handler.IOIDCHandler
+	SAMLHandler             handler.ISAMLHandler
+	SCIMHandler             handler.ISCIMHandler
+	SessionWebHandler       web.SessionHandlerInterface
+	MFAWebHandler           web.MFAHandlerInterface
+	PasskeyWebHandler       web.PasskeyHandlerInterface
+	IdentityWebHandler      web.IdentityHandlerInterface
+	RateLimiter             *middleware.RateLimiter
 }
 
 func (c *RouteConfig) SetupRoutes() {
-	// Setup API, OAuth, and Web routes
+	// Setup API, OAuth, OpenID Connect, SAML, SCIM, and Web routes
 	c.SetupApiRoutes()
 	c.SetupOAuthRoutes()
+	c.SetupOIDCRoutes()
+	c.SetupSAMLRoutes()
+	c.SetupSCIMRoutes()
 	c.SetupWebRoutes()
 }
 
@@ -39,64 +51,72 @@ func (c *RouteConfig) SetupApiRoutes() {
 	apiRoute := c.App.Group("/api")
 	{
 		apiRoute.GET("/check-jwt-token", c.UserHandler.CheckStoredCookie)
-		apiRoute.POST("/login", c.UserHandler.Login)
+		apiRoute.POST("/login", c.RateLimiter.Limit("auth"), c.UserHandler.Login)
+		apiRoute.POST("/login/mfa", c.RateLimiter.Limit("auth"), c.UserHandler.LoginMFA)
+		apiRoute.POST("/password/forgot", c.RateLimiter.Limit("auth"), c.UserHandler.ForgotPassword)
+		apiRoute.POST("/password/reset", c.RateLimiter.Limit("auth"), c.UserHandler.ResetPassword)
+		apiRoute.POST("/refresh-token", c.RateLimiter.Limit("auth"), c.UserHandler.RefreshToken)
 
+		// redirect URLs registered at the providers before /oauth/:provider/callback
 		oAuthRoute := apiRoute.Group("/oauth")
 		{
 			oAuthRoute.GET("/callback", c.UserHandler.CallbackOAuth)
-			oAuthRoute.GET("/google/callback", c.UserHandler.GoogleCallbackOAuth)
-			oAuthRoute.GET("/zitadel/callback", c.UserHandler.ZitadelCallbackOAuth)
+			oAuthRoute.GET("/:provider/callback", c.UserHandler.CallbackOAuth)
 		}
 
-		apiRoute.Use(c.AuthMiddleware)
+		apiRoute.Use(c.AuthMiddleware, c.RateLimiter.Limit("api"))
 		{
 			// User routes
-			apiRoute.GET("/users", c.UserHandler.FindAllPaginated)
+			apiRoute.GET("/users", c.RateLimiter.Limit("list"), c.UserHandler.FindAllPaginated)
 			apiRoute.GET("/users/me", c.UserHandler.Me)
 			apiRoute.GET("/users/logout/token", c.UserHandler.Logout)
+			apiRoute.POST("/users/logout/token", c.UserHandler.Logout)
 			apiRoute.GET("/users/logout", c.UserHandler.LogoutCookie)
 			apiRoute.GET("/users/:id", c.UserHandler.FindById)
 			apiRoute.POST("/check-token", c.UserHandler.CheckAuthToken)
 			apiRoute.GET("/check-cookie", c.UserHandler.CheckStoredCookie)
 
 			// Organization routes
-			apiRoute.GET("/organizations", c.OrganizationHandler.FindAllPaginated)
+			apiRoute.GET("/organizations", c.RateLimiter.Limit("list"), c.OrganizationHandler.FindAllPaginated)
 			apiRoute.GET("/organizations/:id", c.OrganizationHandler.FindById)
 			apiRoute.PUT("/organizations/:id/upload-logo", c.OrganizationHandler.UploadLogoOrganization)
 
 			// Organization structure routes
-			apiRoute.GET("/organization-structures", c.OrganizationHandler.FindOrganizationStructurePaginated)
+			apiRoute.GET("/organization-structures", c.RateLimiter.Limit("list"), c.OrganizationHandler.FindOrganizationStructurePaginated)
 			apiRoute.GET("/organization-structures/:id", c.OrganizationHandler.FindOrganizationStructureById)
 			apiRoute.GET("/organization-structures/parents/:id", c.OrganizationHandler.FindOrganizationStructureByIdWithParents)
 
 			// Organization location routes
-			apiRoute.GET("/organization-locations", c.OrganizationHandler.FindOrganizationLocationsPaginated)
+			apiRoute.GET("/organization-locations", c.RateLimiter.Limit("list"), c.OrganizationHandler.FindOrganizationLocationsPaginated)
 			apiRoute.GET("/organization-locations/organization/:organization_id", c.OrganizationHandler.FindOrganizationLocationByOrganizationId)
 			apiRoute.GET("/organization-locations/:id", c.OrganizationHandler.FindOrganizationLocationById)
 
 			// Organization type routes
-			apiRoute.GET("/organization-types", c.OrganizationHandler.FindOrganizationTypesPaginated)
+			apiRoute.GET("/organization-types", c.RateLimiter.Limit("list"), c.OrganizationHandler.FindOrganizationTypesPaginated)
 			apiRoute.GET("/organization-types/:id", c.OrganizationHandler.FindOrganizationTypeById)
 
 			// Job routes
-			apiRoute.GET("/jobs", c.JobHandler.FindAllPaginated)
+			apiRoute.GET("/jobs", c.RateLimiter.Limit("list"), c.JobHandler.FindAllPaginated)
 			apiRoute.GET("/jobs/:id", c.JobHandler.FindById)
 			apiRoute.GET("/jobs/job-level/:job_level_id", c.JobHandler.GetJobsByJobLevelId)
 			apiRoute.GET("/jobs/organization/:organization_id", c.JobHandler.GetJobsByOrganizationId)
 
 			// Job level routes
-			apiRoute.GET("/job-levels", c.JobHandler.FindAllJobLevelsPaginated)
+			apiRoute.GET("/job-levels", c.RateLimiter.Limit("list"), c.JobHandler.FindAllJobLevelsPaginated)
 			apiRoute.GET("/job-levels/:id", c.JobHandler.FindJobLevelById)
 			apiRoute.GET("/job-levels/organization/:organization_id", c.JobHandler.FindJobLevelsByOrganizationId)
 
 			// Employee routes
-			apiRoute.GET("/employees", c.EmployeeHandler.FindAllPaginated)
+			apiRoute.GET("/employees", c.RateLimiter.Limit("list"), c.EmployeeHandler.FindAllPaginated)
 			apiRoute.GET("/employees/turnover", c.EmployeeHandler.CountEmployeeRetiredEndByDateRange)
 			apiRoute.GET("/employees/recruitment-manager", c.EmployeeHandler.FindEmployeeRecruitmentManager)
 			apiRoute.GET("/employees/:id", c.EmployeeHandler.FindById)
 
 			// Grade routes
 			apiRoute.GET("/grades/job-level/:job_level_id", c.GradeHandler.FindAllByJobLevelID)
+
+			// Application routes
+			apiRoute.POST("/applications/:name/saml", c.SAMLHandler.ImportServiceProvider)
 		}
 	}
 }
@@ -106,26 +126,72 @@ func (c *RouteConfig) SetupWebRoutes() {
 	webRoute.GET("/login", c.AuthWebHandler.LoginView)
 	webRoute.GET("/choose-roles", c.AuthWebHandler.ChooseRoles)
 	webRoute.POST("/continue-login", c.AuthWebHandler.ContinueLogin)
-	webRoute.POST("/login", c.AuthWebHandler.Login)
+	webRoute.POST("/login", c.RateLimiter.Limit("auth"), c.AuthWebHandler.Login)
+	webRoute.GET("/login/mfa", c.AuthWebHandler.MFAView)
+	webRoute.POST("/login/mfa", c.RateLimiter.Limit("auth"), c.AuthWebHandler.VerifyMFA)
+	webRoute.GET("/login/mfa/passkey/options", c.AuthWebHandler.PasskeyMFAOptions)
+	webRoute.POST("/login/mfa/passkey", c.RateLimiter.Limit("auth"), c.AuthWebHandler.PasskeyMFA)
+	webRoute.GET("/login/passkey/options", c.AuthWebHandler.PasskeyLoginOptions)
+	webRoute.POST("/login/passkey", c.RateLimiter.Limit("auth"), c.AuthWebHandler.PasskeyLogin)
+	webRoute.GET("/login/magic-link", c.AuthWebHandler.MagicLinkView)
+	webRoute.POST("/login/magic-link", c.RateLimiter.Limit("auth"), c.AuthWebHandler.RequestMagicLink)
+	webRoute.GET("/login/magic-link/verify", c.AuthWebHandler.MagicLinkLoginView)
+	webRoute.POST("/login/magic-link/verify", c.RateLimiter.Limit("auth"), c.AuthWebHandler.MagicLinkLogin)
 	webRoute.GET("/register", c.AuthWebHandler.RegisterView)
-	webRoute.POST("/register", c.AuthWebHandler.Register)
+	webRoute.POST("/register", c.RateLimiter.Limit("register"), c.AuthWebHandler.Register)
+	webRoute.GET("/forgot-password", c.AuthWebHandler.ForgotPasswordView)
+	webRoute.POST("/forgot-password", c.RateLimiter.Limit("auth"), c.AuthWebHandler.ForgotPassword)
+	webRoute.GET("/reset-password", c.AuthWebHandler.ResetPasswordView)
+	webRoute.POST("/reset-password", c.RateLimiter.Limit("auth"), c.AuthWebHandler.ResetPassword)
+	webRoute.GET("/logout", c.AuthWebHandler.Logout)
+	webRoute.GET("/device", c.AuthWebHandler.DeviceView)
 	webRoute.Use(c.WebAuthMiddleware)
 	{
 		webRoute.GET("/", c.DashboardHandler.Index)
 		webRoute.GET("/test", c.AuthWebHandler.CheckCookieTest)
-		webRoute.GET("/logout", c.AuthWebHandler.Logout)
 		webRoute.GET("/otp", c.AuthWebHandler.OtpView)
-		webRoute.POST("/verify-email", c.AuthWebHandler.VerifyEmail)
-		webRoute.GET("/resend-verify-email/:email", c.AuthWebHandler.ResendVerifyEmail)
+		webRoute.POST("/verify-email", c.RateLimiter.Limit("verify_email"), c.AuthWebHandler.VerifyEmail)
+		webRoute.GET("/verify-email/link", c.RateLimiter.Limit("verify_email"), c.AuthWebHandler.VerifyEmailLink)
+		webRoute.GET("/resend-verify-email/:email", c.RateLimiter.Limit("verify_email"), c.AuthWebHandler.ResendVerifyEmail)
 		webRoute.Use(c.EmailVerifiedMiddleware)
 		{
 			webRoute.GET("/portal", c.DashboardHandler.Portal)
+			webRoute.GET("/saml/idp/:application", c.SAMLHandler.IDPInitiated)
+			webRoute.GET("/device/approve", c.AuthWebHandler.DeviceApproveView)
+			webRoute.POST("/device/approve", c.AuthWebHandler.DeviceApprove)
+			webRoute.GET("/sessions", c.SessionWebHandler.Index)
+			webRoute.POST("/sessions/terminate", c.SessionWebHandler.Terminate)
+			passkeyRoutes := webRoute.Group("/passkeys")
+			{
+				passkeyRoutes.GET("", c.PasskeyWebHandler.Index)
+				passkeyRoutes.GET("/options", c.PasskeyWebHandler.RegistrationOptions)
+				passkeyRoutes.POST("", c.PasskeyWebHandler.Register)
+				passkeyRoutes.POST("/rename", c.PasskeyWebHandler.Rename)
+				passkeyRoutes.POST("/delete", c.PasskeyWebHandler.Delete)
+			}
+			identityRoutes := webRoute.Group("/linked-accounts")
+			{
+				identityRoutes.GET("", c.IdentityWebHandler.Index)
+				identityRoutes.POST("/link", c.UserHandler.LinkOAuth)
+				identityRoutes.POST("/unlink", c.IdentityWebHandler.Unlink)
+			}
+			mfaRoutes := webRoute.Group("/two-factor")
+			{
+				mfaRoutes.GET("", c.MFAWebHandler.Index)
+				mfaRoutes.POST("/enroll", c.MFAWebHandler.Enroll)
+				mfaRoutes.POST("/confirm", c.MFAWebHandler.Confirm)
+				mfaRoutes.POST("/disable", c.MFAWebHandler.Disable)
+				mfaRoutes.POST("/recovery-codes", c.MFAWebHandler.RegenerateRecoveryCodes)
+			}
 			userRoutes := webRoute.Group("/users")
 			{
 				userRoutes.GET("/", c.UserWebHandler.Index)
 				userRoutes.POST("/", c.UserWebHandler.StoreUser)
 				userRoutes.POST("/update", c.UserWebHandler.UpdateUser)
 				userRoutes.POST("/delete", c.UserWebHandler.DeleteUser)
+				userRoutes.POST("/revoke-tokens", c.UserWebHandler.RevokeTokens)
+				userRoutes.POST("/terminate-sessions", c.UserWebHandler.TerminateSessions)
+				userRoutes.POST("/unlock", c.UserWebHandler.Unlock)
 			}
 			roleRoutes := webRoute.Group("/roles")
 			{
@@ -162,7 +228,61 @@ func (c *RouteConfig) SetupOAuthRoutes() {
 	oAuthRoute := c.App.Group("/oauth")
 	{
 		oAuthRoute.GET("/login", c.UserHandler.LoginOAuth)
-		oAuthRoute.GET("/google/login", c.UserHandler.GoogleLoginOAuth)
-		oAuthRoute.GET("/zitadel/login", c.UserHandler.ZitadelLoginOAuth)
+		oAuthRoute.GET("/:provider/login", c.UserHandler.LoginOAuth)
+		oAuthRoute.GET("/:provider/callback", c.UserHandler.CallbackOAuth)
+	}
+}
+
+func (c *RouteConfig) SetupOIDCRoutes() {
+	c.App.GET("/.well-known/openid-configuration", c.OIDCHandler.Discovery)
+	oidcRoute := c.App.Group("/oauth2")
+	{
+		oidcRoute.GET("/authorize", c.OIDCHandler.Authorize)
+		oidcRoute.POST("/authorize", c.OIDCHandler.Authorize)
+		oidcRoute.POST("/token", c.RateLimiter.Limit("oauth2"), c.OIDCHandler.Token)
+		oidcRoute.GET("/jwks", c.OIDCHandler.JWKS)
+		oidcRoute.POST("/revoke", c.RateLimiter.Limit("oauth2"), c.OIDCHandler.Revoke)
+		oidcRoute.POST("/introspect", c.RateLimiter.Limit("oauth2"), c.OIDCHandler.Introspect)
+		oidcRoute.POST("/device_authorization", c.RateLimiter.Limit("oauth2"), c.OIDCHandler.DeviceAuthorization)
+		oidcRoute.GET("/userinfo", c.AuthMiddleware, c.OIDCHandler.UserInfo)
+		oidcRoute.POST("/userinfo", c.AuthMiddleware, c.OIDCHandler.UserInfo)
+	}
+}
+
+func (c *RouteConfig) SetupSAMLRoutes() {
+	samlRoute := c.App.Group("/saml")
+	{
+		samlRoute.GET("/metadata", c.SAMLHandler.Metadata)
+		samlRoute.GET("/sso", c.SAMLHandler.SSO)
+		samlRoute.POST("/sso", c.SAMLHandler.SSO)
+		samlRoute.GET("/sso/resume", c.SAMLHandler.ResumeSSO)
+	}
+}
+
+// SetupSCIMRoutes serves the SCIM 2.0 provisioning API to bearer tokens with
+// the read-scim and write-scim permissions, usually client credentials tokens
+// of an identity provider.
+func (c *RouteConfig) SetupSCIMRoutes() {
+	scimRoute := c.App.Group("/scim/v2", c.AuthMiddleware, c.RateLimiter.Limit("api"))
+	{
+		scimRoute.GET("/ServiceProviderConfig", c.SCIMHandler.ServiceProviderConfig)
+		scimRoute.GET("/Schemas", c.SCIMHandler.Schemas)
+		scimRoute.GET("/Schemas/:id", c.SCIMHandler.Schema)
+		scimRoute.GET("/ResourceTypes", c.SCIMHandler.ResourceTypes)
+		scimRoute.GET("/ResourceTypes/:id", c.SCIMHandler.ResourceType)
+
+		scimRoute.GET("/Users", c.SCIMHandler.ListUsers)
+		scimRoute.POST("/Users", c.SCIMHandler.CreateUser)
+		scimRoute.GET("/Users/:id", c.SCIMHandler.GetUser)
+		scimRoute.PUT("/Users/:id", c.SCIMHandler.ReplaceUser)
+		scimRoute.PATCH("/Users/:id", c.SCIMHandler.PatchUser)
+		scimRoute.DELETE("/Users/:id", c.SCIMHandler.DeleteUser)
+
+		scimRoute.GET("/Groups", c.SCIMHandler.ListGroups)
+		scimRoute.POST("/Groups", c.SCIMHandler.CreateGroup)
+		scimRoute.GET("/Groups/:id", c.SCIMHandler.GetGroup)
+		scimRoute.PUT("/Groups/:id", c.SCIMHandler.ReplaceGroup)
+		scimRoute.PATCH("/Groups/:id", c.SCIMHandler.PatchGroup)
+		scimRoute.DELETE("/Groups/:id", c.SCIMHandler.DeleteGroup)
 	}
 }
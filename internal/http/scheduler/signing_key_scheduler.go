@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"app/go-sso/internal/entity"
+	usecase "app/go-sso/internal/usecase/signing_key"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type ISigningKeyScheduler interface {
+	RotateSigningKey() error
+}
+
+type SigningKeyScheduler struct {
+	Viper *viper.Viper
+	Log   *logrus.Logger
+}
+
+func NewSigningKeyScheduler(viper *viper.Viper, log *logrus.Logger) ISigningKeyScheduler {
+	return &SigningKeyScheduler{
+		Viper: viper,
+		Log:   log,
+	}
+}
+
+func SigningKeySchedulerFactory(viper *viper.Viper, log *logrus.Logger) ISigningKeyScheduler {
+	return NewSigningKeyScheduler(viper, log)
+}
+
+func (s *SigningKeyScheduler) RotateSigningKey() error {
+	algorithm := entity.SigningKeyAlgorithm(s.Viper.GetString("jwt.signing_algorithm"))
+	if algorithm == "" {
+		algorithm = entity.SIGNING_KEY_RS256
+	}
+
+	// keep old keys published for at least as long as the tokens they signed live
+	gracePeriod := time.Duration(s.Viper.GetInt("jwt.key_grace_period")) * time.Hour
+	if gracePeriod < utils.AccessTokenTTL() {
+		gracePeriod = utils.AccessTokenTTL()
+	}
+
+	factory := usecase.RotateSigningKeyUseCaseFactory(s.Log)
+	resp, err := factory.Execute(&usecase.IRotateSigningKeyUseCaseRequest{
+		Algorithm:   algorithm,
+		GracePeriod: gracePeriod,
+	})
+	if err != nil {
+		s.Log.Error("[SigningKeyScheduler.RotateSigningKey] " + err.Error())
+		return err
+	}
+
+	s.Log.Infof("Rotated signing key, new kid %s", resp.SigningKey.KeyID)
+	return nil
+}
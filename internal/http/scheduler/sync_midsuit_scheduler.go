@@ -4,6 +4,7 @@ import (
 	"app/go-sso/internal/config"
 	"app/go-sso/internal/entity"
 	messaging "app/go-sso/internal/messaging/user"
+	"app/go-sso/utils"
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
@@ -19,7 +20,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -1270,7 +1270,7 @@ func (s *SyncMidsuitScheduler) SyncEmployee(jwtToken string) error {
 		}
 
 		// create or update user
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("changeme"), bcrypt.DefaultCost)
+		hashedPassword, err := utils.PasswordHasherFromConfig(s.Viper).Hash("changeme")
 		if err != nil {
 			s.Log.Error("[UserUseCase.Register] " + err.Error())
 			s.createLogTxt("Error when hashing password: "+err.Error(), "storage/logs/sync_employees/")
@@ -1279,7 +1279,7 @@ func (s *SyncMidsuitScheduler) SyncEmployee(jwtToken string) error {
 		user := &entity.User{
 			Email:    employee.Email,
 			Username: employee.Email,
-			Password: string(hashedPassword),
+			Password: hashedPassword,
 			Name:     employee.Name,
 			EmployeeID: func() *uuid.UUID {
 				if employeeExist.ID != uuid.Nil {
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	usecase "app/go-sso/internal/usecase/user_session"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type IUserSessionScheduler interface {
+	DeleteExpiredSessions() error
+}
+
+type UserSessionScheduler struct {
+	Viper *viper.Viper
+	Log   *logrus.Logger
+}
+
+func NewUserSessionScheduler(viper *viper.Viper, log *logrus.Logger) IUserSessionScheduler {
+	return &UserSessionScheduler{
+		Viper: viper,
+		Log:   log,
+	}
+}
+
+func UserSessionSchedulerFactory(viper *viper.Viper, log *logrus.Logger) IUserSessionScheduler {
+	return NewUserSessionScheduler(viper, log)
+}
+
+func (s *UserSessionScheduler) DeleteExpiredSessions() error {
+	factory := usecase.DeleteExpiredUserSessionsUseCaseFactory(s.Log)
+	resp, err := factory.Execute()
+	if err != nil {
+		s.Log.Error("[UserSessionScheduler.DeleteExpiredSessions] " + err.Error())
+		return err
+	}
+
+	s.Log.Infof("Deleted %d expired sessions", resp.Deleted)
+	return nil
+}
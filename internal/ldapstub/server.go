@@ -0,0 +1,273 @@
+// Package ldapstub is an in-process LDAP server holding a fixed directory,
+// for running the LDAP credential backend without a directory server. It
+// answers simple binds, searches and unbinds, nothing else.
+package ldapstub
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Entry is an object of the directory. Binds match DN or, like Active
+// Directory, the userPrincipalName attribute, and need Password.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+type Server struct {
+	Entries []Entry
+
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func NewServer(entries ...Entry) *Server {
+	return &Server{
+		Entries: entries,
+	}
+}
+
+// Start listens on a random local port and returns the URL of the server.
+func (s *Server) Start() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+
+	return "ldap://" + listener.Addr().String(), nil
+}
+
+// Close stops the server and waits for its connections to end.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return errors.New("ldapstub: server not started")
+	}
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			conn.Write(response(messageID, result(ldap.ApplicationBindResponse, s.bind(op))).Bytes())
+		case ldap.ApplicationSearchRequest:
+			entries, code := s.search(op)
+			for _, entry := range entries {
+				conn.Write(response(messageID, entry).Bytes())
+			}
+			conn.Write(response(messageID, result(ldap.ApplicationSearchResultDone, code)).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			conn.Write(response(messageID, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform)).Bytes())
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) uint16 {
+	if len(op.Children) < 3 {
+		return ldap.LDAPResultProtocolError
+	}
+	name := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+	if name == "" && password == "" {
+		return ldap.LDAPResultSuccess
+	}
+
+	for _, entry := range s.Entries {
+		if !strings.EqualFold(entry.DN, name) && !hasValue(entry, "userPrincipalName", name) {
+			continue
+		}
+		if password != "" && entry.Password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (s *Server) search(op *ber.Packet) ([]*ber.Packet, uint16) {
+	if len(op.Children) < 8 {
+		return nil, ldap.LDAPResultProtocolError
+	}
+	baseDN := op.Children[0].Data.String()
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	requested := []string{}
+	for _, attribute := range op.Children[7].Children {
+		requested = append(requested, attribute.Data.String())
+	}
+
+	entries := []*ber.Packet{}
+	for _, entry := range s.Entries {
+		if !inScope(entry.DN, baseDN, scope) {
+			continue
+		}
+		matched, err := matches(entry, filter)
+		if err != nil {
+			return nil, ldap.LDAPResultFilterError
+		}
+		if matched {
+			entries = append(entries, searchResultEntry(entry, requested))
+		}
+	}
+	if len(entries) == 0 && scope == ldap.ScopeBaseObject {
+		return nil, ldap.LDAPResultNoSuchObject
+	}
+	return entries, ldap.LDAPResultSuccess
+}
+
+func inScope(dn string, baseDN string, scope int64) bool {
+	dn = strings.ToLower(dn)
+	baseDN = strings.ToLower(baseDN)
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		parent := ""
+		if comma := strings.Index(dn, ","); comma >= 0 {
+			parent = dn[comma+1:]
+		}
+		return parent == baseDN
+	default:
+		return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+func matches(entry Entry, filter *ber.Packet) (bool, error) {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			matched, err := matches(entry, child)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			matched, err := matches(entry, child)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	case ldap.FilterNot:
+		if len(filter.Children) != 1 {
+			return false, errors.New("ldapstub: invalid not filter")
+		}
+		matched, err := matches(entry, filter.Children[0])
+		return !matched, err
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		if len(filter.Children) != 2 {
+			return false, errors.New("ldapstub: invalid equality filter")
+		}
+		return hasValue(entry, filter.Children[0].Data.String(), filter.Children[1].Data.String()), nil
+	case ldap.FilterPresent:
+		name := filter.Data.String()
+		if strings.EqualFold(name, "objectClass") {
+			return true, nil
+		}
+		return len(values(entry, name)) > 0, nil
+	}
+	return false, errors.New("ldapstub: unsupported filter " + ldap.FilterMap[uint64(filter.Tag)])
+}
+
+func values(entry Entry, name string) []string {
+	for attribute, values := range entry.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func hasValue(entry Entry, name string, value string) bool {
+	for _, v := range values(entry, name) {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func searchResultEntry(entry Entry, requested []string) *ber.Packet {
+	all := len(requested) == 0
+	for _, name := range requested {
+		if name == "*" {
+			all = true
+		}
+	}
+
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, vals := range entry.Attributes {
+		wanted := all
+		for _, r := range requested {
+			wanted = wanted || strings.EqualFold(r, name)
+		}
+		if !wanted {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range vals {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return op
+}
+
+func response(messageID int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
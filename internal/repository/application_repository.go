@@ -11,6 +11,7 @@ import (
 type IApplicationRepository interface {
 	GetAllApplications() (*[]entity.Application, error)
 	FindApplicationByName(name string) (*entity.Application, error)
+	FindApplicationWithClientPermissions(name string) (*entity.Application, error)
 	GetAllApplicationDomains() ([]string, error)
 }
 
@@ -33,7 +34,7 @@ func ApplicationRepositoryFactory(log *logrus.Logger) *ApplicationRepository {
 
 func (r *ApplicationRepository) GetAllApplications() (*[]entity.Application, error) {
 	var applications []entity.Application
-	if err := r.DB.Find(&applications).Error; err != nil {
+	if err := r.DB.Preload("SAMLServiceProvider").Find(&applications).Error; err != nil {
 		r.Log.Error(err)
 		return nil, err
 	}
@@ -42,7 +43,16 @@ func (r *ApplicationRepository) GetAllApplications() (*[]entity.Application, err
 
 func (r *ApplicationRepository) FindApplicationByName(name string) (*entity.Application, error) {
 	var application entity.Application
-	if err := r.DB.Where("name = ?", name).First(&application).Error; err != nil {
+	if err := r.DB.Preload("RedirectURIs").Where("name = ?", name).First(&application).Error; err != nil {
+		r.Log.Error(err)
+		return nil, err
+	}
+	return &application, nil
+}
+
+func (r *ApplicationRepository) FindApplicationWithClientPermissions(name string) (*entity.Application, error) {
+	var application entity.Application
+	if err := r.DB.Preload("ClientPermissions").Where("name = ?", name).First(&application).Error; err != nil {
 		r.Log.Error(err)
 		return nil, err
 	}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IApplicationSessionRepository interface {
+	CreateApplicationSession(applicationSession *entity.ApplicationSession) error
+	FindBySessionID(sessionID string) ([]entity.ApplicationSession, error)
+	FindByUserID(userID uuid.UUID) ([]entity.ApplicationSession, error)
+	DeleteApplicationSessions(applicationSessions []entity.ApplicationSession) error
+}
+
+type ApplicationSessionRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewApplicationSessionRepository(log *logrus.Logger, db *gorm.DB) IApplicationSessionRepository {
+	return &ApplicationSessionRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func ApplicationSessionRepositoryFactory(log *logrus.Logger) IApplicationSessionRepository {
+	db := config.NewDatabase()
+	return NewApplicationSessionRepository(log, db)
+}
+
+// CreateApplicationSession records the application once per session, signing
+// in to the same application again is not an error.
+func (r *ApplicationSessionRepository) CreateApplicationSession(applicationSession *entity.ApplicationSession) error {
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(applicationSession).Error; err != nil {
+		r.Log.Error("[ApplicationSessionRepository.CreateApplicationSession] " + err.Error())
+		return errors.New("[ApplicationSessionRepository.CreateApplicationSession] " + err.Error())
+	}
+	return nil
+}
+
+func (r *ApplicationSessionRepository) FindBySessionID(sessionID string) ([]entity.ApplicationSession, error) {
+	var applicationSessions []entity.ApplicationSession
+	if err := r.DB.Preload("Application.RedirectURIs").Where("session_id = ?", sessionID).Find(&applicationSessions).Error; err != nil {
+		r.Log.Error("[ApplicationSessionRepository.FindBySessionID] " + err.Error())
+		return nil, errors.New("[ApplicationSessionRepository.FindBySessionID] " + err.Error())
+	}
+	return applicationSessions, nil
+}
+
+func (r *ApplicationSessionRepository) FindByUserID(userID uuid.UUID) ([]entity.ApplicationSession, error) {
+	var applicationSessions []entity.ApplicationSession
+	if err := r.DB.Preload("Application.RedirectURIs").Where("user_id = ?", userID).Find(&applicationSessions).Error; err != nil {
+		r.Log.Error("[ApplicationSessionRepository.FindByUserID] " + err.Error())
+		return nil, errors.New("[ApplicationSessionRepository.FindByUserID] " + err.Error())
+	}
+	return applicationSessions, nil
+}
+
+func (r *ApplicationSessionRepository) DeleteApplicationSessions(applicationSessions []entity.ApplicationSession) error {
+	if len(applicationSessions) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(applicationSessions))
+	for _, applicationSession := range applicationSessions {
+		ids = append(ids, applicationSession.ID)
+	}
+	if err := r.DB.Where("id IN ?", ids).Delete(&entity.ApplicationSession{}).Error; err != nil {
+		r.Log.Error("[ApplicationSessionRepository.DeleteApplicationSessions] " + err.Error())
+		return errors.New("[ApplicationSessionRepository.DeleteApplicationSessions] " + err.Error())
+	}
+	return nil
+}
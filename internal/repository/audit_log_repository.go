@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IAuditLogRepository interface {
+	CreateAuditLog(auditLog *entity.AuditLog) (*entity.AuditLog, error)
+}
+
+type AuditLogRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewAuditLogRepository(log *logrus.Logger, db *gorm.DB) IAuditLogRepository {
+	return &AuditLogRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func AuditLogRepositoryFactory(log *logrus.Logger) IAuditLogRepository {
+	db := config.NewDatabase()
+	return NewAuditLogRepository(log, db)
+}
+
+func (r *AuditLogRepository) CreateAuditLog(auditLog *entity.AuditLog) (*entity.AuditLog, error) {
+	if err := r.DB.Create(auditLog).Error; err != nil {
+		r.Log.Error("[AuditLogRepository.CreateAuditLog] " + err.Error())
+		return nil, errors.New("[AuditLogRepository.CreateAuditLog] " + err.Error())
+	}
+	return auditLog, nil
+}
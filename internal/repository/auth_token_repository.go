@@ -3,6 +3,8 @@ package repository
 import (
 	"app/go-sso/internal/config"
 	"app/go-sso/internal/entity"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -13,6 +15,11 @@ type IAuthTokenRepository interface {
 	StoreAuthToken(user *entity.User, token *entity.AuthToken) error
 	FindAuthToken(userID uuid.UUID, token string) (*entity.AuthToken, error)
 	DeleteAuthToken(userID string, token string) error
+	CreateAuthToken(authToken *entity.AuthToken) (*entity.AuthToken, error)
+	FindAuthTokenByToken(token string) (*entity.AuthToken, error)
+	ConsumeAuthToken(authToken *entity.AuthToken) error
+	RevokeAuthTokenFamily(familyID uuid.UUID) error
+	RevokeAuthTokensByUserID(userID uuid.UUID) error
 }
 
 type AuthTokenRepository struct {
@@ -54,6 +61,70 @@ func (r *AuthTokenRepository) DeleteAuthToken(userID string, token string) error
 	return nil
 }
 
+func (r *AuthTokenRepository) CreateAuthToken(authToken *entity.AuthToken) (*entity.AuthToken, error) {
+	if err := r.DB.Create(authToken).Error; err != nil {
+		r.Log.Error("[AuthTokenRepository.CreateAuthToken] " + err.Error())
+		return nil, errors.New("[AuthTokenRepository.CreateAuthToken] " + err.Error())
+	}
+	return authToken, nil
+}
+
+func (r *AuthTokenRepository) FindAuthTokenByToken(token string) (*entity.AuthToken, error) {
+	var authToken entity.AuthToken
+	err := r.DB.Where("token = ?", token).First(&authToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[AuthTokenRepository.FindAuthTokenByToken] Auth token not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[AuthTokenRepository.FindAuthTokenByToken] " + err.Error())
+			return nil, errors.New("[AuthTokenRepository.FindAuthTokenByToken] " + err.Error())
+		}
+	}
+	return &authToken, nil
+}
+
+// ConsumeAuthToken marks the refresh token as used. The update only matches an
+// unused, unrevoked row so a token can be rotated exactly once.
+func (r *AuthTokenRepository) ConsumeAuthToken(authToken *entity.AuthToken) error {
+	now := time.Now()
+	result := r.DB.Model(&entity.AuthToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", authToken.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		r.Log.Error("[AuthTokenRepository.ConsumeAuthToken] " + result.Error.Error())
+		return errors.New("[AuthTokenRepository.ConsumeAuthToken] " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		r.Log.Warn("[AuthTokenRepository.ConsumeAuthToken] Auth token already used")
+		return errors.New("[AuthTokenRepository.ConsumeAuthToken] auth token already used")
+	}
+	authToken.UsedAt = &now
+	return nil
+}
+
+func (r *AuthTokenRepository) RevokeAuthTokenFamily(familyID uuid.UUID) error {
+	err := r.DB.Model(&entity.AuthToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		r.Log.Error("[AuthTokenRepository.RevokeAuthTokenFamily] " + err.Error())
+		return errors.New("[AuthTokenRepository.RevokeAuthTokenFamily] " + err.Error())
+	}
+	return nil
+}
+
+func (r *AuthTokenRepository) RevokeAuthTokensByUserID(userID uuid.UUID) error {
+	err := r.DB.Model(&entity.AuthToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		r.Log.Error("[AuthTokenRepository.RevokeAuthTokensByUserID] " + err.Error())
+		return errors.New("[AuthTokenRepository.RevokeAuthTokensByUserID] " + err.Error())
+	}
+	return nil
+}
+
 func AuthTokenRepositoryFactory(log *logrus.Logger) IAuthTokenRepository {
 	db := config.NewDatabase()
 	return NewAuthTokenRepository(log, db)
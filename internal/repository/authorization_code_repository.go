@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IAuthorizationCodeRepository interface {
+	CreateAuthorizationCode(authorizationCode *entity.AuthorizationCode) (*entity.AuthorizationCode, error)
+	FindByCode(code string) (*entity.AuthorizationCode, error)
+	ConsumeAuthorizationCode(authorizationCode *entity.AuthorizationCode) error
+}
+
+type AuthorizationCodeRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewAuthorizationCodeRepository(log *logrus.Logger, db *gorm.DB) IAuthorizationCodeRepository {
+	return &AuthorizationCodeRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func AuthorizationCodeRepositoryFactory(log *logrus.Logger) IAuthorizationCodeRepository {
+	db := config.NewDatabase()
+	return NewAuthorizationCodeRepository(log, db)
+}
+
+func (r *AuthorizationCodeRepository) CreateAuthorizationCode(authorizationCode *entity.AuthorizationCode) (*entity.AuthorizationCode, error) {
+	if err := r.DB.Create(authorizationCode).Error; err != nil {
+		r.Log.Error("[AuthorizationCodeRepository.CreateAuthorizationCode] " + err.Error())
+		return nil, errors.New("[AuthorizationCodeRepository.CreateAuthorizationCode] " + err.Error())
+	}
+	return authorizationCode, nil
+}
+
+func (r *AuthorizationCodeRepository) FindByCode(code string) (*entity.AuthorizationCode, error) {
+	var authorizationCode entity.AuthorizationCode
+	err := r.DB.Preload("Application").Where("code = ?", code).First(&authorizationCode).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[AuthorizationCodeRepository.FindByCode] Authorization code not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[AuthorizationCodeRepository.FindByCode] " + err.Error())
+			return nil, errors.New("[AuthorizationCodeRepository.FindByCode] " + err.Error())
+		}
+	}
+	return &authorizationCode, nil
+}
+
+// ConsumeAuthorizationCode marks the code as used. The update is conditional on
+// used_at still being null so two concurrent exchanges cannot both succeed.
+func (r *AuthorizationCodeRepository) ConsumeAuthorizationCode(authorizationCode *entity.AuthorizationCode) error {
+	now := time.Now()
+	result := r.DB.Model(&entity.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", authorizationCode.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		r.Log.Error("[AuthorizationCodeRepository.ConsumeAuthorizationCode] " + result.Error.Error())
+		return errors.New("[AuthorizationCodeRepository.ConsumeAuthorizationCode] " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		r.Log.Warn("[AuthorizationCodeRepository.ConsumeAuthorizationCode] Authorization code already used")
+		return errors.New("[AuthorizationCodeRepository.ConsumeAuthorizationCode] authorization code already used")
+	}
+	authorizationCode.UsedAt = &now
+	return nil
+}
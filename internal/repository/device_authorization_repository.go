@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IDeviceAuthorizationRepository interface {
+	CreateDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization) (*entity.DeviceAuthorization, error)
+	FindByDeviceCode(deviceCode string) (*entity.DeviceAuthorization, error)
+	FindByUserCode(userCode string) (*entity.DeviceAuthorization, error)
+	UpdateLastPolledAt(deviceAuthorization *entity.DeviceAuthorization, polledAt time.Time) error
+	DecideDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization, status entity.DeviceAuthorizationStatus, userID uuid.UUID, roleID uuid.UUID, authTime time.Time) error
+	ConsumeDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization) error
+}
+
+type DeviceAuthorizationRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewDeviceAuthorizationRepository(log *logrus.Logger, db *gorm.DB) IDeviceAuthorizationRepository {
+	return &DeviceAuthorizationRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func DeviceAuthorizationRepositoryFactory(log *logrus.Logger) IDeviceAuthorizationRepository {
+	db := config.NewDatabase()
+	return NewDeviceAuthorizationRepository(log, db)
+}
+
+func (r *DeviceAuthorizationRepository) CreateDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization) (*entity.DeviceAuthorization, error) {
+	if err := r.DB.Create(deviceAuthorization).Error; err != nil {
+		r.Log.Error("[DeviceAuthorizationRepository.CreateDeviceAuthorization] " + err.Error())
+		return nil, errors.New("[DeviceAuthorizationRepository.CreateDeviceAuthorization] " + err.Error())
+	}
+	return deviceAuthorization, nil
+}
+
+func (r *DeviceAuthorizationRepository) FindByDeviceCode(deviceCode string) (*entity.DeviceAuthorization, error) {
+	var deviceAuthorization entity.DeviceAuthorization
+	err := r.DB.Preload("Application").Where("device_code = ?", deviceCode).First(&deviceAuthorization).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[DeviceAuthorizationRepository.FindByDeviceCode] Device authorization not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[DeviceAuthorizationRepository.FindByDeviceCode] " + err.Error())
+			return nil, errors.New("[DeviceAuthorizationRepository.FindByDeviceCode] " + err.Error())
+		}
+	}
+	return &deviceAuthorization, nil
+}
+
+func (r *DeviceAuthorizationRepository) FindByUserCode(userCode string) (*entity.DeviceAuthorization, error) {
+	var deviceAuthorization entity.DeviceAuthorization
+	err := r.DB.Preload("Application").Where("user_code = ?", userCode).First(&deviceAuthorization).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[DeviceAuthorizationRepository.FindByUserCode] Device authorization not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[DeviceAuthorizationRepository.FindByUserCode] " + err.Error())
+			return nil, errors.New("[DeviceAuthorizationRepository.FindByUserCode] " + err.Error())
+		}
+	}
+	return &deviceAuthorization, nil
+}
+
+func (r *DeviceAuthorizationRepository) UpdateLastPolledAt(deviceAuthorization *entity.DeviceAuthorization, polledAt time.Time) error {
+	if err := r.DB.Model(deviceAuthorization).Update("last_polled_at", polledAt).Error; err != nil {
+		r.Log.Error("[DeviceAuthorizationRepository.UpdateLastPolledAt] " + err.Error())
+		return errors.New("[DeviceAuthorizationRepository.UpdateLastPolledAt] " + err.Error())
+	}
+	return nil
+}
+
+// DecideDeviceAuthorization records the user's approval or denial. It only
+// applies to a request that is still pending, so a code cannot be decided twice.
+func (r *DeviceAuthorizationRepository) DecideDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization, status entity.DeviceAuthorizationStatus, userID uuid.UUID, roleID uuid.UUID, authTime time.Time) error {
+	result := r.DB.Model(&entity.DeviceAuthorization{}).
+		Where("id = ? AND status = ?", deviceAuthorization.ID, entity.DEVICE_AUTHORIZATION_PENDING).
+		Updates(map[string]interface{}{
+			"status":    status,
+			"user_id":   userID,
+			"role_id":   roleID,
+			"auth_time": authTime,
+		})
+	if result.Error != nil {
+		r.Log.Error("[DeviceAuthorizationRepository.DecideDeviceAuthorization] " + result.Error.Error())
+		return errors.New("[DeviceAuthorizationRepository.DecideDeviceAuthorization] " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		r.Log.Warn("[DeviceAuthorizationRepository.DecideDeviceAuthorization] Device authorization already decided")
+		return errors.New("[DeviceAuthorizationRepository.DecideDeviceAuthorization] device authorization already decided")
+	}
+	deviceAuthorization.Status = status
+	deviceAuthorization.UserID = &userID
+	deviceAuthorization.RoleID = &roleID
+	deviceAuthorization.AuthTime = &authTime
+	return nil
+}
+
+// ConsumeDeviceAuthorization moves an approved request to CONSUMED so only one
+// poll receives the tokens.
+func (r *DeviceAuthorizationRepository) ConsumeDeviceAuthorization(deviceAuthorization *entity.DeviceAuthorization) error {
+	result := r.DB.Model(&entity.DeviceAuthorization{}).
+		Where("id = ? AND status = ?", deviceAuthorization.ID, entity.DEVICE_AUTHORIZATION_APPROVED).
+		Update("status", entity.DEVICE_AUTHORIZATION_CONSUMED)
+	if result.Error != nil {
+		r.Log.Error("[DeviceAuthorizationRepository.ConsumeDeviceAuthorization] " + result.Error.Error())
+		return errors.New("[DeviceAuthorizationRepository.ConsumeDeviceAuthorization] " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		r.Log.Warn("[DeviceAuthorizationRepository.ConsumeDeviceAuthorization] Device authorization already consumed")
+		return errors.New("[DeviceAuthorizationRepository.ConsumeDeviceAuthorization] device authorization already consumed")
+	}
+	deviceAuthorization.Status = entity.DEVICE_AUTHORIZATION_CONSUMED
+	return nil
+}
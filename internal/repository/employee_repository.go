@@ -3,6 +3,7 @@ package repository
 import (
 	"app/go-sso/internal/config"
 	"app/go-sso/internal/entity"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -21,6 +22,7 @@ type IEmployeeRepository interface {
 	UpdateEmployeeJob(employeeJob *entity.EmployeeJob) (*entity.EmployeeJob, error)
 	FindById(id uuid.UUID) (*entity.Employee, error)
 	FindByMidsuitID(midsuitID string) (*entity.Employee, error)
+	FindByEmail(email string) (*entity.Employee, error)
 	CountEmployeeRetiredEndByDateRange(startDate string, endDate string) (int64, error)
 	GetOrganizationStructureIdDistinct() ([]uuid.UUID, error)
 	CountByOrganizationStructureID(organizationStructureID uuid.UUID) (int, error)
@@ -286,6 +288,19 @@ func (r *EmployeeRepository) FindByMidsuitID(midsuitID string) (*entity.Employee
 	return &employee, nil
 }
 
+func (r *EmployeeRepository) FindByEmail(email string) (*entity.Employee, error) {
+	var employee entity.Employee
+	err := r.DB.Preload("User").Where("email = ?", email).First(&employee).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.Log.Error("[EmployeeRepository.FindByEmail] " + err.Error())
+		return nil, errors.New("[EmployeeRepository.FindByEmail] " + err.Error())
+	}
+	return &employee, nil
+}
+
 func (r *EmployeeRepository) UpdateEmployeeMidsuitID(id uuid.UUID, midsuitID string) (*entity.Employee, error) {
 	tx := r.DB.Begin()
 	if tx.Error != nil {
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ILoginThrottleRepository interface {
+	FindByIdentifier(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string) (*entity.LoginThrottle, error)
+	FindLockedAccounts() ([]entity.LoginThrottle, error)
+	RegisterFailure(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string, windowStart time.Time) (*entity.LoginThrottle, error)
+	Lock(loginThrottle *entity.LoginThrottle, lockedUntil time.Time) (bool, error)
+	DeleteByIdentifier(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string) error
+	DeleteAccount(email string) error
+}
+
+type LoginThrottleRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewLoginThrottleRepository(log *logrus.Logger, db *gorm.DB) ILoginThrottleRepository {
+	return &LoginThrottleRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func LoginThrottleRepositoryFactory(log *logrus.Logger) ILoginThrottleRepository {
+	db := config.NewDatabase()
+	return NewLoginThrottleRepository(log, db)
+}
+
+func (r *LoginThrottleRepository) FindByIdentifier(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string) (*entity.LoginThrottle, error) {
+	var loginThrottle entity.LoginThrottle
+	err := r.DB.Where("action = ? AND scope = ? AND identifier = ?", action, scope, identifier).First(&loginThrottle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[LoginThrottleRepository.FindByIdentifier] " + err.Error())
+			return nil, errors.New("[LoginThrottleRepository.FindByIdentifier] " + err.Error())
+		}
+	}
+	return &loginThrottle, nil
+}
+
+// FindLockedAccounts returns the currently locked accounts, of any action.
+func (r *LoginThrottleRepository) FindLockedAccounts() ([]entity.LoginThrottle, error) {
+	var loginThrottles []entity.LoginThrottle
+	err := r.DB.Where("scope = ? AND locked_until > ?", entity.LOGIN_THROTTLE_ACCOUNT, time.Now()).Find(&loginThrottles).Error
+	if err != nil {
+		r.Log.Error("[LoginThrottleRepository.FindLockedAccounts] " + err.Error())
+		return nil, errors.New("[LoginThrottleRepository.FindLockedAccounts] " + err.Error())
+	}
+	return loginThrottles, nil
+}
+
+// RegisterFailure counts a failed attempt and returns the updated row. The
+// row is locked while it is updated so concurrent attempts are all counted;
+// failures from before windowStart are forgotten.
+func (r *LoginThrottleRepository) RegisterFailure(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string, windowStart time.Time) (*entity.LoginThrottle, error) {
+	var loginThrottle entity.LoginThrottle
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.LoginThrottle{
+			Action:       action,
+			Scope:        scope,
+			Identifier:   identifier,
+			LastFailedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("action = ? AND scope = ? AND identifier = ?", action, scope, identifier).
+			First(&loginThrottle).Error; err != nil {
+			return err
+		}
+
+		if loginThrottle.LastFailedAt.Before(windowStart) {
+			loginThrottle.Failures = 0
+		}
+		loginThrottle.Failures++
+		loginThrottle.LastFailedAt = time.Now()
+		return tx.Model(&loginThrottle).Updates(map[string]interface{}{
+			"failures":       loginThrottle.Failures,
+			"last_failed_at": loginThrottle.LastFailedAt,
+		}).Error
+	})
+	if err != nil {
+		r.Log.Error("[LoginThrottleRepository.RegisterFailure] " + err.Error())
+		return nil, errors.New("[LoginThrottleRepository.RegisterFailure] " + err.Error())
+	}
+	return &loginThrottle, nil
+}
+
+// Lock locks the identifier and starts counting its failures again. It reports
+// false when a concurrent attempt locked it first.
+func (r *LoginThrottleRepository) Lock(loginThrottle *entity.LoginThrottle, lockedUntil time.Time) (bool, error) {
+	result := r.DB.Model(&entity.LoginThrottle{}).
+		Where("id = ? AND failures >= ?", loginThrottle.ID, loginThrottle.Failures).
+		Updates(map[string]interface{}{
+			"failures":     0,
+			"locked_until": lockedUntil,
+		})
+	if result.Error != nil {
+		r.Log.Error("[LoginThrottleRepository.Lock] " + result.Error.Error())
+		return false, errors.New("[LoginThrottleRepository.Lock] " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *LoginThrottleRepository) DeleteByIdentifier(action entity.LoginThrottleAction, scope entity.LoginThrottleScope, identifier string) error {
+	if err := r.DB.Where("action = ? AND scope = ? AND identifier = ?", action, scope, identifier).Delete(&entity.LoginThrottle{}).Error; err != nil {
+		r.Log.Error("[LoginThrottleRepository.DeleteByIdentifier] " + err.Error())
+		return errors.New("[LoginThrottleRepository.DeleteByIdentifier] " + err.Error())
+	}
+	return nil
+}
+
+// DeleteAccount clears the failures and locks of an account for every action.
+func (r *LoginThrottleRepository) DeleteAccount(email string) error {
+	if err := r.DB.Where("scope = ? AND identifier = ?", entity.LOGIN_THROTTLE_ACCOUNT, email).Delete(&entity.LoginThrottle{}).Error; err != nil {
+		r.Log.Error("[LoginThrottleRepository.DeleteAccount] " + err.Error())
+		return errors.New("[LoginThrottleRepository.DeleteAccount] " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IMFAChallengeRepository interface {
+	CreateMFAChallenge(mfaChallenge *entity.MFAChallenge) (*entity.MFAChallenge, error)
+	FindByTokenHash(tokenHash string) (*entity.MFAChallenge, error)
+	IncrementAttempts(mfaChallenge *entity.MFAChallenge) error
+	UseMFAChallenge(mfaChallenge *entity.MFAChallenge) (bool, error)
+}
+
+type MFAChallengeRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewMFAChallengeRepository(log *logrus.Logger, db *gorm.DB) IMFAChallengeRepository {
+	return &MFAChallengeRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func MFAChallengeRepositoryFactory(log *logrus.Logger) IMFAChallengeRepository {
+	db := config.NewDatabase()
+	return NewMFAChallengeRepository(log, db)
+}
+
+func (r *MFAChallengeRepository) CreateMFAChallenge(mfaChallenge *entity.MFAChallenge) (*entity.MFAChallenge, error) {
+	if err := r.DB.Create(mfaChallenge).Error; err != nil {
+		r.Log.Error("[MFAChallengeRepository.CreateMFAChallenge] " + err.Error())
+		return nil, errors.New("[MFAChallengeRepository.CreateMFAChallenge] " + err.Error())
+	}
+	return mfaChallenge, nil
+}
+
+func (r *MFAChallengeRepository) FindByTokenHash(tokenHash string) (*entity.MFAChallenge, error) {
+	var mfaChallenge entity.MFAChallenge
+	err := r.DB.Where("token_hash = ?", tokenHash).First(&mfaChallenge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[MFAChallengeRepository.FindByTokenHash] " + err.Error())
+			return nil, errors.New("[MFAChallengeRepository.FindByTokenHash] " + err.Error())
+		}
+	}
+	return &mfaChallenge, nil
+}
+
+func (r *MFAChallengeRepository) IncrementAttempts(mfaChallenge *entity.MFAChallenge) error {
+	err := r.DB.Model(mfaChallenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		r.Log.Error("[MFAChallengeRepository.IncrementAttempts] " + err.Error())
+		return errors.New("[MFAChallengeRepository.IncrementAttempts] " + err.Error())
+	}
+	return nil
+}
+
+// UseMFAChallenge marks the challenge as answered. It returns false when it
+// was answered already.
+func (r *MFAChallengeRepository) UseMFAChallenge(mfaChallenge *entity.MFAChallenge) (bool, error) {
+	result := r.DB.Model(&entity.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", mfaChallenge.ID).
+		Updates(map[string]interface{}{
+			"used_at":    time.Now(),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		r.Log.Error("[MFAChallengeRepository.UseMFAChallenge] " + result.Error.Error())
+		return false, errors.New("[MFAChallengeRepository.UseMFAChallenge] " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
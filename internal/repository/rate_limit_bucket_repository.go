@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IRateLimitBucketRepository interface {
+	UpdateBucket(id string, initial *entity.RateLimitBucket, update func(bucket *entity.RateLimitBucket)) error
+	DeleteExpired() (int64, error)
+}
+
+type RateLimitBucketRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewRateLimitBucketRepository(log *logrus.Logger, db *gorm.DB) IRateLimitBucketRepository {
+	return &RateLimitBucketRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func RateLimitBucketRepositoryFactory(log *logrus.Logger) IRateLimitBucketRepository {
+	db := config.NewDatabase()
+	return NewRateLimitBucketRepository(log, db)
+}
+
+// UpdateBucket locks the bucket, creating it from initial when it does not
+// exist yet, lets update change it and stores the result. Concurrent requests
+// of every instance are serialized on the row.
+func (r *RateLimitBucketRepository) UpdateBucket(id string, initial *entity.RateLimitBucket, update func(bucket *entity.RateLimitBucket)) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		initial.ID = id
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(initial).Error; err != nil {
+			return err
+		}
+
+		var bucket entity.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		update(&bucket)
+		return tx.Model(&entity.RateLimitBucket{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"tokens":     bucket.Tokens,
+			"updated_at": bucket.UpdatedAt,
+			"expired_at": bucket.ExpiredAt,
+		}).Error
+	})
+	if err != nil {
+		r.Log.Error("[RateLimitBucketRepository.UpdateBucket] " + err.Error())
+		return errors.New("[RateLimitBucketRepository.UpdateBucket] " + err.Error())
+	}
+	return nil
+}
+
+func (r *RateLimitBucketRepository) DeleteExpired() (int64, error) {
+	result := r.DB.Where("expired_at <= ?", time.Now()).Delete(&entity.RateLimitBucket{})
+	if result.Error != nil {
+		r.Log.Error("[RateLimitBucketRepository.DeleteExpired] " + result.Error.Error())
+		return 0, errors.New("[RateLimitBucketRepository.DeleteExpired] " + result.Error.Error())
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IRevokedTokenRepository interface {
+	CreateRevokedToken(revokedToken *entity.RevokedToken) (*entity.RevokedToken, error)
+	FindUnexpiredRevokedTokens() (*[]entity.RevokedToken, error)
+}
+
+type RevokedTokenRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewRevokedTokenRepository(log *logrus.Logger, db *gorm.DB) IRevokedTokenRepository {
+	return &RevokedTokenRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func RevokedTokenRepositoryFactory(log *logrus.Logger) IRevokedTokenRepository {
+	db := config.NewDatabase()
+	return NewRevokedTokenRepository(log, db)
+}
+
+func (r *RevokedTokenRepository) CreateRevokedToken(revokedToken *entity.RevokedToken) (*entity.RevokedToken, error) {
+	if err := r.DB.Create(revokedToken).Error; err != nil {
+		r.Log.Error("[RevokedTokenRepository.CreateRevokedToken] " + err.Error())
+		return nil, errors.New("[RevokedTokenRepository.CreateRevokedToken] " + err.Error())
+	}
+	return revokedToken, nil
+}
+
+func (r *RevokedTokenRepository) FindUnexpiredRevokedTokens() (*[]entity.RevokedToken, error) {
+	var revokedTokens []entity.RevokedToken
+	if err := r.DB.Where("expired_at > ?", time.Now()).Find(&revokedTokens).Error; err != nil {
+		r.Log.Error("[RevokedTokenRepository.FindUnexpiredRevokedTokens] " + err.Error())
+		return nil, errors.New("[RevokedTokenRepository.FindUnexpiredRevokedTokens] " + err.Error())
+	}
+	return &revokedTokens, nil
+}
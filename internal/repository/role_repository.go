@@ -13,6 +13,7 @@ import (
 type IRoleRepository interface {
 	GetAllRoles() (*[]entity.Role, error)
 	FindByName(name string) (*entity.Role, error)
+	FindByApplicationIDAndName(applicationID uuid.UUID, name string) (*entity.Role, error)
 	FindById(id uuid.UUID) (*entity.Role, error)
 	StoreRole(role *entity.Role) (*entity.Role, error)
 	UpdateRole(role *entity.Role) (*entity.Role, error)
@@ -21,6 +22,8 @@ type IRoleRepository interface {
 	GetAllRolesNotInUserID(userID uuid.UUID) (*[]entity.Role, error)
 	GetAllRolesInUserID(userID uuid.UUID) (*[]entity.Role, error)
 	DeleteRole(id uuid.UUID) error
+	FindAllByCondition(condition string, args []interface{}, order string, offset int, limit int) ([]entity.Role, int64, error)
+	UpdateRoleUsers(roleID uuid.UUID, addUserIDs []uuid.UUID, removeUserIDs []uuid.UUID) error
 }
 
 type RoleRepository struct {
@@ -72,6 +75,20 @@ func (r *RoleRepository) FindByName(name string) (*entity.Role, error) {
 	return &role, nil
 }
 
+func (r *RoleRepository) FindByApplicationIDAndName(applicationID uuid.UUID, name string) (*entity.Role, error) {
+	var role entity.Role
+
+	if err := r.DB.Where("application_id = ? AND name = ?", applicationID, name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.Log.Error(err)
+		return nil, err
+	}
+
+	return &role, nil
+}
+
 func (r *RoleRepository) StoreRole(role *entity.Role) (*entity.Role, error) {
 	tx := r.DB.Begin()
 	if tx.Error != nil {
@@ -129,6 +146,74 @@ func (r *RoleRepository) DeleteRole(id uuid.UUID) error {
 	return nil
 }
 
+// FindAllByCondition finds a page of the roles matching condition, with their
+// application and users, and counts all the matches. The roles are ordered by
+// order, or by creation when it is empty.
+func (r *RoleRepository) FindAllByCondition(condition string, args []interface{}, order string, offset int, limit int) ([]entity.Role, int64, error) {
+	var roles []entity.Role
+	var total int64
+
+	query := r.DB.Model(&entity.Role{})
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		r.Log.Error("[RoleRepository.FindAllByCondition] " + err.Error())
+		return nil, 0, errors.New("[RoleRepository.FindAllByCondition] " + err.Error())
+	}
+
+	if order == "" {
+		order = "roles.created_at, roles.id"
+	}
+	if err := query.Preload("Application").Preload("Users").Order(order).Offset(offset).Limit(limit).Find(&roles).Error; err != nil {
+		r.Log.Error("[RoleRepository.FindAllByCondition] " + err.Error())
+		return nil, 0, errors.New("[RoleRepository.FindAllByCondition] " + err.Error())
+	}
+
+	return roles, total, nil
+}
+
+// UpdateRoleUsers gives the role to addUserIDs and takes it from
+// removeUserIDs, leaving its other users alone.
+func (r *RoleRepository) UpdateRoleUsers(roleID uuid.UUID, addUserIDs []uuid.UUID, removeUserIDs []uuid.UUID) error {
+	tx := r.DB.Begin()
+	if tx.Error != nil {
+		return errors.New("[RoleRepository.UpdateRoleUsers] failed to begin transaction: " + tx.Error.Error())
+	}
+
+	if len(removeUserIDs) > 0 {
+		if err := tx.Where("role_id = ? AND user_id IN ?", roleID, removeUserIDs).Delete(&entity.UserRole{}).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[RoleRepository.UpdateRoleUsers] " + err.Error())
+			return errors.New("[RoleRepository.UpdateRoleUsers] " + err.Error())
+		}
+	}
+
+	for _, userID := range addUserIDs {
+		var count int64
+		if err := tx.Model(&entity.UserRole{}).Where("role_id = ? AND user_id = ?", roleID, userID).Count(&count).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[RoleRepository.UpdateRoleUsers] " + err.Error())
+			return errors.New("[RoleRepository.UpdateRoleUsers] " + err.Error())
+		}
+		if count > 0 {
+			continue
+		}
+		if err := tx.Create(&entity.UserRole{UserID: userID, RoleID: roleID}).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[RoleRepository.UpdateRoleUsers] " + err.Error())
+			return errors.New("[RoleRepository.UpdateRoleUsers] " + err.Error())
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		r.Log.Error("[RoleRepository.UpdateRoleUsers] failed to commit transaction: " + err.Error())
+		return errors.New("[RoleRepository.UpdateRoleUsers] failed to commit transaction: " + err.Error())
+	}
+	return nil
+}
+
 func (r *RoleRepository) GetAllRolesNotInUserID(userID uuid.UUID) (*[]entity.Role, error) {
 	var roles []entity.Role
 
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ISAMLServiceProviderRepository interface {
+	FindByEntityID(entityID string) (*entity.SAMLServiceProvider, error)
+	FindByApplicationID(applicationID uuid.UUID) (*entity.SAMLServiceProvider, error)
+	SaveServiceProvider(serviceProvider *entity.SAMLServiceProvider) (*entity.SAMLServiceProvider, error)
+}
+
+type SAMLServiceProviderRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewSAMLServiceProviderRepository(log *logrus.Logger, db *gorm.DB) ISAMLServiceProviderRepository {
+	return &SAMLServiceProviderRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func SAMLServiceProviderRepositoryFactory(log *logrus.Logger) ISAMLServiceProviderRepository {
+	db := config.NewDatabase()
+	return NewSAMLServiceProviderRepository(log, db)
+}
+
+func (r *SAMLServiceProviderRepository) FindByEntityID(entityID string) (*entity.SAMLServiceProvider, error) {
+	var serviceProvider entity.SAMLServiceProvider
+	err := r.DB.Preload("Application").Where("entity_id = ?", entityID).First(&serviceProvider).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[SAMLServiceProviderRepository.FindByEntityID] Service provider not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[SAMLServiceProviderRepository.FindByEntityID] " + err.Error())
+			return nil, errors.New("[SAMLServiceProviderRepository.FindByEntityID] " + err.Error())
+		}
+	}
+	return &serviceProvider, nil
+}
+
+func (r *SAMLServiceProviderRepository) FindByApplicationID(applicationID uuid.UUID) (*entity.SAMLServiceProvider, error) {
+	var serviceProvider entity.SAMLServiceProvider
+	err := r.DB.Preload("Application").Where("application_id = ?", applicationID).First(&serviceProvider).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[SAMLServiceProviderRepository.FindByApplicationID] Service provider not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[SAMLServiceProviderRepository.FindByApplicationID] " + err.Error())
+			return nil, errors.New("[SAMLServiceProviderRepository.FindByApplicationID] " + err.Error())
+		}
+	}
+	return &serviceProvider, nil
+}
+
+// SaveServiceProvider creates the application's service provider or replaces
+// the metadata and mapping of the existing one.
+func (r *SAMLServiceProviderRepository) SaveServiceProvider(serviceProvider *entity.SAMLServiceProvider) (*entity.SAMLServiceProvider, error) {
+	existing, err := r.FindByApplicationID(serviceProvider.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		err = r.DB.Create(serviceProvider).Error
+	} else {
+		serviceProvider.ID = existing.ID
+		err = r.DB.Model(existing).Updates(map[string]interface{}{
+			"entity_id":         serviceProvider.EntityID,
+			"metadata":          serviceProvider.Metadata,
+			"name_id_format":    serviceProvider.NameIDFormat,
+			"attribute_mapping": serviceProvider.AttributeMapping,
+			"idp_initiated":     serviceProvider.IDPInitiated,
+		}).Error
+	}
+	if err != nil {
+		r.Log.Error("[SAMLServiceProviderRepository.SaveServiceProvider] " + err.Error())
+		return nil, errors.New("[SAMLServiceProviderRepository.SaveServiceProvider] " + err.Error())
+	}
+	return serviceProvider, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ISigningKeyRepository interface {
+	FindActiveSigningKey() (*entity.SigningKey, error)
+	FindPublishedSigningKeys() (*[]entity.SigningKey, error)
+	RotateSigningKey(signingKey *entity.SigningKey, gracePeriod time.Duration) (*entity.SigningKey, error)
+}
+
+type SigningKeyRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewSigningKeyRepository(log *logrus.Logger, db *gorm.DB) ISigningKeyRepository {
+	return &SigningKeyRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func SigningKeyRepositoryFactory(log *logrus.Logger) ISigningKeyRepository {
+	db := config.NewDatabase()
+	return NewSigningKeyRepository(log, db)
+}
+
+func (r *SigningKeyRepository) FindActiveSigningKey() (*entity.SigningKey, error) {
+	var signingKey entity.SigningKey
+	err := r.DB.Where("status = ?", entity.SIGNING_KEY_ACTIVE).Order("activated_at desc").First(&signingKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[SigningKeyRepository.FindActiveSigningKey] Active signing key not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[SigningKeyRepository.FindActiveSigningKey] " + err.Error())
+			return nil, errors.New("[SigningKeyRepository.FindActiveSigningKey] " + err.Error())
+		}
+	}
+	return &signingKey, nil
+}
+
+// FindPublishedSigningKeys returns the active key plus every retired key that
+// is still inside its grace period.
+func (r *SigningKeyRepository) FindPublishedSigningKeys() (*[]entity.SigningKey, error) {
+	var signingKeys []entity.SigningKey
+	err := r.DB.Where("status = ? OR (status = ? AND expired_at > ?)", entity.SIGNING_KEY_ACTIVE, entity.SIGNING_KEY_RETIRED, time.Now()).
+		Order("activated_at desc").
+		Find(&signingKeys).Error
+	if err != nil {
+		r.Log.Error("[SigningKeyRepository.FindPublishedSigningKeys] " + err.Error())
+		return nil, errors.New("[SigningKeyRepository.FindPublishedSigningKeys] " + err.Error())
+	}
+	return &signingKeys, nil
+}
+
+// RotateSigningKey retires the currently active keys with the given grace
+// period and stores the new key as the active one in a single transaction.
+func (r *SigningKeyRepository) RotateSigningKey(signingKey *entity.SigningKey, gracePeriod time.Duration) (*entity.SigningKey, error) {
+	now := time.Now()
+	expiredAt := now.Add(gracePeriod)
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.SigningKey{}).
+			Where("status = ?", entity.SIGNING_KEY_ACTIVE).
+			Updates(map[string]interface{}{
+				"status":     entity.SIGNING_KEY_RETIRED,
+				"retired_at": now,
+				"expired_at": expiredAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		signingKey.Status = entity.SIGNING_KEY_ACTIVE
+		signingKey.ActivatedAt = now
+		return tx.Create(signingKey).Error
+	})
+	if err != nil {
+		r.Log.Error("[SigningKeyRepository.RotateSigningKey] " + err.Error())
+		return nil, errors.New("[SigningKeyRepository.RotateSigningKey] " + err.Error())
+	}
+	return signingKey, nil
+}
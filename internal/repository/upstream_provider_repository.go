@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUpstreamProviderRepository interface {
+	FindEnabled() ([]entity.UpstreamProvider, error)
+	FindEnabledByName(name string) (*entity.UpstreamProvider, error)
+}
+
+type UpstreamProviderRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUpstreamProviderRepository(log *logrus.Logger, db *gorm.DB) IUpstreamProviderRepository {
+	return &UpstreamProviderRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UpstreamProviderRepositoryFactory(log *logrus.Logger) IUpstreamProviderRepository {
+	db := config.NewDatabase()
+	return NewUpstreamProviderRepository(log, db)
+}
+
+func (r *UpstreamProviderRepository) FindEnabled() ([]entity.UpstreamProvider, error) {
+	var upstreamProviders []entity.UpstreamProvider
+	err := r.DB.Where("enabled = ?", true).Order("label ASC").Find(&upstreamProviders).Error
+	if err != nil {
+		r.Log.Error("[UpstreamProviderRepository.FindEnabled] " + err.Error())
+		return nil, errors.New("[UpstreamProviderRepository.FindEnabled] " + err.Error())
+	}
+	return upstreamProviders, nil
+}
+
+func (r *UpstreamProviderRepository) FindEnabledByName(name string) (*entity.UpstreamProvider, error) {
+	var upstreamProvider entity.UpstreamProvider
+	err := r.DB.Where("name = ? AND enabled = ?", name, true).First(&upstreamProvider).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[UpstreamProviderRepository.FindEnabledByName] " + err.Error())
+			return nil, errors.New("[UpstreamProviderRepository.FindEnabledByName] " + err.Error())
+		}
+	}
+	return &upstreamProvider, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUserIdentityRepository interface {
+	CreateUserIdentity(userIdentity *entity.UserIdentity) (*entity.UserIdentity, error)
+	FindByProviderAndSubject(provider entity.UserIdentityProvider, subject string) (*entity.UserIdentity, error)
+	FindByUserIDAndProvider(userID uuid.UUID, provider entity.UserIdentityProvider) (*entity.UserIdentity, error)
+	FindByUserID(userID uuid.UUID) ([]entity.UserIdentity, error)
+	UpdateEmail(userIdentity *entity.UserIdentity, email string) error
+	DeleteUserIdentity(id uuid.UUID) error
+}
+
+type UserIdentityRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUserIdentityRepository(log *logrus.Logger, db *gorm.DB) IUserIdentityRepository {
+	return &UserIdentityRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UserIdentityRepositoryFactory(log *logrus.Logger) IUserIdentityRepository {
+	db := config.NewDatabase()
+	return NewUserIdentityRepository(log, db)
+}
+
+func (r *UserIdentityRepository) CreateUserIdentity(userIdentity *entity.UserIdentity) (*entity.UserIdentity, error) {
+	if err := r.DB.Create(userIdentity).Error; err != nil {
+		r.Log.Error("[UserIdentityRepository.CreateUserIdentity] " + err.Error())
+		return nil, errors.New("[UserIdentityRepository.CreateUserIdentity] " + err.Error())
+	}
+	return userIdentity, nil
+}
+
+func (r *UserIdentityRepository) FindByProviderAndSubject(provider entity.UserIdentityProvider, subject string) (*entity.UserIdentity, error) {
+	var userIdentity entity.UserIdentity
+	err := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(&userIdentity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[UserIdentityRepository.FindByProviderAndSubject] " + err.Error())
+			return nil, errors.New("[UserIdentityRepository.FindByProviderAndSubject] " + err.Error())
+		}
+	}
+	return &userIdentity, nil
+}
+
+func (r *UserIdentityRepository) FindByUserIDAndProvider(userID uuid.UUID, provider entity.UserIdentityProvider) (*entity.UserIdentity, error) {
+	var userIdentity entity.UserIdentity
+	err := r.DB.Where("user_id = ? AND provider = ?", userID, provider).First(&userIdentity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[UserIdentityRepository.FindByUserIDAndProvider] " + err.Error())
+			return nil, errors.New("[UserIdentityRepository.FindByUserIDAndProvider] " + err.Error())
+		}
+	}
+	return &userIdentity, nil
+}
+
+func (r *UserIdentityRepository) FindByUserID(userID uuid.UUID) ([]entity.UserIdentity, error) {
+	var userIdentities []entity.UserIdentity
+	err := r.DB.Where("user_id = ?", userID).Order("linked_at ASC").Find(&userIdentities).Error
+	if err != nil {
+		r.Log.Error("[UserIdentityRepository.FindByUserID] " + err.Error())
+		return nil, errors.New("[UserIdentityRepository.FindByUserID] " + err.Error())
+	}
+	return userIdentities, nil
+}
+
+func (r *UserIdentityRepository) UpdateEmail(userIdentity *entity.UserIdentity, email string) error {
+	if err := r.DB.Model(userIdentity).Update("email", email).Error; err != nil {
+		r.Log.Error("[UserIdentityRepository.UpdateEmail] " + err.Error())
+		return errors.New("[UserIdentityRepository.UpdateEmail] " + err.Error())
+	}
+	return nil
+}
+
+func (r *UserIdentityRepository) DeleteUserIdentity(id uuid.UUID) error {
+	if err := r.DB.Where("id = ?", id).Delete(&entity.UserIdentity{}).Error; err != nil {
+		r.Log.Error("[UserIdentityRepository.DeleteUserIdentity] " + err.Error())
+		return errors.New("[UserIdentityRepository.DeleteUserIdentity] " + err.Error())
+	}
+	return nil
+}
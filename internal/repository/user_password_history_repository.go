@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUserPasswordHistoryRepository interface {
+	FindRecentByUserID(userID uuid.UUID, limit int) ([]entity.UserPasswordHistory, error)
+	AddPasswordHistory(userID uuid.UUID, passwordHash string, keep int) error
+}
+
+type UserPasswordHistoryRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUserPasswordHistoryRepository(log *logrus.Logger, db *gorm.DB) IUserPasswordHistoryRepository {
+	return &UserPasswordHistoryRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UserPasswordHistoryRepositoryFactory(log *logrus.Logger) IUserPasswordHistoryRepository {
+	db := config.NewDatabase()
+	return NewUserPasswordHistoryRepository(log, db)
+}
+
+// FindRecentByUserID returns the last limit passwords of the user, newest
+// first.
+func (r *UserPasswordHistoryRepository) FindRecentByUserID(userID uuid.UUID, limit int) ([]entity.UserPasswordHistory, error) {
+	var userPasswordHistories []entity.UserPasswordHistory
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&userPasswordHistories).Error
+	if err != nil {
+		r.Log.Error("[UserPasswordHistoryRepository.FindRecentByUserID] " + err.Error())
+		return nil, errors.New("[UserPasswordHistoryRepository.FindRecentByUserID] " + err.Error())
+	}
+	return userPasswordHistories, nil
+}
+
+// AddPasswordHistory records a new password of the user and forgets all but
+// the last keep of them.
+func (r *UserPasswordHistoryRepository) AddPasswordHistory(userID uuid.UUID, passwordHash string, keep int) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entity.UserPasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+			return err
+		}
+
+		var staleIDs []uuid.UUID
+		err := tx.Model(&entity.UserPasswordHistory{}).
+			Where("user_id = ?", userID).
+			Order("created_at DESC").
+			Offset(keep).
+			Limit(1000).
+			Pluck("id", &staleIDs).Error
+		if err != nil {
+			return err
+		}
+		if len(staleIDs) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", staleIDs).Delete(&entity.UserPasswordHistory{}).Error
+	})
+	if err != nil {
+		r.Log.Error("[UserPasswordHistoryRepository.AddPasswordHistory] " + err.Error())
+		return errors.New("[UserPasswordHistoryRepository.AddPasswordHistory] " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUserRecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	CountUnused(userID uuid.UUID) (int64, error)
+	DeleteByUserID(userID uuid.UUID) error
+}
+
+type UserRecoveryCodeRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUserRecoveryCodeRepository(log *logrus.Logger, db *gorm.DB) IUserRecoveryCodeRepository {
+	return &UserRecoveryCodeRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UserRecoveryCodeRepositoryFactory(log *logrus.Logger) IUserRecoveryCodeRepository {
+	db := config.NewDatabase()
+	return NewUserRecoveryCodeRepository(log, db)
+}
+
+// ReplaceRecoveryCodes drops every code of the user, used or not, and stores
+// the new ones.
+func (r *UserRecoveryCodeRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for _, codeHash := range codeHashes {
+			if err := tx.Create(&entity.UserRecoveryCode{UserID: userID, CodeHash: codeHash}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.Log.Error("[UserRecoveryCodeRepository.ReplaceRecoveryCodes] " + err.Error())
+		return errors.New("[UserRecoveryCodeRepository.ReplaceRecoveryCodes] " + err.Error())
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the code as used. It returns false when the code does
+// not belong to the user or was already used.
+func (r *UserRecoveryCodeRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.DB.Model(&entity.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Updates(map[string]interface{}{
+			"used_at":    time.Now(),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		r.Log.Error("[UserRecoveryCodeRepository.UseRecoveryCode] " + result.Error.Error())
+		return false, errors.New("[UserRecoveryCodeRepository.UseRecoveryCode] " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRecoveryCodeRepository) CountUnused(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entity.UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	if err != nil {
+		r.Log.Error("[UserRecoveryCodeRepository.CountUnused] " + err.Error())
+		return 0, errors.New("[UserRecoveryCodeRepository.CountUnused] " + err.Error())
+	}
+	return count, nil
+}
+
+func (r *UserRecoveryCodeRepository) DeleteByUserID(userID uuid.UUID) error {
+	if err := r.DB.Where("user_id = ?", userID).Delete(&entity.UserRecoveryCode{}).Error; err != nil {
+		r.Log.Error("[UserRecoveryCodeRepository.DeleteByUserID] " + err.Error())
+		return errors.New("[UserRecoveryCodeRepository.DeleteByUserID] " + err.Error())
+	}
+	return nil
+}
@@ -24,13 +24,18 @@ type IUserRepository interface {
 	UpdateUser(user *entity.User, roleIDs []uuid.UUID) (*entity.User, error)
 	UpdateUserOnly(user *entity.User) (*entity.User, error)
 	UpdateEmployeeIdToNull(user *entity.User) (*entity.User, error)
+	UpdateUserRoles(userID uuid.UUID, grantRoleIDs []uuid.UUID, revokeRoleIDs []uuid.UUID) error
+	FindAllByCondition(condition string, args []interface{}, order string, offset int, limit int) ([]entity.User, int64, error)
+	UpdateUserColumns(id uuid.UUID, columns map[string]interface{}) error
 	DeleteUser(id uuid.UUID) error
-	CreateUserToken(email string, token int, tokenType entity.UserTokenType) error
-	AcknowledgeUserToken(email string, token int) error
 	VerifyUserEmail(email string) error
 	FindUserTokenByEmail(email string) (*entity.UserToken, error)
-	FindUserTokenByEmailAndToken(email string, token int) (*entity.UserToken, error)
-	DeleteUserToken(email string, tokenType entity.UserTokenType) error
+	ReplaceHashedUserToken(userToken *entity.UserToken) error
+	FindHashedUserToken(tokenHash string, tokenType entity.UserTokenType) (*entity.UserToken, error)
+	IncrementUserTokenAttempts(email string, tokenType entity.UserTokenType) error
+	ConsumeHashedUserToken(tokenHash string, tokenType entity.UserTokenType) (*entity.UserToken, error)
+	UpdatePassword(id uuid.UUID, hashedPassword string) error
+	UpdatePasswordHash(id uuid.UUID, oldHashedPassword string, newHashedPassword string) (bool, error)
 	GetAllUsersByPermissionNames(permissionNames []string) (*[]entity.User, error)
 }
 
@@ -293,6 +298,90 @@ func (r *UserRepository) UpdateUserOnly(user *entity.User) (*entity.User, error)
 	return user, nil
 }
 
+// UpdateUserRoles gives the user the roles of grantRoleIDs it does not have
+// yet and takes the roles of revokeRoleIDs away; its other roles are kept.
+func (r *UserRepository) UpdateUserRoles(userID uuid.UUID, grantRoleIDs []uuid.UUID, revokeRoleIDs []uuid.UUID) error {
+	tx := r.DB.Begin()
+	if tx.Error != nil {
+		return errors.New("[UserRepository.UpdateUserRoles] failed to begin transaction: " + tx.Error.Error())
+	}
+
+	if len(revokeRoleIDs) > 0 {
+		if err := tx.Where("user_id = ? AND role_id IN ?", userID, revokeRoleIDs).Delete(&entity.UserRole{}).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[UserRepository.UpdateUserRoles] " + err.Error())
+			return errors.New("[UserRepository.UpdateUserRoles] " + err.Error())
+		}
+	}
+
+	for _, roleID := range grantRoleIDs {
+		var count int64
+		if err := tx.Model(&entity.UserRole{}).Where("user_id = ? AND role_id = ?", userID, roleID).Count(&count).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[UserRepository.UpdateUserRoles] " + err.Error())
+			return errors.New("[UserRepository.UpdateUserRoles] " + err.Error())
+		}
+		if count > 0 {
+			continue
+		}
+		if err := tx.Create(&entity.UserRole{UserID: userID, RoleID: roleID}).Error; err != nil {
+			tx.Rollback()
+			r.Log.Error("[UserRepository.UpdateUserRoles] " + err.Error())
+			return errors.New("[UserRepository.UpdateUserRoles] " + err.Error())
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		r.Log.Error("[UserRepository.UpdateUserRoles] failed to commit transaction: " + err.Error())
+		return errors.New("[UserRepository.UpdateUserRoles] failed to commit transaction: " + err.Error())
+	}
+	return nil
+}
+
+// FindAllByCondition finds a page of the users matching condition, with
+// their roles and the job of their employee, and counts all the matches. The
+// users are ordered by order, or by creation when it is empty.
+func (r *UserRepository) FindAllByCondition(condition string, args []interface{}, order string, offset int, limit int) ([]entity.User, int64, error) {
+	var users []entity.User
+	var total int64
+
+	query := r.DB.Model(&entity.User{})
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		r.Log.Error("[UserRepository.FindAllByCondition] " + err.Error())
+		return nil, 0, errors.New("[UserRepository.FindAllByCondition] " + err.Error())
+	}
+
+	if order == "" {
+		order = "users.created_at, users.id"
+	}
+	if err := query.
+		Preload("Roles.Application").
+		Preload("Employee.Organization").
+		Preload("Employee.EmployeeJob.OrganizationStructure").
+		Preload("Employee.EmployeeJob.Job.Parent.EmployeeJobs.Employee.User").
+		Order(order).Offset(offset).Limit(limit).
+		Find(&users).Error; err != nil {
+		r.Log.Error("[UserRepository.FindAllByCondition] " + err.Error())
+		return nil, 0, errors.New("[UserRepository.FindAllByCondition] " + err.Error())
+	}
+
+	return users, total, nil
+}
+
+// UpdateUserColumns sets the given columns of the user, zero values and nil
+// included.
+func (r *UserRepository) UpdateUserColumns(id uuid.UUID, columns map[string]interface{}) error {
+	if err := r.DB.Model(&entity.User{}).Where("id = ?", id).Updates(columns).Error; err != nil {
+		r.Log.Error("[UserRepository.UpdateUserColumns] " + err.Error())
+		return errors.New("[UserRepository.UpdateUserColumns] " + err.Error())
+	}
+	return nil
+}
+
 func (r *UserRepository) UpdateEmployeeIdToNull(user *entity.User) (*entity.User, error) {
 	tx := r.DB.Begin()
 	if tx.Error != nil {
@@ -349,68 +438,6 @@ func (r *UserRepository) DeleteUser(id uuid.UUID) error {
 	return nil
 }
 
-func (r *UserRepository) CreateUserToken(email string, token int, tokenType entity.UserTokenType) error {
-	tx := r.DB.Begin()
-	if tx.Error != nil {
-		return errors.New("[UserRepository.CreateUserToken] failed to begin transaction: " + tx.Error.Error())
-	}
-
-	var user entity.User
-	if err := tx.First(&user, "email = ?", email).Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.CreateUserToken] User not found: " + err.Error())
-		return errors.New("[UserRepository.CreateUserToken] User not found: " + err.Error())
-	}
-
-	var userToken = entity.UserToken{
-		Email:     email,
-		Token:     token,
-		TokenType: tokenType,
-	}
-
-	if err := tx.Create(&userToken).Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.CreateUserToken] " + err.Error())
-		return errors.New("[UserRepository.CreateUserToken] " + err.Error())
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.CreateUserToken] failed to commit transaction: " + err.Error())
-		return errors.New("[UserRepository.CreateUserToken] failed to commit transaction: " + err.Error())
-	}
-
-	return nil
-}
-
-func (r *UserRepository) AcknowledgeUserToken(email string, token int) error {
-	tx := r.DB.Begin()
-	if tx.Error != nil {
-		return errors.New("[UserRepository.AcknowledgeUserToken] failed to begin transaction: " + tx.Error.Error())
-	}
-
-	var userToken entity.UserToken
-	if err := tx.First(&userToken, "email = ? AND token = ?", email, token).Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.AcknowledgeUserToken] User token not found: " + err.Error())
-		return errors.New("[UserRepository.AcknowledgeUserToken] User token not found: " + err.Error())
-	}
-
-	if err := tx.Where("email = ? AND token = ?", userToken.Email, userToken.Token).Delete(&userToken).Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.AcknowledgeUserToken] " + err.Error())
-		return errors.New("[UserRepository.AcknowledgeUserToken] " + err.Error())
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		r.Log.Error("[UserRepository.AcknowledgeUserToken] failed to commit transaction: " + err.Error())
-		return errors.New("[UserRepository.AcknowledgeUserToken] failed to commit transaction: " + err.Error())
-	}
-
-	return nil
-}
-
 func (r *UserRepository) VerifyUserEmail(email string) error {
 	tx := r.DB.Begin()
 	if tx.Error != nil {
@@ -457,49 +484,116 @@ func (r *UserRepository) FindUserTokenByEmail(email string) (*entity.UserToken,
 	return &userToken, nil
 }
 
-func (r *UserRepository) DeleteUserToken(email string, tokenType entity.UserTokenType) error {
+// ReplaceHashedUserToken stores a token of which only the hash is kept,
+// dropping the tokens of the same type sent to the email before.
+func (r *UserRepository) ReplaceHashedUserToken(userToken *entity.UserToken) error {
 	tx := r.DB.Begin()
 	if tx.Error != nil {
-		return errors.New("[UserRepository.DeleteUserToken] failed to begin transaction: " + tx.Error.Error())
+		return errors.New("[UserRepository.ReplaceHashedUserToken] failed to begin transaction: " + tx.Error.Error())
 	}
 
-	var userToken entity.UserToken
-	if err := tx.First(&userToken, "email = ? AND token_type = ?", email, tokenType).Error; err != nil {
+	if err := tx.Where("email = ? AND token_type = ?", userToken.Email, userToken.TokenType).Delete(&entity.UserToken{}).Error; err != nil {
 		tx.Rollback()
-		r.Log.Error("[UserRepository.DeleteUserToken] User token not found: " + err.Error())
-		return errors.New("[UserRepository.DeleteUserToken] User token not found: " + err.Error())
+		r.Log.Error("[UserRepository.ReplaceHashedUserToken] " + err.Error())
+		return errors.New("[UserRepository.ReplaceHashedUserToken] " + err.Error())
 	}
 
-	if err := tx.Where("email = ? AND token_type = ?", userToken.Email, userToken.TokenType).Delete(&userToken).Error; err != nil {
+	if err := tx.Create(userToken).Error; err != nil {
 		tx.Rollback()
-		r.Log.Error("[UserRepository.DeleteUserToken] " + err.Error())
-		return errors.New("[UserRepository.DeleteUserToken] " + err.Error())
+		r.Log.Error("[UserRepository.ReplaceHashedUserToken] " + err.Error())
+		return errors.New("[UserRepository.ReplaceHashedUserToken] " + err.Error())
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
-		r.Log.Error("[UserRepository.DeleteUserToken] failed to commit transaction: " + err.Error())
-		return errors.New("[UserRepository.DeleteUserToken] failed to commit transaction: " + err.Error())
+		r.Log.Error("[UserRepository.ReplaceHashedUserToken] failed to commit transaction: " + err.Error())
+		return errors.New("[UserRepository.ReplaceHashedUserToken] failed to commit transaction: " + err.Error())
 	}
 
 	return nil
 }
 
-func (r *UserRepository) FindUserTokenByEmailAndToken(email string, token int) (*entity.UserToken, error) {
+// FindHashedUserToken returns an unexpired token without consuming it.
+func (r *UserRepository) FindHashedUserToken(tokenHash string, tokenType entity.UserTokenType) (*entity.UserToken, error) {
 	var userToken entity.UserToken
-	err := r.DB.First(&userToken, "email = ? AND token = ?", email, token).Error
+	err := r.DB.Where("token_hash = ? AND token_type = ? AND expired_at > ?", tokenHash, tokenType, time.Now()).First(&userToken).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.Log.Warn("[UserRepository.FindUserTokenByEmailAndToken] User token not found")
+			r.Log.Warn("[UserRepository.FindHashedUserToken] User token not found")
 			return nil, nil
 		} else {
-			r.Log.Error("[UserRepository.FindUserTokenByEmailAndToken] " + err.Error())
-			return nil, errors.New("[UserRepository.FindUserTokenByEmailAndToken] " + err.Error())
+			r.Log.Error("[UserRepository.FindHashedUserToken] " + err.Error())
+			return nil, errors.New("[UserRepository.FindHashedUserToken] " + err.Error())
 		}
 	}
 	return &userToken, nil
 }
 
+// IncrementUserTokenAttempts counts a wrong code entered for the token of the
+// email.
+func (r *UserRepository) IncrementUserTokenAttempts(email string, tokenType entity.UserTokenType) error {
+	err := r.DB.Model(&entity.UserToken{}).
+		Where("email = ? AND token_type = ?", email, tokenType).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		r.Log.Error("[UserRepository.IncrementUserTokenAttempts] " + err.Error())
+		return errors.New("[UserRepository.IncrementUserTokenAttempts] " + err.Error())
+	}
+	return nil
+}
+
+// ConsumeHashedUserToken deletes an unexpired token and returns it. Nil is
+// returned when the token is unknown, expired or was consumed concurrently.
+func (r *UserRepository) ConsumeHashedUserToken(tokenHash string, tokenType entity.UserTokenType) (*entity.UserToken, error) {
+	var userToken entity.UserToken
+	err := r.DB.Where("token_hash = ? AND token_type = ? AND expired_at > ?", tokenHash, tokenType, time.Now()).First(&userToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[UserRepository.ConsumeHashedUserToken] User token not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[UserRepository.ConsumeHashedUserToken] " + err.Error())
+			return nil, errors.New("[UserRepository.ConsumeHashedUserToken] " + err.Error())
+		}
+	}
+
+	result := r.DB.Where("token_hash = ? AND token_type = ?", tokenHash, tokenType).Delete(&entity.UserToken{})
+	if result.Error != nil {
+		r.Log.Error("[UserRepository.ConsumeHashedUserToken] " + result.Error.Error())
+		return nil, errors.New("[UserRepository.ConsumeHashedUserToken] " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &userToken, nil
+}
+
+// UpdatePassword sets the password and restarts its age.
+func (r *UserRepository) UpdatePassword(id uuid.UUID, hashedPassword string) error {
+	err := r.DB.Model(&entity.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":            hashedPassword,
+		"password_changed_at": time.Now(),
+	}).Error
+	if err != nil {
+		r.Log.Error("[UserRepository.UpdatePassword] " + err.Error())
+		return errors.New("[UserRepository.UpdatePassword] " + err.Error())
+	}
+	return nil
+}
+
+// UpdatePasswordHash replaces the hash of an unchanged password, keeping its
+// age. It returns false when the password was changed in the meantime.
+func (r *UserRepository) UpdatePasswordHash(id uuid.UUID, oldHashedPassword string, newHashedPassword string) (bool, error) {
+	result := r.DB.Model(&entity.User{}).
+		Where("id = ? AND password = ?", id, oldHashedPassword).
+		Update("password", newHashedPassword)
+	if result.Error != nil {
+		r.Log.Error("[UserRepository.UpdatePasswordHash] " + result.Error.Error())
+		return false, errors.New("[UserRepository.UpdatePasswordHash] " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (r *UserRepository) GetAllUsersByPermissionNames(permissionNames []string) (*[]entity.User, error) {
 	var users []entity.User
 	err := r.DB.Preload("Roles.Permissions").
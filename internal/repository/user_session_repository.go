@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUserSessionRepository interface {
+	CreateUserSession(userSession *entity.UserSession) (*entity.UserSession, error)
+	FindByTokenHash(tokenHash string) (*entity.UserSession, error)
+	FindByID(id uuid.UUID) (*entity.UserSession, error)
+	FindActiveByUserID(userID uuid.UUID) ([]entity.UserSession, error)
+	UpdateUserSession(id uuid.UUID, values map[string]interface{}) error
+	DeleteUserSession(id uuid.UUID) error
+	DeleteByUserID(userID uuid.UUID) error
+	DeleteExpired() (int64, error)
+}
+
+type UserSessionRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUserSessionRepository(log *logrus.Logger, db *gorm.DB) IUserSessionRepository {
+	return &UserSessionRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UserSessionRepositoryFactory(log *logrus.Logger) IUserSessionRepository {
+	db := config.NewDatabase()
+	return NewUserSessionRepository(log, db)
+}
+
+func (r *UserSessionRepository) CreateUserSession(userSession *entity.UserSession) (*entity.UserSession, error) {
+	if err := r.DB.Create(userSession).Error; err != nil {
+		r.Log.Error("[UserSessionRepository.CreateUserSession] " + err.Error())
+		return nil, errors.New("[UserSessionRepository.CreateUserSession] " + err.Error())
+	}
+	return userSession, nil
+}
+
+func (r *UserSessionRepository) FindByTokenHash(tokenHash string) (*entity.UserSession, error) {
+	var userSession entity.UserSession
+	err := r.DB.Where("token_hash = ? AND expired_at > ?", tokenHash, time.Now()).First(&userSession).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[UserSessionRepository.FindByTokenHash] " + err.Error())
+			return nil, errors.New("[UserSessionRepository.FindByTokenHash] " + err.Error())
+		}
+	}
+	return &userSession, nil
+}
+
+func (r *UserSessionRepository) FindByID(id uuid.UUID) (*entity.UserSession, error) {
+	var userSession entity.UserSession
+	err := r.DB.Where("id = ?", id).First(&userSession).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.Log.Warn("[UserSessionRepository.FindByID] User session not found")
+			return nil, nil
+		} else {
+			r.Log.Error("[UserSessionRepository.FindByID] " + err.Error())
+			return nil, errors.New("[UserSessionRepository.FindByID] " + err.Error())
+		}
+	}
+	return &userSession, nil
+}
+
+func (r *UserSessionRepository) FindActiveByUserID(userID uuid.UUID) ([]entity.UserSession, error) {
+	var userSessions []entity.UserSession
+	err := r.DB.Where("user_id = ? AND expired_at > ?", userID, time.Now()).Order("last_seen_at DESC").Find(&userSessions).Error
+	if err != nil {
+		r.Log.Error("[UserSessionRepository.FindActiveByUserID] " + err.Error())
+		return nil, errors.New("[UserSessionRepository.FindActiveByUserID] " + err.Error())
+	}
+	return userSessions, nil
+}
+
+func (r *UserSessionRepository) UpdateUserSession(id uuid.UUID, values map[string]interface{}) error {
+	if err := r.DB.Model(&entity.UserSession{}).Where("id = ?", id).Updates(values).Error; err != nil {
+		r.Log.Error("[UserSessionRepository.UpdateUserSession] " + err.Error())
+		return errors.New("[UserSessionRepository.UpdateUserSession] " + err.Error())
+	}
+	return nil
+}
+
+func (r *UserSessionRepository) DeleteUserSession(id uuid.UUID) error {
+	if err := r.DB.Where("id = ?", id).Delete(&entity.UserSession{}).Error; err != nil {
+		r.Log.Error("[UserSessionRepository.DeleteUserSession] " + err.Error())
+		return errors.New("[UserSessionRepository.DeleteUserSession] " + err.Error())
+	}
+	return nil
+}
+
+func (r *UserSessionRepository) DeleteByUserID(userID uuid.UUID) error {
+	if err := r.DB.Where("user_id = ?", userID).Delete(&entity.UserSession{}).Error; err != nil {
+		r.Log.Error("[UserSessionRepository.DeleteByUserID] " + err.Error())
+		return errors.New("[UserSessionRepository.DeleteByUserID] " + err.Error())
+	}
+	return nil
+}
+
+func (r *UserSessionRepository) DeleteExpired() (int64, error) {
+	result := r.DB.Where("expired_at <= ?", time.Now()).Delete(&entity.UserSession{})
+	if result.Error != nil {
+		r.Log.Error("[UserSessionRepository.DeleteExpired] " + result.Error.Error())
+		return 0, errors.New("[UserSessionRepository.DeleteExpired] " + result.Error.Error())
+	}
+	return result.RowsAffected, nil
+}
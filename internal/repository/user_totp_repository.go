@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IUserTOTPRepository interface {
+	FindByUserID(userID uuid.UUID) (*entity.UserTOTP, error)
+	SaveUserTOTP(userTOTP *entity.UserTOTP) (*entity.UserTOTP, error)
+	ConfirmUserTOTP(userTOTP *entity.UserTOTP, step int64) error
+	UseStep(userTOTP *entity.UserTOTP, step int64) (bool, error)
+	DeleteByUserID(userID uuid.UUID) error
+}
+
+type UserTOTPRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewUserTOTPRepository(log *logrus.Logger, db *gorm.DB) IUserTOTPRepository {
+	return &UserTOTPRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func UserTOTPRepositoryFactory(log *logrus.Logger) IUserTOTPRepository {
+	db := config.NewDatabase()
+	return NewUserTOTPRepository(log, db)
+}
+
+func (r *UserTOTPRepository) FindByUserID(userID uuid.UUID) (*entity.UserTOTP, error) {
+	var userTOTP entity.UserTOTP
+	err := r.DB.Where("user_id = ?", userID).First(&userTOTP).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[UserTOTPRepository.FindByUserID] " + err.Error())
+			return nil, errors.New("[UserTOTPRepository.FindByUserID] " + err.Error())
+		}
+	}
+	return &userTOTP, nil
+}
+
+// SaveUserTOTP replaces the enrolment of the user with a new, unconfirmed one.
+func (r *UserTOTPRepository) SaveUserTOTP(userTOTP *entity.UserTOTP) (*entity.UserTOTP, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userTOTP.UserID).Delete(&entity.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(userTOTP).Error
+	})
+	if err != nil {
+		r.Log.Error("[UserTOTPRepository.SaveUserTOTP] " + err.Error())
+		return nil, errors.New("[UserTOTPRepository.SaveUserTOTP] " + err.Error())
+	}
+	return userTOTP, nil
+}
+
+func (r *UserTOTPRepository) ConfirmUserTOTP(userTOTP *entity.UserTOTP, step int64) error {
+	now := time.Now()
+	err := r.DB.Model(userTOTP).Updates(map[string]interface{}{
+		"confirmed_at":   now,
+		"last_used_step": step,
+	}).Error
+	if err != nil {
+		r.Log.Error("[UserTOTPRepository.ConfirmUserTOTP] " + err.Error())
+		return errors.New("[UserTOTPRepository.ConfirmUserTOTP] " + err.Error())
+	}
+	return nil
+}
+
+// UseStep records step as the last accepted one. It returns false when the
+// step, or a later one, was already used.
+func (r *UserTOTPRepository) UseStep(userTOTP *entity.UserTOTP, step int64) (bool, error) {
+	result := r.DB.Model(&entity.UserTOTP{}).
+		Where("id = ? AND last_used_step < ?", userTOTP.ID, step).
+		Updates(map[string]interface{}{
+			"last_used_step": step,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		r.Log.Error("[UserTOTPRepository.UseStep] " + result.Error.Error())
+		return false, errors.New("[UserTOTPRepository.UseStep] " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserTOTPRepository) DeleteByUserID(userID uuid.UUID) error {
+	if err := r.DB.Where("user_id = ?", userID).Delete(&entity.UserTOTP{}).Error; err != nil {
+		r.Log.Error("[UserTOTPRepository.DeleteByUserID] " + err.Error())
+		return errors.New("[UserTOTPRepository.DeleteByUserID] " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type IWebAuthnCredentialRepository interface {
+	CreateWebAuthnCredential(webAuthnCredential *entity.WebAuthnCredential) (*entity.WebAuthnCredential, error)
+	FindByCredentialID(credentialID string) (*entity.WebAuthnCredential, error)
+	FindByID(id uuid.UUID) (*entity.WebAuthnCredential, error)
+	FindByUserID(userID uuid.UUID) ([]entity.WebAuthnCredential, error)
+	UpdateSignCount(webAuthnCredential *entity.WebAuthnCredential, signCount uint32) error
+	RenameWebAuthnCredential(webAuthnCredential *entity.WebAuthnCredential, name string) error
+	DeleteWebAuthnCredential(id uuid.UUID) error
+}
+
+type WebAuthnCredentialRepository struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(log *logrus.Logger, db *gorm.DB) IWebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{
+		Log: log,
+		DB:  db,
+	}
+}
+
+func WebAuthnCredentialRepositoryFactory(log *logrus.Logger) IWebAuthnCredentialRepository {
+	db := config.NewDatabase()
+	return NewWebAuthnCredentialRepository(log, db)
+}
+
+func (r *WebAuthnCredentialRepository) CreateWebAuthnCredential(webAuthnCredential *entity.WebAuthnCredential) (*entity.WebAuthnCredential, error) {
+	if err := r.DB.Create(webAuthnCredential).Error; err != nil {
+		r.Log.Error("[WebAuthnCredentialRepository.CreateWebAuthnCredential] " + err.Error())
+		return nil, errors.New("[WebAuthnCredentialRepository.CreateWebAuthnCredential] " + err.Error())
+	}
+	return webAuthnCredential, nil
+}
+
+func (r *WebAuthnCredentialRepository) FindByCredentialID(credentialID string) (*entity.WebAuthnCredential, error) {
+	var webAuthnCredential entity.WebAuthnCredential
+	err := r.DB.Where("credential_id = ?", credentialID).First(&webAuthnCredential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[WebAuthnCredentialRepository.FindByCredentialID] " + err.Error())
+			return nil, errors.New("[WebAuthnCredentialRepository.FindByCredentialID] " + err.Error())
+		}
+	}
+	return &webAuthnCredential, nil
+}
+
+func (r *WebAuthnCredentialRepository) FindByID(id uuid.UUID) (*entity.WebAuthnCredential, error) {
+	var webAuthnCredential entity.WebAuthnCredential
+	err := r.DB.Where("id = ?", id).First(&webAuthnCredential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			r.Log.Error("[WebAuthnCredentialRepository.FindByID] " + err.Error())
+			return nil, errors.New("[WebAuthnCredentialRepository.FindByID] " + err.Error())
+		}
+	}
+	return &webAuthnCredential, nil
+}
+
+func (r *WebAuthnCredentialRepository) FindByUserID(userID uuid.UUID) ([]entity.WebAuthnCredential, error) {
+	var webAuthnCredentials []entity.WebAuthnCredential
+	err := r.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&webAuthnCredentials).Error
+	if err != nil {
+		r.Log.Error("[WebAuthnCredentialRepository.FindByUserID] " + err.Error())
+		return nil, errors.New("[WebAuthnCredentialRepository.FindByUserID] " + err.Error())
+	}
+	return webAuthnCredentials, nil
+}
+
+func (r *WebAuthnCredentialRepository) UpdateSignCount(webAuthnCredential *entity.WebAuthnCredential, signCount uint32) error {
+	err := r.DB.Model(webAuthnCredential).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"last_used_at": time.Now(),
+	}).Error
+	if err != nil {
+		r.Log.Error("[WebAuthnCredentialRepository.UpdateSignCount] " + err.Error())
+		return errors.New("[WebAuthnCredentialRepository.UpdateSignCount] " + err.Error())
+	}
+	return nil
+}
+
+func (r *WebAuthnCredentialRepository) RenameWebAuthnCredential(webAuthnCredential *entity.WebAuthnCredential, name string) error {
+	if err := r.DB.Model(webAuthnCredential).Update("name", name).Error; err != nil {
+		r.Log.Error("[WebAuthnCredentialRepository.RenameWebAuthnCredential] " + err.Error())
+		return errors.New("[WebAuthnCredentialRepository.RenameWebAuthnCredential] " + err.Error())
+	}
+	return nil
+}
+
+func (r *WebAuthnCredentialRepository) DeleteWebAuthnCredential(id uuid.UUID) error {
+	if err := r.DB.Where("id = ?", id).Delete(&entity.WebAuthnCredential{}).Error; err != nil {
+		r.Log.Error("[WebAuthnCredentialRepository.DeleteWebAuthnCredential] " + err.Error())
+		return errors.New("[WebAuthnCredentialRepository.DeleteWebAuthnCredential] " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"app/go-sso/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CredentialIdentity is an account a credential backend checked the password
+// of, with the attributes it keeps for it. Subject is the stable id of the
+// account in the backend.
+type CredentialIdentity struct {
+	Subject     string
+	Username    string
+	Email       string
+	Name        string
+	MobilePhone string
+	Groups      []string
+}
+
+// CredentialRoleMapping gives the members of Group the Role of Application.
+type CredentialRoleMapping struct {
+	Group       string `mapstructure:"group"`
+	Application string `mapstructure:"application"`
+	Role        string `mapstructure:"role"`
+}
+
+// ICredentialBackend checks passwords of accounts kept outside the users
+// table. Authenticate returns no identity and no error for unknown logins and
+// wrong passwords, so the next backend can be tried. Name is the provider of
+// the identities linked to the users of the backend. LinkByEmail is whether
+// an account may be linked to the existing user with its email on its first
+// sign-in, which trusts the backend with the emails of every local user.
+type ICredentialBackend interface {
+	Name() string
+	Authenticate(login string, password string) (*CredentialIdentity, error)
+	RoleMappings() []CredentialRoleMapping
+	LinkByEmail() bool
+}
+
+// CredentialBackendsFactory returns the enabled credential backends, tried in
+// this order after the local password.
+func CredentialBackendsFactory(log *logrus.Logger) []ICredentialBackend {
+	viper := config.NewViper()
+	backends := []ICredentialBackend{}
+	if viper.GetBool("ldap.enabled") {
+		backends = append(backends, NewLDAPCredentialBackend(log, LDAPConfigFromViper(viper)))
+	}
+	return backends
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// LDAPConfig is the ldap section of config.json. Users are bound directly
+// with the first of UserDNTemplates that accepts the password, or, without
+// templates, found with UserFilter under BaseDN using the BindDN account and
+// then bound. {username} in both is replaced by the login, escaped.
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	BindDN             string
+	BindPassword       string
+	UserDNTemplates    []string
+	BaseDN             string
+	UserFilter         string
+	// attributes read into the user, SubjectAttribute defaults to the DN
+	SubjectAttribute     string
+	UsernameAttribute    string
+	EmailAttribute       string
+	NameAttribute        string
+	MobilePhoneAttribute string
+	GroupAttribute       string
+	RoleMappings         []CredentialRoleMapping
+	LinkByEmail          bool
+}
+
+// LDAPConfigFromViper reads ldap.*. The filter and attributes default to the
+// ones of Active Directory.
+func LDAPConfigFromViper(config *viper.Viper) LDAPConfig {
+	text := func(key string, fallback string) string {
+		if value := config.GetString(key); value != "" {
+			return value
+		}
+		return fallback
+	}
+	timeout := 10 * time.Second
+	if seconds := config.GetInt("ldap.timeout"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	roleMappings := []CredentialRoleMapping{}
+	if err := config.UnmarshalKey("ldap.group_roles", &roleMappings); err != nil {
+		roleMappings = []CredentialRoleMapping{}
+	}
+
+	return LDAPConfig{
+		URL:                  config.GetString("ldap.url"),
+		StartTLS:             config.GetBool("ldap.start_tls"),
+		InsecureSkipVerify:   config.GetBool("ldap.insecure_skip_verify"),
+		Timeout:              timeout,
+		BindDN:               config.GetString("ldap.bind_dn"),
+		BindPassword:         config.GetString("ldap.bind_password"),
+		UserDNTemplates:      config.GetStringSlice("ldap.user_dn_templates"),
+		BaseDN:               config.GetString("ldap.base_dn"),
+		UserFilter:           text("ldap.user_filter", "(|(sAMAccountName={username})(userPrincipalName={username})(mail={username}))"),
+		SubjectAttribute:     config.GetString("ldap.attributes.subject"),
+		UsernameAttribute:    text("ldap.attributes.username", "sAMAccountName"),
+		EmailAttribute:       text("ldap.attributes.email", "mail"),
+		NameAttribute:        text("ldap.attributes.name", "displayName"),
+		MobilePhoneAttribute: text("ldap.attributes.mobile_phone", "mobile"),
+		GroupAttribute:       text("ldap.attributes.groups", "memberOf"),
+		RoleMappings:         roleMappings,
+		LinkByEmail:          config.GetBool("ldap.link_by_email"),
+	}
+}
+
+type LDAPCredentialBackend struct {
+	Log    *logrus.Logger
+	Config LDAPConfig
+}
+
+func NewLDAPCredentialBackend(log *logrus.Logger, config LDAPConfig) ICredentialBackend {
+	return &LDAPCredentialBackend{
+		Log:    log,
+		Config: config,
+	}
+}
+
+func (b *LDAPCredentialBackend) Name() string {
+	return "ldap"
+}
+
+func (b *LDAPCredentialBackend) RoleMappings() []CredentialRoleMapping {
+	return b.Config.RoleMappings
+}
+
+func (b *LDAPCredentialBackend) LinkByEmail() bool {
+	return b.Config.LinkByEmail
+}
+
+// Authenticate binds as the user of login with password and reads the
+// attributes of the entry. An empty password is refused up front, LDAP
+// servers accept it as an anonymous bind.
+func (b *LDAPCredentialBackend) Authenticate(login string, password string) (*CredentialIdentity, error) {
+	if login == "" || password == "" {
+		return nil, nil
+	}
+
+	conn, err := b.dial()
+	if err != nil {
+		return nil, errors.New("[LDAPCredentialBackend.Authenticate] " + err.Error())
+	}
+	defer conn.Close()
+
+	var entry *ldap.Entry
+	if len(b.Config.UserDNTemplates) > 0 {
+		entry, err = b.bindTemplate(conn, login, password)
+	} else {
+		entry, err = b.bindSearch(conn, login, password)
+	}
+	if err != nil {
+		return nil, errors.New("[LDAPCredentialBackend.Authenticate] " + err.Error())
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return b.identity(entry)
+}
+
+func (b *LDAPCredentialBackend) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: b.Config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(
+		b.Config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: b.Config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(b.Config.Timeout)
+
+	if b.Config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// bindTemplate binds with the DN templates in turn, then reads the entry as
+// the user.
+func (b *LDAPCredentialBackend) bindTemplate(conn *ldap.Conn, login string, password string) (*ldap.Entry, error) {
+	for _, template := range b.Config.UserDNTemplates {
+		dn := strings.ReplaceAll(template, "{username}", ldap.EscapeDN(login))
+		err := conn.Bind(dn, password)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the bound name may be a user principal name rather than a DN, the
+		// entry is then looked up with the filter
+		if b.Config.BaseDN != "" {
+			return b.findEntry(conn, login)
+		}
+		result, err := conn.Search(b.searchRequest(dn, ldap.ScopeBaseObject, "(objectClass=*)"))
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Entries) != 1 {
+			return nil, fmt.Errorf("the entry of %s cannot be read", dn)
+		}
+		return result.Entries[0], nil
+	}
+	return nil, nil
+}
+
+// bindSearch finds the entry of login with the service account, then binds as
+// the entry.
+func (b *LDAPCredentialBackend) bindSearch(conn *ldap.Conn, login string, password string) (*ldap.Entry, error) {
+	if b.Config.BindDN != "" {
+		if err := conn.Bind(b.Config.BindDN, b.Config.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := b.findEntry(conn, login)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	err = conn.Bind(entry.DN, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (b *LDAPCredentialBackend) findEntry(conn *ldap.Conn, login string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(b.Config.UserFilter, "{username}", ldap.EscapeFilter(login))
+	result, err := conn.Search(b.searchRequest(b.Config.BaseDN, ldap.ScopeWholeSubtree, filter))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		b.Log.Warn("[LDAPCredentialBackend.findEntry] the filter matches more than one entry")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) > 1 {
+		b.Log.Warn("[LDAPCredentialBackend.findEntry] the filter matches more than one entry")
+		return nil, nil
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	return result.Entries[0], nil
+}
+
+func (b *LDAPCredentialBackend) searchRequest(baseDN string, scope int, filter string) *ldap.SearchRequest {
+	attributes := []string{
+		b.Config.UsernameAttribute,
+		b.Config.EmailAttribute,
+		b.Config.NameAttribute,
+		b.Config.MobilePhoneAttribute,
+		b.Config.GroupAttribute,
+	}
+	if b.Config.SubjectAttribute != "" {
+		attributes = append(attributes, b.Config.SubjectAttribute)
+	}
+	return ldap.NewSearchRequest(
+		baseDN, scope, ldap.NeverDerefAliases, 2, int(b.Config.Timeout.Seconds()), false,
+		filter, attributes, nil,
+	)
+}
+
+func (b *LDAPCredentialBackend) identity(entry *ldap.Entry) (*CredentialIdentity, error) {
+	subject := entry.DN
+	if b.Config.SubjectAttribute != "" {
+		raw := entry.GetRawAttributeValue(b.Config.SubjectAttribute)
+		if len(raw) == 0 {
+			return nil, errors.New("[LDAPCredentialBackend.identity] " + entry.DN + " has no " + b.Config.SubjectAttribute)
+		}
+		// binary ids like objectGUID are kept in hex
+		subject = string(raw)
+		if !utf8.Valid(raw) {
+			subject = hex.EncodeToString(raw)
+		}
+	}
+
+	identity := &CredentialIdentity{
+		Subject:     subject,
+		Username:    entry.GetAttributeValue(b.Config.UsernameAttribute),
+		Email:       entry.GetAttributeValue(b.Config.EmailAttribute),
+		Name:        entry.GetAttributeValue(b.Config.NameAttribute),
+		MobilePhone: entry.GetAttributeValue(b.Config.MobilePhoneAttribute),
+		Groups:      entry.GetAttributeValues(b.Config.GroupAttribute),
+	}
+	if identity.Email == "" {
+		return nil, errors.New("[LDAPCredentialBackend.identity] " + entry.DN + " has no " + b.Config.EmailAttribute)
+	}
+	return identity, nil
+}
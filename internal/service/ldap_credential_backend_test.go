@@ -0,0 +1,156 @@
+package service
+
+import (
+	"app/go-sso/internal/ldapstub"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func startDirectory(t *testing.T) (*ldapstub.Server, string) {
+	t.Helper()
+	server := ldapstub.NewServer(
+		ldapstub.Entry{
+			DN:       "cn=service,dc=example,dc=com",
+			Password: "service-secret",
+		},
+		ldapstub.Entry{
+			DN:       "uid=jdoe,ou=people,dc=example,dc=com",
+			Password: "directory-secret",
+			Attributes: map[string][]string{
+				"objectClass":    {"person"},
+				"uid":            {"jdoe"},
+				"sAMAccountName": {"jdoe"},
+				"mail":           {"jdoe@example.com"},
+				"displayName":    {"John Doe"},
+				"mobile":         {"+620000001"},
+				"entryUUID":      {"4a7f9c1e-0000-4000-8000-000000000001"},
+				"memberOf":       {"cn=hr,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+	)
+	url, err := server.Start()
+	if err != nil {
+		t.Fatalf("starting the directory: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, url
+}
+
+func testLDAPConfig(url string) LDAPConfig {
+	return LDAPConfig{
+		URL:                  url,
+		Timeout:              5 * time.Second,
+		BindDN:               "cn=service,dc=example,dc=com",
+		BindPassword:         "service-secret",
+		BaseDN:               "dc=example,dc=com",
+		UserFilter:           "(|(sAMAccountName={username})(mail={username}))",
+		SubjectAttribute:     "entryUUID",
+		UsernameAttribute:    "sAMAccountName",
+		EmailAttribute:       "mail",
+		NameAttribute:        "displayName",
+		MobilePhoneAttribute: "mobile",
+		GroupAttribute:       "memberOf",
+	}
+}
+
+func testLDAPBackend(config LDAPConfig) ICredentialBackend {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewLDAPCredentialBackend(log, config)
+}
+
+func TestLDAPCredentialBackendAuthenticate(t *testing.T) {
+	_, url := startDirectory(t)
+	searchConfig := testLDAPConfig(url)
+	templateConfig := testLDAPConfig(url)
+	templateConfig.UserDNTemplates = []string{"uid={username},ou=people,dc=example,dc=com"}
+
+	for name, config := range map[string]LDAPConfig{"search": searchConfig, "template": templateConfig} {
+		t.Run(name, func(t *testing.T) {
+			backend := testLDAPBackend(config)
+
+			identity, err := backend.Authenticate("jdoe", "directory-secret")
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if identity == nil {
+				t.Fatal("Authenticate returned no identity for the right password")
+			}
+			want := &CredentialIdentity{
+				Subject:     "4a7f9c1e-0000-4000-8000-000000000001",
+				Username:    "jdoe",
+				Email:       "jdoe@example.com",
+				Name:        "John Doe",
+				MobilePhone: "+620000001",
+				Groups:      []string{"cn=hr,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			}
+			if !reflect.DeepEqual(identity, want) {
+				t.Errorf("identity = %+v, want %+v", identity, want)
+			}
+		})
+	}
+}
+
+func TestLDAPCredentialBackendRefusesWrongPassword(t *testing.T) {
+	_, url := startDirectory(t)
+	backend := testLDAPBackend(testLDAPConfig(url))
+
+	for _, password := range []string{"wrong", ""} {
+		identity, err := backend.Authenticate("jdoe", password)
+		if err != nil {
+			t.Fatalf("Authenticate(%q): %v", password, err)
+		}
+		if identity != nil {
+			t.Errorf("Authenticate(%q) = %+v, want no identity", password, identity)
+		}
+	}
+}
+
+func TestLDAPCredentialBackendIgnoresUnknownUser(t *testing.T) {
+	_, url := startDirectory(t)
+	backend := testLDAPBackend(testLDAPConfig(url))
+
+	identity, err := backend.Authenticate("nobody", "directory-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if identity != nil {
+		t.Errorf("Authenticate = %+v, want no identity", identity)
+	}
+}
+
+func TestLDAPCredentialBackendSyncsAttributes(t *testing.T) {
+	server, url := startDirectory(t)
+	backend := testLDAPBackend(testLDAPConfig(url))
+
+	// the directory changes between two sign-ins, the second one reads the
+	// new attributes while the subject stays the same
+	entry := &server.Entries[1]
+	entry.Attributes["displayName"] = []string{"John A. Doe"}
+	entry.Attributes["mobile"] = []string{"+620000002"}
+	entry.Attributes["memberOf"] = []string{"cn=finance,ou=groups,dc=example,dc=com"}
+
+	identity, err := backend.Authenticate("jdoe", "directory-secret")
+	if err != nil || identity == nil {
+		t.Fatalf("Authenticate = %+v, %v", identity, err)
+	}
+	if identity.Subject != "4a7f9c1e-0000-4000-8000-000000000001" {
+		t.Errorf("Subject = %q, want the entryUUID", identity.Subject)
+	}
+	if identity.Name != "John A. Doe" || identity.MobilePhone != "+620000002" {
+		t.Errorf("Name, MobilePhone = %q, %q, want the changed ones", identity.Name, identity.MobilePhone)
+	}
+	if !reflect.DeepEqual(identity.Groups, []string{"cn=finance,ou=groups,dc=example,dc=com"}) {
+		t.Errorf("Groups = %v, want the changed ones", identity.Groups)
+	}
+
+	// without an email the entry cannot be matched to a user
+	delete(entry.Attributes, "mail")
+	if identity, err := backend.Authenticate("jdoe", "directory-secret"); err == nil || identity != nil {
+		t.Errorf("Authenticate without mail = %+v, %v, want an error", identity, err)
+	}
+}
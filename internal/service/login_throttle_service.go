@@ -0,0 +1,251 @@
+package service
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/request"
+	"app/go-sso/internal/messaging"
+	"app/go-sso/internal/repository"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// LoginThrottlePolicy limits failed attempts, read from the brute_force
+// section of config.json. Durations are configured in seconds; a zero limit
+// turns that lock off.
+type LoginThrottlePolicy struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	DelayAfter         int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	LockoutDuration    time.Duration
+	FailureWindow      time.Duration
+}
+
+func LoginThrottlePolicyFromConfig(config *viper.Viper) LoginThrottlePolicy {
+	seconds := func(key string, fallback time.Duration) time.Duration {
+		if !config.IsSet(key) {
+			return fallback
+		}
+		return time.Duration(config.GetInt(key)) * time.Second
+	}
+	integer := func(key string, fallback int) int {
+		if !config.IsSet(key) {
+			return fallback
+		}
+		return config.GetInt(key)
+	}
+
+	return LoginThrottlePolicy{
+		MaxAccountFailures: integer("brute_force.max_account_failures", 5),
+		MaxIPFailures:      integer("brute_force.max_ip_failures", 50),
+		DelayAfter:         integer("brute_force.delay_after", 3),
+		BaseDelay:          seconds("brute_force.base_delay", time.Second),
+		MaxDelay:           seconds("brute_force.max_delay", 30*time.Second),
+		LockoutDuration:    seconds("brute_force.lockout_duration", 15*time.Minute),
+		FailureWindow:      seconds("brute_force.failure_window", 15*time.Minute),
+	}
+}
+
+// delay is how long an account has to wait after its latest failure before
+// the next attempt, doubling with every failure past DelayAfter.
+func (p LoginThrottlePolicy) delay(failures int) time.Duration {
+	if p.DelayAfter <= 0 || failures < p.DelayAfter {
+		return 0
+	}
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.DelayAfter)))
+	if delay > p.MaxDelay || delay < 0 {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// LoginThrottledError is returned for attempts made while the account or the
+// IP address is locked or still has to wait.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if e.Locked {
+		return fmt.Sprintf("Too many failed attempts, the account is locked. Try again in %s", wait)
+	}
+	return fmt.Sprintf("Too many failed attempts, try again in %s", wait)
+}
+
+type ILoginThrottleService interface {
+	Check(action entity.LoginThrottleAction, email string, ipAddress string) error
+	RecordFailure(action entity.LoginThrottleAction, email string, ipAddress string) error
+	Reset(action entity.LoginThrottleAction, email string) error
+}
+
+type LoginThrottleService struct {
+	Log            *logrus.Logger
+	Policy         LoginThrottlePolicy
+	MailFrom       string
+	Repository     repository.ILoginThrottleRepository
+	UserRepository repository.IUserRepository
+	MailMessage    messaging.IMailMessage
+}
+
+func NewLoginThrottleService(
+	log *logrus.Logger,
+	policy LoginThrottlePolicy,
+	mailFrom string,
+	repository repository.ILoginThrottleRepository,
+	userRepository repository.IUserRepository,
+	mailMessage messaging.IMailMessage,
+) ILoginThrottleService {
+	return &LoginThrottleService{
+		Log:            log,
+		Policy:         policy,
+		MailFrom:       mailFrom,
+		Repository:     repository,
+		UserRepository: userRepository,
+		MailMessage:    mailMessage,
+	}
+}
+
+func LoginThrottleServiceFactory(log *logrus.Logger) ILoginThrottleService {
+	viper := config.NewViper()
+	loginThrottleRepository := repository.LoginThrottleRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	mailMessage := messaging.MailMessageFactory(log)
+	return NewLoginThrottleService(log, LoginThrottlePolicyFromConfig(viper), viper.GetString("mail.from"), loginThrottleRepository, userRepository, mailMessage)
+}
+
+// Check returns a *LoginThrottledError when the account or the IP address may
+// not make an attempt yet.
+func (s *LoginThrottleService) Check(action entity.LoginThrottleAction, email string, ipAddress string) error {
+	now := time.Now()
+	throttled := &LoginThrottledError{}
+
+	if email = normalizeThrottleEmail(email); email != "" {
+		loginThrottle, err := s.Repository.FindByIdentifier(action, entity.LOGIN_THROTTLE_ACCOUNT, email)
+		if err != nil {
+			return err
+		}
+		if loginThrottle != nil {
+			if loginThrottle.IsLocked(now) {
+				throttled.RetryAfter = loginThrottle.LockedUntil.Sub(now)
+				throttled.Locked = true
+			} else if wait := loginThrottle.LastFailedAt.Add(s.Policy.delay(loginThrottle.Failures)).Sub(now); wait > 0 && now.Sub(loginThrottle.LastFailedAt) < s.Policy.FailureWindow {
+				throttled.RetryAfter = wait
+			}
+		}
+	}
+
+	if ipAddress != "" {
+		loginThrottle, err := s.Repository.FindByIdentifier(action, entity.LOGIN_THROTTLE_IP, ipAddress)
+		if err != nil {
+			return err
+		}
+		if loginThrottle != nil && loginThrottle.IsLocked(now) {
+			if wait := loginThrottle.LockedUntil.Sub(now); wait > throttled.RetryAfter {
+				throttled.RetryAfter = wait
+			}
+			throttled.Locked = true
+		}
+	}
+
+	if throttled.RetryAfter > 0 {
+		return throttled
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against the account and the IP
+// address, locking them once they reach their limit. The owner of a locked
+// account is told by email.
+func (s *LoginThrottleService) RecordFailure(action entity.LoginThrottleAction, email string, ipAddress string) error {
+	now := time.Now()
+	windowStart := now.Add(-s.Policy.FailureWindow)
+
+	if email = normalizeThrottleEmail(email); email != "" {
+		loginThrottle, err := s.Repository.RegisterFailure(action, entity.LOGIN_THROTTLE_ACCOUNT, email, windowStart)
+		if err != nil {
+			return err
+		}
+		if s.Policy.MaxAccountFailures > 0 && loginThrottle.Failures >= s.Policy.MaxAccountFailures {
+			lockedUntil := now.Add(s.Policy.LockoutDuration)
+			locked, err := s.Repository.Lock(loginThrottle, lockedUntil)
+			if err != nil {
+				return err
+			}
+			if locked {
+				s.Log.Warnf("[LoginThrottleService.RecordFailure] %s of %s locked until %s", action, email, lockedUntil.Format(time.RFC3339))
+				go s.notifyLocked(action, email, lockedUntil)
+			}
+		}
+	}
+
+	if ipAddress != "" {
+		loginThrottle, err := s.Repository.RegisterFailure(action, entity.LOGIN_THROTTLE_IP, ipAddress, windowStart)
+		if err != nil {
+			return err
+		}
+		if s.Policy.MaxIPFailures > 0 && loginThrottle.Failures >= s.Policy.MaxIPFailures {
+			lockedUntil := now.Add(s.Policy.LockoutDuration)
+			locked, err := s.Repository.Lock(loginThrottle, lockedUntil)
+			if err != nil {
+				return err
+			}
+			if locked {
+				s.Log.Warnf("[LoginThrottleService.RecordFailure] %s from %s locked until %s", action, ipAddress, lockedUntil.Format(time.RFC3339))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reset forgets the failures of the account after a successful attempt. The
+// IP address keeps its count, signing in to one account must not clear the
+// guesses made against others.
+func (s *LoginThrottleService) Reset(action entity.LoginThrottleAction, email string) error {
+	if email = normalizeThrottleEmail(email); email == "" {
+		return nil
+	}
+	return s.Repository.DeleteByIdentifier(action, entity.LOGIN_THROTTLE_ACCOUNT, email)
+}
+
+func (s *LoginThrottleService) notifyLocked(action entity.LoginThrottleAction, email string, lockedUntil time.Time) {
+	// resending verification codes and asking for magic links is only rate
+	// limited, telling the owner would send yet another email
+	if action == entity.LOGIN_THROTTLE_RESEND_VERIFY_EMAIL || action == entity.LOGIN_THROTTLE_MAGIC_LINK {
+		return
+	}
+
+	user, err := s.UserRepository.FindByEmailOnly(email)
+	if err != nil || user == nil {
+		return
+	}
+
+	if _, err := s.MailMessage.SendMail(&request.MailRequest{
+		Email:   user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf(
+			"We locked your account after %d failed attempts to sign in or verify your email. You can try again after %s.\n\nIf these attempts were not made by you, reset your password once the lock has ended or ask an administrator to unlock your account.",
+			s.Policy.MaxAccountFailures, lockedUntil.Format("2006-01-02 15:04 MST"),
+		),
+		From: s.MailFrom,
+		To:   user.Email,
+	}); err != nil {
+		s.Log.Error("[LoginThrottleService.notifyLocked] " + err.Error())
+	}
+}
+
+func normalizeThrottleEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,12 @@
+package service
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/utils"
+)
+
+// PasswordHasherFactory returns the password hasher configured by
+// password_hashing.*.
+func PasswordHasherFactory() utils.PasswordHasher {
+	return utils.PasswordHasherFromConfig(config.NewViper())
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"app/go-sso/internal/config"
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnknownUpstreamProvider is returned for provider names that are neither
+// configured nor enabled in the database.
+var ErrUnknownUpstreamProvider = errors.New("Unknown sign-in provider")
+
+// UpstreamIdentity is the account that signed in at an upstream provider,
+// read with the claim mapping of the provider.
+type UpstreamIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type IUpstreamProviderService interface {
+	FindAll() ([]entity.UpstreamProvider, error)
+	Find(name string) (*entity.UpstreamProvider, error)
+	AuthCodeURL(upstreamProvider *entity.UpstreamProvider, state string, nonce string, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, upstreamProvider *entity.UpstreamProvider, code string, nonce string, codeVerifier string) (*UpstreamIdentity, error)
+}
+
+type UpstreamProviderService struct {
+	Log *logrus.Logger
+	// Providers are the ones of config.json, providers of the database with
+	// the same name replace them.
+	Providers  []entity.UpstreamProvider
+	AppURL     string
+	Repository repository.IUpstreamProviderRepository
+}
+
+func NewUpstreamProviderService(log *logrus.Logger, providers []entity.UpstreamProvider, appURL string, repository repository.IUpstreamProviderRepository) IUpstreamProviderService {
+	return &UpstreamProviderService{
+		Log:        log,
+		Providers:  providers,
+		AppURL:     strings.TrimRight(appURL, "/"),
+		Repository: repository,
+	}
+}
+
+func UpstreamProviderServiceFactory(log *logrus.Logger) IUpstreamProviderService {
+	viper := config.NewViper()
+	upstreamProviderRepository := repository.UpstreamProviderRepositoryFactory(log)
+	return NewUpstreamProviderService(log, UpstreamProvidersFromConfig(viper), viper.GetString("app.url"), upstreamProviderRepository)
+}
+
+// UpstreamProvidersFromConfig reads the providers of upstream_providers, keyed
+// by name. The auth0, google and zitadel sections of older configurations are
+// read as providers of those names unless upstream_providers defines them.
+func UpstreamProvidersFromConfig(config *viper.Viper) []entity.UpstreamProvider {
+	providers := []entity.UpstreamProvider{}
+	defined := map[string]bool{}
+
+	for name := range config.GetStringMap("upstream_providers") {
+		key := "upstream_providers." + name + "."
+		pkce := true
+		if config.IsSet(key + "pkce") {
+			pkce = config.GetBool(key + "pkce")
+		}
+		label := config.GetString(key + "label")
+		if label == "" {
+			label = name
+		}
+		providers = append(providers, entity.UpstreamProvider{
+			Name:               name,
+			Label:              label,
+			Issuer:             config.GetString(key + "issuer"),
+			ClientID:           config.GetString(key + "client_id"),
+			ClientSecret:       config.GetString(key + "client_secret"),
+			RedirectURL:        config.GetString(key + "redirect_url"),
+			Scopes:             strings.Join(config.GetStringSlice(key+"scopes"), " "),
+			AuthURL:            config.GetString(key + "auth_url"),
+			TokenURL:           config.GetString(key + "token_url"),
+			UserInfoURL:        config.GetString(key + "userinfo_url"),
+			PKCE:               pkce,
+			TrustEmail:         config.GetBool(key + "trust_email"),
+			SubjectClaim:       config.GetString(key + "claims.subject"),
+			EmailClaim:         config.GetString(key + "claims.email"),
+			EmailVerifiedClaim: config.GetString(key + "claims.email_verified"),
+			NameClaim:          config.GetString(key + "claims.name"),
+			Enabled:            true,
+		})
+		defined[name] = true
+	}
+
+	// the sign-ins of these providers matched on email before, their email
+	// stays trusted
+	if !defined["auth0"] && config.GetString("auth0.client_id") != "" {
+		providers = append(providers, entity.UpstreamProvider{
+			Name:         "auth0",
+			Label:        "Auth0",
+			Issuer:       "https://" + config.GetString("auth0.domain") + "/",
+			ClientID:     config.GetString("auth0.client_id"),
+			ClientSecret: config.GetString("auth0.client_secret"),
+			RedirectURL:  config.GetString("auth0.redirect_url"),
+			TrustEmail:   true,
+			Enabled:      true,
+		})
+	}
+	if !defined["google"] && config.GetString("google.client_id") != "" {
+		providers = append(providers, entity.UpstreamProvider{
+			Name:         "google",
+			Label:        "Google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     config.GetString("google.client_id"),
+			ClientSecret: config.GetString("google.client_secret"),
+			RedirectURL:  config.GetString("google.redirect_url"),
+			TrustEmail:   true,
+			Enabled:      true,
+		})
+	}
+	if !defined["zitadel"] && config.GetString("zitadel.client_id") != "" {
+		providers = append(providers, entity.UpstreamProvider{
+			Name:         "zitadel",
+			Label:        "Zitadel",
+			Issuer:       config.GetString("zitadel.issuer"),
+			ClientID:     config.GetString("zitadel.client_id"),
+			ClientSecret: config.GetString("zitadel.client_secret"),
+			RedirectURL:  config.GetString("zitadel.redirect_url"),
+			AuthURL:      config.GetString("zitadel.auth_url"),
+			TokenURL:     config.GetString("zitadel.token_url"),
+			UserInfoURL:  config.GetString("zitadel.userinfo_url"),
+			PKCE:         true,
+			TrustEmail:   true,
+			Enabled:      true,
+		})
+	}
+
+	return providers
+}
+
+// FindAll returns every provider users can sign in with, ordered by label.
+func (s *UpstreamProviderService) FindAll() ([]entity.UpstreamProvider, error) {
+	byName := map[string]entity.UpstreamProvider{}
+	for _, upstreamProvider := range s.Providers {
+		byName[upstreamProvider.Name] = upstreamProvider
+	}
+	upstreamProviders, err := s.Repository.FindEnabled()
+	if err != nil {
+		return nil, err
+	}
+	for _, upstreamProvider := range upstreamProviders {
+		byName[upstreamProvider.Name] = upstreamProvider
+	}
+
+	all := make([]entity.UpstreamProvider, 0, len(byName))
+	for _, upstreamProvider := range byName {
+		all = append(all, upstreamProvider)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Label < all[j].Label
+	})
+	return all, nil
+}
+
+func (s *UpstreamProviderService) Find(name string) (*entity.UpstreamProvider, error) {
+	if name == "" {
+		return nil, ErrUnknownUpstreamProvider
+	}
+	upstreamProvider, err := s.Repository.FindEnabledByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if upstreamProvider != nil {
+		return upstreamProvider, nil
+	}
+	for i := range s.Providers {
+		if s.Providers[i].Name == name {
+			return &s.Providers[i], nil
+		}
+	}
+	return nil, ErrUnknownUpstreamProvider
+}
+
+// AuthCodeURL is where the user signs in at the provider. The nonce comes back
+// in the ID token, the code verifier is sent with the code when the provider
+// uses PKCE.
+func (s *UpstreamProviderService) AuthCodeURL(upstreamProvider *entity.UpstreamProvider, state string, nonce string, codeVerifier string) (string, error) {
+	conf, _, err := s.oauth2Config(upstreamProvider)
+	if err != nil {
+		return "", err
+	}
+
+	options := []oauth2.AuthCodeOption{oidc.Nonce(nonce)}
+	if upstreamProvider.PKCE {
+		options = append(options, oauth2.S256ChallengeOption(codeVerifier))
+	}
+	return conf.AuthCodeURL(state, options...), nil
+}
+
+// Exchange trades the code of the callback for the identity of the user. The
+// ID token, when the provider is discovered from an issuer, is verified and
+// has to carry the nonce of the login; claims missing from it are read from
+// the userinfo endpoint.
+func (s *UpstreamProviderService) Exchange(ctx context.Context, upstreamProvider *entity.UpstreamProvider, code string, nonce string, codeVerifier string) (*UpstreamIdentity, error) {
+	conf, oidcProvider, err := s.oauth2Config(upstreamProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []oauth2.AuthCodeOption{}
+	if upstreamProvider.PKCE {
+		options = append(options, oauth2.VerifierOption(codeVerifier))
+	}
+	token, err := conf.Exchange(ctx, code, options...)
+	if err != nil {
+		s.Log.Error("[UpstreamProviderService.Exchange] " + err.Error())
+		return nil, errors.New("Failed to exchange the code with the provider")
+	}
+
+	claims := map[string]interface{}{}
+	if rawIDToken, ok := token.Extra("id_token").(string); ok && oidcProvider != nil {
+		idToken, err := oidcProvider.Verifier(&oidc.Config{ClientID: upstreamProvider.ClientID}).Verify(ctx, rawIDToken)
+		if err != nil {
+			s.Log.Error("[UpstreamProviderService.Exchange] " + err.Error())
+			return nil, errors.New("The ID token of the provider is invalid")
+		}
+		if idToken.Nonce != nonce {
+			return nil, errors.New("The ID token of the provider is not for this login")
+		}
+		if err := idToken.Claims(&claims); err != nil {
+			return nil, err
+		}
+	} else if oidcProvider != nil && containsScope(upstreamProvider.ScopeList(), oidc.ScopeOpenID) {
+		return nil, errors.New("The provider did not return an ID token")
+	}
+
+	userInfoURL := upstreamProvider.UserInfoURL
+	if userInfoURL == "" && oidcProvider != nil {
+		userInfoURL = oidcProvider.UserInfoEndpoint()
+	}
+	if userInfoURL != "" {
+		userInfo, err := s.userInfo(ctx, conf, token, userInfoURL)
+		if err != nil {
+			return nil, err
+		}
+		if subject, ok := claims["sub"]; ok && userInfo["sub"] != nil && userInfo["sub"] != subject {
+			return nil, errors.New("The userinfo of the provider is for another user")
+		}
+		for claim, value := range userInfo {
+			if _, ok := claims[claim]; !ok {
+				claims[claim] = value
+			}
+		}
+	}
+
+	identity := &UpstreamIdentity{
+		Provider: upstreamProvider.Name,
+		Subject:  claimString(claims, upstreamProvider.SubjectClaim, "sub"),
+		Email:    claimString(claims, upstreamProvider.EmailClaim, "email"),
+		Name:     claimString(claims, upstreamProvider.NameClaim, "name"),
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("The provider did not return an account id")
+	}
+	emailVerifiedClaim := upstreamProvider.EmailVerifiedClaim
+	if emailVerifiedClaim == "" {
+		emailVerifiedClaim = "email_verified"
+	}
+	switch verified := claims[emailVerifiedClaim].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	default:
+		identity.EmailVerified = upstreamProvider.TrustEmail
+	}
+	identity.EmailVerified = identity.EmailVerified && identity.Email != ""
+
+	return identity, nil
+}
+
+// oauth2Config builds the client of the provider. The oidc.Provider is nil for
+// providers without an issuer.
+func (s *UpstreamProviderService) oauth2Config(upstreamProvider *entity.UpstreamProvider) (*oauth2.Config, *oidc.Provider, error) {
+	var oidcProvider *oidc.Provider
+	endpoint := oauth2.Endpoint{}
+	if upstreamProvider.Issuer != "" {
+		var err error
+		oidcProvider, err = discoverOIDCProvider(upstreamProvider.Issuer)
+		if err != nil {
+			s.Log.Error("[UpstreamProviderService.oauth2Config] " + err.Error())
+			return nil, nil, fmt.Errorf("The provider %s is not reachable", upstreamProvider.Label)
+		}
+		endpoint = oidcProvider.Endpoint()
+	}
+	if upstreamProvider.AuthURL != "" {
+		endpoint.AuthURL = upstreamProvider.AuthURL
+	}
+	if upstreamProvider.TokenURL != "" {
+		endpoint.TokenURL = upstreamProvider.TokenURL
+	}
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" {
+		return nil, nil, fmt.Errorf("The provider %s has no issuer nor endpoints", upstreamProvider.Label)
+	}
+
+	redirectURL := upstreamProvider.RedirectURL
+	if redirectURL == "" {
+		redirectURL = s.AppURL + "/oauth/" + upstreamProvider.Name + "/callback"
+	}
+
+	return &oauth2.Config{
+		ClientID:     upstreamProvider.ClientID,
+		ClientSecret: upstreamProvider.ClientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     endpoint,
+		Scopes:       upstreamProvider.ScopeList(),
+	}, oidcProvider, nil
+}
+
+func (s *UpstreamProviderService) userInfo(ctx context.Context, conf *oauth2.Config, token *oauth2.Token, userInfoURL string) (map[string]interface{}, error) {
+	resp, err := conf.Client(ctx, token).Get(userInfoURL)
+	if err != nil {
+		s.Log.Error("[UpstreamProviderService.userInfo] " + err.Error())
+		return nil, errors.New("Failed to get the user info from the provider")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.Log.Errorf("[UpstreamProviderService.userInfo] userinfo answered %d", resp.StatusCode)
+		return nil, errors.New("Failed to get the user info from the provider")
+	}
+
+	userInfo := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+		s.Log.Error("[UpstreamProviderService.userInfo] " + err.Error())
+		return nil, errors.New("Failed to decode the user info of the provider")
+	}
+	return userInfo, nil
+}
+
+// Discovered providers are kept for the life of the process, they hold the
+// signing keys of the issuer.
+var (
+	oidcProvidersMu sync.Mutex
+	oidcProviders   = map[string]*oidc.Provider{}
+)
+
+func discoverOIDCProvider(issuer string) (*oidc.Provider, error) {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	if oidcProvider, ok := oidcProviders[issuer]; ok {
+		return oidcProvider, nil
+	}
+	// the provider fetches the keys of the issuer with this context later on
+	oidcProvider, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return nil, err
+	}
+	oidcProviders[issuer] = oidcProvider
+	return oidcProvider, nil
+}
+
+// claimString reads the claim, or fallback when the provider does not map it,
+// as a string. Numeric ids are formatted without exponent.
+func claimString(claims map[string]interface{}, claim string, fallback string) string {
+	if claim == "" {
+		claim = fallback
+	}
+	switch value := claims[claim].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	case json.Number:
+		return value.String()
+	}
+	return ""
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
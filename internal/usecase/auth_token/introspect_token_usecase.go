@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IIntrospectTokenUseCaseRequest struct {
+	Token         string     `json:"token"`
+	TokenTypeHint string     `json:"token_type_hint"`
+	ApplicationID *uuid.UUID `json:"application_id"`
+}
+
+type IIntrospectTokenUseCaseResponse struct {
+	Active bool                   `json:"active"`
+	Claims map[string]interface{} `json:"claims"`
+}
+
+type IIntrospectTokenUseCase interface {
+	Execute(request *IIntrospectTokenUseCaseRequest) (*IIntrospectTokenUseCaseResponse, error)
+}
+
+type IntrospectTokenUseCase struct {
+	Log                 *logrus.Logger
+	AuthTokenRepository repository.IAuthTokenRepository
+}
+
+func NewIntrospectTokenUseCase(log *logrus.Logger, authTokenRepository repository.IAuthTokenRepository) IIntrospectTokenUseCase {
+	return &IntrospectTokenUseCase{
+		Log:                 log,
+		AuthTokenRepository: authTokenRepository,
+	}
+}
+
+// Execute reports whether the token is currently usable, following RFC 7662.
+func (uc *IntrospectTokenUseCase) Execute(request *IIntrospectTokenUseCaseRequest) (*IIntrospectTokenUseCaseResponse, error) {
+	inactive := &IIntrospectTokenUseCaseResponse{Active: false}
+
+	if request.TokenTypeHint != "access_token" {
+		authToken, err := uc.AuthTokenRepository.FindAuthTokenByToken(utils.HashToken(request.Token))
+		if err != nil {
+			return nil, err
+		}
+		if authToken != nil && authToken.FamilyID != uuid.Nil {
+			// refresh tokens are only disclosed to the client they belong to
+			if !sameApplication(authToken.ApplicationID, request.ApplicationID) ||
+				authToken.UsedAt != nil || authToken.RevokedAt != nil || authToken.ExpiredAt.Before(time.Now()) {
+				return inactive, nil
+			}
+			return &IIntrospectTokenUseCaseResponse{
+				Active: true,
+				Claims: map[string]interface{}{
+					"token_type": "refresh_token",
+					"sub":        authToken.UserID.String(),
+					"scope":      authToken.Scope,
+					"exp":        authToken.ExpiredAt.Unix(),
+				},
+			}, nil
+		}
+	}
+
+	claims, err := utils.ParseToken(request.Token)
+	if err != nil || !utils.IsAccessToken(claims) {
+		return inactive, nil
+	}
+
+	revoked, err := utils.IsTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	return &IIntrospectTokenUseCaseResponse{
+		Active: true,
+		Claims: map[string]interface{}{
+			"token_type":   "access_token",
+			"iss":          claims["iss"],
+			"sub":          claims["sub"],
+			"username":     claims["username"],
+			"email":        claims["email"],
+			"choosed_role": claims["choosed_role"],
+			"jti":          claims["jti"],
+			"iat":          claims["iat"],
+			"exp":          claims["exp"],
+		},
+	}, nil
+}
+
+func IntrospectTokenUseCaseFactory(log *logrus.Logger) IIntrospectTokenUseCase {
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	return NewIntrospectTokenUseCase(log, authTokenRepository)
+}
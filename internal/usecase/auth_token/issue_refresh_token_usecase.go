@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IIssueRefreshTokenUseCaseRequest struct {
+	UserID        uuid.UUID     `json:"user_id"`
+	RoleID        uuid.UUID     `json:"role_id"`
+	ApplicationID *uuid.UUID    `json:"application_id"`
+	Scope         string        `json:"scope"`
+	TTL           time.Duration `json:"ttl"`
+	// FamilyID names the new family after the grant it comes from, so the
+	// family can be revoked with it. A random one is used when it is nil.
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+type IIssueRefreshTokenUseCaseResponse struct {
+	RefreshToken string            `json:"refresh_token"`
+	AuthToken    *entity.AuthToken `json:"auth_token"`
+}
+
+type IIssueRefreshTokenUseCase interface {
+	Execute(request *IIssueRefreshTokenUseCaseRequest) (*IIssueRefreshTokenUseCaseResponse, error)
+}
+
+type IssueRefreshTokenUseCase struct {
+	Log                 *logrus.Logger
+	AuthTokenRepository repository.IAuthTokenRepository
+}
+
+func NewIssueRefreshTokenUseCase(log *logrus.Logger, authTokenRepository repository.IAuthTokenRepository) IIssueRefreshTokenUseCase {
+	return &IssueRefreshTokenUseCase{
+		Log:                 log,
+		AuthTokenRepository: authTokenRepository,
+	}
+}
+
+// Execute starts a new refresh token family for a fresh sign in.
+func (uc *IssueRefreshTokenUseCase) Execute(request *IIssueRefreshTokenUseCaseRequest) (*IIssueRefreshTokenUseCaseResponse, error) {
+	roleID := request.RoleID
+	refreshToken := utils.GenerateRandomStringToken(64)
+	familyID := request.FamilyID
+	if familyID == uuid.Nil {
+		familyID = uuid.New()
+	}
+
+	authToken, err := uc.AuthTokenRepository.CreateAuthToken(&entity.AuthToken{
+		UserID:        request.UserID,
+		FamilyID:      familyID,
+		ApplicationID: request.ApplicationID,
+		RoleID:        &roleID,
+		Scope:         request.Scope,
+		Token:         utils.HashToken(refreshToken),
+		ExpiredAt:     time.Now().Add(request.TTL),
+	})
+	if err != nil {
+		uc.Log.Error("[IssueRefreshTokenUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	return &IIssueRefreshTokenUseCaseResponse{
+		RefreshToken: refreshToken,
+		AuthToken:    authToken,
+	}, nil
+}
+
+func IssueRefreshTokenUseCaseFactory(log *logrus.Logger) IIssueRefreshTokenUseCase {
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	return NewIssueRefreshTokenUseCase(log, authTokenRepository)
+}
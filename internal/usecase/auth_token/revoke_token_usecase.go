@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTokenNotRevocable is returned for access tokens without a jti, which the
+// revocation list cannot name.
+var ErrTokenNotRevocable = errors.New("Token has no jti and cannot be revoked")
+
+type IRevokeTokenUseCaseRequest struct {
+	Token         string     `json:"token"`
+	TokenTypeHint string     `json:"token_type_hint"`
+	ApplicationID *uuid.UUID `json:"application_id"`
+	// ClientID is the name of the application calling /oauth2/revoke, set
+	// along with ApplicationID
+	ClientID string `json:"client_id"`
+}
+
+type IRevokeTokenUseCaseResponse struct {
+	Message string `json:"message"`
+}
+
+type IRevokeTokenUseCase interface {
+	Execute(request *IRevokeTokenUseCaseRequest) (*IRevokeTokenUseCaseResponse, error)
+}
+
+type RevokeTokenUseCase struct {
+	Log                    *logrus.Logger
+	AuthTokenRepository    repository.IAuthTokenRepository
+	RevokedTokenRepository repository.IRevokedTokenRepository
+}
+
+func NewRevokeTokenUseCase(log *logrus.Logger, authTokenRepository repository.IAuthTokenRepository, revokedTokenRepository repository.IRevokedTokenRepository) IRevokeTokenUseCase {
+	return &RevokeTokenUseCase{
+		Log:                    log,
+		AuthTokenRepository:    authTokenRepository,
+		RevokedTokenRepository: revokedTokenRepository,
+	}
+}
+
+// Execute revokes a refresh token (with its whole family) or an access token.
+// Unknown or invalid tokens are not an error, as required by RFC 7009.
+func (uc *RevokeTokenUseCase) Execute(request *IRevokeTokenUseCaseRequest) (*IRevokeTokenUseCaseResponse, error) {
+	if request.TokenTypeHint != "access_token" {
+		revoked, err := uc.revokeRefreshToken(request)
+		if err != nil || revoked {
+			return &IRevokeTokenUseCaseResponse{Message: "Token revoked"}, err
+		}
+	}
+
+	claims, err := utils.ParseToken(request.Token)
+	if err != nil {
+		return &IRevokeTokenUseCaseResponse{Message: "Token revoked"}, nil
+	}
+
+	// a client may only revoke access tokens that were issued to it, others
+	// are left alone without telling it (RFC 7009 section 2.1)
+	if request.ApplicationID != nil && claimString(claims, "client_id") != request.ClientID {
+		uc.Log.Warn("[RevokeTokenUseCase.Execute] " + request.ClientID + " tried to revoke an access token of another client")
+		return &IRevokeTokenUseCaseResponse{Message: "Token revoked"}, nil
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		uc.Log.Warn("[RevokeTokenUseCase.Execute] Access token has no jti, cannot revoke it")
+		return nil, ErrTokenNotRevocable
+	}
+
+	// client credentials tokens have no user, they are only revoked by jti
+	userID, err := uuid.Parse(claimString(claims, "id"))
+	if err != nil {
+		userID = uuid.Nil
+	}
+
+	expiredAt := time.Now().Add(utils.AccessTokenTTL())
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiredAt = exp.Time
+	}
+
+	revokedToken, err := uc.RevokedTokenRepository.CreateRevokedToken(&entity.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		RevokedAt: time.Now(),
+		ExpiredAt: expiredAt,
+	})
+	if err != nil {
+		uc.Log.Error("[RevokeTokenUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	utils.MarkTokenRevoked(revokedToken)
+
+	return &IRevokeTokenUseCaseResponse{
+		Message: "Token revoked",
+	}, nil
+}
+
+func (uc *RevokeTokenUseCase) revokeRefreshToken(request *IRevokeTokenUseCaseRequest) (bool, error) {
+	authToken, err := uc.AuthTokenRepository.FindAuthTokenByToken(utils.HashToken(request.Token))
+	if err != nil {
+		return false, err
+	}
+
+	if authToken == nil || authToken.FamilyID == uuid.Nil {
+		return false, nil
+	}
+
+	// a client may only revoke refresh tokens that were issued to it
+	if !sameApplication(authToken.ApplicationID, request.ApplicationID) {
+		return true, nil
+	}
+
+	if err := uc.AuthTokenRepository.RevokeAuthTokenFamily(authToken.FamilyID); err != nil {
+		uc.Log.Error("[RevokeTokenUseCase.Execute] " + err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+func RevokeTokenUseCaseFactory(log *logrus.Logger) IRevokeTokenUseCase {
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	revokedTokenRepository := repository.RevokedTokenRepositoryFactory(log)
+	return NewRevokeTokenUseCase(log, authTokenRepository, revokedTokenRepository)
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IRevokeUserTokensUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type IRevokeUserTokensUseCaseResponse struct {
+	Message string `json:"message"`
+}
+
+type IRevokeUserTokensUseCase interface {
+	Execute(request *IRevokeUserTokensUseCaseRequest) (*IRevokeUserTokensUseCaseResponse, error)
+}
+
+type RevokeUserTokensUseCase struct {
+	Log                    *logrus.Logger
+	AuthTokenRepository    repository.IAuthTokenRepository
+	RevokedTokenRepository repository.IRevokedTokenRepository
+}
+
+func NewRevokeUserTokensUseCase(log *logrus.Logger, authTokenRepository repository.IAuthTokenRepository, revokedTokenRepository repository.IRevokedTokenRepository) IRevokeUserTokensUseCase {
+	return &RevokeUserTokensUseCase{
+		Log:                    log,
+		AuthTokenRepository:    authTokenRepository,
+		RevokedTokenRepository: revokedTokenRepository,
+	}
+}
+
+// Execute revokes every refresh token of the user and every access token
+// issued to them so far.
+func (uc *RevokeUserTokensUseCase) Execute(request *IRevokeUserTokensUseCaseRequest) (*IRevokeUserTokensUseCaseResponse, error) {
+	if err := uc.AuthTokenRepository.RevokeAuthTokensByUserID(request.UserID); err != nil {
+		uc.Log.Error("[RevokeUserTokensUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	revokedToken, err := uc.RevokedTokenRepository.CreateRevokedToken(&entity.RevokedToken{
+		UserID:    request.UserID,
+		RevokedAt: now,
+		ExpiredAt: now.Add(utils.AccessTokenTTL()),
+	})
+	if err != nil {
+		uc.Log.Error("[RevokeUserTokensUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	utils.MarkTokenRevoked(revokedToken)
+
+	return &IRevokeUserTokensUseCaseResponse{
+		Message: "All tokens of the user have been revoked",
+	}, nil
+}
+
+func RevokeUserTokensUseCaseFactory(log *logrus.Logger) IRevokeUserTokensUseCase {
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	revokedTokenRepository := repository.RevokedTokenRepositoryFactory(log)
+	return NewRevokeUserTokensUseCase(log, authTokenRepository, revokedTokenRepository)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired, revoked or reused
+// refresh tokens so handlers can answer with a client error.
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid or has expired")
+
+type IRotateRefreshTokenUseCaseRequest struct {
+	RefreshToken  string        `json:"refresh_token"`
+	ApplicationID *uuid.UUID    `json:"application_id"`
+	TTL           time.Duration `json:"ttl"`
+}
+
+type IRotateRefreshTokenUseCaseResponse struct {
+	User         *entity.User      `json:"user"`
+	RefreshToken string            `json:"refresh_token"`
+	AuthToken    *entity.AuthToken `json:"auth_token"`
+}
+
+type IRotateRefreshTokenUseCase interface {
+	Execute(request *IRotateRefreshTokenUseCaseRequest) (*IRotateRefreshTokenUseCaseResponse, error)
+}
+
+type RotateRefreshTokenUseCase struct {
+	Log                 *logrus.Logger
+	AuthTokenRepository repository.IAuthTokenRepository
+	UserRepository      repository.IUserRepository
+}
+
+func NewRotateRefreshTokenUseCase(log *logrus.Logger, authTokenRepository repository.IAuthTokenRepository, userRepository repository.IUserRepository) IRotateRefreshTokenUseCase {
+	return &RotateRefreshTokenUseCase{
+		Log:                 log,
+		AuthTokenRepository: authTokenRepository,
+		UserRepository:      userRepository,
+	}
+}
+
+// Execute exchanges a refresh token for a new one in the same family. A token
+// that was already used (or revoked) means it leaked, so the whole family is
+// revoked and the legitimate holder has to sign in again.
+func (uc *RotateRefreshTokenUseCase) Execute(request *IRotateRefreshTokenUseCaseRequest) (*IRotateRefreshTokenUseCaseResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	authToken, err := uc.AuthTokenRepository.FindAuthTokenByToken(utils.HashToken(request.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if authToken == nil || authToken.FamilyID == uuid.Nil || authToken.RoleID == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if !sameApplication(authToken.ApplicationID, request.ApplicationID) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if authToken.UsedAt != nil || authToken.RevokedAt != nil {
+		uc.Log.Warnf("[RotateRefreshTokenUseCase.Execute] Refresh token reuse detected, revoking family %s", authToken.FamilyID)
+		if err := uc.AuthTokenRepository.RevokeAuthTokenFamily(authToken.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if authToken.ExpiredAt.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := uc.AuthTokenRepository.ConsumeAuthToken(authToken); err != nil {
+		// lost the race against a concurrent refresh with the same token
+		if err := uc.AuthTokenRepository.RevokeAuthTokenFamily(authToken.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := uc.UserRepository.FindByIdOnly(authToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	filteredRoles := []entity.Role{}
+	for _, role := range user.Roles {
+		if role.ID == *authToken.RoleID {
+			filteredRoles = append(filteredRoles, role)
+			break
+		}
+	}
+	if len(filteredRoles) == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+	user.Roles = filteredRoles
+
+	refreshToken := utils.GenerateRandomStringToken(64)
+	newAuthToken, err := uc.AuthTokenRepository.CreateAuthToken(&entity.AuthToken{
+		UserID:        authToken.UserID,
+		FamilyID:      authToken.FamilyID,
+		ApplicationID: authToken.ApplicationID,
+		RoleID:        authToken.RoleID,
+		Scope:         authToken.Scope,
+		Token:         utils.HashToken(refreshToken),
+		ExpiredAt:     time.Now().Add(request.TTL),
+	})
+	if err != nil {
+		uc.Log.Error("[RotateRefreshTokenUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	return &IRotateRefreshTokenUseCaseResponse{
+		User:         user,
+		RefreshToken: refreshToken,
+		AuthToken:    newAuthToken,
+	}, nil
+}
+
+func sameApplication(stored *uuid.UUID, requested *uuid.UUID) bool {
+	if stored == nil || requested == nil {
+		return stored == nil && requested == nil
+	}
+	return *stored == *requested
+}
+
+func RotateRefreshTokenUseCaseFactory(log *logrus.Logger) IRotateRefreshTokenUseCase {
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewRotateRefreshTokenUseCase(log, authTokenRepository, userRepository)
+}
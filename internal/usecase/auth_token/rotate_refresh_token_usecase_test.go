@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAuthTokenRepository keeps the refresh tokens in memory and hands out
+// copies, the way rows loaded from the database behave.
+type fakeAuthTokenRepository struct {
+	repository.IAuthTokenRepository
+	authTokens []*entity.AuthToken
+}
+
+func (r *fakeAuthTokenRepository) FindAuthTokenByToken(token string) (*entity.AuthToken, error) {
+	for _, authToken := range r.authTokens {
+		if authToken.Token == token {
+			found := *authToken
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthTokenRepository) ConsumeAuthToken(authToken *entity.AuthToken) error {
+	for _, stored := range r.authTokens {
+		if stored.ID == authToken.ID && stored.UsedAt == nil && stored.RevokedAt == nil {
+			now := time.Now()
+			stored.UsedAt = &now
+			authToken.UsedAt = &now
+			return nil
+		}
+	}
+	return errors.New("[fakeAuthTokenRepository.ConsumeAuthToken] auth token already used")
+}
+
+func (r *fakeAuthTokenRepository) RevokeAuthTokenFamily(familyID uuid.UUID) error {
+	now := time.Now()
+	for _, stored := range r.authTokens {
+		if stored.FamilyID == familyID && stored.RevokedAt == nil {
+			stored.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeAuthTokenRepository) CreateAuthToken(authToken *entity.AuthToken) (*entity.AuthToken, error) {
+	authToken.ID = uuid.New()
+	stored := *authToken
+	r.authTokens = append(r.authTokens, &stored)
+	return authToken, nil
+}
+
+type fakeUserRepository struct {
+	repository.IUserRepository
+	users map[uuid.UUID]*entity.User
+}
+
+func (r *fakeUserRepository) FindByIdOnly(id uuid.UUID) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *user
+	return &found, nil
+}
+
+type refreshTokenFixture struct {
+	authTokens    *fakeAuthTokenRepository
+	useCase       IRotateRefreshTokenUseCase
+	user          *entity.User
+	applicationID uuid.UUID
+	familyID      uuid.UUID
+}
+
+func newRefreshTokenFixture(t *testing.T, refreshToken string, expiredAt time.Time) *refreshTokenFixture {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	roleID := uuid.New()
+	user := &entity.User{
+		ID:    uuid.New(),
+		Roles: []entity.Role{{ID: uuid.New()}, {ID: roleID}},
+	}
+	applicationID := uuid.New()
+	familyID := uuid.New()
+
+	authTokens := &fakeAuthTokenRepository{authTokens: []*entity.AuthToken{{
+		ID:            uuid.New(),
+		UserID:        user.ID,
+		FamilyID:      familyID,
+		ApplicationID: &applicationID,
+		RoleID:        &roleID,
+		Scope:         "openid offline_access",
+		Token:         utils.HashToken(refreshToken),
+		ExpiredAt:     expiredAt,
+	}}}
+	users := &fakeUserRepository{users: map[uuid.UUID]*entity.User{user.ID: user}}
+
+	return &refreshTokenFixture{
+		authTokens:    authTokens,
+		useCase:       NewRotateRefreshTokenUseCase(log, authTokens, users),
+		user:          user,
+		applicationID: applicationID,
+		familyID:      familyID,
+	}
+}
+
+func (f *refreshTokenFixture) familyRevoked() bool {
+	for _, authToken := range f.authTokens.authTokens {
+		if authToken.FamilyID == f.familyID && authToken.RevokedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	f := newRefreshTokenFixture(t, "first", time.Now().Add(time.Hour))
+
+	resp, err := f.useCase.Execute(&IRotateRefreshTokenUseCaseRequest{
+		RefreshToken:  "first",
+		ApplicationID: &f.applicationID,
+		TTL:           time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if resp.RefreshToken == "" || resp.RefreshToken == "first" {
+		t.Errorf("RefreshToken = %q, want a new token", resp.RefreshToken)
+	}
+	if resp.AuthToken.FamilyID != f.familyID || resp.AuthToken.Token != utils.HashToken(resp.RefreshToken) {
+		t.Errorf("AuthToken = %+v, want the hash of the new token in family %s", resp.AuthToken, f.familyID)
+	}
+	if resp.AuthToken.Scope != "openid offline_access" || *resp.AuthToken.ApplicationID != f.applicationID {
+		t.Errorf("AuthToken = %+v, want the scope and application of the rotated token", resp.AuthToken)
+	}
+	if len(resp.User.Roles) != 1 || resp.User.Roles[0].ID != *resp.AuthToken.RoleID {
+		t.Errorf("User.Roles = %+v, want only the role of the refresh token", resp.User.Roles)
+	}
+	if f.authTokens.authTokens[0].UsedAt == nil {
+		t.Errorf("rotated token was not marked as used")
+	}
+
+	// the new token rotates in turn
+	if _, err := f.useCase.Execute(&IRotateRefreshTokenUseCaseRequest{
+		RefreshToken:  resp.RefreshToken,
+		ApplicationID: &f.applicationID,
+		TTL:           time.Hour,
+	}); err != nil {
+		t.Errorf("Execute with the rotated token: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	f := newRefreshTokenFixture(t, "first", time.Now().Add(time.Hour))
+	request := &IRotateRefreshTokenUseCaseRequest{
+		RefreshToken:  "first",
+		ApplicationID: &f.applicationID,
+		TTL:           time.Hour,
+	}
+
+	resp, err := f.useCase.Execute(request)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if _, err := f.useCase.Execute(request); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Execute with a used token = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if !f.familyRevoked() {
+		t.Errorf("family %s was not revoked after reuse", f.familyID)
+	}
+
+	// the token handed out before the reuse went down with its family
+	if _, err := f.useCase.Execute(&IRotateRefreshTokenUseCaseRequest{
+		RefreshToken:  resp.RefreshToken,
+		ApplicationID: &f.applicationID,
+		TTL:           time.Hour,
+	}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Execute with a token of a revoked family = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRotateRefreshTokenRefusesInvalidTokens(t *testing.T) {
+	otherApplicationID := uuid.New()
+
+	tests := []struct {
+		name          string
+		refreshToken  string
+		expiredAt     time.Time
+		applicationID func(f *refreshTokenFixture) *uuid.UUID
+	}{
+		{"empty token", "", time.Now().Add(time.Hour), func(f *refreshTokenFixture) *uuid.UUID { return &f.applicationID }},
+		{"unknown token", "unknown", time.Now().Add(time.Hour), func(f *refreshTokenFixture) *uuid.UUID { return &f.applicationID }},
+		{"expired token", "first", time.Now().Add(-time.Minute), func(f *refreshTokenFixture) *uuid.UUID { return &f.applicationID }},
+		{"other application", "first", time.Now().Add(time.Hour), func(f *refreshTokenFixture) *uuid.UUID { return &otherApplicationID }},
+		{"no application", "first", time.Now().Add(time.Hour), func(f *refreshTokenFixture) *uuid.UUID { return nil }},
+	}
+
+	for _, test := range tests {
+		f := newRefreshTokenFixture(t, "first", test.expiredAt)
+		_, err := f.useCase.Execute(&IRotateRefreshTokenUseCaseRequest{
+			RefreshToken:  test.refreshToken,
+			ApplicationID: test.applicationID(f),
+			TTL:           time.Hour,
+		})
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%s: Execute = %v, want %v", test.name, err, ErrInvalidRefreshToken)
+		}
+		if f.authTokens.authTokens[0].UsedAt != nil || len(f.authTokens.authTokens) != 1 {
+			t.Errorf("%s: refused token was rotated", test.name)
+		}
+	}
+}
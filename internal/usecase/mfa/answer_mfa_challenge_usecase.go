@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxMFAAttempts is how many wrong codes a login may send before it has to
+// start over with the password.
+const MaxMFAAttempts = 5
+
+type IAnswerMFAChallengeUseCaseRequest struct {
+	Token string `json:"token"`
+	Code  string `json:"code"`
+}
+
+type IAnswerMFAChallengeUseCaseResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	Method MFAMethod `json:"method"`
+}
+
+type IAnswerMFAChallengeUseCase interface {
+	Execute(request *IAnswerMFAChallengeUseCaseRequest) (*IAnswerMFAChallengeUseCaseResponse, error)
+}
+
+type AnswerMFAChallengeUseCase struct {
+	Log                        *logrus.Logger
+	MFAChallengeRepository     repository.IMFAChallengeRepository
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewAnswerMFAChallengeUseCase(log *logrus.Logger, mfaChallengeRepository repository.IMFAChallengeRepository, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IAnswerMFAChallengeUseCase {
+	return &AnswerMFAChallengeUseCase{
+		Log:                        log,
+		MFAChallengeRepository:     mfaChallengeRepository,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute completes a login started by /api/login. The challenge can be
+// answered once, before it expires and within MaxMFAAttempts tries.
+func (uc *AnswerMFAChallengeUseCase) Execute(request *IAnswerMFAChallengeUseCaseRequest) (*IAnswerMFAChallengeUseCaseResponse, error) {
+	mfaChallenge, err := uc.MFAChallengeRepository.FindByTokenHash(utils.HashToken(request.Token))
+	if err != nil {
+		return nil, err
+	}
+	if mfaChallenge == nil || mfaChallenge.UsedAt != nil || time.Now().After(mfaChallenge.ExpiredAt) || mfaChallenge.Attempts >= MaxMFAAttempts {
+		return nil, errors.New("The login has expired, please sign in again")
+	}
+
+	method, err := verifySecondFactor(uc.UserTOTPRepository, uc.UserRecoveryCodeRepository, mfaChallenge.UserID, request.Code)
+	if err != nil {
+		if incErr := uc.MFAChallengeRepository.IncrementAttempts(mfaChallenge); incErr != nil {
+			return nil, incErr
+		}
+		return nil, err
+	}
+
+	used, err := uc.MFAChallengeRepository.UseMFAChallenge(mfaChallenge)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("The login has expired, please sign in again")
+	}
+
+	return &IAnswerMFAChallengeUseCaseResponse{
+		UserID: mfaChallenge.UserID,
+		Method: method,
+	}, nil
+}
+
+func AnswerMFAChallengeUseCaseFactory(log *logrus.Logger) IAnswerMFAChallengeUseCase {
+	mfaChallengeRepository := repository.MFAChallengeRepositoryFactory(log)
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewAnswerMFAChallengeUseCase(log, mfaChallengeRepository, userTOTPRepository, userRecoveryCodeRepository)
+}
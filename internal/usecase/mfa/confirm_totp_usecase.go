@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IConfirmTOTPUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code"`
+}
+
+type IConfirmTOTPUseCaseResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type IConfirmTOTPUseCase interface {
+	Execute(request *IConfirmTOTPUseCaseRequest) (*IConfirmTOTPUseCaseResponse, error)
+}
+
+type ConfirmTOTPUseCase struct {
+	Log                        *logrus.Logger
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewConfirmTOTPUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IConfirmTOTPUseCase {
+	return &ConfirmTOTPUseCase{
+		Log:                        log,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute enables two-factor authentication once the user proves the
+// authenticator app was set up, and hands out the first recovery codes.
+func (uc *ConfirmTOTPUseCase) Execute(request *IConfirmTOTPUseCaseRequest) (*IConfirmTOTPUseCaseResponse, error) {
+	userTOTP, err := uc.UserTOTPRepository.FindByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP == nil {
+		return nil, errors.New("Two-factor authentication has not been set up")
+	}
+	if userTOTP.ConfirmedAt != nil {
+		return nil, errors.New("Two-factor authentication is already enabled")
+	}
+
+	step, ok := utils.ValidateTOTP(userTOTP.Secret, request.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	if err := uc.UserTOTPRepository.ConfirmUserTOTP(userTOTP, step); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := replaceRecoveryCodes(uc.UserRecoveryCodeRepository, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IConfirmTOTPUseCaseResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func ConfirmTOTPUseCaseFactory(log *logrus.Logger) IConfirmTOTPUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewConfirmTOTPUseCase(log, userTOTPRepository, userRecoveryCodeRepository)
+}
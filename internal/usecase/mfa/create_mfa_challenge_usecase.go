@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ICreateMFAChallengeUseCaseRequest struct {
+	UserID uuid.UUID     `json:"user_id"`
+	TTL    time.Duration `json:"ttl"`
+}
+
+type ICreateMFAChallengeUseCaseResponse struct {
+	Token     string    `json:"token"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type ICreateMFAChallengeUseCase interface {
+	Execute(request *ICreateMFAChallengeUseCaseRequest) (*ICreateMFAChallengeUseCaseResponse, error)
+}
+
+type CreateMFAChallengeUseCase struct {
+	Log                    *logrus.Logger
+	MFAChallengeRepository repository.IMFAChallengeRepository
+}
+
+func NewCreateMFAChallengeUseCase(log *logrus.Logger, mfaChallengeRepository repository.IMFAChallengeRepository) ICreateMFAChallengeUseCase {
+	return &CreateMFAChallengeUseCase{
+		Log:                    log,
+		MFAChallengeRepository: mfaChallengeRepository,
+	}
+}
+
+func (uc *CreateMFAChallengeUseCase) Execute(request *ICreateMFAChallengeUseCaseRequest) (*ICreateMFAChallengeUseCaseResponse, error) {
+	token := utils.GenerateRandomStringToken(64)
+	mfaChallenge, err := uc.MFAChallengeRepository.CreateMFAChallenge(&entity.MFAChallenge{
+		TokenHash: utils.HashToken(token),
+		UserID:    request.UserID,
+		ExpiredAt: time.Now().Add(request.TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ICreateMFAChallengeUseCaseResponse{
+		Token:     token,
+		ExpiredAt: mfaChallenge.ExpiredAt,
+	}, nil
+}
+
+func CreateMFAChallengeUseCaseFactory(log *logrus.Logger) ICreateMFAChallengeUseCase {
+	mfaChallengeRepository := repository.MFAChallengeRepositoryFactory(log)
+	return NewCreateMFAChallengeUseCase(log, mfaChallengeRepository)
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IDisableTOTPUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code"`
+}
+
+type IDisableTOTPUseCase interface {
+	Execute(request *IDisableTOTPUseCaseRequest) error
+}
+
+type DisableTOTPUseCase struct {
+	Log                        *logrus.Logger
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewDisableTOTPUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IDisableTOTPUseCase {
+	return &DisableTOTPUseCase{
+		Log:                        log,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute turns two-factor authentication off. A current code, or a recovery
+// code, is required so a hijacked session cannot remove the second factor.
+func (uc *DisableTOTPUseCase) Execute(request *IDisableTOTPUseCaseRequest) error {
+	if _, err := verifySecondFactor(uc.UserTOTPRepository, uc.UserRecoveryCodeRepository, request.UserID, request.Code); err != nil {
+		return err
+	}
+
+	if err := uc.UserTOTPRepository.DeleteByUserID(request.UserID); err != nil {
+		return err
+	}
+	return uc.UserRecoveryCodeRepository.DeleteByUserID(request.UserID)
+}
+
+func DisableTOTPUseCaseFactory(log *logrus.Logger) IDisableTOTPUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewDisableTOTPUseCase(log, userTOTPRepository, userRecoveryCodeRepository)
+}
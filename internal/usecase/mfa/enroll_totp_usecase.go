@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IEnrollTOTPUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type IEnrollTOTPUseCaseResponse struct {
+	UserTOTP *entity.UserTOTP `json:"user_totp"`
+}
+
+type IEnrollTOTPUseCase interface {
+	Execute(request *IEnrollTOTPUseCaseRequest) (*IEnrollTOTPUseCaseResponse, error)
+}
+
+type EnrollTOTPUseCase struct {
+	Log                *logrus.Logger
+	UserTOTPRepository repository.IUserTOTPRepository
+}
+
+func NewEnrollTOTPUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository) IEnrollTOTPUseCase {
+	return &EnrollTOTPUseCase{
+		Log:                log,
+		UserTOTPRepository: userTOTPRepository,
+	}
+}
+
+// Execute starts an enrolment with a new secret. It replaces an enrolment that
+// was never confirmed, but not an enabled one, which has to be disabled first.
+func (uc *EnrollTOTPUseCase) Execute(request *IEnrollTOTPUseCaseRequest) (*IEnrollTOTPUseCaseResponse, error) {
+	userTOTP, err := uc.UserTOTPRepository.FindByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP != nil && userTOTP.ConfirmedAt != nil {
+		return nil, errors.New("Two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		uc.Log.Error("[EnrollTOTPUseCase.Execute] " + err.Error())
+		return nil, errors.New("[EnrollTOTPUseCase.Execute] " + err.Error())
+	}
+
+	userTOTP, err = uc.UserTOTPRepository.SaveUserTOTP(&entity.UserTOTP{
+		UserID: request.UserID,
+		Secret: secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IEnrollTOTPUseCaseResponse{
+		UserTOTP: userTOTP,
+	}, nil
+}
+
+func EnrollTOTPUseCaseFactory(log *logrus.Logger) IEnrollTOTPUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	return NewEnrollTOTPUseCase(log, userTOTPRepository)
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IFindMFAStatusUseCaseRequest struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Issuer      string    `json:"issuer"`
+	AccountName string    `json:"account_name"`
+}
+
+type IFindMFAStatusUseCaseResponse struct {
+	Enabled                bool   `json:"enabled"`
+	Pending                bool   `json:"pending"`
+	Secret                 string `json:"secret,omitempty"`
+	ProvisioningURI        string `json:"provisioning_uri,omitempty"`
+	RemainingRecoveryCodes int64  `json:"remaining_recovery_codes"`
+}
+
+type IFindMFAStatusUseCase interface {
+	Execute(request *IFindMFAStatusUseCaseRequest) (*IFindMFAStatusUseCaseResponse, error)
+}
+
+type FindMFAStatusUseCase struct {
+	Log                        *logrus.Logger
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewFindMFAStatusUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IFindMFAStatusUseCase {
+	return &FindMFAStatusUseCase{
+		Log:                        log,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute tells whether the user has two-factor authentication enabled. While
+// an enrolment is waiting for its first code, the secret and provisioning URI
+// are returned so the user can still scan it.
+func (uc *FindMFAStatusUseCase) Execute(request *IFindMFAStatusUseCaseRequest) (*IFindMFAStatusUseCaseResponse, error) {
+	userTOTP, err := uc.UserTOTPRepository.FindByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &IFindMFAStatusUseCaseResponse{}
+	if userTOTP == nil {
+		return response, nil
+	}
+
+	if userTOTP.ConfirmedAt == nil {
+		response.Pending = true
+		response.Secret = userTOTP.Secret
+		response.ProvisioningURI = utils.TOTPProvisioningURI(request.Issuer, request.AccountName, userTOTP.Secret)
+		return response, nil
+	}
+
+	remaining, err := uc.UserRecoveryCodeRepository.CountUnused(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	response.Enabled = true
+	response.RemainingRecoveryCodes = remaining
+
+	return response, nil
+}
+
+func FindMFAStatusUseCaseFactory(log *logrus.Logger) IFindMFAStatusUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewFindMFAStatusUseCase(log, userTOTPRepository, userRecoveryCodeRepository)
+}
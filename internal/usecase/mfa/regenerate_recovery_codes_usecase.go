@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IRegenerateRecoveryCodesUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code"`
+}
+
+type IRegenerateRecoveryCodesUseCaseResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type IRegenerateRecoveryCodesUseCase interface {
+	Execute(request *IRegenerateRecoveryCodesUseCaseRequest) (*IRegenerateRecoveryCodesUseCaseResponse, error)
+}
+
+type RegenerateRecoveryCodesUseCase struct {
+	Log                        *logrus.Logger
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewRegenerateRecoveryCodesUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IRegenerateRecoveryCodesUseCase {
+	return &RegenerateRecoveryCodesUseCase{
+		Log:                        log,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute replaces the recovery codes of the user; the old ones stop working.
+func (uc *RegenerateRecoveryCodesUseCase) Execute(request *IRegenerateRecoveryCodesUseCaseRequest) (*IRegenerateRecoveryCodesUseCaseResponse, error) {
+	if _, err := verifySecondFactor(uc.UserTOTPRepository, uc.UserRecoveryCodeRepository, request.UserID, request.Code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := replaceRecoveryCodes(uc.UserRecoveryCodeRepository, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IRegenerateRecoveryCodesUseCaseResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func RegenerateRecoveryCodesUseCaseFactory(log *logrus.Logger) IRegenerateRecoveryCodesUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewRegenerateRecoveryCodesUseCase(log, userTOTPRepository, userRecoveryCodeRepository)
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type MFAMethod string
+
+const (
+	MFA_METHOD_TOTP          MFAMethod = "totp"
+	MFA_METHOD_RECOVERY_CODE MFAMethod = "recovery_code"
+)
+
+var ErrInvalidMFACode = errors.New("The code is invalid")
+
+type IVerifyMFAUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code"`
+}
+
+type IVerifyMFAUseCaseResponse struct {
+	Method MFAMethod `json:"method"`
+}
+
+type IVerifyMFAUseCase interface {
+	Execute(request *IVerifyMFAUseCaseRequest) (*IVerifyMFAUseCaseResponse, error)
+}
+
+type VerifyMFAUseCase struct {
+	Log                        *logrus.Logger
+	UserTOTPRepository         repository.IUserTOTPRepository
+	UserRecoveryCodeRepository repository.IUserRecoveryCodeRepository
+}
+
+func NewVerifyMFAUseCase(log *logrus.Logger, userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository) IVerifyMFAUseCase {
+	return &VerifyMFAUseCase{
+		Log:                        log,
+		UserTOTPRepository:         userTOTPRepository,
+		UserRecoveryCodeRepository: userRecoveryCodeRepository,
+	}
+}
+
+// Execute checks the second factor of a user, either a code from the
+// authenticator app or one of the recovery codes. Both can only be used once.
+func (uc *VerifyMFAUseCase) Execute(request *IVerifyMFAUseCaseRequest) (*IVerifyMFAUseCaseResponse, error) {
+	method, err := verifySecondFactor(uc.UserTOTPRepository, uc.UserRecoveryCodeRepository, request.UserID, request.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IVerifyMFAUseCaseResponse{
+		Method: method,
+	}, nil
+}
+
+func VerifyMFAUseCaseFactory(log *logrus.Logger) IVerifyMFAUseCase {
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	userRecoveryCodeRepository := repository.UserRecoveryCodeRepositoryFactory(log)
+	return NewVerifyMFAUseCase(log, userTOTPRepository, userRecoveryCodeRepository)
+}
+
+func verifySecondFactor(userTOTPRepository repository.IUserTOTPRepository, userRecoveryCodeRepository repository.IUserRecoveryCodeRepository, userID uuid.UUID, code string) (MFAMethod, error) {
+	userTOTP, err := userTOTPRepository.FindByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+	if userTOTP == nil || userTOTP.ConfirmedAt == nil {
+		return "", errors.New("Two-factor authentication is not enabled")
+	}
+
+	if step, ok := utils.ValidateTOTP(userTOTP.Secret, code, time.Now()); ok {
+		used, err := userTOTPRepository.UseStep(userTOTP, step)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return "", ErrInvalidMFACode
+		}
+		return MFA_METHOD_TOTP, nil
+	}
+
+	used, err := userRecoveryCodeRepository.UseRecoveryCode(userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrInvalidMFACode
+	}
+	return MFA_METHOD_RECOVERY_CODE, nil
+}
+
+// recoveryCodeCount is how many recovery codes a user gets at a time.
+const recoveryCodeCount = 10
+
+// replaceRecoveryCodes gives the user a new set of recovery codes and returns
+// them in clear text, the only time they are available.
+func replaceRecoveryCodes(userRecoveryCodeRepository repository.IUserRecoveryCodeRepository, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code := utils.GenerateRecoveryCode()
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, utils.HashToken(code))
+	}
+
+	if err := userRecoveryCodeRepository.ReplaceRecoveryCodes(userID, codeHashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IAuthenticateClientUseCaseRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// AllowPublic lets public applications identify with client_id alone,
+	// for grants that prove the rest themselves
+	AllowPublic bool `json:"allow_public"`
+}
+
+type IAuthenticateClientUseCaseResponse struct {
+	Application *entity.Application `json:"application"`
+}
+
+type IAuthenticateClientUseCase interface {
+	Execute(request *IAuthenticateClientUseCaseRequest) (*IAuthenticateClientUseCaseResponse, error)
+}
+
+type AuthenticateClientUseCase struct {
+	Log                   *logrus.Logger
+	ApplicationRepository repository.IApplicationRepository
+}
+
+func NewAuthenticateClientUseCase(log *logrus.Logger, applicationRepository repository.IApplicationRepository) IAuthenticateClientUseCase {
+	return &AuthenticateClientUseCase{
+		Log:                   log,
+		ApplicationRepository: applicationRepository,
+	}
+}
+
+func (uc *AuthenticateClientUseCase) Execute(request *IAuthenticateClientUseCaseRequest) (*IAuthenticateClientUseCaseResponse, error) {
+	authenticate := authenticateClient
+	if request.AllowPublic {
+		authenticate = identifyClient
+	}
+	application, err := authenticate(uc.ApplicationRepository, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IAuthenticateClientUseCaseResponse{
+		Application: application,
+	}, nil
+}
+
+func AuthenticateClientUseCaseFactory(log *logrus.Logger) IAuthenticateClientUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	return NewAuthenticateClientUseCase(log, applicationRepository)
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ICreateAuthorizationCodeUseCaseRequest struct {
+	ApplicationID       uuid.UUID     `json:"application_id"`
+	UserID              uuid.UUID     `json:"user_id"`
+	RoleID              uuid.UUID     `json:"role_id"`
+	RedirectURI         string        `json:"redirect_uri"`
+	RedirectURIExplicit bool          `json:"redirect_uri_explicit"`
+	Scope               string        `json:"scope"`
+	Nonce               string        `json:"nonce"`
+	CodeChallenge       string        `json:"code_challenge"`
+	CodeChallengeMethod string        `json:"code_challenge_method"`
+	AuthTime            time.Time     `json:"auth_time"`
+	SessionID           string        `json:"session_id"`
+	TTL                 time.Duration `json:"ttl"`
+}
+
+type ICreateAuthorizationCodeUseCaseResponse struct {
+	Code string `json:"code"`
+}
+
+type ICreateAuthorizationCodeUseCase interface {
+	Execute(request *ICreateAuthorizationCodeUseCaseRequest) (*ICreateAuthorizationCodeUseCaseResponse, error)
+}
+
+type CreateAuthorizationCodeUseCase struct {
+	Log                         *logrus.Logger
+	AuthorizationCodeRepository repository.IAuthorizationCodeRepository
+}
+
+func NewCreateAuthorizationCodeUseCase(log *logrus.Logger, authorizationCodeRepository repository.IAuthorizationCodeRepository) ICreateAuthorizationCodeUseCase {
+	return &CreateAuthorizationCodeUseCase{
+		Log:                         log,
+		AuthorizationCodeRepository: authorizationCodeRepository,
+	}
+}
+
+func (uc *CreateAuthorizationCodeUseCase) Execute(request *ICreateAuthorizationCodeUseCaseRequest) (*ICreateAuthorizationCodeUseCaseResponse, error) {
+	code := utils.GenerateRandomStringToken(48)
+
+	// only the hash is persisted, the plain code lives in the redirect
+	_, err := uc.AuthorizationCodeRepository.CreateAuthorizationCode(&entity.AuthorizationCode{
+		Code:                utils.HashToken(code),
+		ApplicationID:       request.ApplicationID,
+		UserID:              request.UserID,
+		RoleID:              request.RoleID,
+		RedirectURI:         request.RedirectURI,
+		RedirectURIExplicit: request.RedirectURIExplicit,
+		Scope:               request.Scope,
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		AuthTime:            request.AuthTime,
+		SessionID:           request.SessionID,
+		ExpiredAt:           time.Now().Add(request.TTL),
+	})
+	if err != nil {
+		uc.Log.Error("[CreateAuthorizationCodeUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	return &ICreateAuthorizationCodeUseCaseResponse{
+		Code: code,
+	}, nil
+}
+
+func CreateAuthorizationCodeUseCaseFactory(log *logrus.Logger) ICreateAuthorizationCodeUseCase {
+	authorizationCodeRepository := repository.AuthorizationCodeRepositoryFactory(log)
+	return NewCreateAuthorizationCodeUseCase(log, authorizationCodeRepository)
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// userCodeCharset leaves out vowels and look-alike characters so user codes
+// are easy to type and never spell words (RFC 8628 section 6.1).
+const userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+type ICreateDeviceAuthorizationUseCaseRequest struct {
+	ClientID     string        `json:"client_id"`
+	ClientSecret string        `json:"client_secret"`
+	Scope        string        `json:"scope"`
+	TTL          time.Duration `json:"ttl"`
+	Interval     time.Duration `json:"interval"`
+}
+
+type ICreateDeviceAuthorizationUseCaseResponse struct {
+	DeviceCode string `json:"device_code"`
+	UserCode   string `json:"user_code"`
+	ExpiresIn  int64  `json:"expires_in"`
+	Interval   int64  `json:"interval"`
+}
+
+type ICreateDeviceAuthorizationUseCase interface {
+	Execute(request *ICreateDeviceAuthorizationUseCaseRequest) (*ICreateDeviceAuthorizationUseCaseResponse, error)
+}
+
+type CreateDeviceAuthorizationUseCase struct {
+	Log                           *logrus.Logger
+	ApplicationRepository         repository.IApplicationRepository
+	DeviceAuthorizationRepository repository.IDeviceAuthorizationRepository
+}
+
+func NewCreateDeviceAuthorizationUseCase(
+	log *logrus.Logger,
+	applicationRepository repository.IApplicationRepository,
+	deviceAuthorizationRepository repository.IDeviceAuthorizationRepository,
+) ICreateDeviceAuthorizationUseCase {
+	return &CreateDeviceAuthorizationUseCase{
+		Log:                           log,
+		ApplicationRepository:         applicationRepository,
+		DeviceAuthorizationRepository: deviceAuthorizationRepository,
+	}
+}
+
+func (uc *CreateDeviceAuthorizationUseCase) Execute(request *ICreateDeviceAuthorizationUseCaseRequest) (*ICreateDeviceAuthorizationUseCaseResponse, error) {
+	application, err := authenticateClient(uc.ApplicationRepository, request.ClientID, request.ClientSecret)
+	if err != nil {
+		uc.Log.Warn("[CreateDeviceAuthorizationUseCase.Execute] Client authentication failed for " + request.ClientID)
+		return nil, err
+	}
+
+	scope := request.Scope
+	if scope == "" {
+		scope = "openid profile email"
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		uc.Log.Error("[CreateDeviceAuthorizationUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	deviceCode := utils.GenerateRandomStringToken(48)
+
+	// only the hash of the device code is persisted, like authorization codes
+	_, err = uc.DeviceAuthorizationRepository.CreateDeviceAuthorization(&entity.DeviceAuthorization{
+		DeviceCode:    utils.HashToken(deviceCode),
+		UserCode:      userCode,
+		ApplicationID: application.ID,
+		Scope:         scope,
+		Interval:      int(request.Interval.Seconds()),
+		ExpiredAt:     time.Now().Add(request.TTL),
+	})
+	if err != nil {
+		uc.Log.Error("[CreateDeviceAuthorizationUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	return &ICreateDeviceAuthorizationUseCaseResponse{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ExpiresIn:  int64(request.TTL.Seconds()),
+		Interval:   int64(request.Interval.Seconds()),
+	}, nil
+}
+
+func CreateDeviceAuthorizationUseCaseFactory(log *logrus.Logger) ICreateDeviceAuthorizationUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	deviceAuthorizationRepository := repository.DeviceAuthorizationRepositoryFactory(log)
+	return NewCreateDeviceAuthorizationUseCase(log, applicationRepository, deviceAuthorizationRepository)
+}
+
+// generateUserCode returns a code formatted as XXXX-XXXX.
+func generateUserCode() (string, error) {
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeCharset))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeCharset[n.Int64()]
+	}
+	return string(code[:4]) + "-" + string(code[4:]), nil
+}
+
+// NormalizeUserCode accepts a user code typed in any case, with or without
+// the dash and surrounding spaces.
+func NormalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+	if len(userCode) != 8 {
+		return userCode
+	}
+	return userCode[:4] + "-" + userCode[4:]
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IDecideDeviceAuthorizationUseCaseRequest struct {
+	UserCode string    `json:"user_code"`
+	UserID   uuid.UUID `json:"user_id"`
+	RoleID   uuid.UUID `json:"role_id"`
+	AuthTime time.Time `json:"auth_time"`
+	Approve  bool      `json:"approve"`
+}
+
+type IDecideDeviceAuthorizationUseCaseResponse struct {
+	DeviceAuthorization *entity.DeviceAuthorization `json:"device_authorization"`
+}
+
+type IDecideDeviceAuthorizationUseCase interface {
+	Execute(request *IDecideDeviceAuthorizationUseCaseRequest) (*IDecideDeviceAuthorizationUseCaseResponse, error)
+}
+
+type DecideDeviceAuthorizationUseCase struct {
+	Log                           *logrus.Logger
+	DeviceAuthorizationRepository repository.IDeviceAuthorizationRepository
+}
+
+func NewDecideDeviceAuthorizationUseCase(log *logrus.Logger, deviceAuthorizationRepository repository.IDeviceAuthorizationRepository) IDecideDeviceAuthorizationUseCase {
+	return &DecideDeviceAuthorizationUseCase{
+		Log:                           log,
+		DeviceAuthorizationRepository: deviceAuthorizationRepository,
+	}
+}
+
+func (uc *DecideDeviceAuthorizationUseCase) Execute(request *IDecideDeviceAuthorizationUseCaseRequest) (*IDecideDeviceAuthorizationUseCaseResponse, error) {
+	findUseCase := NewFindDeviceAuthorizationUseCase(uc.Log, uc.DeviceAuthorizationRepository)
+	found, err := findUseCase.Execute(&IFindDeviceAuthorizationUseCaseRequest{
+		UserCode: request.UserCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := entity.DEVICE_AUTHORIZATION_DENIED
+	if request.Approve {
+		status = entity.DEVICE_AUTHORIZATION_APPROVED
+	}
+
+	if err := uc.DeviceAuthorizationRepository.DecideDeviceAuthorization(found.DeviceAuthorization, status, request.UserID, request.RoleID, request.AuthTime); err != nil {
+		return nil, errors.New("The code has already been used")
+	}
+
+	return &IDecideDeviceAuthorizationUseCaseResponse{
+		DeviceAuthorization: found.DeviceAuthorization,
+	}, nil
+}
+
+func DecideDeviceAuthorizationUseCaseFactory(log *logrus.Logger) IDecideDeviceAuthorizationUseCase {
+	deviceAuthorizationRepository := repository.DeviceAuthorizationRepositoryFactory(log)
+	return NewDecideDeviceAuthorizationUseCase(log, deviceAuthorizationRepository)
+}
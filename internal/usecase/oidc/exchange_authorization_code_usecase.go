@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IExchangeAuthorizationCodeUseCaseRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Code         string `json:"code"`
+	RedirectURI  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+type IExchangeAuthorizationCodeUseCaseResponse struct {
+	User        *entity.User        `json:"user"`
+	Application *entity.Application `json:"application"`
+	Scope       string              `json:"scope"`
+	Nonce       string              `json:"nonce"`
+	AuthTime    time.Time           `json:"auth_time"`
+	SessionID   string              `json:"session_id"`
+	// GrantID names the refresh family and the access token issued for the
+	// code, a replay of the code revokes them
+	GrantID uuid.UUID `json:"grant_id"`
+}
+
+type IExchangeAuthorizationCodeUseCase interface {
+	Execute(request *IExchangeAuthorizationCodeUseCaseRequest) (*IExchangeAuthorizationCodeUseCaseResponse, error)
+}
+
+type ExchangeAuthorizationCodeUseCase struct {
+	Log                          *logrus.Logger
+	ApplicationRepository        repository.IApplicationRepository
+	AuthorizationCodeRepository  repository.IAuthorizationCodeRepository
+	UserRepository               repository.IUserRepository
+	ApplicationSessionRepository repository.IApplicationSessionRepository
+	AuthTokenRepository          repository.IAuthTokenRepository
+	RevokedTokenRepository       repository.IRevokedTokenRepository
+}
+
+func NewExchangeAuthorizationCodeUseCase(
+	log *logrus.Logger,
+	applicationRepository repository.IApplicationRepository,
+	authorizationCodeRepository repository.IAuthorizationCodeRepository,
+	userRepository repository.IUserRepository,
+	applicationSessionRepository repository.IApplicationSessionRepository,
+	authTokenRepository repository.IAuthTokenRepository,
+	revokedTokenRepository repository.IRevokedTokenRepository,
+) IExchangeAuthorizationCodeUseCase {
+	return &ExchangeAuthorizationCodeUseCase{
+		Log:                          log,
+		ApplicationRepository:        applicationRepository,
+		AuthorizationCodeRepository:  authorizationCodeRepository,
+		UserRepository:               userRepository,
+		ApplicationSessionRepository: applicationSessionRepository,
+		AuthTokenRepository:          authTokenRepository,
+		RevokedTokenRepository:       revokedTokenRepository,
+	}
+}
+
+func (uc *ExchangeAuthorizationCodeUseCase) Execute(request *IExchangeAuthorizationCodeUseCaseRequest) (*IExchangeAuthorizationCodeUseCaseResponse, error) {
+	application, err := identifyClient(uc.ApplicationRepository, request.ClientID, request.ClientSecret)
+	if err != nil {
+		uc.Log.Warn("[ExchangeAuthorizationCodeUseCase.Execute] Client authentication failed for " + request.ClientID)
+		return nil, err
+	}
+
+	if request.Code == "" {
+		return nil, NewOAuthError("invalid_request", "code is required", http.StatusBadRequest)
+	}
+
+	authorizationCode, err := uc.AuthorizationCodeRepository.FindByCode(utils.HashToken(request.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	if authorizationCode == nil || authorizationCode.ApplicationID != application.ID {
+		return nil, NewOAuthError("invalid_grant", "authorization code is invalid", http.StatusBadRequest)
+	}
+
+	if authorizationCode.UsedAt != nil {
+		uc.revokeGrant(authorizationCode)
+		return nil, NewOAuthError("invalid_grant", "authorization code has already been used", http.StatusBadRequest)
+	}
+
+	if authorizationCode.ExpiredAt.Before(time.Now()) {
+		return nil, NewOAuthError("invalid_grant", "authorization code has expired", http.StatusBadRequest)
+	}
+
+	// redirect_uri is only required when the authorization request had one
+	// (RFC 6749 section 4.1.3), but if sent it has to match anyway
+	explicit := authorizationCode.RedirectURIExplicit || request.RedirectURI != ""
+	if explicit && authorizationCode.RedirectURI != request.RedirectURI {
+		return nil, NewOAuthError("invalid_grant", "redirect_uri does not match the authorization request", http.StatusBadRequest)
+	}
+
+	// codes of confidential clients may come without PKCE, a public client
+	// has nothing else to prove the code is its own
+	if application.Public && authorizationCode.CodeChallenge == "" {
+		return nil, NewOAuthError("invalid_grant", "authorization code was issued without a code challenge", http.StatusBadRequest)
+	}
+	if authorizationCode.CodeChallenge != "" && !verifyCodeChallenge(authorizationCode.CodeChallenge, authorizationCode.CodeChallengeMethod, request.CodeVerifier) {
+		return nil, NewOAuthError("invalid_grant", "code_verifier does not match the code challenge", http.StatusBadRequest)
+	}
+
+	if err := uc.AuthorizationCodeRepository.ConsumeAuthorizationCode(authorizationCode); err != nil {
+		// lost the race against a concurrent redemption of the same code
+		uc.revokeGrant(authorizationCode)
+		return nil, NewOAuthError("invalid_grant", "authorization code has already been used", http.StatusBadRequest)
+	}
+
+	user, err := uc.UserRepository.FindByIdOnly(authorizationCode.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, NewOAuthError("invalid_grant", "user no longer exists", http.StatusBadRequest)
+	}
+
+	filteredRoles := []entity.Role{}
+	for _, role := range user.Roles {
+		if role.ID == authorizationCode.RoleID {
+			filteredRoles = append(filteredRoles, role)
+			break
+		}
+	}
+	if len(filteredRoles) == 0 {
+		return nil, NewOAuthError("invalid_grant", "the selected role is no longer assigned to the user", http.StatusBadRequest)
+	}
+	user.Roles = filteredRoles
+
+	// remember the application got tokens from this login so it is notified on logout
+	if authorizationCode.SessionID != "" {
+		if err := uc.ApplicationSessionRepository.CreateApplicationSession(&entity.ApplicationSession{
+			SessionID:     authorizationCode.SessionID,
+			ApplicationID: application.ID,
+			UserID:        user.ID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IExchangeAuthorizationCodeUseCaseResponse{
+		User:        user,
+		Application: application,
+		Scope:       authorizationCode.Scope,
+		Nonce:       authorizationCode.Nonce,
+		AuthTime:    authorizationCode.AuthTime,
+		SessionID:   authorizationCode.SessionID,
+		GrantID:     authorizationCode.ID,
+	}, nil
+}
+
+// revokeGrant revokes the refresh family and the access token issued for a
+// code that is redeemed again, as whoever replays it may have the tokens too
+// (RFC 6749 section 4.1.2).
+func (uc *ExchangeAuthorizationCodeUseCase) revokeGrant(authorizationCode *entity.AuthorizationCode) {
+	uc.Log.Warn("[ExchangeAuthorizationCodeUseCase.revokeGrant] Authorization code replayed, revoking the tokens issued for " + authorizationCode.ID.String())
+	if err := uc.AuthTokenRepository.RevokeAuthTokenFamily(authorizationCode.ID); err != nil {
+		uc.Log.Error("[ExchangeAuthorizationCodeUseCase.revokeGrant] " + err.Error())
+	}
+
+	now := time.Now()
+	revokedToken, err := uc.RevokedTokenRepository.CreateRevokedToken(&entity.RevokedToken{
+		JTI:       authorizationCode.ID.String(),
+		UserID:    authorizationCode.UserID,
+		RevokedAt: now,
+		ExpiredAt: now.Add(utils.AccessTokenTTL()),
+	})
+	if err != nil {
+		uc.Log.Error("[ExchangeAuthorizationCodeUseCase.revokeGrant] " + err.Error())
+		return
+	}
+	utils.MarkTokenRevoked(revokedToken)
+}
+
+func ExchangeAuthorizationCodeUseCaseFactory(log *logrus.Logger) IExchangeAuthorizationCodeUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	authorizationCodeRepository := repository.AuthorizationCodeRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	applicationSessionRepository := repository.ApplicationSessionRepositoryFactory(log)
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	revokedTokenRepository := repository.RevokedTokenRepositoryFactory(log)
+	return NewExchangeAuthorizationCodeUseCase(log, applicationRepository, authorizationCodeRepository, userRepository, applicationSessionRepository, authTokenRepository, revokedTokenRepository)
+}
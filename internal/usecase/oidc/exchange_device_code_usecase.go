@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IExchangeDeviceCodeUseCaseRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	DeviceCode   string `json:"device_code"`
+}
+
+type IExchangeDeviceCodeUseCaseResponse struct {
+	User        *entity.User        `json:"user"`
+	Application *entity.Application `json:"application"`
+	Scope       string              `json:"scope"`
+	AuthTime    time.Time           `json:"auth_time"`
+}
+
+type IExchangeDeviceCodeUseCase interface {
+	Execute(request *IExchangeDeviceCodeUseCaseRequest) (*IExchangeDeviceCodeUseCaseResponse, error)
+}
+
+type ExchangeDeviceCodeUseCase struct {
+	Log                           *logrus.Logger
+	ApplicationRepository         repository.IApplicationRepository
+	DeviceAuthorizationRepository repository.IDeviceAuthorizationRepository
+	UserRepository                repository.IUserRepository
+}
+
+func NewExchangeDeviceCodeUseCase(
+	log *logrus.Logger,
+	applicationRepository repository.IApplicationRepository,
+	deviceAuthorizationRepository repository.IDeviceAuthorizationRepository,
+	userRepository repository.IUserRepository,
+) IExchangeDeviceCodeUseCase {
+	return &ExchangeDeviceCodeUseCase{
+		Log:                           log,
+		ApplicationRepository:         applicationRepository,
+		DeviceAuthorizationRepository: deviceAuthorizationRepository,
+		UserRepository:                userRepository,
+	}
+}
+
+func (uc *ExchangeDeviceCodeUseCase) Execute(request *IExchangeDeviceCodeUseCaseRequest) (*IExchangeDeviceCodeUseCaseResponse, error) {
+	application, err := authenticateClient(uc.ApplicationRepository, request.ClientID, request.ClientSecret)
+	if err != nil {
+		uc.Log.Warn("[ExchangeDeviceCodeUseCase.Execute] Client authentication failed for " + request.ClientID)
+		return nil, err
+	}
+
+	if request.DeviceCode == "" {
+		return nil, NewOAuthError("invalid_request", "device_code is required", http.StatusBadRequest)
+	}
+
+	deviceAuthorization, err := uc.DeviceAuthorizationRepository.FindByDeviceCode(utils.HashToken(request.DeviceCode))
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceAuthorization == nil || deviceAuthorization.ApplicationID != application.ID {
+		return nil, NewOAuthError("invalid_grant", "device code is invalid", http.StatusBadRequest)
+	}
+
+	now := time.Now()
+	if deviceAuthorization.ExpiredAt.Before(now) {
+		return nil, NewOAuthError("expired_token", "device code has expired", http.StatusBadRequest)
+	}
+
+	// devices polling faster than the advertised interval are told to back off
+	interval := time.Duration(deviceAuthorization.Interval) * time.Second
+	if deviceAuthorization.LastPolledAt != nil && now.Sub(*deviceAuthorization.LastPolledAt) < interval {
+		if err := uc.DeviceAuthorizationRepository.UpdateLastPolledAt(deviceAuthorization, now); err != nil {
+			return nil, err
+		}
+		return nil, NewOAuthError("slow_down", "polling too frequently", http.StatusBadRequest)
+	}
+	if err := uc.DeviceAuthorizationRepository.UpdateLastPolledAt(deviceAuthorization, now); err != nil {
+		return nil, err
+	}
+
+	switch deviceAuthorization.Status {
+	case entity.DEVICE_AUTHORIZATION_PENDING:
+		return nil, NewOAuthError("authorization_pending", "the user has not approved the request yet", http.StatusBadRequest)
+	case entity.DEVICE_AUTHORIZATION_DENIED:
+		return nil, NewOAuthError("access_denied", "the user denied the request", http.StatusBadRequest)
+	case entity.DEVICE_AUTHORIZATION_APPROVED:
+	default:
+		return nil, NewOAuthError("invalid_grant", "device code has already been used", http.StatusBadRequest)
+	}
+
+	if err := uc.DeviceAuthorizationRepository.ConsumeDeviceAuthorization(deviceAuthorization); err != nil {
+		return nil, NewOAuthError("invalid_grant", "device code has already been used", http.StatusBadRequest)
+	}
+
+	user, err := uc.UserRepository.FindByIdOnly(*deviceAuthorization.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, NewOAuthError("invalid_grant", "user no longer exists", http.StatusBadRequest)
+	}
+
+	filteredRoles := []entity.Role{}
+	for _, role := range user.Roles {
+		if role.ID == *deviceAuthorization.RoleID {
+			filteredRoles = append(filteredRoles, role)
+			break
+		}
+	}
+	if len(filteredRoles) == 0 {
+		return nil, NewOAuthError("invalid_grant", "the selected role is no longer assigned to the user", http.StatusBadRequest)
+	}
+	user.Roles = filteredRoles
+
+	return &IExchangeDeviceCodeUseCaseResponse{
+		User:        user,
+		Application: application,
+		Scope:       deviceAuthorization.Scope,
+		AuthTime:    *deviceAuthorization.AuthTime,
+	}, nil
+}
+
+func ExchangeDeviceCodeUseCaseFactory(log *logrus.Logger) IExchangeDeviceCodeUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	deviceAuthorizationRepository := repository.DeviceAuthorizationRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewExchangeDeviceCodeUseCase(log, applicationRepository, deviceAuthorizationRepository, userRepository)
+}
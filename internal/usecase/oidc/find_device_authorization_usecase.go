@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IFindDeviceAuthorizationUseCaseRequest struct {
+	UserCode string `json:"user_code"`
+}
+
+type IFindDeviceAuthorizationUseCaseResponse struct {
+	DeviceAuthorization *entity.DeviceAuthorization `json:"device_authorization"`
+}
+
+type IFindDeviceAuthorizationUseCase interface {
+	Execute(request *IFindDeviceAuthorizationUseCaseRequest) (*IFindDeviceAuthorizationUseCaseResponse, error)
+}
+
+type FindDeviceAuthorizationUseCase struct {
+	Log                           *logrus.Logger
+	DeviceAuthorizationRepository repository.IDeviceAuthorizationRepository
+}
+
+func NewFindDeviceAuthorizationUseCase(log *logrus.Logger, deviceAuthorizationRepository repository.IDeviceAuthorizationRepository) IFindDeviceAuthorizationUseCase {
+	return &FindDeviceAuthorizationUseCase{
+		Log:                           log,
+		DeviceAuthorizationRepository: deviceAuthorizationRepository,
+	}
+}
+
+// Execute returns the pending device authorization for a user code, so the
+// /device page only ever shows requests that can still be approved.
+func (uc *FindDeviceAuthorizationUseCase) Execute(request *IFindDeviceAuthorizationUseCaseRequest) (*IFindDeviceAuthorizationUseCaseResponse, error) {
+	deviceAuthorization, err := uc.DeviceAuthorizationRepository.FindByUserCode(NormalizeUserCode(request.UserCode))
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceAuthorization == nil || deviceAuthorization.Status != entity.DEVICE_AUTHORIZATION_PENDING || deviceAuthorization.ExpiredAt.Before(time.Now()) {
+		return nil, errors.New("The code is invalid or has expired")
+	}
+
+	return &IFindDeviceAuthorizationUseCaseResponse{
+		DeviceAuthorization: deviceAuthorization,
+	}, nil
+}
+
+func FindDeviceAuthorizationUseCaseFactory(log *logrus.Logger) IFindDeviceAuthorizationUseCase {
+	deviceAuthorizationRepository := repository.DeviceAuthorizationRepositoryFactory(log)
+	return NewFindDeviceAuthorizationUseCase(log, deviceAuthorizationRepository)
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IIssueClientCredentialsTokenUseCaseRequest struct {
+	ClientID     string        `json:"client_id"`
+	ClientSecret string        `json:"client_secret"`
+	Scope        string        `json:"scope"`
+	TTL          time.Duration `json:"ttl"`
+}
+
+type IIssueClientCredentialsTokenUseCaseResponse struct {
+	AccessToken string `json:"access_token"`
+	Scope       string `json:"scope"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type IIssueClientCredentialsTokenUseCase interface {
+	Execute(request *IIssueClientCredentialsTokenUseCaseRequest) (*IIssueClientCredentialsTokenUseCaseResponse, error)
+}
+
+type IssueClientCredentialsTokenUseCase struct {
+	Log                   *logrus.Logger
+	ApplicationRepository repository.IApplicationRepository
+}
+
+func NewIssueClientCredentialsTokenUseCase(log *logrus.Logger, applicationRepository repository.IApplicationRepository) IIssueClientCredentialsTokenUseCase {
+	return &IssueClientCredentialsTokenUseCase{
+		Log:                   log,
+		ApplicationRepository: applicationRepository,
+	}
+}
+
+func (uc *IssueClientCredentialsTokenUseCase) Execute(request *IIssueClientCredentialsTokenUseCaseRequest) (*IIssueClientCredentialsTokenUseCaseResponse, error) {
+	if _, err := authenticateClient(uc.ApplicationRepository, request.ClientID, request.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	application, err := uc.ApplicationRepository.FindApplicationWithClientPermissions(request.ClientID)
+	if err != nil {
+		uc.Log.Error("[IssueClientCredentialsTokenUseCase.Execute] " + err.Error())
+		return nil, NewOAuthError("server_error", "failed to load client permissions", http.StatusInternalServerError)
+	}
+
+	granted := make([]string, 0, len(application.ClientPermissions))
+	for _, permission := range application.ClientPermissions {
+		granted = append(granted, permission.Name)
+	}
+
+	// without a scope the token carries every granted permission, otherwise
+	// each requested permission has to be granted to the client
+	scope := strings.Join(granted, " ")
+	if request.Scope != "" {
+		for _, requested := range strings.Fields(request.Scope) {
+			if !HasScope(scope, requested) {
+				return nil, NewOAuthError("invalid_scope", "permission "+requested+" is not granted to this client", http.StatusBadRequest)
+			}
+		}
+		scope = strings.Join(strings.Fields(request.Scope), " ")
+	}
+
+	accessToken, err := utils.GenerateClientToken(application, scope, request.TTL)
+	if err != nil {
+		uc.Log.Error("[IssueClientCredentialsTokenUseCase.Execute] " + err.Error())
+		return nil, NewOAuthError("server_error", "failed to issue token", http.StatusInternalServerError)
+	}
+
+	return &IIssueClientCredentialsTokenUseCaseResponse{
+		AccessToken: accessToken,
+		Scope:       scope,
+		ExpiresIn:   int64(request.TTL.Seconds()),
+	}, nil
+}
+
+func IssueClientCredentialsTokenUseCaseFactory(log *logrus.Logger) IIssueClientCredentialsTokenUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	return NewIssueClientCredentialsTokenUseCase(log, applicationRepository)
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+	// logoutTokenTTL is how long applications may take to accept a logout token.
+	logoutTokenTTL = 2 * time.Minute
+)
+
+var backchannelLogoutClient = &http.Client{Timeout: 5 * time.Second}
+
+type ILogoutSessionUseCaseRequest struct {
+	SessionID string    `json:"session_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+}
+
+type ILogoutSessionUseCaseResponse struct {
+	FrontChannelLogoutURIs []string `json:"frontchannel_logout_uris"`
+}
+
+type ILogoutSessionUseCase interface {
+	Execute(request *ILogoutSessionUseCaseRequest) (*ILogoutSessionUseCaseResponse, error)
+}
+
+type LogoutSessionUseCase struct {
+	Log                          *logrus.Logger
+	ApplicationSessionRepository repository.IApplicationSessionRepository
+}
+
+func NewLogoutSessionUseCase(log *logrus.Logger, applicationSessionRepository repository.IApplicationSessionRepository) ILogoutSessionUseCase {
+	return &LogoutSessionUseCase{
+		Log:                          log,
+		ApplicationSessionRepository: applicationSessionRepository,
+	}
+}
+
+// Execute ends the applications signed in through a login session, or through
+// every session of UserID when SessionID is empty. Back-channel logout URIs
+// receive a logout token in the background; the front-channel logout URIs are
+// returned for the browser to load.
+func (uc *LogoutSessionUseCase) Execute(request *ILogoutSessionUseCaseRequest) (*ILogoutSessionUseCaseResponse, error) {
+	var applicationSessions []entity.ApplicationSession
+	var err error
+	switch {
+	case request.SessionID != "":
+		applicationSessions, err = uc.ApplicationSessionRepository.FindBySessionID(request.SessionID)
+	case request.UserID != uuid.Nil:
+		applicationSessions, err = uc.ApplicationSessionRepository.FindByUserID(request.UserID)
+	default:
+		return nil, errors.New("a session or a user is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	frontChannelLogoutURIs := []string{}
+	for _, applicationSession := range applicationSessions {
+		if applicationSession.Application == nil {
+			continue
+		}
+		application := applicationSession.Application
+
+		for _, uri := range application.RedirectURIsOfType(entity.REDIRECT_URI_FRONTCHANNEL_LOGOUT) {
+			frontChannelLogoutURIs = append(frontChannelLogoutURIs, frontChannelLogoutURI(uri, request.Issuer, applicationSession.SessionID))
+		}
+
+		backChannelLogoutURIs := application.RedirectURIsOfType(entity.REDIRECT_URI_BACKCHANNEL_LOGOUT)
+		if len(backChannelLogoutURIs) == 0 {
+			continue
+		}
+		logoutToken, err := generateLogoutToken(request.Issuer, application, applicationSession)
+		if err != nil {
+			uc.Log.Error("[LogoutSessionUseCase.Execute] " + err.Error())
+			continue
+		}
+		for _, uri := range backChannelLogoutURIs {
+			go uc.sendBackChannelLogout(uri, logoutToken)
+		}
+	}
+
+	if err := uc.ApplicationSessionRepository.DeleteApplicationSessions(applicationSessions); err != nil {
+		return nil, err
+	}
+
+	return &ILogoutSessionUseCaseResponse{
+		FrontChannelLogoutURIs: frontChannelLogoutURIs,
+	}, nil
+}
+
+func (uc *LogoutSessionUseCase) sendBackChannelLogout(uri string, logoutToken string) {
+	resp, err := backchannelLogoutClient.PostForm(uri, url.Values{"logout_token": {logoutToken}})
+	if err != nil {
+		uc.Log.Warn("[LogoutSessionUseCase.sendBackChannelLogout] " + uri + ": " + err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		uc.Log.Warn("[LogoutSessionUseCase.sendBackChannelLogout] " + uri + " answered " + resp.Status)
+	}
+}
+
+func generateLogoutToken(issuer string, application *entity.Application, applicationSession entity.ApplicationSession) (string, error) {
+	now := time.Now()
+	return utils.SignToken(jwt.MapClaims{
+		"iss":    issuer,
+		"aud":    application.Name,
+		"iat":    now.Unix(),
+		"exp":    now.Add(logoutTokenTTL).Unix(),
+		"jti":    uuid.New().String(),
+		"sub":    applicationSession.UserID.String(),
+		"sid":    applicationSession.SessionID,
+		"events": map[string]interface{}{backchannelLogoutEvent: map[string]interface{}{}},
+	})
+}
+
+func frontChannelLogoutURI(uri string, issuer string, sessionID string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := parsed.Query()
+	query.Set("iss", issuer)
+	query.Set("sid", sessionID)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func LogoutSessionUseCaseFactory(log *logrus.Logger) ILogoutSessionUseCase {
+	applicationSessionRepository := repository.ApplicationSessionRepositoryFactory(log)
+	return NewLogoutSessionUseCase(log, applicationSessionRepository)
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// OAuthError carries an RFC 6749 error code so handlers can render it in the
+// format the OAuth2 client expects. RedirectURI is only set once the client
+// and its redirect URI have been validated; otherwise the error must be shown
+// to the user instead of being sent back to the client.
+type OAuthError struct {
+	Code        string
+	Description string
+	StatusCode  int
+	RedirectURI string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func NewOAuthError(code string, description string, statusCode int) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+		StatusCode:  statusCode,
+	}
+}
+
+func authenticateClient(applicationRepository repository.IApplicationRepository, clientID string, clientSecret string) (*entity.Application, error) {
+	if clientID == "" {
+		return nil, NewOAuthError("invalid_client", "client authentication failed", http.StatusUnauthorized)
+	}
+
+	application, err := applicationRepository.FindApplicationByName(clientID)
+	if err != nil || application == nil {
+		return nil, NewOAuthError("invalid_client", "client authentication failed", http.StatusUnauthorized)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(application.Secret), []byte(clientSecret)) != 1 {
+		return nil, NewOAuthError("invalid_client", "client authentication failed", http.StatusUnauthorized)
+	}
+
+	return application, nil
+}
+
+// identifyClient authenticates a confidential client by its secret. Public
+// clients cannot keep one and are identified by client_id alone, the grant
+// they present has to prove the rest.
+func identifyClient(applicationRepository repository.IApplicationRepository, clientID string, clientSecret string) (*entity.Application, error) {
+	if clientID != "" && clientSecret == "" {
+		application, err := applicationRepository.FindApplicationByName(clientID)
+		if err == nil && application != nil && application.Public {
+			return application, nil
+		}
+	}
+	return authenticateClient(applicationRepository, clientID, clientSecret)
+}
+
+func verifyCodeChallenge(challenge string, method string, verifier string) bool {
+	if challenge == "" || verifier == "" {
+		return false
+	}
+
+	// only S256 challenges are accepted, codes stored with another method
+	// cannot be redeemed
+	if method != "S256" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(hash[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// HasScope reports whether the space separated scope list contains expected.
+func HasScope(scope string, expected string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakeApplicationRepository struct {
+	repository.IApplicationRepository
+	applications map[string]*entity.Application
+}
+
+func (r *fakeApplicationRepository) FindApplicationByName(name string) (*entity.Application, error) {
+	application, ok := r.applications[name]
+	if !ok {
+		return nil, errors.New("[fakeApplicationRepository.FindApplicationByName] record not found")
+	}
+	return application, nil
+}
+
+func newFakeApplicationRepository() *fakeApplicationRepository {
+	return &fakeApplicationRepository{applications: map[string]*entity.Application{
+		"web": {Name: "web", Secret: "web-secret", RedirectURI: "https://web.example.com/callback"},
+		"spa": {Name: "spa", RedirectURI: "https://spa.example.com/callback", Public: true},
+	}}
+}
+
+func s256Challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := s256Challenge(verifier)
+
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		verifier  string
+		want      bool
+	}{
+		{"S256", challenge, "S256", verifier, true},
+		{"wrong verifier", challenge, "S256", verifier + "x", false},
+		{"missing verifier", challenge, "S256", "", false},
+		{"missing challenge", "", "S256", verifier, false},
+		{"plain", verifier, "plain", verifier, false},
+		{"no method", verifier, "", verifier, false},
+		{"lowercase method", challenge, "s256", verifier, false},
+	}
+
+	for _, test := range tests {
+		if got := verifyCodeChallenge(test.challenge, test.method, test.verifier); got != test.want {
+			t.Errorf("%s: verifyCodeChallenge = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateAuthorizationRequestPKCE(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	uc := NewValidateAuthorizationRequestUseCase(log, newFakeApplicationRepository())
+	challenge := s256Challenge("verifier")
+
+	tests := []struct {
+		name                string
+		clientID            string
+		codeChallenge       string
+		codeChallengeMethod string
+		wantError           bool
+	}{
+		{"confidential without challenge", "web", "", "", false},
+		{"confidential with S256", "web", challenge, "S256", false},
+		{"confidential with plain", "web", challenge, "plain", true},
+		{"confidential with no method", "web", challenge, "", true},
+		{"public without challenge", "spa", "", "", true},
+		{"public with S256", "spa", challenge, "S256", false},
+		{"public with plain", "spa", challenge, "plain", true},
+	}
+
+	for _, test := range tests {
+		application, _ := newFakeApplicationRepository().FindApplicationByName(test.clientID)
+		_, err := uc.Execute(&IValidateAuthorizationRequestUseCaseRequest{
+			ClientID:            test.clientID,
+			RedirectURI:         application.RedirectURI,
+			ResponseType:        "code",
+			Scope:               "openid profile",
+			CodeChallenge:       test.codeChallenge,
+			CodeChallengeMethod: test.codeChallengeMethod,
+		})
+		if test.wantError {
+			var oauthErr *OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_request" || oauthErr.RedirectURI != application.RedirectURI {
+				t.Errorf("%s: Execute = %v, want an invalid_request error sent to the client", test.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: Execute = %v, want no error", test.name, err)
+		}
+	}
+}
+
+func TestIdentifyClient(t *testing.T) {
+	applicationRepository := newFakeApplicationRepository()
+
+	tests := []struct {
+		name         string
+		clientID     string
+		clientSecret string
+		wantError    bool
+	}{
+		{"confidential with secret", "web", "web-secret", false},
+		{"confidential with wrong secret", "web", "nope", true},
+		{"confidential without secret", "web", "", true},
+		{"public without secret", "spa", "", false},
+		{"public with a guessed secret", "spa", "anything", true},
+		{"unknown client", "mobile", "", true},
+		{"no client", "", "", true},
+	}
+
+	for _, test := range tests {
+		application, err := identifyClient(applicationRepository, test.clientID, test.clientSecret)
+		if test.wantError {
+			var oauthErr *OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" {
+				t.Errorf("%s: identifyClient = %v, want invalid_client", test.name, err)
+			}
+		} else if err != nil || application == nil || application.Name != test.clientID {
+			t.Errorf("%s: identifyClient = %+v, %v, want %s", test.name, application, err, test.clientID)
+		}
+	}
+}
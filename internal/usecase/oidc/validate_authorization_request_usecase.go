@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IValidateAuthorizationRequestUseCaseRequest struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	ResponseType        string `json:"response_type"`
+	Scope               string `json:"scope"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+type IValidateAuthorizationRequestUseCaseResponse struct {
+	Application *entity.Application `json:"application"`
+	RedirectURI string              `json:"redirect_uri"`
+}
+
+type IValidateAuthorizationRequestUseCase interface {
+	Execute(request *IValidateAuthorizationRequestUseCaseRequest) (*IValidateAuthorizationRequestUseCaseResponse, error)
+}
+
+type ValidateAuthorizationRequestUseCase struct {
+	Log                   *logrus.Logger
+	ApplicationRepository repository.IApplicationRepository
+}
+
+func NewValidateAuthorizationRequestUseCase(log *logrus.Logger, applicationRepository repository.IApplicationRepository) IValidateAuthorizationRequestUseCase {
+	return &ValidateAuthorizationRequestUseCase{
+		Log:                   log,
+		ApplicationRepository: applicationRepository,
+	}
+}
+
+func (uc *ValidateAuthorizationRequestUseCase) Execute(request *IValidateAuthorizationRequestUseCaseRequest) (*IValidateAuthorizationRequestUseCaseResponse, error) {
+	if request.ClientID == "" {
+		return nil, NewOAuthError("invalid_request", "client_id is required", http.StatusBadRequest)
+	}
+
+	application, err := uc.ApplicationRepository.FindApplicationByName(request.ClientID)
+	if err != nil || application == nil {
+		uc.Log.Warn("[ValidateAuthorizationRequestUseCase.Execute] Unknown client " + request.ClientID)
+		return nil, NewOAuthError("invalid_request", "unknown client_id", http.StatusBadRequest)
+	}
+
+	// redirect_uri may only be omitted when the default one is meant, anything
+	// else has to match a registered login redirect URI exactly
+	redirectURI := request.RedirectURI
+	if redirectURI == "" {
+		redirectURI = application.RedirectURI
+	}
+	if !application.HasRedirectURI(redirectURI, entity.REDIRECT_URI_LOGIN) {
+		uc.Log.Warn("[ValidateAuthorizationRequestUseCase.Execute] Redirect URI mismatch for client " + request.ClientID)
+		return nil, NewOAuthError("invalid_request", "redirect_uri is not registered for this client", http.StatusBadRequest)
+	}
+
+	// From here on the redirect URI is trusted, so errors are sent back to the client.
+	if request.ResponseType != "code" {
+		return nil, &OAuthError{Code: "unsupported_response_type", Description: "only the code response type is supported", StatusCode: http.StatusBadRequest, RedirectURI: redirectURI}
+	}
+
+	if !HasScope(request.Scope, "openid") {
+		return nil, &OAuthError{Code: "invalid_scope", Description: "the openid scope is required", StatusCode: http.StatusBadRequest, RedirectURI: redirectURI}
+	}
+
+	// plain gives nothing over no challenge once the request leaks, and a
+	// missing method means plain, so S256 is the only method
+	if request.CodeChallenge != "" && request.CodeChallengeMethod != "S256" {
+		return nil, &OAuthError{Code: "invalid_request", Description: "code_challenge_method must be S256", StatusCode: http.StatusBadRequest, RedirectURI: redirectURI}
+	}
+
+	// public clients have no secret, PKCE is what keeps an intercepted code
+	// useless to anyone else
+	if application.Public && request.CodeChallenge == "" {
+		return nil, &OAuthError{Code: "invalid_request", Description: "code_challenge is required", StatusCode: http.StatusBadRequest, RedirectURI: redirectURI}
+	}
+
+	return &IValidateAuthorizationRequestUseCaseResponse{
+		Application: application,
+		RedirectURI: redirectURI,
+	}, nil
+}
+
+func ValidateAuthorizationRequestUseCaseFactory(log *logrus.Logger) IValidateAuthorizationRequestUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	return NewValidateAuthorizationRequestUseCase(log, applicationRepository)
+}
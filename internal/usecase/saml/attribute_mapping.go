@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// DefaultAttributeMapping is sent to service providers that were imported
+// without an attribute mapping.
+var DefaultAttributeMapping = map[string]string{
+	"email":    "user.email",
+	"name":     "user.name",
+	"username": "user.username",
+	"role":     "role.name",
+}
+
+// attributeSources lists the user fields a SAML attribute can be filled from.
+var attributeSources = map[string]func(user *entity.User, role *entity.Role) string{
+	"user.id":       func(user *entity.User, role *entity.Role) string { return user.ID.String() },
+	"user.name":     func(user *entity.User, role *entity.Role) string { return user.Name },
+	"user.username": func(user *entity.User, role *entity.Role) string { return user.Username },
+	"user.email":    func(user *entity.User, role *entity.Role) string { return user.Email },
+	"user.gender":   func(user *entity.User, role *entity.Role) string { return string(user.Gender) },
+	"role.name": func(user *entity.User, role *entity.Role) string {
+		if role == nil {
+			return ""
+		}
+		return role.Name
+	},
+	"employee.name": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil {
+			return ""
+		}
+		return user.Employee.Name
+	},
+	"employee.email": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil {
+			return ""
+		}
+		return user.Employee.Email
+	},
+	"employee.nik": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil {
+			return ""
+		}
+		return user.Employee.NIK
+	},
+	"employee.mobile_phone": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil {
+			return ""
+		}
+		return user.Employee.MobilePhone
+	},
+	"employee.organization": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil {
+			return ""
+		}
+		return user.Employee.Organization.Name
+	},
+	"employee_job.name": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil || user.Employee.EmployeeJob == nil {
+			return ""
+		}
+		return user.Employee.EmployeeJob.Name
+	},
+	"employee_job.job": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil || user.Employee.EmployeeJob == nil || user.Employee.EmployeeJob.Job == nil {
+			return ""
+		}
+		return user.Employee.EmployeeJob.Job.Name
+	},
+	"employee_job.organization_structure": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil || user.Employee.EmployeeJob == nil || user.Employee.EmployeeJob.OrganizationStructure == nil {
+			return ""
+		}
+		return user.Employee.EmployeeJob.OrganizationStructure.Name
+	},
+	"employee_job.organization_location": func(user *entity.User, role *entity.Role) string {
+		if user.Employee == nil || user.Employee.EmployeeJob == nil || user.Employee.EmployeeJob.OrganizationLocation == nil {
+			return ""
+		}
+		return user.Employee.EmployeeJob.OrganizationLocation.Name
+	},
+}
+
+// AttributeSources returns the names usable as values of an attribute mapping.
+func AttributeSources() []string {
+	sources := make([]string, 0, len(attributeSources))
+	for source := range attributeSources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func parseAttributeMapping(serviceProvider *entity.SAMLServiceProvider) (map[string]string, error) {
+	if serviceProvider.AttributeMapping == "" {
+		return DefaultAttributeMapping, nil
+	}
+
+	mapping := map[string]string{}
+	if err := json.Unmarshal([]byte(serviceProvider.AttributeMapping), &mapping); err != nil {
+		return nil, errors.New("invalid attribute mapping: " + err.Error())
+	}
+	return mapping, nil
+}
+
+func validateAttributeMapping(mapping map[string]string) error {
+	for attribute, source := range mapping {
+		if attribute == "" {
+			return errors.New("attribute names cannot be empty")
+		}
+		if _, ok := attributeSources[source]; !ok {
+			return errors.New("unknown attribute source " + source)
+		}
+	}
+	return nil
+}
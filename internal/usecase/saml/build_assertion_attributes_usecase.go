@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	NameIDFormatEmail      = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	NameIDFormatPersistent = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+)
+
+type IBuildAssertionAttributesUseCaseRequest struct {
+	UserID          uuid.UUID                   `json:"user_id"`
+	RoleID          uuid.UUID                   `json:"role_id"`
+	ServiceProvider *entity.SAMLServiceProvider `json:"service_provider"`
+}
+
+type IBuildAssertionAttributesUseCaseResponse struct {
+	NameID       string            `json:"name_id"`
+	NameIDFormat string            `json:"name_id_format"`
+	Attributes   map[string]string `json:"attributes"`
+}
+
+type IBuildAssertionAttributesUseCase interface {
+	Execute(request *IBuildAssertionAttributesUseCaseRequest) (*IBuildAssertionAttributesUseCaseResponse, error)
+}
+
+type BuildAssertionAttributesUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+}
+
+func NewBuildAssertionAttributesUseCase(log *logrus.Logger, userRepository repository.IUserRepository) IBuildAssertionAttributesUseCase {
+	return &BuildAssertionAttributesUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+	}
+}
+
+// Execute resolves the subject and the attributes of the assertion sent to a
+// service provider, following its attribute mapping. Empty values are left out.
+func (uc *BuildAssertionAttributesUseCase) Execute(request *IBuildAssertionAttributesUseCaseRequest) (*IBuildAssertionAttributesUseCaseResponse, error) {
+	user, err := uc.UserRepository.FindById(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("User not found")
+	}
+
+	var role *entity.Role
+	for i := range user.Roles {
+		if user.Roles[i].ID == request.RoleID {
+			role = &user.Roles[i]
+			break
+		}
+	}
+	if role == nil {
+		return nil, errors.New("The chosen role does not belong to the user")
+	}
+
+	mapping, err := parseAttributeMapping(request.ServiceProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]string{}
+	for attribute, source := range mapping {
+		resolve, ok := attributeSources[source]
+		if !ok {
+			continue
+		}
+		if value := resolve(user, role); value != "" {
+			attributes[attribute] = value
+		}
+	}
+
+	resp := &IBuildAssertionAttributesUseCaseResponse{
+		NameID:       user.Email,
+		NameIDFormat: NameIDFormatEmail,
+		Attributes:   attributes,
+	}
+	if request.ServiceProvider.NameIDFormat == entity.SAML_NAME_ID_PERSISTENT {
+		resp.NameID = user.ID.String()
+		resp.NameIDFormat = NameIDFormatPersistent
+	}
+
+	return resp, nil
+}
+
+func BuildAssertionAttributesUseCaseFactory(log *logrus.Logger) IBuildAssertionAttributesUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewBuildAssertionAttributesUseCase(log, userRepository)
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"encoding/xml"
+	"errors"
+
+	"github.com/crewjam/saml"
+	"github.com/sirupsen/logrus"
+)
+
+type IFindServiceProviderUseCaseRequest struct {
+	EntityID        string `json:"entity_id"`
+	ApplicationName string `json:"application_name"`
+}
+
+type IFindServiceProviderUseCaseResponse struct {
+	ServiceProvider *entity.SAMLServiceProvider `json:"service_provider"`
+	Metadata        *saml.EntityDescriptor      `json:"-"`
+}
+
+type IFindServiceProviderUseCase interface {
+	Execute(request *IFindServiceProviderUseCaseRequest) (*IFindServiceProviderUseCaseResponse, error)
+}
+
+type FindServiceProviderUseCase struct {
+	Log                           *logrus.Logger
+	ApplicationRepository         repository.IApplicationRepository
+	SAMLServiceProviderRepository repository.ISAMLServiceProviderRepository
+}
+
+func NewFindServiceProviderUseCase(log *logrus.Logger, applicationRepository repository.IApplicationRepository, samlServiceProviderRepository repository.ISAMLServiceProviderRepository) IFindServiceProviderUseCase {
+	return &FindServiceProviderUseCase{
+		Log:                           log,
+		ApplicationRepository:         applicationRepository,
+		SAMLServiceProviderRepository: samlServiceProviderRepository,
+	}
+}
+
+// Execute looks the service provider up by entity ID (SP-initiated logins) or
+// by application name (IdP-initiated logins from the portal). A nil
+// ServiceProvider means none is registered.
+func (uc *FindServiceProviderUseCase) Execute(request *IFindServiceProviderUseCaseRequest) (*IFindServiceProviderUseCaseResponse, error) {
+	var serviceProvider *entity.SAMLServiceProvider
+	var err error
+	if request.EntityID != "" {
+		serviceProvider, err = uc.SAMLServiceProviderRepository.FindByEntityID(request.EntityID)
+	} else {
+		application, appErr := uc.ApplicationRepository.FindApplicationByName(request.ApplicationName)
+		if appErr != nil {
+			return &IFindServiceProviderUseCaseResponse{}, nil
+		}
+		serviceProvider, err = uc.SAMLServiceProviderRepository.FindByApplicationID(application.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if serviceProvider == nil {
+		return &IFindServiceProviderUseCaseResponse{}, nil
+	}
+
+	metadata := new(saml.EntityDescriptor)
+	if err := xml.Unmarshal([]byte(serviceProvider.Metadata), metadata); err != nil {
+		uc.Log.Error("[FindServiceProviderUseCase.Execute] " + err.Error())
+		return nil, errors.New("[FindServiceProviderUseCase.Execute] " + err.Error())
+	}
+
+	return &IFindServiceProviderUseCaseResponse{
+		ServiceProvider: serviceProvider,
+		Metadata:        metadata,
+	}, nil
+}
+
+func FindServiceProviderUseCaseFactory(log *logrus.Logger) IFindServiceProviderUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	samlServiceProviderRepository := repository.SAMLServiceProviderRepositoryFactory(log)
+	return NewFindServiceProviderUseCase(log, applicationRepository, samlServiceProviderRepository)
+}
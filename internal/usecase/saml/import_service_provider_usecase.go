@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/sirupsen/logrus"
+)
+
+// metadataFetchTimeout bounds how long importing from a metadata URL may take.
+const metadataFetchTimeout = 10 * time.Second
+
+type IImportServiceProviderUseCaseRequest struct {
+	ApplicationName  string            `json:"application_name"`
+	Metadata         string            `json:"metadata"`
+	MetadataURL      string            `json:"metadata_url"`
+	NameIDFormat     string            `json:"name_id_format"`
+	AttributeMapping map[string]string `json:"attribute_mapping"`
+	IDPInitiated     bool              `json:"idp_initiated"`
+}
+
+type IImportServiceProviderUseCaseResponse struct {
+	ServiceProvider *entity.SAMLServiceProvider `json:"service_provider"`
+}
+
+type IImportServiceProviderUseCase interface {
+	Execute(request *IImportServiceProviderUseCaseRequest) (*IImportServiceProviderUseCaseResponse, error)
+}
+
+type ImportServiceProviderUseCase struct {
+	Log                           *logrus.Logger
+	ApplicationRepository         repository.IApplicationRepository
+	SAMLServiceProviderRepository repository.ISAMLServiceProviderRepository
+}
+
+func NewImportServiceProviderUseCase(log *logrus.Logger, applicationRepository repository.IApplicationRepository, samlServiceProviderRepository repository.ISAMLServiceProviderRepository) IImportServiceProviderUseCase {
+	return &ImportServiceProviderUseCase{
+		Log:                           log,
+		ApplicationRepository:         applicationRepository,
+		SAMLServiceProviderRepository: samlServiceProviderRepository,
+	}
+}
+
+// Execute registers the SAML metadata of a service provider for an application,
+// replacing what was imported before. The metadata is taken as is or fetched
+// from MetadataURL.
+func (uc *ImportServiceProviderUseCase) Execute(request *IImportServiceProviderUseCaseRequest) (*IImportServiceProviderUseCaseResponse, error) {
+	application, err := uc.ApplicationRepository.FindApplicationByName(request.ApplicationName)
+	if err != nil {
+		return nil, errors.New("Application not found")
+	}
+
+	metadata := []byte(request.Metadata)
+	if len(metadata) == 0 {
+		if request.MetadataURL == "" {
+			return nil, errors.New("metadata or metadata_url is required")
+		}
+		metadata, err = uc.fetchMetadata(request.MetadataURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	descriptor := new(saml.EntityDescriptor)
+	if err := xml.Unmarshal(metadata, descriptor); err != nil {
+		return nil, errors.New("invalid SAML metadata: " + err.Error())
+	}
+	if descriptor.EntityID == "" {
+		return nil, errors.New("the metadata has no entityID")
+	}
+	if !hasPostAssertionConsumerService(descriptor) {
+		return nil, errors.New("the metadata has no HTTP-POST assertion consumer service")
+	}
+
+	existing, err := uc.SAMLServiceProviderRepository.FindByEntityID(descriptor.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ApplicationID != application.ID {
+		return nil, errors.New("the entity ID is already registered for another application")
+	}
+
+	nameIDFormat := entity.SAMLNameIDFormat(request.NameIDFormat)
+	switch nameIDFormat {
+	case "":
+		nameIDFormat = entity.SAML_NAME_ID_EMAIL
+	case entity.SAML_NAME_ID_EMAIL, entity.SAML_NAME_ID_PERSISTENT:
+	default:
+		return nil, errors.New("name_id_format must be EMAIL or PERSISTENT")
+	}
+
+	attributeMapping := ""
+	if len(request.AttributeMapping) > 0 {
+		if err := validateAttributeMapping(request.AttributeMapping); err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(request.AttributeMapping)
+		if err != nil {
+			return nil, err
+		}
+		attributeMapping = string(encoded)
+	}
+
+	serviceProvider, err := uc.SAMLServiceProviderRepository.SaveServiceProvider(&entity.SAMLServiceProvider{
+		ApplicationID:    application.ID,
+		EntityID:         descriptor.EntityID,
+		Metadata:         string(metadata),
+		NameIDFormat:     nameIDFormat,
+		AttributeMapping: attributeMapping,
+		IDPInitiated:     request.IDPInitiated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IImportServiceProviderUseCaseResponse{
+		ServiceProvider: serviceProvider,
+	}, nil
+}
+
+func (uc *ImportServiceProviderUseCase) fetchMetadata(metadataURL string) ([]byte, error) {
+	client := &http.Client{Timeout: metadataFetchTimeout}
+	resp, err := client.Get(metadataURL)
+	if err != nil {
+		uc.Log.Error("[ImportServiceProviderUseCase.fetchMetadata] " + err.Error())
+		return nil, errors.New("cannot fetch the metadata: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("cannot fetch the metadata: " + resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func hasPostAssertionConsumerService(descriptor *saml.EntityDescriptor) bool {
+	for _, spssoDescriptor := range descriptor.SPSSODescriptors {
+		for _, endpoint := range spssoDescriptor.AssertionConsumerServices {
+			if endpoint.Binding == saml.HTTPPostBinding {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ImportServiceProviderUseCaseFactory(log *logrus.Logger) IImportServiceProviderUseCase {
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	samlServiceProviderRepository := repository.SAMLServiceProviderRepositoryFactory(log)
+	return NewImportServiceProviderUseCase(log, applicationRepository, samlServiceProviderRepository)
+}
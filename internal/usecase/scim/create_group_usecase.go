@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ICreateGroupUseCaseRequest struct {
+	Group              *GroupResource `json:"group"`
+	DefaultApplication string         `json:"default_application"`
+	BaseURL            string         `json:"base_url"`
+}
+
+type ICreateGroupUseCaseResponse struct {
+	Group *GroupResource `json:"group"`
+}
+
+type ICreateGroupUseCase interface {
+	Execute(request *ICreateGroupUseCaseRequest) (*ICreateGroupUseCaseResponse, error)
+}
+
+type CreateGroupUseCase struct {
+	Log                   *logrus.Logger
+	RoleRepository        repository.IRoleRepository
+	ApplicationRepository repository.IApplicationRepository
+	UserRepository        repository.IUserRepository
+}
+
+func NewCreateGroupUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository, applicationRepository repository.IApplicationRepository, userRepository repository.IUserRepository) ICreateGroupUseCase {
+	return &CreateGroupUseCase{
+		Log:                   log,
+		RoleRepository:        roleRepository,
+		ApplicationRepository: applicationRepository,
+		UserRepository:        userRepository,
+	}
+}
+
+// Execute creates the role of the group in its application and gives it to
+// the members.
+func (uc *CreateGroupUseCase) Execute(request *ICreateGroupUseCaseRequest) (*ICreateGroupUseCaseResponse, error) {
+	role, err := saveGroup(uc.RoleRepository, uc.ApplicationRepository, uc.UserRepository, request.Group, nil, request.DefaultApplication)
+	if err != nil {
+		return nil, err
+	}
+	uc.Log.Info("[CreateGroupUseCase.Execute] provisioned role " + role.ID.String())
+	return &ICreateGroupUseCaseResponse{
+		Group: groupResource(role, request.BaseURL),
+	}, nil
+}
+
+func CreateGroupUseCaseFactory(log *logrus.Logger) ICreateGroupUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewCreateGroupUseCase(log, roleRepository, applicationRepository, userRepository)
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ICreateUserUseCaseRequest struct {
+	User    *UserResource `json:"user"`
+	BaseURL string        `json:"base_url"`
+}
+
+type ICreateUserUseCaseResponse struct {
+	User *UserResource `json:"user"`
+}
+
+type ICreateUserUseCase interface {
+	Execute(request *ICreateUserUseCaseRequest) (*ICreateUserUseCaseResponse, error)
+}
+
+type CreateUserUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+	PasswordHasher utils.PasswordHasher
+}
+
+func NewCreateUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository, passwordHasher utils.PasswordHasher) ICreateUserUseCase {
+	return &CreateUserUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+		PasswordHasher: passwordHasher,
+	}
+}
+
+// Execute creates an active user with a verified email and a random
+// password, unless the resource sets active to false.
+func (uc *CreateUserUseCase) Execute(request *ICreateUserUseCaseRequest) (*ICreateUserUseCaseResponse, error) {
+	user, err := saveUser(uc.UserRepository, uc.PasswordHasher, request.User, nil)
+	if err != nil {
+		return nil, err
+	}
+	uc.Log.Info("[CreateUserUseCase.Execute] provisioned user " + user.ID.String())
+	return &ICreateUserUseCaseResponse{
+		User: userResource(user, request.BaseURL),
+	}, nil
+}
+
+func CreateUserUseCaseFactory(log *logrus.Logger) ICreateUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewCreateUserUseCase(log, userRepository, passwordHasher)
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IDeleteGroupUseCaseRequest struct {
+	ID string `json:"id"`
+}
+
+type IDeleteGroupUseCase interface {
+	Execute(request *IDeleteGroupUseCaseRequest) error
+}
+
+type DeleteGroupUseCase struct {
+	Log            *logrus.Logger
+	RoleRepository repository.IRoleRepository
+}
+
+func NewDeleteGroupUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository) IDeleteGroupUseCase {
+	return &DeleteGroupUseCase{
+		Log:            log,
+		RoleRepository: roleRepository,
+	}
+}
+
+func (uc *DeleteGroupUseCase) Execute(request *IDeleteGroupUseCaseRequest) error {
+	role, err := findGroup(uc.RoleRepository, request.ID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errNotFound("Group", request.ID)
+	}
+
+	if err := uc.RoleRepository.DeleteRole(role.ID); err != nil {
+		return err
+	}
+	uc.Log.Info("[DeleteGroupUseCase.Execute] deprovisioned role " + role.ID.String())
+	return nil
+}
+
+func DeleteGroupUseCaseFactory(log *logrus.Logger) IDeleteGroupUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	return NewDeleteGroupUseCase(log, roleRepository)
+}
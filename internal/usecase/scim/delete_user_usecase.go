@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IDeleteUserUseCaseRequest struct {
+	ID string `json:"id"`
+}
+
+type IDeleteUserUseCase interface {
+	Execute(request *IDeleteUserUseCaseRequest) error
+}
+
+type DeleteUserUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+}
+
+func NewDeleteUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository) IDeleteUserUseCase {
+	return &DeleteUserUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+	}
+}
+
+func (uc *DeleteUserUseCase) Execute(request *IDeleteUserUseCaseRequest) error {
+	user, err := findUser(uc.UserRepository, request.ID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errNotFound("User", request.ID)
+	}
+
+	if err := uc.UserRepository.DeleteUser(user.ID); err != nil {
+		return err
+	}
+	uc.Log.Info("[DeleteUserUseCase.Execute] deprovisioned user " + user.ID.String())
+	return nil
+}
+
+func DeleteUserUseCaseFactory(log *logrus.Logger) IDeleteUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewDeleteUserUseCase(log, userRepository)
+}
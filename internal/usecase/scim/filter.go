@@ -0,0 +1,539 @@
+package usecase
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed filter of RFC 7644 section 3.4.2.2. And, or and not
+// combine Left and Right; "[]" is a value path, Left filtering the values of
+// Path; the other operators compare Path with Value.
+type Filter struct {
+	Op    string
+	Path  string
+	Value interface{}
+	Left  *Filter
+	Right *Filter
+}
+
+func errInvalidFilter(detail string) *SCIMError {
+	return NewSCIMError(http.StatusBadRequest, "invalidFilter", detail)
+}
+
+// ParseFilter parses a filter expression. Operators and keywords are case
+// insensitive.
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	filter, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position < len(parser.tokens) {
+		return nil, errInvalidFilter("unexpected " + parser.tokens[parser.position].text)
+	}
+	return filter, nil
+}
+
+type filterToken struct {
+	text string
+	// quoted is set for string values, text is then unquoted
+	quoted bool
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		case r == '"':
+			end := i + 1
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(runes) {
+				return nil, errInvalidFilter("unterminated string")
+			}
+			value, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return nil, errInvalidFilter("invalid string " + string(runes[i:end+1]))
+			}
+			tokens = append(tokens, filterToken{text: value, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()[]\"", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens   []filterToken
+	position int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.position >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.position], true
+}
+
+func (p *filterParser) keyword(keyword string) bool {
+	token, ok := p.peek()
+	return ok && !token.quoted && strings.EqualFold(token.text, keyword)
+}
+
+func (p *filterParser) expect(text string) error {
+	if !p.keyword(text) {
+		return errInvalidFilter("expected " + text)
+	}
+	p.position++
+	return nil
+}
+
+func (p *filterParser) parseOr() (*Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Filter{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (*Filter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.position++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &Filter{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (*Filter, error) {
+	if p.keyword("not") && p.position+1 < len(p.tokens) && p.tokens[p.position+1].text == "(" {
+		p.position++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Filter{Op: "not", Left: operand}, nil
+	}
+	return p.parseAttribute()
+}
+
+func (p *filterParser) parseAttribute() (*Filter, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, errInvalidFilter("unexpected end of filter")
+	}
+	if token.text == "(" && !token.quoted {
+		p.position++
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return filter, p.expect(")")
+	}
+	if token.quoted || strings.ContainsAny(token.text, "()[]") {
+		return nil, errInvalidFilter("expected an attribute, got " + token.text)
+	}
+	p.position++
+
+	if p.keyword("[") {
+		p.position++
+		valueFilter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &Filter{Op: "[]", Path: token.text, Left: valueFilter}, nil
+	}
+
+	operator, ok := p.peek()
+	if !ok || operator.quoted {
+		return nil, errInvalidFilter("expected an operator after " + token.text)
+	}
+	op := strings.ToLower(operator.text)
+	p.position++
+	switch op {
+	case "pr":
+		return &Filter{Op: op, Path: token.text}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, errInvalidFilter("unknown operator " + operator.text)
+	}
+
+	value, ok := p.peek()
+	if !ok {
+		return nil, errInvalidFilter("expected a value after " + operator.text)
+	}
+	p.position++
+	filter := &Filter{Op: op, Path: token.text}
+	if value.quoted {
+		filter.Value = value.text
+		return filter, nil
+	}
+	switch strings.ToLower(value.text) {
+	case "true":
+		filter.Value = true
+	case "false":
+		filter.Value = false
+	case "null":
+		filter.Value = nil
+	default:
+		number, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, errInvalidFilter("invalid value " + value.text)
+		}
+		filter.Value = number
+	}
+	return filter, nil
+}
+
+// Matches evaluates the filter against a resource or a value of a
+// multi-valued attribute, decoded from JSON. Attribute names are case
+// insensitive and strings are compared case insensitively.
+func (f *Filter) Matches(resource map[string]interface{}) bool {
+	switch f.Op {
+	case "and":
+		return f.Left.Matches(resource) && f.Right.Matches(resource)
+	case "or":
+		return f.Left.Matches(resource) || f.Right.Matches(resource)
+	case "not":
+		return !f.Left.Matches(resource)
+	case "[]":
+		for _, value := range lookupPath(resource, f.Path) {
+			if element, ok := value.(map[string]interface{}); ok && f.Left.Matches(element) {
+				return true
+			}
+		}
+		return false
+	}
+
+	values := lookupPath(resource, f.Path)
+	if f.Op == "pr" || (f.Op == "eq" && f.Value == nil) || (f.Op == "ne" && f.Value == nil) {
+		present := false
+		for _, value := range values {
+			if value != nil && value != "" {
+				present = true
+			}
+		}
+		return present == (f.Op != "eq")
+	}
+	for _, value := range values {
+		if compareValue(value, f.Op, f.Value) {
+			return true
+		}
+	}
+	return f.Op == "ne" && len(values) == 0
+}
+
+// lookupPath returns the values at path, flattening multi-valued attributes.
+func lookupPath(resource map[string]interface{}, path string) []interface{} {
+	schema, attribute := splitSchema(path)
+	current := []interface{}{resource}
+	if schema != "" {
+		if extension, ok := lookupKey(resource, schema); ok {
+			current = []interface{}{extension}
+		}
+	}
+
+	for _, name := range strings.Split(attribute, ".") {
+		next := []interface{}{}
+		for _, value := range current {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			found, ok := lookupKey(object, name)
+			if !ok {
+				continue
+			}
+			if list, ok := found.([]interface{}); ok {
+				next = append(next, list...)
+			} else {
+				next = append(next, found)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func lookupKey(object map[string]interface{}, name string) (interface{}, bool) {
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func compareValue(value interface{}, op string, expected interface{}) bool {
+	switch expected := expected.(type) {
+	case bool:
+		actual, ok := value.(bool)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return actual == expected
+		case "ne":
+			return actual != expected
+		}
+		return false
+	case float64:
+		actual, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return actual == expected
+		case "ne":
+			return actual != expected
+		case "gt":
+			return actual > expected
+		case "ge":
+			return actual >= expected
+		case "lt":
+			return actual < expected
+		case "le":
+			return actual <= expected
+		}
+		return false
+	case string:
+		actual := strings.ToLower(fmt.Sprint(value))
+		expected = strings.ToLower(expected)
+		switch op {
+		case "eq":
+			return actual == expected
+		case "ne":
+			return actual != expected
+		case "co":
+			return strings.Contains(actual, expected)
+		case "sw":
+			return strings.HasPrefix(actual, expected)
+		case "ew":
+			return strings.HasSuffix(actual, expected)
+		case "gt":
+			return actual > expected
+		case "ge":
+			return actual >= expected
+		case "lt":
+			return actual < expected
+		case "le":
+			return actual <= expected
+		}
+	}
+	return false
+}
+
+// filterColumn is where an attribute is kept. Attributes of another table set
+// Subquery, a condition on the resource with one %s for the comparison of
+// Column.
+type filterColumn struct {
+	Column   string
+	Type     string
+	Subquery string
+	// True is the value of Column that makes a boolean attribute true
+	True string
+}
+
+const (
+	ATTRIBUTE_STRING    = "string"
+	ATTRIBUTE_BOOLEAN   = "boolean"
+	ATTRIBUTE_DATE_TIME = "dateTime"
+)
+
+// filterCondition translates filter into an SQL condition on the columns of
+// the attributes, keyed by their normalized path. Filters on attributes that
+// are not in columns are refused.
+func filterCondition(filter *Filter, columns map[string]filterColumn, coreSchema string, prefix string) (string, []interface{}, error) {
+	switch filter.Op {
+	case "and", "or":
+		left, leftArgs, err := filterCondition(filter.Left, columns, coreSchema, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := filterCondition(filter.Right, columns, coreSchema, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + strings.ToUpper(filter.Op) + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case "not":
+		condition, args, err := filterCondition(filter.Left, columns, coreSchema, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + condition + ")", args, nil
+	case "[]":
+		return filterCondition(filter.Left, columns, coreSchema, prefix+filter.Path+".")
+	}
+
+	path := normalizePath(prefix+filter.Path, coreSchema)
+	column, ok := columns[path]
+	if !ok {
+		return "", nil, errInvalidFilter("filtering on " + prefix + filter.Path + " is not supported")
+	}
+	condition, args, err := compareCondition(column, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if column.Subquery != "" {
+		condition = fmt.Sprintf(column.Subquery, condition)
+	}
+	return condition, args, nil
+}
+
+func compareCondition(column filterColumn, filter *Filter) (string, []interface{}, error) {
+	op := filter.Op
+	if filter.Value == nil && op != "pr" {
+		switch op {
+		case "eq":
+			condition, args, err := compareCondition(column, &Filter{Op: "pr", Path: filter.Path})
+			return "NOT (" + condition + ")", args, err
+		case "ne":
+			op = "pr"
+		default:
+			return "", nil, errInvalidFilter(op + " null is not supported")
+		}
+	}
+
+	switch column.Type {
+	case ATTRIBUTE_BOOLEAN:
+		if op == "pr" {
+			return column.Column + " IS NOT NULL", nil, nil
+		}
+		value, ok := filter.Value.(bool)
+		if !ok || (op != "eq" && op != "ne") {
+			return "", nil, errInvalidFilter(filter.Path + " only supports eq and ne with true or false")
+		}
+		if value == (op == "eq") {
+			return column.Column + " = ?", []interface{}{column.True}, nil
+		}
+		return "(" + column.Column + " IS NULL OR " + column.Column + " <> ?)", []interface{}{column.True}, nil
+
+	case ATTRIBUTE_DATE_TIME:
+		if op == "pr" {
+			return column.Column + " IS NOT NULL", nil, nil
+		}
+		text, _ := filter.Value.(string)
+		value, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return "", nil, errInvalidFilter(filter.Path + " has to be compared with a date and time like 2024-01-31T08:00:00Z")
+		}
+		sqlOperator, ok := sqlOperators[op]
+		if !ok {
+			return "", nil, errInvalidFilter(op + " is not supported on " + filter.Path)
+		}
+		return column.Column + " " + sqlOperator + " ?", []interface{}{value}, nil
+	}
+
+	if op == "pr" {
+		return "(" + column.Column + " IS NOT NULL AND " + column.Column + " <> '')", nil, nil
+	}
+	text, ok := filter.Value.(string)
+	if !ok {
+		return "", nil, errInvalidFilter(filter.Path + " has to be compared with a string")
+	}
+	text = strings.ToLower(text)
+	lower := "LOWER(" + column.Column + ")"
+	switch op {
+	case "co":
+		return lower + " LIKE ?", []interface{}{"%" + escapeLike(text) + "%"}, nil
+	case "sw":
+		return lower + " LIKE ?", []interface{}{escapeLike(text) + "%"}, nil
+	case "ew":
+		return lower + " LIKE ?", []interface{}{"%" + escapeLike(text)}, nil
+	case "ne":
+		return "(" + column.Column + " IS NULL OR " + lower + " <> ?)", []interface{}{text}, nil
+	}
+	return lower + " " + sqlOperators[op] + " ?", []interface{}{text}, nil
+}
+
+var sqlOperators = map[string]string{
+	"eq": "=",
+	"ne": "<>",
+	"gt": ">",
+	"ge": ">=",
+	"lt": "<",
+	"le": "<=",
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// sortColumn returns the column to order a list by, for sortBy.
+func sortColumn(sortBy string, columns map[string]filterColumn, coreSchema string) (string, error) {
+	column, ok := columns[normalizePath(sortBy, coreSchema)]
+	if !ok || column.Subquery != "" || column.Type == ATTRIBUTE_BOOLEAN {
+		return "", NewSCIMError(http.StatusBadRequest, "invalidValue", "sorting by "+sortBy+" is not supported")
+	}
+	return column.Column, nil
+}
+
+// listCondition returns the SQL condition and order of the filter and sort of
+// query.
+func listCondition(query ListQuery, columns map[string]filterColumn, coreSchema string) (string, []interface{}, string, error) {
+	condition, args, order := "", []interface{}{}, ""
+	if strings.TrimSpace(query.Filter) != "" {
+		filter, err := ParseFilter(query.Filter)
+		if err != nil {
+			return "", nil, "", err
+		}
+		condition, args, err = filterCondition(filter, columns, coreSchema, "")
+		if err != nil {
+			return "", nil, "", err
+		}
+	}
+	if query.SortBy != "" {
+		column, err := sortColumn(query.SortBy, columns, coreSchema)
+		if err != nil {
+			return "", nil, "", err
+		}
+		order = column
+		if query.Descending {
+			order += " DESC"
+		}
+	}
+	return condition, args, order, nil
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       *Filter
+	}{
+		{
+			`userName eq "bjensen"`,
+			&Filter{Op: "eq", Path: "userName", Value: "bjensen"},
+		},
+		{
+			`title PR`,
+			&Filter{Op: "pr", Path: "title"},
+		},
+		{
+			`active Eq false and manager eq null`,
+			&Filter{
+				Op:    "and",
+				Left:  &Filter{Op: "eq", Path: "active", Value: false},
+				Right: &Filter{Op: "eq", Path: "manager", Value: nil},
+			},
+		},
+		{
+			// and binds tighter than or
+			`a eq 1 or b eq 2 and c eq "3"`,
+			&Filter{
+				Op:   "or",
+				Left: &Filter{Op: "eq", Path: "a", Value: float64(1)},
+				Right: &Filter{
+					Op:    "and",
+					Left:  &Filter{Op: "eq", Path: "b", Value: float64(2)},
+					Right: &Filter{Op: "eq", Path: "c", Value: "3"},
+				},
+			},
+		},
+		{
+			`not (userName sw "j") and (title pr or nickName co "\"b\"")`,
+			&Filter{
+				Op:   "and",
+				Left: &Filter{Op: "not", Left: &Filter{Op: "sw", Path: "userName", Value: "j"}},
+				Right: &Filter{
+					Op:    "or",
+					Left:  &Filter{Op: "pr", Path: "title"},
+					Right: &Filter{Op: "co", Path: "nickName", Value: `"b"`},
+				},
+			},
+		},
+		{
+			`emails[type eq "work" and value ew "@example.com"]`,
+			&Filter{
+				Op:   "[]",
+				Path: "emails",
+				Left: &Filter{
+					Op:    "and",
+					Left:  &Filter{Op: "eq", Path: "type", Value: "work"},
+					Right: &Filter{Op: "ew", Path: "value", Value: "@example.com"},
+				},
+			},
+		},
+		{
+			`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`,
+			&Filter{Op: "eq", Path: "urn:ietf:params:scim:schemas:core:2.0:User:userName", Value: "bjensen"},
+		},
+	}
+
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expression)
+		if err != nil {
+			t.Errorf("ParseFilter(%s): %v", test.expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(filter, test.want) {
+			t.Errorf("ParseFilter(%s) = %+v, want %+v", test.expression, filter, test.want)
+		}
+	}
+}
+
+func TestParseFilterRefusesInvalidFilters(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName is "bjensen"`,
+		`userName eq bjensen`,
+		`userName eq "bjensen`,
+		`"userName" eq "bjensen"`,
+		`(userName eq "bjensen"`,
+		`userName eq "bjensen")`,
+		`userName eq "bjensen" and`,
+		`emails[type eq "work"`,
+	} {
+		_, err := ParseFilter(expression)
+		var scimErr *SCIMError
+		if !errors.As(err, &scimErr) || scimErr.ScimType != "invalidFilter" {
+			t.Errorf("ParseFilter(%s) = %v, want an invalidFilter error", expression, err)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	resource := map[string]interface{}{
+		"userName": "BJensen",
+		"active":   true,
+		"emails": []interface{}{
+			map[string]interface{}{"type": "work", "value": "bjensen@example.com"},
+			map[string]interface{}{"type": "home", "value": "babs@example.org"},
+		},
+	}
+
+	tests := map[string]bool{
+		`username eq "bjensen"`:                             true,
+		`userName ne "bjensen"`:                             false,
+		`active eq true and userName sw "bj"`:               true,
+		`emails.value ew "example.org"`:                     true,
+		`emails[type eq "work" and value ew "example.org"]`: false,
+		`emails[type eq "home" and value ew "example.org"]`: true,
+		`title pr`:       false,
+		`not (title pr)`: true,
+		`title eq null`:  true,
+	}
+	for expression, want := range tests {
+		filter, err := ParseFilter(expression)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s): %v", expression, err)
+		}
+		if got := filter.Matches(resource); got != want {
+			t.Errorf("%s matches = %v, want %v", expression, got, want)
+		}
+	}
+}
+
+func TestListCondition(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2024-01-31T08:00:00Z")
+
+	tests := []struct {
+		query     ListQuery
+		condition string
+		args      []interface{}
+		order     string
+	}{
+		{
+			ListQuery{},
+			"", []interface{}{}, "",
+		},
+		{
+			ListQuery{Filter: `userName eq "BJensen"`},
+			"LOWER(users.username) = ?", []interface{}{"bjensen"}, "",
+		},
+		{
+			ListQuery{Filter: `active eq true`},
+			"users.status = ?", []interface{}{"ACTIVE"}, "",
+		},
+		{
+			ListQuery{Filter: `active eq false`},
+			"(users.status IS NULL OR users.status <> ?)", []interface{}{"ACTIVE"}, "",
+		},
+		{
+			// LIKE wildcards in the value are escaped
+			ListQuery{Filter: `emails co "50%_off"`},
+			"LOWER(users.email) LIKE ?", []interface{}{`%50\%\_off%`}, "",
+		},
+		{
+			ListQuery{Filter: `userName eq null`},
+			"NOT ((users.username IS NOT NULL AND users.username <> ''))", nil, "",
+		},
+		{
+			ListQuery{Filter: `meta.created ge "2024-01-31T08:00:00Z"`, SortBy: "meta.created", Descending: true},
+			"users.created_at >= ?", []interface{}{created}, "users.created_at DESC",
+		},
+		{
+			ListQuery{
+				Filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "b" and not (groups[display eq "Admin"])`,
+				SortBy: "userName",
+			},
+			"(LOWER(users.username) LIKE ? AND NOT (users.id IN (SELECT user_roles.user_id FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE roles.deleted_at IS NULL AND LOWER(roles.name) = ?)))",
+			[]interface{}{"b%", "admin"},
+			"users.username",
+		},
+	}
+
+	for _, test := range tests {
+		condition, args, order, err := listCondition(test.query, userColumns, SCHEMA_USER)
+		if err != nil {
+			t.Errorf("listCondition(%+v): %v", test.query, err)
+			continue
+		}
+		if condition != test.condition || !reflect.DeepEqual(args, test.args) || order != test.order {
+			t.Errorf("listCondition(%+v) = %q, %v, %q, want %q, %v, %q",
+				test.query, condition, args, order, test.condition, test.args, test.order)
+		}
+	}
+}
+
+func TestListConditionRefusesUnsupportedQueries(t *testing.T) {
+	for _, query := range []ListQuery{
+		{Filter: `password eq "secret"`},
+		{Filter: `active co "ACT"`},
+		{Filter: `meta.created gt "yesterday"`},
+		{Filter: `userName gt null`},
+		{Filter: `userName eq 1`},
+		{SortBy: "groups"},
+		{SortBy: "active"},
+	} {
+		_, _, _, err := listCondition(query, userColumns, SCHEMA_USER)
+		var scimErr *SCIMError
+		if !errors.As(err, &scimErr) || scimErr.Status != 400 {
+			t.Errorf("listCondition(%+v) = %v, want a 400 error", query, err)
+		}
+	}
+}
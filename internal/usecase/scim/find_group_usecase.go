@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IFindGroupUseCaseRequest struct {
+	ID      string `json:"id"`
+	BaseURL string `json:"base_url"`
+}
+
+type IFindGroupUseCaseResponse struct {
+	Group *GroupResource `json:"group"`
+}
+
+type IFindGroupUseCase interface {
+	Execute(request *IFindGroupUseCaseRequest) (*IFindGroupUseCaseResponse, error)
+}
+
+type FindGroupUseCase struct {
+	Log            *logrus.Logger
+	RoleRepository repository.IRoleRepository
+}
+
+func NewFindGroupUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository) IFindGroupUseCase {
+	return &FindGroupUseCase{
+		Log:            log,
+		RoleRepository: roleRepository,
+	}
+}
+
+func (uc *FindGroupUseCase) Execute(request *IFindGroupUseCaseRequest) (*IFindGroupUseCaseResponse, error) {
+	role, err := findGroup(uc.RoleRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errNotFound("Group", request.ID)
+	}
+	return &IFindGroupUseCaseResponse{
+		Group: groupResource(role, request.BaseURL),
+	}, nil
+}
+
+func FindGroupUseCaseFactory(log *logrus.Logger) IFindGroupUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	return NewFindGroupUseCase(log, roleRepository)
+}
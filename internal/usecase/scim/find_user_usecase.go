@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IFindUserUseCaseRequest struct {
+	ID      string `json:"id"`
+	BaseURL string `json:"base_url"`
+}
+
+type IFindUserUseCaseResponse struct {
+	User *UserResource `json:"user"`
+}
+
+type IFindUserUseCase interface {
+	Execute(request *IFindUserUseCaseRequest) (*IFindUserUseCaseResponse, error)
+}
+
+type FindUserUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+}
+
+func NewFindUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository) IFindUserUseCase {
+	return &FindUserUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+	}
+}
+
+func (uc *FindUserUseCase) Execute(request *IFindUserUseCaseRequest) (*IFindUserUseCaseResponse, error) {
+	user, err := findUser(uc.UserRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errNotFound("User", request.ID)
+	}
+	return &IFindUserUseCaseResponse{
+		User: userResource(user, request.BaseURL),
+	}, nil
+}
+
+func FindUserUseCaseFactory(log *logrus.Logger) IFindUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewFindUserUseCase(log, userRepository)
+}
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GroupResource is the SCIM Group of an entity.Role, its members the users
+// with the role. Roles belong to an application, named in the go-sso group
+// extension.
+type GroupResource struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []MultiValue      `json:"members,omitempty"`
+	Application *GroupApplication `json:"urn:go-sso:params:scim:schemas:extension:2.0:Group,omitempty"`
+	Meta        *Meta             `json:"meta,omitempty"`
+}
+
+type GroupApplication struct {
+	Application string `json:"application"`
+}
+
+// groupColumns are the group attributes lists can be filtered and sorted on.
+var groupColumns = map[string]filterColumn{
+	"id":                {Column: "roles.id", Type: ATTRIBUTE_STRING},
+	"displayname":       {Column: "roles.name", Type: ATTRIBUTE_STRING},
+	"members":           {Column: "user_roles.user_id", Type: ATTRIBUTE_STRING, Subquery: "roles.id IN (SELECT user_roles.role_id FROM user_roles WHERE %s)"},
+	"members.value":     {Column: "user_roles.user_id", Type: ATTRIBUTE_STRING, Subquery: "roles.id IN (SELECT user_roles.role_id FROM user_roles WHERE %s)"},
+	"meta.created":      {Column: "roles.created_at", Type: ATTRIBUTE_DATE_TIME},
+	"meta.lastmodified": {Column: "roles.updated_at", Type: ATTRIBUTE_DATE_TIME},
+	strings.ToLower(SCHEMA_GROUP_APPLICATION) + ":application": {Column: "applications.name", Type: ATTRIBUTE_STRING, Subquery: "roles.application_id IN (SELECT applications.id FROM applications WHERE %s)"},
+}
+
+func groupLocation(baseURL string, id uuid.UUID) string {
+	return baseURL + "/Groups/" + id.String()
+}
+
+// groupResource maps role, loaded by RoleRepository.FindAllByCondition, to
+// its SCIM Group.
+func groupResource(role *entity.Role, baseURL string) *GroupResource {
+	resource := &GroupResource{
+		Schemas:     []string{SCHEMA_GROUP, SCHEMA_GROUP_APPLICATION},
+		ID:          role.ID.String(),
+		DisplayName: role.Name,
+		Members:     []MultiValue{},
+		Application: &GroupApplication{Application: role.Application.Name},
+	}
+	meta := newMeta("Group", groupLocation(baseURL, role.ID), role.CreatedAt, role.UpdatedAt)
+	resource.Meta = &meta
+	for _, user := range role.Users {
+		resource.Members = append(resource.Members, MultiValue{
+			Value:   user.ID.String(),
+			Display: user.Name,
+			Type:    "User",
+			Ref:     userLocation(baseURL, user.ID),
+		})
+	}
+	return resource
+}
+
+// findGroup returns the role of id, nil when there is none.
+func findGroup(roleRepository repository.IRoleRepository, id string) (*entity.Role, error) {
+	roleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+	roles, _, err := roleRepository.FindAllByCondition("roles.id = ?", []interface{}{roleID}, "", 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, nil
+	}
+	return &roles[0], nil
+}
+
+// saveGroup creates the role of resource in its application, or renames role
+// and replaces its users with the members of resource. The application of a
+// role cannot change; defaultApplication is used for new groups that do not
+// name one.
+func saveGroup(roleRepository repository.IRoleRepository, applicationRepository repository.IApplicationRepository, userRepository repository.IUserRepository, resource *GroupResource, role *entity.Role, defaultApplication string) (*entity.Role, error) {
+	displayName := strings.TrimSpace(resource.DisplayName)
+	if displayName == "" {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+
+	applicationID := uuid.Nil
+	if role != nil {
+		applicationID = role.ApplicationID
+	} else {
+		applicationName := defaultApplication
+		if resource.Application != nil && resource.Application.Application != "" {
+			applicationName = resource.Application.Application
+		}
+		if applicationName == "" {
+			return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "the application of the group is required")
+		}
+		application, err := applicationRepository.FindApplicationByName(applicationName)
+		if err != nil || application == nil {
+			return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "application "+applicationName+" not found")
+		}
+		applicationID = application.ID
+	}
+
+	condition := "roles.application_id = ? AND LOWER(roles.name) = ?"
+	args := []interface{}{applicationID, strings.ToLower(displayName)}
+	if role != nil {
+		condition += " AND roles.id <> ?"
+		args = append(args, role.ID)
+	}
+	_, taken, err := roleRepository.FindAllByCondition(condition, args, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "the application already has a group named "+displayName)
+	}
+
+	memberIDs, err := groupMemberIDs(userRepository, resource.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		role, err = roleRepository.StoreRole(&entity.Role{
+			Name:          displayName,
+			GuardName:     "web",
+			ApplicationID: applicationID,
+			Status:        entity.ROLE_ACTIVE,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if role.Name != displayName {
+		if _, err := roleRepository.UpdateRole(&entity.Role{ID: role.ID, Name: displayName}); err != nil {
+			return nil, err
+		}
+	}
+
+	current := map[uuid.UUID]bool{}
+	for _, user := range role.Users {
+		current[user.ID] = true
+	}
+	addUserIDs := []uuid.UUID{}
+	for userID := range memberIDs {
+		if !current[userID] {
+			addUserIDs = append(addUserIDs, userID)
+		}
+	}
+	removeUserIDs := []uuid.UUID{}
+	for userID := range current {
+		if !memberIDs[userID] {
+			removeUserIDs = append(removeUserIDs, userID)
+		}
+	}
+	if len(addUserIDs) > 0 || len(removeUserIDs) > 0 {
+		if err := roleRepository.UpdateRoleUsers(role.ID, addUserIDs, removeUserIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return findGroup(roleRepository, role.ID.String())
+}
+
+// groupMemberIDs returns the users of members, refusing members that are not
+// users.
+func groupMemberIDs(userRepository repository.IUserRepository, members []MultiValue) (map[uuid.UUID]bool, error) {
+	memberIDs := map[uuid.UUID]bool{}
+	for _, member := range members {
+		userID, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "member "+member.Value+" is not a user")
+		}
+		memberIDs[userID] = true
+	}
+	if len(memberIDs) == 0 {
+		return memberIDs, nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(memberIDs))
+	for userID := range memberIDs {
+		userIDs = append(userIDs, userID)
+	}
+	_, found, err := userRepository.FindAllByCondition("users.id IN ?", []interface{}{userIDs}, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if found != int64(len(userIDs)) {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "some members are not users")
+	}
+	return memberIDs, nil
+}
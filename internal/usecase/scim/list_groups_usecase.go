@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IListGroupsUseCaseRequest struct {
+	Query   ListQuery `json:"query"`
+	BaseURL string    `json:"base_url"`
+}
+
+type IListGroupsUseCaseResponse struct {
+	TotalResults int64            `json:"total_results"`
+	StartIndex   int              `json:"start_index"`
+	Groups       []*GroupResource `json:"groups"`
+}
+
+type IListGroupsUseCase interface {
+	Execute(request *IListGroupsUseCaseRequest) (*IListGroupsUseCaseResponse, error)
+}
+
+type ListGroupsUseCase struct {
+	Log            *logrus.Logger
+	RoleRepository repository.IRoleRepository
+}
+
+func NewListGroupsUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository) IListGroupsUseCase {
+	return &ListGroupsUseCase{
+		Log:            log,
+		RoleRepository: roleRepository,
+	}
+}
+
+// Execute returns a page of the roles matching the filter of the query.
+func (uc *ListGroupsUseCase) Execute(request *IListGroupsUseCaseRequest) (*IListGroupsUseCaseResponse, error) {
+	condition, args, order, err := listCondition(request.Query, groupColumns, SCHEMA_GROUP)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count := request.Query.page()
+	roles, total, err := uc.RoleRepository.FindAllByCondition(condition, args, order, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*GroupResource, 0, len(roles))
+	for i := range roles {
+		resources = append(resources, groupResource(&roles[i], request.BaseURL))
+	}
+	return &IListGroupsUseCaseResponse{
+		TotalResults: total,
+		StartIndex:   startIndex,
+		Groups:       resources,
+	}, nil
+}
+
+func ListGroupsUseCaseFactory(log *logrus.Logger) IListGroupsUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	return NewListGroupsUseCase(log, roleRepository)
+}
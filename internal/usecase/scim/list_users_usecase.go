@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IListUsersUseCaseRequest struct {
+	Query   ListQuery `json:"query"`
+	BaseURL string    `json:"base_url"`
+}
+
+type IListUsersUseCaseResponse struct {
+	TotalResults int64           `json:"total_results"`
+	StartIndex   int             `json:"start_index"`
+	Users        []*UserResource `json:"users"`
+}
+
+type IListUsersUseCase interface {
+	Execute(request *IListUsersUseCaseRequest) (*IListUsersUseCaseResponse, error)
+}
+
+type ListUsersUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+}
+
+func NewListUsersUseCase(log *logrus.Logger, userRepository repository.IUserRepository) IListUsersUseCase {
+	return &ListUsersUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+	}
+}
+
+// Execute returns a page of the users matching the filter of the query.
+func (uc *ListUsersUseCase) Execute(request *IListUsersUseCaseRequest) (*IListUsersUseCaseResponse, error) {
+	condition, args, order, err := listCondition(request.Query, userColumns, SCHEMA_USER)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count := request.Query.page()
+	users, total, err := uc.UserRepository.FindAllByCondition(condition, args, order, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*UserResource, 0, len(users))
+	for i := range users {
+		resources = append(resources, userResource(&users[i], request.BaseURL))
+	}
+	return &IListUsersUseCaseResponse{
+		TotalResults: total,
+		StartIndex:   startIndex,
+		Users:        resources,
+	}, nil
+}
+
+func ListUsersUseCaseFactory(log *logrus.Logger) IListUsersUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewListUsersUseCase(log, userRepository)
+}
@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PatchOperation is an operation of a PatchOp request, RFC 7644 section
+// 3.5.2. Op is add, replace or remove, in any case.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func errInvalidPath(detail string) *SCIMError {
+	return NewSCIMError(http.StatusBadRequest, "invalidPath", detail)
+}
+
+// patchPath is a parsed PATCH path: the attribute in the container of Schema,
+// optionally a filter on its values and a sub-attribute.
+type patchPath struct {
+	Schema       string
+	Attribute    string
+	Filter       *Filter
+	SubAttribute string
+}
+
+// parsePatchPath parses attrPath, valuePath and valuePath.subAttr paths.
+// Paths in the core schema lose their URI, extension attributes keep it in
+// Schema. A path that is only an extension URI has no Attribute.
+func parsePatchPath(path string, coreSchema string, extensions []string) (*patchPath, error) {
+	for _, extension := range extensions {
+		if strings.EqualFold(path, extension) {
+			return &patchPath{Schema: extension}, nil
+		}
+	}
+
+	parsed := &patchPath{}
+	attribute := path
+	if open := strings.Index(path, "["); open >= 0 {
+		closing := strings.LastIndex(path, "]")
+		if closing < open {
+			return nil, errInvalidPath("unbalanced brackets in " + path)
+		}
+		filter, err := ParseFilter(path[open+1 : closing])
+		if err != nil {
+			return nil, errInvalidPath("invalid filter in " + path)
+		}
+		parsed.Filter = filter
+		attribute = path[:open]
+		rest := path[closing+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+				return nil, errInvalidPath("invalid sub-attribute in " + path)
+			}
+			parsed.SubAttribute = rest[1:]
+		}
+	}
+
+	schema, name := splitSchema(attribute)
+	if !strings.EqualFold(schema, coreSchema) {
+		parsed.Schema = schema
+	}
+	if parsed.Filter == nil {
+		if dot := strings.Index(name, "."); dot >= 0 {
+			parsed.SubAttribute = name[dot+1:]
+			name = name[:dot]
+		}
+	}
+	if name == "" {
+		return nil, errInvalidPath("no attribute in " + path)
+	}
+	parsed.Attribute = name
+	return parsed, nil
+}
+
+// applyPatch applies operations to the JSON document of a resource. Unknown
+// attributes are added to the document and dropped when it is decoded back.
+func applyPatch(document map[string]interface{}, operations []PatchOperation, coreSchema string, extensions []string) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return NewSCIMError(http.StatusBadRequest, "invalidSyntax", "unknown operation "+operation.Op)
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return NewSCIMError(http.StatusBadRequest, "noTarget", "remove needs a path")
+			}
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return NewSCIMError(http.StatusBadRequest, "invalidValue", op+" without a path needs an object")
+			}
+			for key, value := range values {
+				// extensions are objects of their attributes, other keys
+				// may be paths like name.givenName
+				if extension, ok := value.(map[string]interface{}); ok && strings.HasPrefix(strings.ToLower(key), "urn:") {
+					for name, extensionValue := range extension {
+						if err := applyOperation(document, op, key+":"+name, extensionValue, coreSchema, extensions); err != nil {
+							return err
+						}
+					}
+					continue
+				}
+				if err := applyOperation(document, op, key, value, coreSchema, extensions); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := applyOperation(document, op, operation.Path, operation.Value, coreSchema, extensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(document map[string]interface{}, op string, path string, value interface{}, coreSchema string, extensions []string) error {
+	parsed, err := parsePatchPath(path, coreSchema, extensions)
+	if err != nil {
+		return err
+	}
+
+	container := document
+	if parsed.Schema != "" {
+		key := documentKey(document, parsed.Schema)
+		extension, ok := document[key].(map[string]interface{})
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			extension = map[string]interface{}{}
+			document[key] = extension
+		}
+		container = extension
+	}
+
+	if parsed.Attribute == "" {
+		if op == "remove" {
+			delete(document, documentKey(document, parsed.Schema))
+			return nil
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return NewSCIMError(http.StatusBadRequest, "invalidValue", path+" needs an object")
+		}
+		for name, attributeValue := range values {
+			setAttribute(container, op, name, attributeValue)
+		}
+		return nil
+	}
+
+	key := documentKey(container, parsed.Attribute)
+	if parsed.Filter != nil {
+		return applyFilteredOperation(container, key, op, parsed, value)
+	}
+
+	if parsed.SubAttribute == "" {
+		if op == "remove" {
+			removeAttribute(container, key, value)
+			return nil
+		}
+		setAttribute(container, op, key, value)
+		return nil
+	}
+
+	switch current := container[key].(type) {
+	case []interface{}:
+		// a sub-attribute of a multi-valued attribute without a filter is
+		// the sub-attribute of every value
+		for _, element := range current {
+			if object, ok := element.(map[string]interface{}); ok {
+				setSubAttribute(object, op, parsed.SubAttribute, value)
+			}
+		}
+	case map[string]interface{}:
+		setSubAttribute(current, op, parsed.SubAttribute, value)
+	default:
+		if op != "remove" {
+			container[key] = map[string]interface{}{parsed.SubAttribute: value}
+		}
+	}
+	return nil
+}
+
+// applyFilteredOperation applies an operation on the values of a multi-valued
+// attribute the filter of path matches. Add and replace on a sub-attribute
+// without a matching value add a value built from the equality tests of the
+// filter, as clients set emails[type eq "work"].value on users without one.
+func applyFilteredOperation(container map[string]interface{}, key string, op string, path *patchPath, value interface{}) error {
+	values, _ := container[key].([]interface{})
+	kept := []interface{}{}
+	matched := false
+	for _, element := range values {
+		object, ok := element.(map[string]interface{})
+		if !ok || !path.Filter.Matches(object) {
+			kept = append(kept, element)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && path.SubAttribute == "":
+			continue
+		case path.SubAttribute != "":
+			setSubAttribute(object, op, path.SubAttribute, value)
+		case op == "replace":
+			if replacement, ok := value.(map[string]interface{}); ok {
+				element = replacement
+			}
+		default:
+			if additions, ok := value.(map[string]interface{}); ok {
+				for name, addition := range additions {
+					object[documentKey(object, name)] = addition
+				}
+			}
+		}
+		kept = append(kept, element)
+	}
+
+	if !matched && op != "remove" {
+		element := filterEqualities(path.Filter)
+		if element == nil || path.SubAttribute == "" {
+			return NewSCIMError(http.StatusBadRequest, "noTarget", "no value of "+key+" matches the filter")
+		}
+		element[path.SubAttribute] = value
+		kept = append(kept, element)
+	}
+	container[key] = kept
+	return nil
+}
+
+// filterEqualities returns the attributes a filter of eq tests joined with
+// and requires, nil for other filters.
+func filterEqualities(filter *Filter) map[string]interface{} {
+	switch filter.Op {
+	case "eq":
+		if strings.Contains(filter.Path, ".") {
+			return nil
+		}
+		return map[string]interface{}{filter.Path: filter.Value}
+	case "and":
+		left := filterEqualities(filter.Left)
+		right := filterEqualities(filter.Right)
+		if left == nil || right == nil {
+			return nil
+		}
+		for name, value := range right {
+			left[name] = value
+		}
+		return left
+	}
+	return nil
+}
+
+// setAttribute replaces an attribute, or for add appends to a multi-valued
+// attribute and merges into a complex one.
+func setAttribute(container map[string]interface{}, op string, name string, value interface{}) {
+	if dot := strings.Index(name, "."); dot >= 0 && !strings.HasPrefix(strings.ToLower(name), "urn:") {
+		key := documentKey(container, name[:dot])
+		object, ok := container[key].(map[string]interface{})
+		if !ok {
+			object = map[string]interface{}{}
+			container[key] = object
+		}
+		setSubAttribute(object, op, name[dot+1:], value)
+		return
+	}
+
+	key := documentKey(container, name)
+	if op == "add" {
+		switch current := container[key].(type) {
+		case []interface{}:
+			if additions, ok := value.([]interface{}); ok {
+				container[key] = append(current, additions...)
+			} else {
+				container[key] = append(current, value)
+			}
+			return
+		case map[string]interface{}:
+			if additions, ok := value.(map[string]interface{}); ok {
+				for subName, addition := range additions {
+					current[documentKey(current, subName)] = addition
+				}
+				return
+			}
+		}
+	}
+	container[key] = value
+}
+
+func setSubAttribute(object map[string]interface{}, op string, name string, value interface{}) {
+	key := documentKey(object, name)
+	if op == "remove" {
+		delete(object, key)
+		return
+	}
+	object[key] = value
+}
+
+// removeAttribute removes an attribute. With a value, only the values of a
+// multi-valued attribute with the same value are removed, as some clients
+// remove group members this way rather than with a filter.
+func removeAttribute(container map[string]interface{}, key string, value interface{}) {
+	current, isList := container[key].([]interface{})
+	removals, hasRemovals := value.([]interface{})
+	if !isList || !hasRemovals {
+		delete(container, key)
+		return
+	}
+
+	removed := map[string]bool{}
+	for _, removal := range removals {
+		if object, ok := removal.(map[string]interface{}); ok {
+			if removedValue, ok := lookupKey(object, "value"); ok {
+				removed[strings.ToLower(stringValue(removedValue))] = true
+			}
+		}
+	}
+	kept := []interface{}{}
+	for _, element := range current {
+		if object, ok := element.(map[string]interface{}); ok {
+			if elementValue, ok := lookupKey(object, "value"); ok && removed[strings.ToLower(stringValue(elementValue))] {
+				continue
+			}
+		}
+		kept = append(kept, element)
+	}
+	container[key] = kept
+}
+
+func stringValue(value interface{}) string {
+	text, _ := value.(string)
+	return text
+}
+
+// documentKey returns the key of name in object, matched case insensitively,
+// or name when object does not have it.
+func documentKey(object map[string]interface{}, name string) string {
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IPatchGroupUseCaseRequest struct {
+	ID         string           `json:"id"`
+	Operations []PatchOperation `json:"operations"`
+	BaseURL    string           `json:"base_url"`
+}
+
+type IPatchGroupUseCaseResponse struct {
+	Group *GroupResource `json:"group"`
+}
+
+type IPatchGroupUseCase interface {
+	Execute(request *IPatchGroupUseCaseRequest) (*IPatchGroupUseCaseResponse, error)
+}
+
+type PatchGroupUseCase struct {
+	Log                   *logrus.Logger
+	RoleRepository        repository.IRoleRepository
+	ApplicationRepository repository.IApplicationRepository
+	UserRepository        repository.IUserRepository
+}
+
+func NewPatchGroupUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository, applicationRepository repository.IApplicationRepository, userRepository repository.IUserRepository) IPatchGroupUseCase {
+	return &PatchGroupUseCase{
+		Log:                   log,
+		RoleRepository:        roleRepository,
+		ApplicationRepository: applicationRepository,
+		UserRepository:        userRepository,
+	}
+}
+
+// Execute applies the operations to the SCIM Group of the role, usually adds
+// and removes of members, and saves the result as a replacement.
+func (uc *PatchGroupUseCase) Execute(request *IPatchGroupUseCaseRequest) (*IPatchGroupUseCaseResponse, error) {
+	role, err := findGroup(uc.RoleRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errNotFound("Group", request.ID)
+	}
+
+	document, err := encodeResource(groupResource(role, request.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(document, request.Operations, SCHEMA_GROUP, []string{SCHEMA_GROUP_APPLICATION}); err != nil {
+		return nil, err
+	}
+	patched := &GroupResource{}
+	if err := decodeResource(document, patched); err != nil {
+		return nil, err
+	}
+
+	role, err = saveGroup(uc.RoleRepository, uc.ApplicationRepository, uc.UserRepository, patched, role, "")
+	if err != nil {
+		return nil, err
+	}
+	return &IPatchGroupUseCaseResponse{
+		Group: groupResource(role, request.BaseURL),
+	}, nil
+}
+
+func PatchGroupUseCaseFactory(log *logrus.Logger) IPatchGroupUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewPatchGroupUseCase(log, roleRepository, applicationRepository, userRepository)
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IPatchUserUseCaseRequest struct {
+	ID         string           `json:"id"`
+	Operations []PatchOperation `json:"operations"`
+	BaseURL    string           `json:"base_url"`
+}
+
+type IPatchUserUseCaseResponse struct {
+	User *UserResource `json:"user"`
+}
+
+type IPatchUserUseCase interface {
+	Execute(request *IPatchUserUseCaseRequest) (*IPatchUserUseCaseResponse, error)
+}
+
+type PatchUserUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+	PasswordHasher utils.PasswordHasher
+}
+
+func NewPatchUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository, passwordHasher utils.PasswordHasher) IPatchUserUseCase {
+	return &PatchUserUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+		PasswordHasher: passwordHasher,
+	}
+}
+
+// Execute applies the operations to the SCIM User of the user and saves the
+// result as a replacement. Operations on read-only attributes have no effect.
+func (uc *PatchUserUseCase) Execute(request *IPatchUserUseCaseRequest) (*IPatchUserUseCaseResponse, error) {
+	user, err := findUser(uc.UserRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errNotFound("User", request.ID)
+	}
+
+	current := userResource(user, request.BaseURL)
+	document, err := encodeResource(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(document, request.Operations, SCHEMA_USER, []string{SCHEMA_ENTERPRISE_USER}); err != nil {
+		return nil, err
+	}
+	patched := &UserResource{}
+	if err := decodeResource(document, patched); err != nil {
+		return nil, err
+	}
+	patchedName(current, patched)
+
+	user, err = saveUser(uc.UserRepository, uc.PasswordHasher, patched, user)
+	if err != nil {
+		return nil, err
+	}
+	return &IPatchUserUseCaseResponse{
+		User: userResource(user, request.BaseURL),
+	}, nil
+}
+
+// patchedName keeps the name the operations changed. The name is returned
+// both as displayName and name.formatted, so a patch of displayName, or of
+// givenName and familyName, would otherwise lose to the unchanged formatted
+// name.
+func patchedName(current *UserResource, patched *UserResource) {
+	formatted := ""
+	if patched.Name != nil {
+		formatted = patched.Name.Formatted
+	}
+	if current.Name != nil && formatted != current.Name.Formatted {
+		return
+	}
+	if patched.DisplayName != current.DisplayName {
+		patched.Name = &UserName{Formatted: patched.DisplayName}
+		return
+	}
+	if patched.Name != nil && (patched.Name.GivenName != "" || patched.Name.FamilyName != "") {
+		patched.Name.Formatted = ""
+	}
+}
+
+func PatchUserUseCaseFactory(log *logrus.Logger) IPatchUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewPatchUserUseCase(log, userRepository, passwordHasher)
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"strings"
+)
+
+// Project returns the JSON document of resource with only the attributes
+// listed in attributes, or without those in excludedAttributes, as asked by
+// the query parameters of RFC 7644 section 3.9. Paths are comma separated and
+// may name sub-attributes and extension attributes; schemas and id are always
+// returned.
+func Project(resource interface{}, attributes string, excludedAttributes string, coreSchema string) (map[string]interface{}, error) {
+	document, err := encodeResource(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if included := splitAttributes(attributes); len(included) > 0 {
+		projected := map[string]interface{}{}
+		for _, name := range []string{"schemas", "id"} {
+			if value, ok := document[name]; ok {
+				projected[name] = value
+			}
+		}
+		for _, path := range included {
+			copyPath(document, projected, path, coreSchema)
+		}
+		return projected, nil
+	}
+
+	for _, path := range splitAttributes(excludedAttributes) {
+		removePath(document, path, coreSchema)
+	}
+	return document, nil
+}
+
+func splitAttributes(attributes string) []string {
+	paths := []string{}
+	for _, path := range strings.Split(attributes, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// resolvePath finds the container of path in document and the attribute and
+// sub-attribute it names. A path that is a whole extension has no attribute.
+func resolvePath(document map[string]interface{}, path string, coreSchema string) (string, string, string) {
+	if key := documentKey(document, path); strings.HasPrefix(strings.ToLower(key), "urn:") {
+		if _, ok := document[key]; ok {
+			return key, "", ""
+		}
+	}
+	schema, attribute := splitSchema(path)
+	if strings.EqualFold(schema, coreSchema) {
+		schema = ""
+	}
+	subAttribute := ""
+	if dot := strings.Index(attribute, "."); dot >= 0 {
+		attribute, subAttribute = attribute[:dot], attribute[dot+1:]
+	}
+	return schema, attribute, subAttribute
+}
+
+func copyPath(document map[string]interface{}, projected map[string]interface{}, path string, coreSchema string) {
+	schema, attribute, subAttribute := resolvePath(document, path, coreSchema)
+
+	source, target := document, projected
+	if schema != "" {
+		key := documentKey(document, schema)
+		extension, ok := document[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		if attribute == "" {
+			projected[key] = extension
+			return
+		}
+		targetExtension, ok := projected[key].(map[string]interface{})
+		if !ok {
+			targetExtension = map[string]interface{}{}
+			projected[key] = targetExtension
+		}
+		source, target = extension, targetExtension
+	}
+
+	key := documentKey(source, attribute)
+	value, ok := source[key]
+	if !ok {
+		return
+	}
+	if subAttribute == "" {
+		target[key] = value
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		object, ok := target[key].(map[string]interface{})
+		if !ok {
+			object = map[string]interface{}{}
+			target[key] = object
+		}
+		if subKey := documentKey(value, subAttribute); value[subKey] != nil {
+			object[subKey] = value[subKey]
+		}
+	case []interface{}:
+		list, ok := target[key].([]interface{})
+		if !ok || len(list) != len(value) {
+			list = make([]interface{}, len(value))
+			for i := range list {
+				list[i] = map[string]interface{}{}
+			}
+			target[key] = list
+		}
+		for i, element := range value {
+			object, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if subKey := documentKey(object, subAttribute); object[subKey] != nil {
+				list[i].(map[string]interface{})[subKey] = object[subKey]
+			}
+		}
+	}
+}
+
+func removePath(document map[string]interface{}, path string, coreSchema string) {
+	schema, attribute, subAttribute := resolvePath(document, path, coreSchema)
+
+	container := document
+	if schema != "" {
+		key := documentKey(document, schema)
+		if attribute == "" {
+			delete(document, key)
+			return
+		}
+		extension, ok := document[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		container = extension
+	}
+
+	key := documentKey(container, attribute)
+	if schema == "" && (strings.EqualFold(key, "id") || strings.EqualFold(key, "schemas")) {
+		return
+	}
+	if subAttribute == "" {
+		delete(container, key)
+		return
+	}
+	switch value := container[key].(type) {
+	case map[string]interface{}:
+		delete(value, documentKey(value, subAttribute))
+	case []interface{}:
+		for _, element := range value {
+			if object, ok := element.(map[string]interface{}); ok {
+				delete(object, documentKey(object, subAttribute))
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IReplaceGroupUseCaseRequest struct {
+	ID      string         `json:"id"`
+	Group   *GroupResource `json:"group"`
+	BaseURL string         `json:"base_url"`
+}
+
+type IReplaceGroupUseCaseResponse struct {
+	Group *GroupResource `json:"group"`
+}
+
+type IReplaceGroupUseCase interface {
+	Execute(request *IReplaceGroupUseCaseRequest) (*IReplaceGroupUseCaseResponse, error)
+}
+
+type ReplaceGroupUseCase struct {
+	Log                   *logrus.Logger
+	RoleRepository        repository.IRoleRepository
+	ApplicationRepository repository.IApplicationRepository
+	UserRepository        repository.IUserRepository
+}
+
+func NewReplaceGroupUseCase(log *logrus.Logger, roleRepository repository.IRoleRepository, applicationRepository repository.IApplicationRepository, userRepository repository.IUserRepository) IReplaceGroupUseCase {
+	return &ReplaceGroupUseCase{
+		Log:                   log,
+		RoleRepository:        roleRepository,
+		ApplicationRepository: applicationRepository,
+		UserRepository:        userRepository,
+	}
+}
+
+// Execute renames the role and replaces its users with the members of the
+// group; members left out lose the role.
+func (uc *ReplaceGroupUseCase) Execute(request *IReplaceGroupUseCaseRequest) (*IReplaceGroupUseCaseResponse, error) {
+	role, err := findGroup(uc.RoleRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errNotFound("Group", request.ID)
+	}
+
+	role, err = saveGroup(uc.RoleRepository, uc.ApplicationRepository, uc.UserRepository, request.Group, role, "")
+	if err != nil {
+		return nil, err
+	}
+	return &IReplaceGroupUseCaseResponse{
+		Group: groupResource(role, request.BaseURL),
+	}, nil
+}
+
+func ReplaceGroupUseCaseFactory(log *logrus.Logger) IReplaceGroupUseCase {
+	roleRepository := repository.RoleRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	userRepository := repository.UserRepositoryFactory(log)
+	return NewReplaceGroupUseCase(log, roleRepository, applicationRepository, userRepository)
+}
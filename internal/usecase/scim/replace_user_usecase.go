@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IReplaceUserUseCaseRequest struct {
+	ID      string        `json:"id"`
+	User    *UserResource `json:"user"`
+	BaseURL string        `json:"base_url"`
+}
+
+type IReplaceUserUseCaseResponse struct {
+	User *UserResource `json:"user"`
+}
+
+type IReplaceUserUseCase interface {
+	Execute(request *IReplaceUserUseCaseRequest) (*IReplaceUserUseCaseResponse, error)
+}
+
+type ReplaceUserUseCase struct {
+	Log            *logrus.Logger
+	UserRepository repository.IUserRepository
+	PasswordHasher utils.PasswordHasher
+}
+
+func NewReplaceUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository, passwordHasher utils.PasswordHasher) IReplaceUserUseCase {
+	return &ReplaceUserUseCase{
+		Log:            log,
+		UserRepository: userRepository,
+		PasswordHasher: passwordHasher,
+	}
+}
+
+// Execute replaces the writable attributes of the user; the ones the
+// resource leaves out are cleared, except active which is then kept.
+func (uc *ReplaceUserUseCase) Execute(request *IReplaceUserUseCaseRequest) (*IReplaceUserUseCaseResponse, error) {
+	user, err := findUser(uc.UserRepository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errNotFound("User", request.ID)
+	}
+
+	user, err = saveUser(uc.UserRepository, uc.PasswordHasher, request.User, user)
+	if err != nil {
+		return nil, err
+	}
+	return &IReplaceUserUseCaseResponse{
+		User: userResource(user, request.BaseURL),
+	}, nil
+}
+
+func ReplaceUserUseCaseFactory(log *logrus.Logger) IReplaceUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewReplaceUserUseCase(log, userRepository, passwordHasher)
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Boolean decodes true and false, and the "True" and "False" strings some
+// clients send.
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case bool:
+		*b = Boolean(value)
+		return nil
+	case string:
+		switch strings.ToLower(value) {
+		case "true":
+			*b = true
+			return nil
+		case "false":
+			*b = false
+			return nil
+		}
+	}
+	return NewSCIMError(http.StatusBadRequest, "invalidValue", string(data)+" is not a boolean")
+}
+
+// MultiValue is a value of a multi-valued attribute like emails or members.
+type MultiValue struct {
+	Value   string  `json:"value"`
+	Display string  `json:"display,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Primary Boolean `json:"primary,omitempty"`
+	Ref     string  `json:"$ref,omitempty"`
+}
+
+// primaryValue returns the primary value of values, the first one of
+// preferredType, or the first one.
+func primaryValue(values []MultiValue, preferredType string) string {
+	for _, value := range values {
+		if value.Primary && value.Value != "" {
+			return value.Value
+		}
+	}
+	for _, value := range values {
+		if strings.EqualFold(value.Type, preferredType) && value.Value != "" {
+			return value.Value
+		}
+	}
+	for _, value := range values {
+		if value.Value != "" {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// decodeResource decodes a JSON document into a resource, reporting
+// malformed documents as invalidSyntax.
+func decodeResource(document interface{}, resource interface{}) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return NewSCIMError(http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		var scimErr *SCIMError
+		if errors.As(err, &scimErr) {
+			return scimErr
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return NewSCIMError(http.StatusBadRequest, "invalidSyntax", typeErr.Field+" has the wrong type")
+		}
+		return NewSCIMError(http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+	return nil
+}
+
+// encodeResource turns a resource into the JSON document PATCH operations
+// and attribute selection work on.
+func encodeResource(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
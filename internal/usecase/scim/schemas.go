@@ -0,0 +1,258 @@
+package usecase
+
+import "strings"
+
+// SchemaAttribute describes an attribute in a Schema, RFC 7643 section 7.
+type SchemaAttribute struct {
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	MultiValued    bool              `json:"multiValued"`
+	Description    string            `json:"description,omitempty"`
+	Required       bool              `json:"required"`
+	CaseExact      bool              `json:"caseExact"`
+	Mutability     string            `json:"mutability"`
+	Returned       string            `json:"returned"`
+	Uniqueness     string            `json:"uniqueness"`
+	ReferenceTypes []string          `json:"referenceTypes,omitempty"`
+	SubAttributes  []SchemaAttribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  []SchemaAttribute `json:"attributes"`
+	Meta        Meta              `json:"meta"`
+}
+
+type ResourceType struct {
+	Schemas          []string                      `json:"schemas"`
+	ID               string                        `json:"id"`
+	Name             string                        `json:"name"`
+	Endpoint         string                        `json:"endpoint"`
+	Description      string                        `json:"description"`
+	Schema           string                        `json:"schema"`
+	SchemaExtensions []ResourceTypeSchemaExtension `json:"schemaExtensions"`
+	Meta             Meta                          `json:"meta"`
+}
+
+type ResourceTypeSchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// attribute returns a single-valued, optional, case insensitive attribute
+// that is returned by default.
+func attribute(name string, attributeType string, mutability string, description string) SchemaAttribute {
+	return SchemaAttribute{
+		Name:        name,
+		Type:        attributeType,
+		Description: description,
+		Mutability:  mutability,
+		Returned:    "default",
+		Uniqueness:  "none",
+	}
+}
+
+func multiValuedAttribute(name string, mutability string, description string, subAttributes ...SchemaAttribute) SchemaAttribute {
+	multiValued := attribute(name, "complex", mutability, description)
+	multiValued.MultiValued = true
+	multiValued.SubAttributes = subAttributes
+	return multiValued
+}
+
+func userSchema() Schema {
+	userName := attribute("userName", "string", "readWrite", "Unique login name of the user.")
+	userName.Required = true
+	userName.Uniqueness = "server"
+
+	name := attribute("name", "complex", "readWrite", "Full name of the user, kept as one formatted name.")
+	name.SubAttributes = []SchemaAttribute{
+		attribute("formatted", "string", "readWrite", "Full name, used before givenName and familyName."),
+		attribute("familyName", "string", "writeOnly", "Family name, joined to givenName when formatted is not given."),
+		attribute("givenName", "string", "writeOnly", "Given name."),
+	}
+
+	emails := multiValuedAttribute("emails", "readWrite", "Email of the user; the primary one, or the first, is kept.",
+		attribute("value", "string", "readWrite", ""),
+		attribute("type", "string", "readWrite", ""),
+		attribute("primary", "boolean", "readWrite", ""),
+	)
+	emails.Required = true
+
+	phoneNumbers := multiValuedAttribute("phoneNumbers", "readWrite", "Mobile phone of the user; the primary one, the mobile one or the first is kept.",
+		attribute("value", "string", "readWrite", ""),
+		attribute("type", "string", "readWrite", ""),
+		attribute("primary", "boolean", "readWrite", ""),
+	)
+
+	groups := multiValuedAttribute("groups", "readOnly", "Roles of the user, changed through the members of Groups.",
+		attribute("value", "string", "readOnly", ""),
+		attribute("$ref", "reference", "readOnly", ""),
+		attribute("display", "string", "readOnly", ""),
+		attribute("type", "string", "readOnly", ""),
+	)
+
+	return Schema{
+		ID:          SCHEMA_USER,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []SchemaAttribute{
+			userName,
+			name,
+			attribute("displayName", "string", "readWrite", "Name of the user, used when name is not given."),
+			attribute("title", "string", "readOnly", "Job of the employee of the user."),
+			attribute("active", "boolean", "readWrite", "Whether the status of the user is ACTIVE; false sets it to INACTIVE."),
+			emails,
+			phoneNumbers,
+			groups,
+		},
+	}
+}
+
+func enterpriseUserSchema() Schema {
+	manager := attribute("manager", "complex", "readOnly", "User of an employee holding the parent job.")
+	manager.SubAttributes = []SchemaAttribute{
+		attribute("value", "string", "readOnly", ""),
+		attribute("$ref", "reference", "readOnly", ""),
+		attribute("displayName", "string", "readOnly", ""),
+	}
+
+	return Schema{
+		ID:          SCHEMA_ENTERPRISE_USER,
+		Name:        "EnterpriseUser",
+		Description: "Enterprise User, read from the employee of the user",
+		Attributes: []SchemaAttribute{
+			attribute("employeeNumber", "string", "readOnly", "NIK of the employee."),
+			attribute("organization", "string", "readOnly", "Organization of the employee."),
+			attribute("department", "string", "readOnly", "Organization structure of the job of the employee."),
+			manager,
+		},
+	}
+}
+
+func groupSchema() Schema {
+	displayName := attribute("displayName", "string", "readWrite", "Name of the role, unique in its application.")
+	displayName.Required = true
+
+	return Schema{
+		ID:          SCHEMA_GROUP,
+		Name:        "Group",
+		Description: "Group, a role of an application",
+		Attributes: []SchemaAttribute{
+			displayName,
+			multiValuedAttribute("members", "readWrite", "Users with the role.",
+				attribute("value", "string", "immutable", ""),
+				attribute("$ref", "reference", "immutable", ""),
+				attribute("display", "string", "readOnly", ""),
+				attribute("type", "string", "immutable", ""),
+			),
+		},
+	}
+}
+
+func groupApplicationSchema() Schema {
+	application := attribute("application", "string", "immutable", "Name of the application of the role, scim.default_application when not given.")
+
+	return Schema{
+		ID:          SCHEMA_GROUP_APPLICATION,
+		Name:        "GroupApplication",
+		Description: "Application a group belongs to",
+		Attributes:  []SchemaAttribute{application},
+	}
+}
+
+// Schemas returns the schemas of the resources, with their location under
+// baseURL.
+func Schemas(baseURL string) []Schema {
+	schemas := []Schema{userSchema(), enterpriseUserSchema(), groupSchema(), groupApplicationSchema()}
+	for i := range schemas {
+		schemas[i].Schemas = []string{SCHEMA_SCHEMA}
+		schemas[i].Meta = Meta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + schemas[i].ID}
+	}
+	return schemas
+}
+
+// FindSchema returns the schema of id, nil when there is none.
+func FindSchema(baseURL string, id string) *Schema {
+	for _, schema := range Schemas(baseURL) {
+		if strings.EqualFold(schema.ID, id) {
+			return &schema
+		}
+	}
+	return nil
+}
+
+func ResourceTypes(baseURL string) []ResourceType {
+	return []ResourceType{
+		{
+			Schemas:     []string{SCHEMA_RESOURCE_TYPE},
+			ID:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      SCHEMA_USER,
+			SchemaExtensions: []ResourceTypeSchemaExtension{
+				{Schema: SCHEMA_ENTERPRISE_USER, Required: false},
+			},
+			Meta: Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/User"},
+		},
+		{
+			Schemas:     []string{SCHEMA_RESOURCE_TYPE},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Group, a role of an application",
+			Schema:      SCHEMA_GROUP,
+			SchemaExtensions: []ResourceTypeSchemaExtension{
+				{Schema: SCHEMA_GROUP_APPLICATION, Required: false},
+			},
+			Meta: Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+// FindResourceType returns the resource type of id, nil when there is none.
+func FindResourceType(baseURL string, id string) *ResourceType {
+	for _, resourceType := range ResourceTypes(baseURL) {
+		if strings.EqualFold(resourceType.ID, id) {
+			return &resourceType
+		}
+	}
+	return nil
+}
+
+// ServiceProviderConfig describes the features of the server, RFC 7643
+// section 5. Clients authenticate with an OAuth bearer token.
+func ServiceProviderConfig(baseURL string) map[string]interface{} {
+	supported := func(supported bool) map[string]interface{} {
+		return map[string]interface{}{"supported": supported}
+	}
+	return map[string]interface{}{
+		"schemas":          []string{SCHEMA_SERVICE_PROVIDER_CONFIG},
+		"documentationUri": "",
+		"patch":            supported(true),
+		"bulk": map[string]interface{}{
+			"supported":      false,
+			"maxOperations":  0,
+			"maxPayloadSize": 0,
+		},
+		"filter": map[string]interface{}{
+			"supported":  true,
+			"maxResults": MAX_RESULTS,
+		},
+		"changePassword": supported(false),
+		"sort":           supported(true),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "OAuth Bearer Token",
+				"description": "An access token of the client credentials grant with the read-scim and write-scim permissions",
+				"primary":     true,
+			},
+		},
+		"meta": Meta{ResourceType: "ServiceProviderConfig", Location: baseURL + "/ServiceProviderConfig"},
+	}
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema URIs of the resources and messages of RFC 7643 and RFC 7644.
+const (
+	SCHEMA_USER                    = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCHEMA_GROUP                   = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCHEMA_ENTERPRISE_USER         = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCHEMA_SERVICE_PROVIDER_CONFIG = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCHEMA_RESOURCE_TYPE           = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCHEMA_SCHEMA                  = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SCHEMA_LIST_RESPONSE           = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCHEMA_PATCH_OP                = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCHEMA_ERROR                   = "urn:ietf:params:scim:api:messages:2.0:Error"
+	// SCHEMA_GROUP_APPLICATION carries the application a group, which is a
+	// role, belongs to
+	SCHEMA_GROUP_APPLICATION = "urn:go-sso:params:scim:schemas:extension:2.0:Group"
+)
+
+// MAX_RESULTS is the largest page a list returns, whatever count asks for.
+const MAX_RESULTS = 200
+
+// SCIMError carries the HTTP status and scimType of RFC 7644 section 3.12 so
+// handlers can render the error in the format SCIM clients expect.
+type SCIMError struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *SCIMError) Error() string {
+	if e.ScimType == "" {
+		return e.Detail
+	}
+	return e.ScimType + ": " + e.Detail
+}
+
+func NewSCIMError(status int, scimType string, detail string) *SCIMError {
+	return &SCIMError{
+		Status:   status,
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func errNotFound(resourceType string, id string) *SCIMError {
+	return NewSCIMError(http.StatusNotFound, "", resourceType+" "+id+" not found")
+}
+
+// Meta is the meta attribute of a resource. Version is a weak ETag of the
+// last modification.
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+func newMeta(resourceType string, location string, created time.Time, lastModified time.Time) Meta {
+	return Meta{
+		ResourceType: resourceType,
+		Created:      created.UTC().Format(time.RFC3339),
+		LastModified: lastModified.UTC().Format(time.RFC3339),
+		Location:     location,
+		Version:      `W/"` + strconv.FormatInt(lastModified.UnixNano(), 36) + `"`,
+	}
+}
+
+// ListResponse is a page of resources. StartIndex is 1-based.
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// ListQuery is the filter, sort and page of a list request. Count is capped
+// at MAX_RESULTS, a negative count asks for the default page.
+type ListQuery struct {
+	Filter     string
+	SortBy     string
+	Descending bool
+	StartIndex int
+	Count      int
+}
+
+func (q ListQuery) page() (int, int) {
+	startIndex := q.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := q.Count
+	if count < 0 || count > MAX_RESULTS {
+		count = MAX_RESULTS
+	}
+	return startIndex, count
+}
+
+// normalizePath lowercases an attribute path and drops the URI of the core
+// schema of the resource, so `urn:...:core:2.0:User:userName` and `userName`
+// are the same attribute. Extension attributes keep their URI.
+func normalizePath(path string, coreSchema string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	prefix := strings.ToLower(coreSchema) + ":"
+	if strings.HasPrefix(path, prefix) {
+		return path[len(prefix):]
+	}
+	return path
+}
+
+// splitSchema splits an attribute path into the URI of its schema and the
+// attribute, for paths starting with urn:. The URI ends at the last colon, as
+// attribute names cannot contain one.
+func splitSchema(path string) (string, string) {
+	if !strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return "", path
+	}
+	colon := strings.LastIndex(path, ":")
+	return path[:colon], path[colon+1:]
+}
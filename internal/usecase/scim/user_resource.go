@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserResource is the SCIM User of an entity.User. The groups are the roles
+// of the user and the enterprise extension comes from the employee; both are
+// read-only, like id and meta.
+type UserResource struct {
+	Schemas      []string        `json:"schemas"`
+	ID           string          `json:"id,omitempty"`
+	UserName     string          `json:"userName"`
+	Name         *UserName       `json:"name,omitempty"`
+	DisplayName  string          `json:"displayName,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Active       *Boolean        `json:"active,omitempty"`
+	Emails       []MultiValue    `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue    `json:"phoneNumbers,omitempty"`
+	Groups       []MultiValue    `json:"groups,omitempty"`
+	Enterprise   *EnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *Meta           `json:"meta,omitempty"`
+}
+
+type UserName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// EnterpriseUser is the enterprise extension of RFC 7643 section 4.3, filled
+// from the employee of the user and its job.
+type EnterpriseUser struct {
+	EmployeeNumber string       `json:"employeeNumber,omitempty"`
+	Organization   string       `json:"organization,omitempty"`
+	Department     string       `json:"department,omitempty"`
+	Manager        *UserManager `json:"manager,omitempty"`
+}
+
+type UserManager struct {
+	Value       string `json:"value,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// userColumns are the user attributes lists can be filtered and sorted on.
+var userColumns = map[string]filterColumn{
+	"id":                 {Column: "users.id", Type: ATTRIBUTE_STRING},
+	"username":           {Column: "users.username", Type: ATTRIBUTE_STRING},
+	"displayname":        {Column: "users.name", Type: ATTRIBUTE_STRING},
+	"name.formatted":     {Column: "users.name", Type: ATTRIBUTE_STRING},
+	"emails":             {Column: "users.email", Type: ATTRIBUTE_STRING},
+	"emails.value":       {Column: "users.email", Type: ATTRIBUTE_STRING},
+	"phonenumbers":       {Column: "users.mobile_phone", Type: ATTRIBUTE_STRING},
+	"phonenumbers.value": {Column: "users.mobile_phone", Type: ATTRIBUTE_STRING},
+	"active":             {Column: "users.status", Type: ATTRIBUTE_BOOLEAN, True: string(entity.USER_ACTIVE)},
+	"meta.created":       {Column: "users.created_at", Type: ATTRIBUTE_DATE_TIME},
+	"meta.lastmodified":  {Column: "users.updated_at", Type: ATTRIBUTE_DATE_TIME},
+	"groups":             {Column: "user_roles.role_id", Type: ATTRIBUTE_STRING, Subquery: "users.id IN (SELECT user_roles.user_id FROM user_roles WHERE %s)"},
+	"groups.value":       {Column: "user_roles.role_id", Type: ATTRIBUTE_STRING, Subquery: "users.id IN (SELECT user_roles.user_id FROM user_roles WHERE %s)"},
+	"groups.display":     {Column: "roles.name", Type: ATTRIBUTE_STRING, Subquery: "users.id IN (SELECT user_roles.user_id FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE roles.deleted_at IS NULL AND %s)"},
+	strings.ToLower(SCHEMA_ENTERPRISE_USER) + ":employeenumber": {Column: "employees.nik", Type: ATTRIBUTE_STRING, Subquery: "users.employee_id IN (SELECT employees.id FROM employees WHERE employees.deleted_at IS NULL AND %s)"},
+}
+
+func userLocation(baseURL string, id uuid.UUID) string {
+	return baseURL + "/Users/" + id.String()
+}
+
+// userResource maps user, loaded by UserRepository.FindAllByCondition, to its
+// SCIM User.
+func userResource(user *entity.User, baseURL string) *UserResource {
+	active := Boolean(user.Status == entity.USER_ACTIVE)
+	resource := &UserResource{
+		Schemas:     []string{SCHEMA_USER},
+		ID:          user.ID.String(),
+		UserName:    user.Username,
+		DisplayName: user.Name,
+		Active:      &active,
+		Emails:      []MultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Groups:      []MultiValue{},
+	}
+	meta := newMeta("User", userLocation(baseURL, user.ID), user.CreatedAt, user.UpdatedAt)
+	resource.Meta = &meta
+	if user.Name != "" {
+		resource.Name = &UserName{Formatted: user.Name}
+	}
+	if user.MobilePhone != "" {
+		resource.PhoneNumbers = []MultiValue{{Value: user.MobilePhone, Type: "mobile", Primary: true}}
+	}
+	for _, role := range user.Roles {
+		resource.Groups = append(resource.Groups, MultiValue{
+			Value:   role.ID.String(),
+			Display: role.Name,
+			Type:    "direct",
+			Ref:     groupLocation(baseURL, role.ID),
+		})
+	}
+
+	if user.Employee != nil {
+		resource.Schemas = append(resource.Schemas, SCHEMA_ENTERPRISE_USER)
+		resource.Enterprise = &EnterpriseUser{
+			EmployeeNumber: user.Employee.NIK,
+			Organization:   user.Employee.Organization.Name,
+		}
+		if employeeJob := user.Employee.EmployeeJob; employeeJob != nil {
+			resource.Title = employeeJob.Name
+			if employeeJob.OrganizationStructure != nil {
+				resource.Enterprise.Department = employeeJob.OrganizationStructure.Name
+			}
+			if employeeJob.Job != nil {
+				if resource.Title == "" {
+					resource.Title = employeeJob.Job.Name
+				}
+				resource.Enterprise.Manager = userManager(employeeJob.Job, baseURL)
+			}
+		}
+	}
+	return resource
+}
+
+// userManager returns the user of an employee holding the parent job of job.
+func userManager(job *entity.Job, baseURL string) *UserManager {
+	if job.Parent == nil {
+		return nil
+	}
+	for _, employeeJob := range job.Parent.EmployeeJobs {
+		if employeeJob.Employee == nil || employeeJob.Employee.User == nil {
+			continue
+		}
+		manager := employeeJob.Employee.User
+		return &UserManager{
+			Value:       manager.ID.String(),
+			Ref:         userLocation(baseURL, manager.ID),
+			DisplayName: manager.Name,
+		}
+	}
+	return nil
+}
+
+// findUser returns the user of id, nil when there is none.
+func findUser(userRepository repository.IUserRepository, id string) (*entity.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+	users, _, err := userRepository.FindAllByCondition("users.id = ?", []interface{}{userID}, "", 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
+// saveUser creates the user of resource, or replaces the writable attributes
+// of user with it: userName, the primary email, the name, the mobile phone
+// and active. Passwords are not taken, users set theirs with a password
+// reset or sign in through a provider.
+func saveUser(userRepository repository.IUserRepository, passwordHasher utils.PasswordHasher, resource *UserResource, user *entity.User) (*entity.User, error) {
+	userName := strings.TrimSpace(resource.UserName)
+	if userName == "" {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	email := strings.TrimSpace(primaryValue(resource.Emails, "work"))
+	if !strings.Contains(email, "@") {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "a valid email is required")
+	}
+	mobilePhone := strings.TrimSpace(primaryValue(resource.PhoneNumbers, "mobile"))
+
+	name := resource.DisplayName
+	if resource.Name != nil {
+		if resource.Name.Formatted != "" {
+			name = resource.Name.Formatted
+		} else if given := strings.TrimSpace(resource.Name.GivenName + " " + resource.Name.FamilyName); given != "" {
+			name = given
+		}
+	}
+
+	condition := "(LOWER(users.username) = ? OR LOWER(users.email) = ?)"
+	args := []interface{}{strings.ToLower(userName), strings.ToLower(email)}
+	if mobilePhone != "" {
+		condition = "(LOWER(users.username) = ? OR LOWER(users.email) = ? OR users.mobile_phone = ?)"
+		args = append(args, mobilePhone)
+	}
+	if user != nil {
+		condition += " AND users.id <> ?"
+		args = append(args, user.ID)
+	}
+	_, taken, err := userRepository.FindAllByCondition(condition, args, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "another user has the same userName, email or phone number")
+	}
+
+	if user == nil {
+		hashedPassword, err := passwordHasher.Hash(utils.GenerateRandomStringToken(64))
+		if err != nil {
+			return nil, err
+		}
+		status := entity.USER_ACTIVE
+		if resource.Active != nil && !*resource.Active {
+			status = entity.USER_INACTIVE
+		}
+		created, err := userRepository.CreateUser(&entity.User{
+			Username:        userName,
+			Email:           email,
+			Name:            name,
+			MobilePhone:     mobilePhone,
+			Password:        hashedPassword,
+			Status:          status,
+			EmailVerifiedAt: time.Now(),
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return findUser(userRepository, created.ID.String())
+	}
+
+	columns := map[string]interface{}{
+		"username":     userName,
+		"email":        email,
+		"name":         name,
+		"mobile_phone": nil,
+	}
+	if mobilePhone != "" {
+		columns["mobile_phone"] = mobilePhone
+	}
+	if resource.Active != nil {
+		if *resource.Active {
+			columns["status"] = entity.USER_ACTIVE
+		} else {
+			columns["status"] = entity.USER_INACTIVE
+		}
+	}
+	if err := userRepository.UpdateUserColumns(user.ID, columns); err != nil {
+		return nil, err
+	}
+	return findUser(userRepository, user.ID.String())
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IRotateSigningKeyUseCaseRequest struct {
+	Algorithm   entity.SigningKeyAlgorithm `json:"algorithm"`
+	GracePeriod time.Duration              `json:"grace_period"`
+}
+
+type IRotateSigningKeyUseCaseResponse struct {
+	SigningKey *entity.SigningKey `json:"signing_key"`
+}
+
+type IRotateSigningKeyUseCase interface {
+	Execute(request *IRotateSigningKeyUseCaseRequest) (*IRotateSigningKeyUseCaseResponse, error)
+}
+
+type RotateSigningKeyUseCase struct {
+	Log                  *logrus.Logger
+	SigningKeyRepository repository.ISigningKeyRepository
+}
+
+func NewRotateSigningKeyUseCase(log *logrus.Logger, signingKeyRepository repository.ISigningKeyRepository) IRotateSigningKeyUseCase {
+	return &RotateSigningKeyUseCase{
+		Log:                  log,
+		SigningKeyRepository: signingKeyRepository,
+	}
+}
+
+func (uc *RotateSigningKeyUseCase) Execute(request *IRotateSigningKeyUseCaseRequest) (*IRotateSigningKeyUseCaseResponse, error) {
+	signingKey, err := utils.GenerateSigningKey(request.Algorithm)
+	if err != nil {
+		uc.Log.Error("[RotateSigningKeyUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	// the previous key stays in the JWKS for the grace period so tokens it
+	// signed keep verifying until they expire
+	signingKey, err = uc.SigningKeyRepository.RotateSigningKey(signingKey, request.GracePeriod)
+	if err != nil {
+		uc.Log.Error("[RotateSigningKeyUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	utils.InvalidateSigningKeys()
+
+	return &IRotateSigningKeyUseCaseResponse{
+		SigningKey: signingKey,
+	}, nil
+}
+
+func RotateSigningKeyUseCaseFactory(log *logrus.Logger) IRotateSigningKeyUseCase {
+	signingKeyRepository := repository.SigningKeyRepositoryFactory(log)
+	return NewRotateSigningKeyUseCase(log, signingKeyRepository)
+}
@@ -3,6 +3,8 @@ package usecase
 import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +14,10 @@ import (
 type ICreateUserUseCaseRequest struct {
 	User    *entity.User `json:"user"`
 	RoleIDs []string     `json:"role_ids[]"`
+	// Password is the plain password of the user, hashed once it passes
+	// PasswordPolicy
+	Password       string               `json:"-"`
+	PasswordPolicy utils.PasswordPolicy `json:"-"`
 }
 
 type ICreateUserUseCaseResponse struct {
@@ -23,14 +29,18 @@ type ICreateUserUseCase interface {
 }
 
 type CreateUserUseCase struct {
-	Log            *logrus.Logger
-	UserRepository repository.IUserRepository
+	Log                           *logrus.Logger
+	UserRepository                repository.IUserRepository
+	UserPasswordHistoryRepository repository.IUserPasswordHistoryRepository
+	PasswordHasher                utils.PasswordHasher
 }
 
-func NewCreateUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository) ICreateUserUseCase {
+func NewCreateUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository, userPasswordHistoryRepository repository.IUserPasswordHistoryRepository, passwordHasher utils.PasswordHasher) ICreateUserUseCase {
 	return &CreateUserUseCase{
-		Log:            log,
-		UserRepository: userRepository,
+		Log:                           log,
+		UserRepository:                userRepository,
+		UserPasswordHistoryRepository: userPasswordHistoryRepository,
+		PasswordHasher:                passwordHasher,
 	}
 }
 
@@ -47,13 +57,30 @@ func (uc *CreateUserUseCase) Execute(request ICreateUserUseCaseRequest) (ICreate
 		roleUUIDs = append(roleUUIDs, roleUUID)
 	}
 
-	request.User.EmailVerifiedAt = time.Now()
+	if err := checkPasswordPolicy(uc.UserPasswordHistoryRepository, uc.PasswordHasher, request.PasswordPolicy, nil, request.Password); err != nil {
+		return ICreateUserUseCaseResponse{}, err
+	}
+
+	hashedPassword, err := uc.PasswordHasher.Hash(request.Password)
+	if err != nil {
+		uc.Log.Error("[CreateUserUseCase.Execute] " + err.Error())
+		return ICreateUserUseCaseResponse{}, err
+	}
+
+	now := time.Now()
+	request.User.Password = hashedPassword
+	request.User.PasswordChangedAt = &now
+	request.User.EmailVerifiedAt = now
 
 	user, err := uc.UserRepository.CreateUser(request.User, roleUUIDs)
 	if err != nil {
 		return ICreateUserUseCaseResponse{}, err
 	}
 
+	if err := recordPasswordHistory(uc.UserPasswordHistoryRepository, request.PasswordPolicy, user.ID, user.Password); err != nil {
+		return ICreateUserUseCaseResponse{}, err
+	}
+
 	return ICreateUserUseCaseResponse{
 		User: user,
 	}, nil
@@ -61,8 +88,7 @@ func (uc *CreateUserUseCase) Execute(request ICreateUserUseCaseRequest) (ICreate
 
 func CreateUserUseCaseFactory(log *logrus.Logger) ICreateUserUseCase {
 	userRepository := repository.UserRepositoryFactory(log)
-	return &CreateUserUseCase{
-		Log:            log,
-		UserRepository: userRepository,
-	}
+	userPasswordHistoryRepository := repository.UserPasswordHistoryRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewCreateUserUseCase(log, userRepository, userPasswordHistoryRepository, passwordHasher)
 }
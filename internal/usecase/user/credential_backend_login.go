@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// backendLogin checks the password with the credential backends in turn and
+// returns the user of the first that accepts it, nil if none does. Errors of a
+// backend are logged and the next one is tried, so an unreachable directory
+// does not lock out the users of another backend.
+func (uc *LoginUseCase) backendLogin(request ILoginUseCaseRequest) (*entity.User, error) {
+	for _, backend := range uc.CredentialBackends {
+		identity, err := backend.Authenticate(request.Email, request.Password)
+		if err != nil {
+			uc.Log.Error("[LoginUseCase.backendLogin] " + backend.Name() + ": " + err.Error())
+			continue
+		}
+		if identity == nil {
+			continue
+		}
+		return uc.backendUser(backend, identity, request.IPAddress)
+	}
+	return nil, nil
+}
+
+// backendUser finds the user of an identity a backend accepted the password
+// of by the linked identity, and otherwise creates it. An existing user with
+// the same email is only linked when the backend allows it, so an account of
+// the backend cannot take over a local one by its email. Name and mobile phone follow the backend, and the roles mapped from
+// groups follow the groups of the identity.
+func (uc *LoginUseCase) backendUser(backend service.ICredentialBackend, identity *service.CredentialIdentity, ipAddress string) (*entity.User, error) {
+	provider := entity.UserIdentityProvider(backend.Name())
+	grantRoleIDs, revokeRoleIDs, roleNames := uc.mappedRoles(backend, identity)
+
+	userIdentity, err := uc.UserIdentityRepository.FindByProviderAndSubject(provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *entity.User
+	if userIdentity != nil {
+		user, err = uc.UserRepository.FindById(userIdentity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, nil
+		}
+		if userIdentity.Email != identity.Email {
+			if err := uc.UserIdentityRepository.UpdateEmail(userIdentity, identity.Email); err != nil {
+				uc.Log.Error("[LoginUseCase.backendUser] " + err.Error())
+			}
+		}
+	} else {
+		user, err = uc.UserRepository.FindByEmail(identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return uc.createBackendUser(provider, identity, grantRoleIDs, roleNames, ipAddress)
+		}
+		if !backend.LinkByEmail() {
+			uc.Log.Warn("[LoginUseCase.backendUser] an account of " + backend.Name() + " has the email of user " + user.ID.String() + ", which is not linked to it")
+			return nil, nil
+		}
+
+		linked, err := uc.UserIdentityRepository.FindByUserIDAndProvider(user.ID, provider)
+		if err != nil {
+			return nil, err
+		}
+		if linked != nil {
+			uc.Log.Warn("[LoginUseCase.backendUser] another account of " + backend.Name() + " is linked to user " + user.ID.String())
+			return nil, nil
+		}
+		if _, err := uc.UserIdentityRepository.CreateUserIdentity(&entity.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if (identity.Name != "" && identity.Name != user.Name) || (identity.MobilePhone != "" && identity.MobilePhone != user.MobilePhone) {
+		if identity.Name != "" {
+			user.Name = identity.Name
+		}
+		if identity.MobilePhone != "" {
+			user.MobilePhone = identity.MobilePhone
+		}
+		if _, err := uc.UserRepository.UpdateUserOnly(user); err != nil {
+			uc.Log.Error("[LoginUseCase.backendUser] " + err.Error())
+		}
+	}
+
+	if len(grantRoleIDs) > 0 || len(revokeRoleIDs) > 0 {
+		if err := uc.UserRepository.UpdateUserRoles(user.ID, grantRoleIDs, revokeRoleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.UserRepository.FindById(user.ID)
+}
+
+// createBackendUser creates the account of an identity signing in for the
+// first time, with the roles mapped from its groups, and records it in the
+// audit log. Its local password is random, the backend keeps the real one.
+// Identities without a mapped role get no account, as a user without roles
+// cannot receive a token.
+func (uc *LoginUseCase) createBackendUser(provider entity.UserIdentityProvider, identity *service.CredentialIdentity, roleIDs []uuid.UUID, roleNames []string, ipAddress string) (*entity.User, error) {
+	if len(roleIDs) == 0 {
+		uc.Log.Warn("[LoginUseCase.createBackendUser] refused to provision " + identity.Subject + " of " + string(provider) + " without roles")
+		return nil, nil
+	}
+
+	hashedPassword, err := uc.PasswordHasher.Hash(utils.GenerateRandomStringToken(64))
+	if err != nil {
+		return nil, errors.New("[LoginUseCase.createBackendUser] " + err.Error())
+	}
+
+	username := identity.Username
+	if username == "" {
+		username = identity.Email
+	}
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	user, err := uc.UserRepository.CreateUser(&entity.User{
+		Username:        username,
+		Email:           identity.Email,
+		Name:            name,
+		MobilePhone:     identity.MobilePhone,
+		Password:        hashedPassword,
+		Status:          entity.USER_ACTIVE,
+		EmailVerifiedAt: time.Now(),
+	}, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.UserIdentityRepository.CreateUserIdentity(&entity.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"provider": provider,
+		"subject":  identity.Subject,
+		"email":    identity.Email,
+		"roles":    roleNames,
+	})
+	if _, err := uc.AuditLogRepository.CreateAuditLog(&entity.AuditLog{
+		Action:    entity.AUDIT_USER_PROVISIONED,
+		UserID:    &user.ID,
+		Source:    string(provider),
+		IPAddress: ipAddress,
+		Details:   string(details),
+	}); err != nil {
+		uc.Log.Error("[LoginUseCase.createBackendUser] " + err.Error())
+	}
+
+	return uc.UserRepository.FindById(user.ID)
+}
+
+// mappedRoles resolves the group role mappings of backend. Roles of the
+// groups of identity are granted, the other mapped roles revoked; roles that
+// are not mapped are left alone. Mappings naming a missing application or
+// role are logged and skipped.
+func (uc *LoginUseCase) mappedRoles(backend service.ICredentialBackend, identity *service.CredentialIdentity) ([]uuid.UUID, []uuid.UUID, []string) {
+	grantRoleIDs := []uuid.UUID{}
+	revokeRoleIDs := []uuid.UUID{}
+	roleNames := []string{}
+
+	granted := map[uuid.UUID]bool{}
+	revoked := map[uuid.UUID]bool{}
+	for _, mapping := range backend.RoleMappings() {
+		application, err := uc.ApplicationRepository.FindApplicationByName(mapping.Application)
+		if err != nil || application == nil {
+			uc.Log.Error("[LoginUseCase.mappedRoles] application " + mapping.Application + " not found")
+			continue
+		}
+		role, err := uc.RoleRepository.FindByApplicationIDAndName(application.ID, mapping.Role)
+		if err != nil || role == nil {
+			uc.Log.Error("[LoginUseCase.mappedRoles] role " + mapping.Role + " of " + mapping.Application + " not found")
+			continue
+		}
+
+		member := false
+		for _, group := range identity.Groups {
+			if strings.EqualFold(group, mapping.Group) {
+				member = true
+				break
+			}
+		}
+		if member {
+			granted[role.ID] = true
+			roleNames = append(roleNames, role.Name)
+		} else {
+			revoked[role.ID] = true
+		}
+	}
+
+	for id := range granted {
+		grantRoleIDs = append(grantRoleIDs, id)
+	}
+	// a role mapped from several groups stays while any of them is held
+	for id := range revoked {
+		if !granted[id] {
+			revokeRoleIDs = append(revokeRoleIDs, id)
+		}
+	}
+	return grantRoleIDs, revokeRoleIDs, roleNames
+}
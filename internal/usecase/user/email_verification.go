@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/request"
+	"app/go-sso/internal/messaging"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"fmt"
+	"time"
+)
+
+// sendVerificationCode mails a new 6 digit code and a signed link to verify
+// the email of the user. Only the keyed hash of the code is stored, replacing
+// the previous code and its link.
+func sendVerificationCode(userRepository repository.IUserRepository, mailMessage messaging.IMailMessage, policy utils.EmailVerificationPolicy, user *entity.User, from string) error {
+	code, err := utils.GenerateRandomIntToken(6)
+	if err != nil {
+		return err
+	}
+
+	codeHash := policy.HashCode(user.Email, code)
+	expiredAt := time.Now().Add(policy.CodeTTL)
+	if err := userRepository.ReplaceHashedUserToken(&entity.UserToken{
+		Email:     user.Email,
+		TokenHash: codeHash,
+		TokenType: entity.UserTokenVerification,
+		ExpiredAt: expiredAt,
+	}); err != nil {
+		return err
+	}
+
+	_, err = mailMessage.SendMail(&request.MailRequest{
+		Email:   user.Email,
+		Subject: "Email Verification",
+		Body: fmt.Sprintf(
+			"Your verification code is %s. It expires in %d minutes.\n\nYou can also verify your email by opening this link:\n\n%s",
+			code, int(policy.CodeTTL.Minutes()), policy.Link(user.Email, codeHash, expiredAt),
+		),
+		From: from,
+		To:   user.Email,
+	})
+	return err
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IFindLockedAccountsUseCaseResponse struct {
+	LoginThrottles []entity.LoginThrottle `json:"login_throttles"`
+}
+
+type IFindLockedAccountsUseCase interface {
+	Execute() (*IFindLockedAccountsUseCaseResponse, error)
+}
+
+type FindLockedAccountsUseCase struct {
+	Log                     *logrus.Logger
+	LoginThrottleRepository repository.ILoginThrottleRepository
+}
+
+func NewFindLockedAccountsUseCase(log *logrus.Logger, loginThrottleRepository repository.ILoginThrottleRepository) IFindLockedAccountsUseCase {
+	return &FindLockedAccountsUseCase{
+		Log:                     log,
+		LoginThrottleRepository: loginThrottleRepository,
+	}
+}
+
+// Execute returns the accounts locked after too many failed attempts, with
+// the email of the account as identifier.
+func (uc *FindLockedAccountsUseCase) Execute() (*IFindLockedAccountsUseCaseResponse, error) {
+	loginThrottles, err := uc.LoginThrottleRepository.FindLockedAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	return &IFindLockedAccountsUseCaseResponse{
+		LoginThrottles: loginThrottles,
+	}, nil
+}
+
+func FindLockedAccountsUseCaseFactory(log *logrus.Logger) IFindLockedAccountsUseCase {
+	loginThrottleRepository := repository.LoginThrottleRepositoryFactory(log)
+	return NewFindLockedAccountsUseCase(log, loginThrottleRepository)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/request"
+	"app/go-sso/internal/messaging"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ForgotPasswordMessage is the answer to every forgot password request, so the
+// response does not tell whether the email belongs to an account.
+const ForgotPasswordMessage = "If an account exists for this email, a password reset link has been sent to it"
+
+type IForgotPasswordUseCaseRequest struct {
+	Email    string        `json:"email"`
+	ResetURL string        `json:"reset_url"`
+	From     string        `json:"from"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+type IForgotPasswordUseCaseResponse struct {
+	Message string `json:"message"`
+}
+
+type IForgotPasswordUseCase interface {
+	Execute(request *IForgotPasswordUseCaseRequest) (*IForgotPasswordUseCaseResponse, error)
+}
+
+type ForgotPasswordUseCase struct {
+	Log         *logrus.Logger
+	Repository  repository.IUserRepository
+	MailMessage messaging.IMailMessage
+}
+
+func NewForgotPasswordUseCase(
+	log *logrus.Logger,
+	repository repository.IUserRepository,
+	mailMessage messaging.IMailMessage,
+) IForgotPasswordUseCase {
+	return &ForgotPasswordUseCase{
+		Log:         log,
+		Repository:  repository,
+		MailMessage: mailMessage,
+	}
+}
+
+// Execute mails a single-use password reset link to the user. Unknown emails
+// and delivery failures are only logged, the caller always gets the same
+// message.
+func (uc *ForgotPasswordUseCase) Execute(req *IForgotPasswordUseCaseRequest) (*IForgotPasswordUseCaseResponse, error) {
+	resp := &IForgotPasswordUseCaseResponse{
+		Message: ForgotPasswordMessage,
+	}
+
+	user, err := uc.Repository.FindByEmailOnly(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		uc.Log.Warn("[ForgotPasswordUseCase.Execute] password reset requested for an unknown email")
+		return resp, nil
+	}
+
+	token := utils.GenerateRandomStringToken(64)
+	if err := uc.Repository.ReplaceHashedUserToken(&entity.UserToken{
+		Email:     user.Email,
+		TokenHash: utils.HashToken(token),
+		TokenType: entity.UserTokenResetPassword,
+		ExpiredAt: time.Now().Add(req.TTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	resetURL, err := url.Parse(req.ResetURL)
+	if err != nil {
+		uc.Log.Error("[ForgotPasswordUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	query := resetURL.Query()
+	query.Set("token", token)
+	resetURL.RawQuery = query.Encode()
+
+	if _, err := uc.MailMessage.SendMail(&request.MailRequest{
+		Email:   user.Email,
+		Subject: "Reset Password",
+		Body: fmt.Sprintf(
+			"Open the link below to choose a new password. The link expires in %d minutes and can be used once.\n\n%s\n\nIf you did not ask for a password reset, you can ignore this email.",
+			int(req.TTL.Minutes()), resetURL.String(),
+		),
+		From: req.From,
+		To:   user.Email,
+	}); err != nil {
+		uc.Log.Error("[ForgotPasswordUseCase.Execute] " + err.Error())
+	}
+
+	return resp, nil
+}
+
+func ForgotPasswordUseCaseFactory(log *logrus.Logger) IForgotPasswordUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	mailMessage := messaging.MailMessageFactory(log)
+	return NewForgotPasswordUseCase(log, userRepository, mailMessage)
+}
@@ -3,19 +3,36 @@ package usecase
 import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrPasswordExpired is returned for a correct password that is older than
+// the maximum age of the password policy.
+var ErrPasswordExpired = errors.New("Your password has expired, please reset it to sign in")
+
+// ErrAwaitingApproval is returned for users provisioned on their first
+// sign-in through a provider that an administrator has not activated yet.
+var ErrAwaitingApproval = errors.New("Your account is waiting for the approval of an administrator")
+
 type ILoginUseCaseRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email          string               `json:"email"`
+	Password       string               `json:"password"`
+	IPAddress      string               `json:"ip_address"`
+	PasswordPolicy utils.PasswordPolicy `json:"-"`
 }
 
 type ILoginUseCaseResponse struct {
 	User entity.User `json:"user"`
+	// MFARequired is set when the password is correct but the user has
+	// two-factor authentication enabled; no session or token may be issued
+	// before the second factor is verified.
+	MFARequired bool `json:"mfa_required"`
 }
 
 type ILoginUseCase interface {
@@ -23,39 +40,177 @@ type ILoginUseCase interface {
 }
 
 type LoginUseCase struct {
-	Log            *logrus.Logger
-	UserRepository repository.IUserRepository
+	Log                  *logrus.Logger
+	UserRepository       repository.IUserRepository
+	UserTOTPRepository   repository.IUserTOTPRepository
+	LoginThrottleService service.ILoginThrottleService
+	PasswordHasher       utils.PasswordHasher
+	// CredentialBackends are tried in turn when the local password does
+	// not match.
+	CredentialBackends     []service.ICredentialBackend
+	UserIdentityRepository repository.IUserIdentityRepository
+	ApplicationRepository  repository.IApplicationRepository
+	RoleRepository         repository.IRoleRepository
+	AuditLogRepository     repository.IAuditLogRepository
 }
 
-func NewLoginUseCase(log *logrus.Logger, userRepository repository.IUserRepository) ILoginUseCase {
+func NewLoginUseCase(
+	log *logrus.Logger,
+	userRepository repository.IUserRepository,
+	userTOTPRepository repository.IUserTOTPRepository,
+	loginThrottleService service.ILoginThrottleService,
+	passwordHasher utils.PasswordHasher,
+	credentialBackends []service.ICredentialBackend,
+	userIdentityRepository repository.IUserIdentityRepository,
+	applicationRepository repository.IApplicationRepository,
+	roleRepository repository.IRoleRepository,
+	auditLogRepository repository.IAuditLogRepository,
+) ILoginUseCase {
 	return &LoginUseCase{
-		Log:            log,
-		UserRepository: userRepository,
+		Log:                    log,
+		UserRepository:         userRepository,
+		UserTOTPRepository:     userTOTPRepository,
+		LoginThrottleService:   loginThrottleService,
+		PasswordHasher:         passwordHasher,
+		CredentialBackends:     credentialBackends,
+		UserIdentityRepository: userIdentityRepository,
+		ApplicationRepository:  applicationRepository,
+		RoleRepository:         roleRepository,
+		AuditLogRepository:     auditLogRepository,
 	}
 }
 
+// Execute checks the password of the user. Failed attempts are counted per
+// account and per IP address; a *service.LoginThrottledError is returned while
+// either has to wait or is locked. A correct password stored with another
+// algorithm or older parameters is rehashed. Employees whose password passed
+// the maximum age get ErrPasswordExpired and have to reset it. Logins the
+// local password does not match are checked by the credential backends.
 func (uc *LoginUseCase) Execute(request ILoginUseCaseRequest) (*ILoginUseCaseResponse, error) {
+	if err := uc.LoginThrottleService.Check(entity.LOGIN_THROTTLE_LOGIN, request.Email, request.IPAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := uc.UserRepository.FindByEmail(request.Email)
 	if err != nil {
 		return nil, errors.New("[LoginUseCase.Execute] " + err.Error())
 	}
 
+	matched := false
 	if user == nil {
 		uc.Log.Error("User not found")
-		return nil, errors.New("Email or password is incorrect")
+		uc.verifyDummyPassword(request.Password)
+	} else {
+		matched, err = uc.PasswordHasher.Verify(user.Password, request.Password)
+		if err != nil {
+			uc.Log.Error("[LoginUseCase.Execute] " + err.Error())
+		}
+		if !matched {
+			uc.Log.Error("Password not match")
+		}
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-		uc.Log.Error("Password not match")
-		return nil, errors.New("Email or password is incorrect")
+	// the password is kept by the backend, it neither expires nor is
+	// rehashed here
+	backendLogin := false
+	if !matched {
+		user, err = uc.backendLogin(request)
+		if err != nil {
+			return nil, errors.New("[LoginUseCase.Execute] " + err.Error())
+		}
+		if user == nil {
+			return nil, uc.loginFailed(request)
+		}
+		backendLogin = true
+	} else {
+		uc.rehashPassword(user, request.Password)
+	}
+
+	if err := uc.LoginThrottleService.Reset(entity.LOGIN_THROTTLE_LOGIN, request.Email); err != nil {
+		return nil, errors.New("[LoginUseCase.Execute] " + err.Error())
+	}
+
+	if user.Status == entity.USER_AWAITING_APPROVAL {
+		return nil, ErrAwaitingApproval
+	}
+
+	if !backendLogin && request.PasswordPolicy.PasswordExpired(user, time.Now()) {
+		uc.Log.Warn("[LoginUseCase.Execute] Password expired for user " + user.ID.String())
+		return nil, ErrPasswordExpired
+	}
+
+	userTOTP, err := uc.UserTOTPRepository.FindByUserID(user.ID)
+	if err != nil {
+		return nil, errors.New("[LoginUseCase.Execute] " + err.Error())
 	}
 
 	return &ILoginUseCaseResponse{
-		User: *user,
+		User:        *user,
+		MFARequired: userTOTP != nil && userTOTP.ConfirmedAt != nil,
 	}, nil
 }
 
+// rehashPassword replaces a hash made with another algorithm or older
+// parameters than the configured ones, now that the password is known. A
+// failure only delays the upgrade to the next login.
+func (uc *LoginUseCase) rehashPassword(user *entity.User, password string) {
+	if !uc.PasswordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := uc.PasswordHasher.Hash(password)
+	if err != nil {
+		uc.Log.Error("[LoginUseCase.rehashPassword] " + err.Error())
+		return
+	}
+	if _, err := uc.UserRepository.UpdatePasswordHash(user.ID, user.Password, hashedPassword); err != nil {
+		uc.Log.Error("[LoginUseCase.rehashPassword] " + err.Error())
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// dummyPasswordHash is checked for unknown emails so they take as long as a
+// wrong password, and the response time does not tell which emails exist.
+var dummyPasswordHash struct {
+	once sync.Once
+	hash string
+}
+
+func (uc *LoginUseCase) verifyDummyPassword(password string) {
+	dummyPasswordHash.once.Do(func() {
+		hash, err := uc.PasswordHasher.Hash(utils.GenerateRandomStringToken(32))
+		if err != nil {
+			uc.Log.Error("[LoginUseCase.verifyDummyPassword] " + err.Error())
+			return
+		}
+		dummyPasswordHash.hash = hash
+	})
+	if dummyPasswordHash.hash != "" {
+		uc.PasswordHasher.Verify(dummyPasswordHash.hash, password)
+	}
+}
+
+// loginFailed counts the failed attempt. Unknown emails are counted too, so a
+// locked account does not tell whether the email exists.
+func (uc *LoginUseCase) loginFailed(request ILoginUseCaseRequest) error {
+	if err := uc.LoginThrottleService.RecordFailure(entity.LOGIN_THROTTLE_LOGIN, request.Email, request.IPAddress); err != nil {
+		uc.Log.Error("[LoginUseCase.Execute] " + err.Error())
+	}
+	return errors.New("Email or password is incorrect")
+}
+
 func LoginUseCaseFactory(log *logrus.Logger) ILoginUseCase {
 	userRepository := repository.UserRepositoryFactory(log)
-	return NewLoginUseCase(log, userRepository)
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	loginThrottleService := service.LoginThrottleServiceFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	credentialBackends := service.CredentialBackendsFactory(log)
+	userIdentityRepository := repository.UserIdentityRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	roleRepository := repository.RoleRepositoryFactory(log)
+	auditLogRepository := repository.AuditLogRepositoryFactory(log)
+	return NewLoginUseCase(
+		log, userRepository, userTOTPRepository, loginThrottleService, passwordHasher,
+		credentialBackends, userIdentityRepository, applicationRepository, roleRepository, auditLogRepository,
+	)
 }
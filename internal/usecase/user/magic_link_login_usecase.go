@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidMagicLink is returned for unknown, expired and used magic links
+// alike.
+var ErrInvalidMagicLink = errors.New("The sign-in link is invalid or has expired")
+
+type IMagicLinkLoginUseCaseRequest struct {
+	Token     string `json:"token"`
+	IPAddress string `json:"ip_address"`
+}
+
+type IMagicLinkLoginUseCaseResponse struct {
+	User        *entity.User        `json:"user"`
+	Application *entity.Application `json:"application"`
+	// MFARequired is set when the user has two-factor authentication
+	// enabled; the link stands in for the password only.
+	MFARequired bool `json:"mfa_required"`
+}
+
+type IMagicLinkLoginUseCase interface {
+	Execute(request *IMagicLinkLoginUseCaseRequest) (*IMagicLinkLoginUseCaseResponse, error)
+}
+
+type MagicLinkLoginUseCase struct {
+	Log                   *logrus.Logger
+	Repository            repository.IUserRepository
+	ApplicationRepository repository.IApplicationRepository
+	UserTOTPRepository    repository.IUserTOTPRepository
+	LoginThrottleService  service.ILoginThrottleService
+}
+
+func NewMagicLinkLoginUseCase(
+	log *logrus.Logger,
+	repository repository.IUserRepository,
+	applicationRepository repository.IApplicationRepository,
+	userTOTPRepository repository.IUserTOTPRepository,
+	loginThrottleService service.ILoginThrottleService,
+) IMagicLinkLoginUseCase {
+	return &MagicLinkLoginUseCase{
+		Log:                   log,
+		Repository:            repository,
+		ApplicationRepository: applicationRepository,
+		UserTOTPRepository:    userTOTPRepository,
+		LoginThrottleService:  loginThrottleService,
+	}
+}
+
+// Execute consumes a magic link and returns the user it was mailed to, with
+// the application it was asked for. Accounts locked by failed logins stay
+// locked, and links of applications that turned magic links off since are
+// refused, as are users waiting for approval. Opening the link proves the
+// email, so an unverified email is verified.
+func (uc *MagicLinkLoginUseCase) Execute(req *IMagicLinkLoginUseCaseRequest) (*IMagicLinkLoginUseCaseResponse, error) {
+	tokenHash := utils.HashToken(req.Token)
+	userToken, err := uc.Repository.FindHashedUserToken(tokenHash, entity.UserTokenMagicLink)
+	if err != nil {
+		return nil, err
+	}
+	if userToken == nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	if err := uc.LoginThrottleService.Check(entity.LOGIN_THROTTLE_LOGIN, userToken.Email, req.IPAddress); err != nil {
+		return nil, err
+	}
+
+	application, err := uc.ApplicationRepository.FindApplicationByName(userToken.Application)
+	if err != nil || !application.MagicLinkEnabled {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	userToken, err = uc.Repository.ConsumeHashedUserToken(tokenHash, entity.UserTokenMagicLink)
+	if err != nil {
+		return nil, err
+	}
+	if userToken == nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	user, err := uc.Repository.FindByEmail(userToken.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidMagicLink
+	}
+	if user.Status == entity.USER_AWAITING_APPROVAL {
+		return nil, ErrAwaitingApproval
+	}
+
+	if user.EmailVerifiedAt.IsZero() {
+		if err := uc.Repository.VerifyUserEmail(user.Email); err != nil {
+			return nil, err
+		}
+		if user, err = uc.Repository.FindByEmail(user.Email); err != nil || user == nil {
+			return nil, ErrInvalidMagicLink
+		}
+	}
+
+	userTOTP, err := uc.UserTOTPRepository.FindByUserID(user.ID)
+	if err != nil {
+		return nil, errors.New("[MagicLinkLoginUseCase.Execute] " + err.Error())
+	}
+
+	return &IMagicLinkLoginUseCaseResponse{
+		User:        user,
+		Application: application,
+		MFARequired: userTOTP != nil && userTOTP.ConfirmedAt != nil,
+	}, nil
+}
+
+func MagicLinkLoginUseCaseFactory(log *logrus.Logger) IMagicLinkLoginUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	userTOTPRepository := repository.UserTOTPRepositoryFactory(log)
+	loginThrottleService := service.LoginThrottleServiceFactory(log)
+	return NewMagicLinkLoginUseCase(log, userRepository, applicationRepository, userTOTPRepository, loginThrottleService)
+}
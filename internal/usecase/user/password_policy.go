@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+
+	"github.com/google/uuid"
+)
+
+// checkPasswordPolicy returns a *utils.PasswordPolicyError listing every rule
+// the password breaks. user is nil for accounts that do not exist yet,
+// otherwise the password may not be one of their last HistorySize passwords,
+// the current one included.
+func checkPasswordPolicy(userPasswordHistoryRepository repository.IUserPasswordHistoryRepository, passwordHasher utils.PasswordHasher, policy utils.PasswordPolicy, user *entity.User, password string) error {
+	violations, err := policy.Check(password)
+	if err != nil {
+		return err
+	}
+
+	if user != nil && policy.HistorySize > 0 {
+		reused, _ := passwordHasher.Verify(user.Password, password)
+		if !reused {
+			userPasswordHistories, err := userPasswordHistoryRepository.FindRecentByUserID(user.ID, policy.HistorySize)
+			if err != nil {
+				return err
+			}
+			for _, userPasswordHistory := range userPasswordHistories {
+				if reused, _ = passwordHasher.Verify(userPasswordHistory.PasswordHash, password); reused {
+					break
+				}
+			}
+		}
+		if reused {
+			violations = append(violations, policy.ReusedPasswordViolation())
+		}
+	}
+
+	if len(violations) > 0 {
+		return &utils.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// recordPasswordHistory remembers the new password hash of the user for the
+// reuse check.
+func recordPasswordHistory(userPasswordHistoryRepository repository.IUserPasswordHistoryRepository, policy utils.PasswordPolicy, userID uuid.UUID, hashedPassword string) error {
+	if policy.HistorySize <= 0 {
+		return nil
+	}
+	return userPasswordHistoryRepository.AddPasswordHistory(userID, hashedPassword, policy.HistorySize)
+}
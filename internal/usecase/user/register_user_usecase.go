@@ -3,19 +3,16 @@ package usecase
 import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/http/dto"
-	"app/go-sso/internal/http/request"
 	"app/go-sso/internal/http/response"
 	"app/go-sso/internal/messaging"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
 	"app/go-sso/utils"
 	"errors"
-	"log"
-	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type IRegisterUserUseCaseRequest struct {
@@ -30,6 +27,10 @@ type IRegisterUserUseCaseRequest struct {
 	Address     string            `json:"address"`
 	NoKTP       string            `json:"no_ktp"`
 	KTP         string            `json:"ktp"`
+
+	PasswordPolicy          utils.PasswordPolicy          `json:"-"`
+	EmailVerificationPolicy utils.EmailVerificationPolicy `json:"-"`
+	From                    string                        `json:"-"`
 }
 
 type IRegisterUserUseCaseResponse struct {
@@ -41,23 +42,29 @@ type IRegisterUserUseCase interface {
 }
 
 type RegisterUserUseCase struct {
-	Log            *logrus.Logger
-	Repository     repository.IUserRepository
-	RoleRepository repository.IRoleRepository
-	MailMessage    messaging.IMailMessage
+	Log                           *logrus.Logger
+	Repository                    repository.IUserRepository
+	RoleRepository                repository.IRoleRepository
+	UserPasswordHistoryRepository repository.IUserPasswordHistoryRepository
+	MailMessage                   messaging.IMailMessage
+	PasswordHasher                utils.PasswordHasher
 }
 
 func NewRegisterUserUseCase(
 	log *logrus.Logger,
 	repository repository.IUserRepository,
 	roleRepository repository.IRoleRepository,
+	userPasswordHistoryRepository repository.IUserPasswordHistoryRepository,
 	mailMessage messaging.IMailMessage,
+	passwordHasher utils.PasswordHasher,
 ) IRegisterUserUseCase {
 	return &RegisterUserUseCase{
-		Log:            log,
-		Repository:     repository,
-		RoleRepository: roleRepository,
-		MailMessage:    mailMessage,
+		Log:                           log,
+		Repository:                    repository,
+		RoleRepository:                roleRepository,
+		UserPasswordHistoryRepository: userPasswordHistoryRepository,
+		MailMessage:                   mailMessage,
+		PasswordHasher:                passwordHasher,
 	}
 }
 
@@ -73,25 +80,33 @@ func (uc *RegisterUserUseCase) Execute(payload IRegisterUserUseCaseRequest) (*IR
 		return nil, errors.New("user already registered")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	if err := checkPasswordPolicy(uc.UserPasswordHistoryRepository, uc.PasswordHasher, payload.PasswordPolicy, nil, payload.Password); err != nil {
+		uc.Log.Warn("[UserUseCase.Register] " + err.Error())
+		return nil, err
+	}
+
+	hashedPassword, err := uc.PasswordHasher.Hash(payload.Password)
 	if err != nil {
 		uc.Log.Error("[UserUseCase.Register] " + err.Error())
 		return nil, err
 	}
 
 	birthDate, err := time.Parse("2006-01-02", payload.BirthDate)
+	passwordChangedAt := time.Now()
 
 	user = &entity.User{
 		Username:    payload.Username,
 		Email:       payload.Email,
 		Name:        payload.Name,
-		Password:    string(hashedPassword),
+		Password:    hashedPassword,
 		Gender:      payload.Gender,
 		Status:      entity.USER_PENDING,
 		BirthDate:   &birthDate,
 		BirthPlace:  payload.BirthPlace,
 		NoKTP:       payload.NoKTP,
 		MobilePhone: payload.MobilePhone,
+
+		PasswordChangedAt: &passwordChangedAt,
 	}
 
 	role, err := uc.RoleRepository.FindByName("Applicant")
@@ -112,27 +127,12 @@ func (uc *RegisterUserUseCase) Execute(payload IRegisterUserUseCaseRequest) (*IR
 		return nil, err
 	}
 
-	randomIntToken, err := utils.GenerateRandomIntToken(6)
-	if err != nil {
-		log.Fatalf("Failed to generate random token: %v", err)
-	}
-	tokenInt, err := strconv.Atoi(randomIntToken)
-	if err != nil {
-		uc.Log.Error("[UserUseCase.Register] " + err.Error())
-		return nil, err
-	}
-	if err := uc.Repository.CreateUserToken(payload.Email, tokenInt, entity.UserTokenVerification); err != nil {
+	if err := recordPasswordHistory(uc.UserPasswordHistoryRepository, payload.PasswordPolicy, user.ID, user.Password); err != nil {
 		uc.Log.Error("[UserUseCase.Register] " + err.Error())
 		return nil, err
 	}
 
-	if _, err := uc.MailMessage.SendMail(&request.MailRequest{
-		Email:   user.Email,
-		Subject: "Email Verification",
-		Body:    "Your verification code is " + randomIntToken,
-		From:    "ilham.ahmadz18@gmail.com",
-		To:      user.Email,
-	}); err != nil {
+	if err := sendVerificationCode(uc.Repository, uc.MailMessage, payload.EmailVerificationPolicy, user, payload.From); err != nil {
 		uc.Log.Error("[UserUseCase.Register] " + err.Error())
 		return nil, err
 	}
@@ -145,6 +145,8 @@ func (uc *RegisterUserUseCase) Execute(payload IRegisterUserUseCaseRequest) (*IR
 func RegisterUserUseCaseFactory(log *logrus.Logger) IRegisterUserUseCase {
 	userRepository := repository.UserRepositoryFactory(log)
 	roleRepository := repository.RoleRepositoryFactory(log)
+	userPasswordHistoryRepository := repository.UserPasswordHistoryRepositoryFactory(log)
 	mailMessage := messaging.MailMessageFactory(log)
-	return NewRegisterUserUseCase(log, userRepository, roleRepository, mailMessage)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewRegisterUserUseCase(log, userRepository, roleRepository, userPasswordHistoryRepository, mailMessage, passwordHasher)
 }
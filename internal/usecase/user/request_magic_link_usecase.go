@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/http/request"
+	"app/go-sso/internal/messaging"
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MagicLinkMessage is the answer to every magic link request, so the response
+// does not tell whether the email belongs to an account.
+const MagicLinkMessage = "If an account exists for this email, a sign-in link has been sent to it"
+
+// ErrMagicLinkDisabled is returned for unknown applications and applications
+// that did not enable magic link sign-in alike.
+var ErrMagicLinkDisabled = errors.New("Signing in with an email link is not available for this application")
+
+type IRequestMagicLinkUseCaseRequest struct {
+	Email       string        `json:"email"`
+	Application string        `json:"application"`
+	LinkURL     string        `json:"link_url"`
+	From        string        `json:"from"`
+	TTL         time.Duration `json:"ttl"`
+	IPAddress   string        `json:"ip_address"`
+}
+
+type IRequestMagicLinkUseCaseResponse struct {
+	Message string `json:"message"`
+}
+
+type IRequestMagicLinkUseCase interface {
+	Execute(request *IRequestMagicLinkUseCaseRequest) (*IRequestMagicLinkUseCaseResponse, error)
+}
+
+type RequestMagicLinkUseCase struct {
+	Log                   *logrus.Logger
+	Repository            repository.IUserRepository
+	ApplicationRepository repository.IApplicationRepository
+	MailMessage           messaging.IMailMessage
+	LoginThrottleService  service.ILoginThrottleService
+}
+
+func NewRequestMagicLinkUseCase(
+	log *logrus.Logger,
+	repository repository.IUserRepository,
+	applicationRepository repository.IApplicationRepository,
+	mailMessage messaging.IMailMessage,
+	loginThrottleService service.ILoginThrottleService,
+) IRequestMagicLinkUseCase {
+	return &RequestMagicLinkUseCase{
+		Log:                   log,
+		Repository:            repository,
+		ApplicationRepository: applicationRepository,
+		MailMessage:           mailMessage,
+		LoginThrottleService:  loginThrottleService,
+	}
+}
+
+// Execute mails a single-use sign-in link for the application to the user,
+// replacing any earlier link. Unknown emails and delivery failures are only
+// logged, the caller always gets the same message.
+func (uc *RequestMagicLinkUseCase) Execute(req *IRequestMagicLinkUseCaseRequest) (*IRequestMagicLinkUseCaseResponse, error) {
+	application, err := uc.ApplicationRepository.FindApplicationByName(req.Application)
+	if err != nil || !application.MagicLinkEnabled {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	// every request counts as an attempt, so the endpoint cannot be used to
+	// flood an inbox
+	if err := uc.LoginThrottleService.Check(entity.LOGIN_THROTTLE_MAGIC_LINK, req.Email, req.IPAddress); err != nil {
+		return nil, err
+	}
+	if err := uc.LoginThrottleService.RecordFailure(entity.LOGIN_THROTTLE_MAGIC_LINK, req.Email, req.IPAddress); err != nil {
+		uc.Log.Error("[RequestMagicLinkUseCase.Execute] " + err.Error())
+	}
+
+	resp := &IRequestMagicLinkUseCaseResponse{
+		Message: MagicLinkMessage,
+	}
+
+	user, err := uc.Repository.FindByEmailOnly(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		uc.Log.Warn("[RequestMagicLinkUseCase.Execute] magic link requested for an unknown email")
+		return resp, nil
+	}
+
+	token := utils.GenerateRandomStringToken(64)
+	if err := uc.Repository.ReplaceHashedUserToken(&entity.UserToken{
+		Email:       user.Email,
+		TokenHash:   utils.HashToken(token),
+		TokenType:   entity.UserTokenMagicLink,
+		Application: application.Name,
+		ExpiredAt:   time.Now().Add(req.TTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	linkURL, err := url.Parse(req.LinkURL)
+	if err != nil {
+		uc.Log.Error("[RequestMagicLinkUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	query := linkURL.Query()
+	query.Set("token", token)
+	linkURL.RawQuery = query.Encode()
+
+	if _, err := uc.MailMessage.SendMail(&request.MailRequest{
+		Email:   user.Email,
+		Subject: "Sign in to " + application.Label,
+		Body: fmt.Sprintf(
+			"Open the link below to sign in to %s. The link expires in %d minutes and can be used once.\n\n%s\n\nIf you did not ask to sign in, you can ignore this email.",
+			application.Label, int(req.TTL.Minutes()), linkURL.String(),
+		),
+		From: req.From,
+		To:   user.Email,
+	}); err != nil {
+		uc.Log.Error("[RequestMagicLinkUseCase.Execute] " + err.Error())
+	}
+
+	return resp, nil
+}
+
+func RequestMagicLinkUseCaseFactory(log *logrus.Logger) IRequestMagicLinkUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	mailMessage := messaging.MailMessageFactory(log)
+	loginThrottleService := service.LoginThrottleServiceFactory(log)
+	return NewRequestMagicLinkUseCase(log, userRepository, applicationRepository, mailMessage, loginThrottleService)
+}
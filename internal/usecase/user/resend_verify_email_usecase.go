@@ -2,19 +2,37 @@ package usecase
 
 import (
 	"app/go-sso/internal/entity"
-	"app/go-sso/internal/http/request"
 	"app/go-sso/internal/messaging"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
 	"app/go-sso/utils"
 	"errors"
-	"log"
-	"strconv"
+	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type IResendVerfiyEmailUseCaseRequest struct {
-	Email string `json:"email"`
+	Email     string `json:"email"`
+	IPAddress string `json:"ip_address"`
+
+	EmailVerificationPolicy utils.EmailVerificationPolicy `json:"-"`
+	From                    string                        `json:"-"`
+}
+
+// VerificationCooldownError is returned when a new code is asked for before
+// the resend cooldown of the previous one has passed.
+type VerificationCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *VerificationCooldownError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return fmt.Sprintf("A code was sent recently, please wait %s before asking for a new one", wait)
 }
 
 type IResendVerfiyEmailUseCaseResponse struct {
@@ -26,24 +44,38 @@ type IResendVerfiyEmailUseCase interface {
 }
 
 type ResendVerfiyEmailUseCase struct {
-	Log         *logrus.Logger
-	Repository  repository.IUserRepository
-	MailMessage messaging.IMailMessage
+	Log                  *logrus.Logger
+	Repository           repository.IUserRepository
+	MailMessage          messaging.IMailMessage
+	LoginThrottleService service.ILoginThrottleService
 }
 
 func NewResendVerfiyEmailUseCase(
 	log *logrus.Logger,
 	repository repository.IUserRepository,
 	mailMessage messaging.IMailMessage,
+	loginThrottleService service.ILoginThrottleService,
 ) IResendVerfiyEmailUseCase {
 	return &ResendVerfiyEmailUseCase{
-		Log:         log,
-		Repository:  repository,
-		MailMessage: mailMessage,
+		Log:                  log,
+		Repository:           repository,
+		MailMessage:          mailMessage,
+		LoginThrottleService: loginThrottleService,
 	}
 }
 
+// Execute mails a new code, replacing the previous one, unless the previous
+// one was sent less than the resend cooldown ago.
 func (u *ResendVerfiyEmailUseCase) Execute(payload IResendVerfiyEmailUseCaseRequest) (*IResendVerfiyEmailUseCaseResponse, error) {
+	// every resend counts as an attempt, so the endpoint cannot be used to
+	// flood an inbox
+	if err := u.LoginThrottleService.Check(entity.LOGIN_THROTTLE_RESEND_VERIFY_EMAIL, payload.Email, payload.IPAddress); err != nil {
+		return nil, err
+	}
+	if err := u.LoginThrottleService.RecordFailure(entity.LOGIN_THROTTLE_RESEND_VERIFY_EMAIL, payload.Email, payload.IPAddress); err != nil {
+		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
+	}
+
 	user, err := u.Repository.FindByEmailOnly(payload.Email)
 	if err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
@@ -55,34 +87,19 @@ func (u *ResendVerfiyEmailUseCase) Execute(payload IResendVerfiyEmailUseCaseRequ
 		return nil, errors.New("user not found")
 	}
 
-	err = u.Repository.DeleteUserToken(user.Email, entity.UserTokenVerification)
-	if err != nil {
-		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
-		return nil, err
-	}
-
-	randomIntToken, err := utils.GenerateRandomIntToken(6)
-	if err != nil {
-		log.Fatalf("Failed to generate random token: %v", err)
-	}
-	randomIntTokenInt, err := strconv.Atoi(randomIntToken)
+	userToken, err := u.Repository.FindUserTokenByEmail(user.Email)
 	if err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
 		return nil, err
 	}
-	if err := u.Repository.CreateUserToken(payload.Email, randomIntTokenInt, entity.UserTokenVerification); err != nil {
-		u.Log.Error("[UserUseCase.Register] " + err.Error())
-		return nil, err
+	if userToken != nil {
+		if wait := time.Until(userToken.CreatedAt.Add(payload.EmailVerificationPolicy.ResendCooldown)); wait > 0 {
+			return nil, &VerificationCooldownError{RetryAfter: wait}
+		}
 	}
 
-	if _, err := u.MailMessage.SendMail(&request.MailRequest{
-		Email:   user.Email,
-		Subject: "Email Verification",
-		Body:    "Your verification code is " + randomIntToken,
-		From:    "ilham.ahmadz18@gmail.com",
-		To:      user.Email,
-	}); err != nil {
-		u.Log.Error("[UserUseCase.Register] " + err.Error())
+	if err := sendVerificationCode(u.Repository, u.MailMessage, payload.EmailVerificationPolicy, user, payload.From); err != nil {
+		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
 		return nil, err
 	}
 
@@ -94,5 +111,6 @@ func (u *ResendVerfiyEmailUseCase) Execute(payload IResendVerfiyEmailUseCaseRequ
 func ResendVerfiyEmailUseCaseFactory(log *logrus.Logger) IResendVerfiyEmailUseCase {
 	userRepository := repository.UserRepositoryFactory(log)
 	mailMessage := messaging.MailMessageFactory(log)
-	return NewResendVerfiyEmailUseCase(log, userRepository, mailMessage)
+	loginThrottleService := service.LoginThrottleServiceFactory(log)
+	return NewResendVerfiyEmailUseCase(log, userRepository, mailMessage, loginThrottleService)
 }
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired and used reset tokens
+// alike.
+var ErrInvalidResetToken = errors.New("The password reset link is invalid or has expired")
+
+type IResetPasswordUseCaseRequest struct {
+	Token          string               `json:"token"`
+	Password       string               `json:"password"`
+	PasswordPolicy utils.PasswordPolicy `json:"-"`
+}
+
+type IResetPasswordUseCaseResponse struct {
+	User *entity.User `json:"user"`
+}
+
+type IResetPasswordUseCase interface {
+	Execute(request *IResetPasswordUseCaseRequest) (*IResetPasswordUseCaseResponse, error)
+}
+
+type ResetPasswordUseCase struct {
+	Log                           *logrus.Logger
+	Repository                    repository.IUserRepository
+	AuthTokenRepository           repository.IAuthTokenRepository
+	RevokedTokenRepository        repository.IRevokedTokenRepository
+	UserSessionRepository         repository.IUserSessionRepository
+	UserPasswordHistoryRepository repository.IUserPasswordHistoryRepository
+	PasswordHasher                utils.PasswordHasher
+}
+
+func NewResetPasswordUseCase(
+	log *logrus.Logger,
+	repository repository.IUserRepository,
+	authTokenRepository repository.IAuthTokenRepository,
+	revokedTokenRepository repository.IRevokedTokenRepository,
+	userSessionRepository repository.IUserSessionRepository,
+	userPasswordHistoryRepository repository.IUserPasswordHistoryRepository,
+	passwordHasher utils.PasswordHasher,
+) IResetPasswordUseCase {
+	return &ResetPasswordUseCase{
+		Log:                           log,
+		Repository:                    repository,
+		AuthTokenRepository:           authTokenRepository,
+		RevokedTokenRepository:        revokedTokenRepository,
+		UserSessionRepository:         userSessionRepository,
+		UserPasswordHistoryRepository: userPasswordHistoryRepository,
+		PasswordHasher:                passwordHasher,
+	}
+}
+
+// Execute consumes a reset token and sets the new password. The token is only
+// consumed once the password passes the policy, so a refused password can be
+// corrected with the same link. Whoever held the old password is signed out: the web sessions of the user are deleted and
+// their refresh and access tokens revoked. The user is returned so the caller
+// can end the application sessions as well.
+func (uc *ResetPasswordUseCase) Execute(req *IResetPasswordUseCaseRequest) (*IResetPasswordUseCaseResponse, error) {
+	tokenHash := utils.HashToken(req.Token)
+	userToken, err := uc.Repository.FindHashedUserToken(tokenHash, entity.UserTokenResetPassword)
+	if err != nil {
+		return nil, err
+	}
+	if userToken == nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	user, err := uc.Repository.FindByEmailOnly(userToken.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	if err := checkPasswordPolicy(uc.UserPasswordHistoryRepository, uc.PasswordHasher, req.PasswordPolicy, user, req.Password); err != nil {
+		return nil, err
+	}
+
+	userToken, err = uc.Repository.ConsumeHashedUserToken(tokenHash, entity.UserTokenResetPassword)
+	if err != nil {
+		return nil, err
+	}
+	if userToken == nil || userToken.Email != user.Email {
+		return nil, ErrInvalidResetToken
+	}
+
+	hashedPassword, err := uc.PasswordHasher.Hash(req.Password)
+	if err != nil {
+		uc.Log.Error("[ResetPasswordUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+	if err := uc.Repository.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+	if err := recordPasswordHistory(uc.UserPasswordHistoryRepository, req.PasswordPolicy, user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := uc.UserSessionRepository.DeleteByUserID(user.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.AuthTokenRepository.RevokeAuthTokensByUserID(user.ID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	revokedToken, err := uc.RevokedTokenRepository.CreateRevokedToken(&entity.RevokedToken{
+		UserID:    user.ID,
+		RevokedAt: now,
+		ExpiredAt: now.Add(utils.AccessTokenTTL()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	utils.MarkTokenRevoked(revokedToken)
+
+	return &IResetPasswordUseCaseResponse{
+		User: user,
+	}, nil
+}
+
+func ResetPasswordUseCaseFactory(log *logrus.Logger) IResetPasswordUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	authTokenRepository := repository.AuthTokenRepositoryFactory(log)
+	revokedTokenRepository := repository.RevokedTokenRepositoryFactory(log)
+	userSessionRepository := repository.UserSessionRepositoryFactory(log)
+	userPasswordHistoryRepository := repository.UserPasswordHistoryRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewResetPasswordUseCase(log, userRepository, authTokenRepository, revokedTokenRepository, userSessionRepository, userPasswordHistoryRepository, passwordHasher)
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IUnlockUserUseCaseRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type IUnlockUserUseCaseResponse struct {
+	Message string `json:"message"`
+}
+
+type IUnlockUserUseCase interface {
+	Execute(request *IUnlockUserUseCaseRequest) (*IUnlockUserUseCaseResponse, error)
+}
+
+type UnlockUserUseCase struct {
+	Log                     *logrus.Logger
+	Repository              repository.IUserRepository
+	LoginThrottleRepository repository.ILoginThrottleRepository
+}
+
+func NewUnlockUserUseCase(log *logrus.Logger, repository repository.IUserRepository, loginThrottleRepository repository.ILoginThrottleRepository) IUnlockUserUseCase {
+	return &UnlockUserUseCase{
+		Log:                     log,
+		Repository:              repository,
+		LoginThrottleRepository: loginThrottleRepository,
+	}
+}
+
+// Execute lifts the lockout of an account and forgets its failed attempts.
+func (uc *UnlockUserUseCase) Execute(request *IUnlockUserUseCaseRequest) (*IUnlockUserUseCaseResponse, error) {
+	user, err := uc.Repository.FindByIdOnly(request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("User not found")
+	}
+
+	if err := uc.LoginThrottleRepository.DeleteAccount(strings.ToLower(strings.TrimSpace(user.Email))); err != nil {
+		return nil, err
+	}
+
+	return &IUnlockUserUseCaseResponse{
+		Message: "The user has been unlocked",
+	}, nil
+}
+
+func UnlockUserUseCaseFactory(log *logrus.Logger) IUnlockUserUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	loginThrottleRepository := repository.LoginThrottleRepositoryFactory(log)
+	return NewUnlockUserUseCase(log, userRepository, loginThrottleRepository)
+}
@@ -3,7 +3,10 @@ package usecase
 import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -12,6 +15,10 @@ import (
 type IUpdateUserUseCaseRequest struct {
 	User    *entity.User `json:"user"`
 	RoleIDs []string     `json:"role_ids,omitempty"`
+	// Password changes the password of the user when set, it has to pass
+	// PasswordPolicy
+	Password       string               `json:"-"`
+	PasswordPolicy utils.PasswordPolicy `json:"-"`
 }
 
 type IUpdateUserUseCaseResponse struct {
@@ -23,14 +30,18 @@ type IUpdateUserUseCase interface {
 }
 
 type UpdateUserUseCase struct {
-	Log            *logrus.Logger
-	userRepository repository.IUserRepository
+	Log                           *logrus.Logger
+	userRepository                repository.IUserRepository
+	userPasswordHistoryRepository repository.IUserPasswordHistoryRepository
+	passwordHasher                utils.PasswordHasher
 }
 
-func NewUpdateUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository) *UpdateUserUseCase {
+func NewUpdateUserUseCase(log *logrus.Logger, userRepository repository.IUserRepository, userPasswordHistoryRepository repository.IUserPasswordHistoryRepository, passwordHasher utils.PasswordHasher) *UpdateUserUseCase {
 	return &UpdateUserUseCase{
-		Log:            log,
-		userRepository: userRepository,
+		Log:                           log,
+		userRepository:                userRepository,
+		userPasswordHistoryRepository: userPasswordHistoryRepository,
+		passwordHasher:                passwordHasher,
 	}
 }
 
@@ -57,12 +68,34 @@ func (uc *UpdateUserUseCase) Execute(request IUpdateUserUseCaseRequest) (IUpdate
 		roleUUIDs = append(roleUUIDs, roleUUID)
 	}
 
+	if request.Password != "" {
+		if err := checkPasswordPolicy(uc.userPasswordHistoryRepository, uc.passwordHasher, request.PasswordPolicy, userExist, request.Password); err != nil {
+			uc.Log.Warn("Update user usecase: " + err.Error())
+			return IUpdateUserUseCaseResponse{}, err
+		}
+		hashedPassword, err := uc.passwordHasher.Hash(request.Password)
+		if err != nil {
+			uc.Log.Error("Update user usecase: " + err.Error())
+			return IUpdateUserUseCaseResponse{}, errors.New("[UpdateUserUseCase] error hash password: " + err.Error())
+		}
+		now := time.Now()
+		request.User.Password = hashedPassword
+		request.User.PasswordChangedAt = &now
+	}
+
 	user, err := uc.userRepository.UpdateUser(request.User, roleUUIDs)
 	if err != nil {
 		uc.Log.Error("Update user usecase: " + err.Error())
 		return IUpdateUserUseCaseResponse{}, errors.New("[UpdateUserUseCase] error update user: " + err.Error())
 	}
 
+	if request.Password != "" {
+		if err := recordPasswordHistory(uc.userPasswordHistoryRepository, request.PasswordPolicy, user.ID, request.User.Password); err != nil {
+			uc.Log.Error("Update user usecase: " + err.Error())
+			return IUpdateUserUseCaseResponse{}, errors.New("[UpdateUserUseCase] error record password history: " + err.Error())
+		}
+	}
+
 	return IUpdateUserUseCaseResponse{
 		User: user,
 	}, nil
@@ -70,5 +103,7 @@ func (uc *UpdateUserUseCase) Execute(request IUpdateUserUseCaseRequest) (IUpdate
 
 func UpdateUserUseCaseFactory(log *logrus.Logger) *UpdateUserUseCase {
 	userRepository := repository.UserRepositoryFactory(log)
-	return NewUpdateUserUseCase(log, userRepository)
+	userPasswordHistoryRepository := repository.UserPasswordHistoryRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewUpdateUserUseCase(log, userRepository, userPasswordHistoryRepository, passwordHasher)
 }
@@ -4,14 +4,31 @@ import (
 	"app/go-sso/internal/entity"
 	"app/go-sso/internal/messaging"
 	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
 	"errors"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrVerificationCodeExpired      = errors.New("The verification code has expired, please ask for a new one")
+	ErrVerificationAttemptsExceeded = errors.New("Too many wrong codes, please ask for a new one")
+	ErrInvalidVerificationCode      = errors.New("The verification code is incorrect")
+	ErrInvalidVerificationLink      = errors.New("The verification link is invalid or has expired")
+)
+
+// IVerifyEmailUseCaseRequest carries either the Token typed by the user or
+// the Expires and Signature of a verification link.
 type IVerifyEmailUseCaseRequest struct {
-	Email string `json:"email"`
-	Token int    `json:"token"`
+	Email     string `json:"email"`
+	Token     string `json:"token"`
+	Expires   string `json:"expires"`
+	Signature string `json:"signature"`
+	IPAddress string `json:"ip_address"`
+
+	EmailVerificationPolicy utils.EmailVerificationPolicy `json:"-"`
 }
 
 type IVerifyEmailUseCaseResponse struct {
@@ -23,24 +40,36 @@ type IVerifyEmailUseCase interface {
 }
 
 type VerifyEmailUseCase struct {
-	Log         *logrus.Logger
-	Repository  repository.IUserRepository
-	MailMessage messaging.IMailMessage
+	Log                  *logrus.Logger
+	Repository           repository.IUserRepository
+	MailMessage          messaging.IMailMessage
+	LoginThrottleService service.ILoginThrottleService
 }
 
 func NewVerifyEmailUseCase(
 	log *logrus.Logger,
 	repository repository.IUserRepository,
 	mailMessage messaging.IMailMessage,
+	loginThrottleService service.ILoginThrottleService,
 ) IVerifyEmailUseCase {
 	return &VerifyEmailUseCase{
-		Log:         log,
-		Repository:  repository,
-		MailMessage: mailMessage,
+		Log:                  log,
+		Repository:           repository,
+		MailMessage:          mailMessage,
+		LoginThrottleService: loginThrottleService,
 	}
 }
 
+// Execute verifies the email with the code or the link of the latest code
+// mailed to it. Codes expire, and are refused once MaxAttempts wrong ones were
+// entered for them.
 func (u *VerifyEmailUseCase) Execute(payload IVerifyEmailUseCaseRequest) (*IVerifyEmailUseCaseResponse, error) {
+	// wrong codes are throttled like passwords, a 6 digit code falls to
+	// unlimited guessing
+	if err := u.LoginThrottleService.Check(entity.LOGIN_THROTTLE_VERIFY_EMAIL, payload.Email, payload.IPAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := u.Repository.FindByEmailOnly(payload.Email)
 	if err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
@@ -49,47 +78,76 @@ func (u *VerifyEmailUseCase) Execute(payload IVerifyEmailUseCaseRequest) (*IVeri
 
 	if user == nil {
 		u.Log.Warn("[UserUseCase.VerifyUserEmail] User not found")
+		u.verifyFailed(payload)
 		return nil, errors.New("user not found")
 	}
 
-	userToken, err := u.Repository.FindUserTokenByEmailAndToken(payload.Email, payload.Token)
+	userToken, err := u.Repository.FindUserTokenByEmail(user.Email)
 	if err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
 		return nil, err
 	}
 
-	if userToken == nil {
-		u.Log.Warn("[UserUseCase.VerifyUserEmail] User token not found")
-		return nil, errors.New("user token not found")
+	now := time.Now()
+	if userToken == nil || userToken.ExpiredAt.Before(now) {
+		u.Log.Warn("[UserUseCase.VerifyUserEmail] User token not found or expired")
+		return nil, ErrVerificationCodeExpired
 	}
 
-	// if userToken.ExpiredAt.Before(time.Now()) {
-	// 	u.Log.Warn("[UserUseCase.VerifyUserEmail] Token expired")
-	// 	return nil, errors.New("token expired")
-	// }
+	policy := payload.EmailVerificationPolicy
+	if policy.MaxAttempts > 0 && userToken.Attempts >= policy.MaxAttempts {
+		u.Log.Warn("[UserUseCase.VerifyUserEmail] Too many wrong codes")
+		return nil, ErrVerificationAttemptsExceeded
+	}
 
-	if userToken.Token != payload.Token {
+	if payload.Signature != "" {
+		if !policy.VerifyLink(user.Email, userToken.TokenHash, payload.Expires, payload.Signature, now) {
+			u.Log.Warn("[UserUseCase.VerifyUserEmail] Invalid link")
+			u.verifyFailed(payload)
+			return nil, ErrInvalidVerificationLink
+		}
+	} else if !policy.VerifyCode(user.Email, payload.Token, userToken.TokenHash) {
 		u.Log.Warn("[UserUseCase.VerifyUserEmail] Invalid token")
-		return nil, errors.New("invalid token")
+		if err := u.Repository.IncrementUserTokenAttempts(user.Email, entity.UserTokenVerification); err != nil {
+			u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
+		}
+		u.verifyFailed(payload)
+		return nil, ErrInvalidVerificationCode
 	}
 
-	if err := u.Repository.VerifyUserEmail(user.Email); err != nil {
+	// the code is single use, a concurrent request may have used it first
+	consumed, err := u.Repository.ConsumeHashedUserToken(userToken.TokenHash, entity.UserTokenVerification)
+	if err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
 		return nil, err
 	}
+	if consumed == nil {
+		return nil, ErrVerificationCodeExpired
+	}
 
-	if err := u.Repository.AcknowledgeUserToken(user.Email, userToken.Token); err != nil {
+	if err := u.Repository.VerifyUserEmail(user.Email); err != nil {
 		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
 		return nil, err
 	}
 
+	if err := u.LoginThrottleService.Reset(entity.LOGIN_THROTTLE_VERIFY_EMAIL, payload.Email); err != nil {
+		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
+	}
+
 	return &IVerifyEmailUseCaseResponse{
 		User: user,
 	}, nil
 }
 
+func (u *VerifyEmailUseCase) verifyFailed(payload IVerifyEmailUseCaseRequest) {
+	if err := u.LoginThrottleService.RecordFailure(entity.LOGIN_THROTTLE_VERIFY_EMAIL, payload.Email, payload.IPAddress); err != nil {
+		u.Log.Error("[UserUseCase.VerifyUserEmail] " + err.Error())
+	}
+}
+
 func VerifyEmailUseCaseFactory(log *logrus.Logger) IVerifyEmailUseCase {
 	repository := repository.UserRepositoryFactory(log)
 	mailMessage := messaging.MailMessageFactory(log)
-	return NewVerifyEmailUseCase(log, repository, mailMessage)
+	loginThrottleService := service.LoginThrottleServiceFactory(log)
+	return NewVerifyEmailUseCase(log, repository, mailMessage, loginThrottleService)
 }
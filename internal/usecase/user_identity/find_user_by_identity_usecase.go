@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrIdentityNotLinked is returned for provider accounts that no user can be
+// found for.
+var ErrIdentityNotLinked = errors.New("This account is not linked to any user, sign in and link it from your account settings")
+
+// ErrNoDefaultRoles is returned for sign-ins that would provision a user
+// without any role, as users need one to receive a token.
+var ErrNoDefaultRoles = errors.New("No role is configured for new accounts of this application, ask an administrator")
+
+// ErrAwaitingApproval is returned for users provisioned on their first
+// sign-in that an administrator has not activated yet.
+var ErrAwaitingApproval = errors.New("Your account is waiting for the approval of an administrator")
+
+type IFindUserByIdentityUseCaseRequest struct {
+	Provider entity.UserIdentityProvider `json:"provider"`
+	Subject  string                      `json:"subject"`
+	Email    string                      `json:"email"`
+	// EmailVerified is whether the provider vouches for Email, only verified
+	// emails are matched to users.
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	// Application is the application the user signs in to, new users get
+	// its default roles.
+	Application           string                      `json:"application"`
+	IPAddress             string                      `json:"ip_address"`
+	JITProvisioningPolicy utils.JITProvisioningPolicy `json:"-"`
+}
+
+type IFindUserByIdentityUseCaseResponse struct {
+	User *entity.User `json:"user"`
+	// Provisioned is set when the user was created by this sign-in.
+	Provisioned bool `json:"provisioned"`
+}
+
+type IFindUserByIdentityUseCase interface {
+	Execute(request *IFindUserByIdentityUseCaseRequest) (*IFindUserByIdentityUseCaseResponse, error)
+}
+
+type FindUserByIdentityUseCase struct {
+	Log                    *logrus.Logger
+	UserRepository         repository.IUserRepository
+	UserIdentityRepository repository.IUserIdentityRepository
+	ApplicationRepository  repository.IApplicationRepository
+	RoleRepository         repository.IRoleRepository
+	EmployeeRepository     repository.IEmployeeRepository
+	AuditLogRepository     repository.IAuditLogRepository
+	PasswordHasher         utils.PasswordHasher
+}
+
+func NewFindUserByIdentityUseCase(
+	log *logrus.Logger,
+	userRepository repository.IUserRepository,
+	userIdentityRepository repository.IUserIdentityRepository,
+	applicationRepository repository.IApplicationRepository,
+	roleRepository repository.IRoleRepository,
+	employeeRepository repository.IEmployeeRepository,
+	auditLogRepository repository.IAuditLogRepository,
+	passwordHasher utils.PasswordHasher,
+) IFindUserByIdentityUseCase {
+	return &FindUserByIdentityUseCase{
+		Log:                    log,
+		UserRepository:         userRepository,
+		UserIdentityRepository: userIdentityRepository,
+		ApplicationRepository:  applicationRepository,
+		RoleRepository:         roleRepository,
+		EmployeeRepository:     employeeRepository,
+		AuditLogRepository:     auditLogRepository,
+		PasswordHasher:         passwordHasher,
+	}
+}
+
+// Execute finds the user signing in through a provider by the linked
+// identity, so a changed email at the provider still signs in the same user.
+// Users that never signed in through the provider are matched on a verified
+// email once, and the identity is linked to them; a user whose identity at the provider is
+// another account is not matched on email.
+func (uc *FindUserByIdentityUseCase) Execute(request *IFindUserByIdentityUseCaseRequest) (*IFindUserByIdentityUseCaseResponse, error) {
+	if request.Subject == "" {
+		return nil, errors.New("The provider did not return an account id")
+	}
+
+	userIdentity, err := uc.UserIdentityRepository.FindByProviderAndSubject(request.Provider, request.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if userIdentity != nil {
+		user, err := uc.UserRepository.FindById(userIdentity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrIdentityNotLinked
+		}
+		if request.Email != "" && userIdentity.Email != request.Email {
+			if err := uc.UserIdentityRepository.UpdateEmail(userIdentity, request.Email); err != nil {
+				uc.Log.Error("[FindUserByIdentityUseCase.Execute] " + err.Error())
+			}
+		}
+		if user.Status == entity.USER_AWAITING_APPROVAL {
+			return nil, ErrAwaitingApproval
+		}
+		return &IFindUserByIdentityUseCaseResponse{User: user}, nil
+	}
+
+	if request.Email == "" || !request.EmailVerified {
+		return nil, ErrIdentityNotLinked
+	}
+	user, err := uc.UserRepository.FindByEmail(request.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return uc.provisionUser(request)
+	}
+	linked, err := uc.UserIdentityRepository.FindByUserIDAndProvider(user.ID, request.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		uc.Log.Warn("[FindUserByIdentityUseCase.Execute] another account of the provider is linked to user " + user.ID.String())
+		return nil, ErrIdentityNotLinked
+	}
+
+	if _, err := uc.UserIdentityRepository.CreateUserIdentity(&entity.UserIdentity{
+		UserID:   user.ID,
+		Provider: request.Provider,
+		Subject:  request.Subject,
+		Email:    request.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	if user.Status == entity.USER_AWAITING_APPROVAL {
+		return nil, ErrAwaitingApproval
+	}
+	return &IFindUserByIdentityUseCaseResponse{
+		User: user,
+	}, nil
+}
+
+func FindUserByIdentityUseCaseFactory(log *logrus.Logger) IFindUserByIdentityUseCase {
+	userRepository := repository.UserRepositoryFactory(log)
+	userIdentityRepository := repository.UserIdentityRepositoryFactory(log)
+	applicationRepository := repository.ApplicationRepositoryFactory(log)
+	roleRepository := repository.RoleRepositoryFactory(log)
+	employeeRepository := repository.EmployeeRepositoryFactory(log)
+	auditLogRepository := repository.AuditLogRepositoryFactory(log)
+	passwordHasher := service.PasswordHasherFactory()
+	return NewFindUserByIdentityUseCase(log, userRepository, userIdentityRepository, applicationRepository, roleRepository, employeeRepository, auditLogRepository, passwordHasher)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IFindUserIdentitiesUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type IFindUserIdentitiesUseCaseResponse struct {
+	UserIdentities []entity.UserIdentity `json:"user_identities"`
+}
+
+type IFindUserIdentitiesUseCase interface {
+	Execute(request *IFindUserIdentitiesUseCaseRequest) (*IFindUserIdentitiesUseCaseResponse, error)
+}
+
+type FindUserIdentitiesUseCase struct {
+	Log                    *logrus.Logger
+	UserIdentityRepository repository.IUserIdentityRepository
+}
+
+func NewFindUserIdentitiesUseCase(log *logrus.Logger, userIdentityRepository repository.IUserIdentityRepository) IFindUserIdentitiesUseCase {
+	return &FindUserIdentitiesUseCase{
+		Log:                    log,
+		UserIdentityRepository: userIdentityRepository,
+	}
+}
+
+func (uc *FindUserIdentitiesUseCase) Execute(request *IFindUserIdentitiesUseCaseRequest) (*IFindUserIdentitiesUseCaseResponse, error) {
+	userIdentities, err := uc.UserIdentityRepository.FindByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IFindUserIdentitiesUseCaseResponse{
+		UserIdentities: userIdentities,
+	}, nil
+}
+
+func FindUserIdentitiesUseCaseFactory(log *logrus.Logger) IFindUserIdentitiesUseCase {
+	userIdentityRepository := repository.UserIdentityRepositoryFactory(log)
+	return NewFindUserIdentitiesUseCase(log, userIdentityRepository)
+}
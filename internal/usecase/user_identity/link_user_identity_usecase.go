@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrIdentityLinkedToAnotherUser = errors.New("This account is already linked to another user")
+	ErrProviderAlreadyLinked       = errors.New("Another account of this provider is already linked, unlink it first")
+)
+
+type ILinkUserIdentityUseCaseRequest struct {
+	UserID   uuid.UUID                   `json:"user_id"`
+	Provider entity.UserIdentityProvider `json:"provider"`
+	Subject  string                      `json:"subject"`
+	Email    string                      `json:"email"`
+}
+
+type ILinkUserIdentityUseCaseResponse struct {
+	UserIdentity *entity.UserIdentity `json:"user_identity"`
+}
+
+type ILinkUserIdentityUseCase interface {
+	Execute(request *ILinkUserIdentityUseCaseRequest) (*ILinkUserIdentityUseCaseResponse, error)
+}
+
+type LinkUserIdentityUseCase struct {
+	Log                    *logrus.Logger
+	UserIdentityRepository repository.IUserIdentityRepository
+}
+
+func NewLinkUserIdentityUseCase(log *logrus.Logger, userIdentityRepository repository.IUserIdentityRepository) ILinkUserIdentityUseCase {
+	return &LinkUserIdentityUseCase{
+		Log:                    log,
+		UserIdentityRepository: userIdentityRepository,
+	}
+}
+
+// Execute links the provider account to the user. Linking the account that is
+// already linked to the user only refreshes its email.
+func (uc *LinkUserIdentityUseCase) Execute(request *ILinkUserIdentityUseCaseRequest) (*ILinkUserIdentityUseCaseResponse, error) {
+	if request.Subject == "" {
+		return nil, errors.New("The provider did not return an account id")
+	}
+
+	userIdentity, err := uc.UserIdentityRepository.FindByProviderAndSubject(request.Provider, request.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if userIdentity != nil {
+		if userIdentity.UserID != request.UserID {
+			return nil, ErrIdentityLinkedToAnotherUser
+		}
+		if userIdentity.Email != request.Email {
+			if err := uc.UserIdentityRepository.UpdateEmail(userIdentity, request.Email); err != nil {
+				return nil, err
+			}
+			userIdentity.Email = request.Email
+		}
+		return &ILinkUserIdentityUseCaseResponse{UserIdentity: userIdentity}, nil
+	}
+
+	linked, err := uc.UserIdentityRepository.FindByUserIDAndProvider(request.UserID, request.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return nil, ErrProviderAlreadyLinked
+	}
+
+	userIdentity, err = uc.UserIdentityRepository.CreateUserIdentity(&entity.UserIdentity{
+		UserID:   request.UserID,
+		Provider: request.Provider,
+		Subject:  request.Subject,
+		Email:    request.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ILinkUserIdentityUseCaseResponse{
+		UserIdentity: userIdentity,
+	}, nil
+}
+
+func LinkUserIdentityUseCaseFactory(log *logrus.Logger) ILinkUserIdentityUseCase {
+	userIdentityRepository := repository.UserIdentityRepositoryFactory(log)
+	return NewLinkUserIdentityUseCase(log, userIdentityRepository)
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/utils"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// provisionUser creates the account of a verified email signing in through a
+// provider for the first time, as the JIT provisioning policy allows, and
+// links the identity to it. The account gets the default roles of the
+// application and the employee with the same email, and an audit entry
+// records it. Without default roles no account is created, as a user without
+// roles cannot receive a token. Its password is random, so it can only be
+// used after a reset.
+func (uc *FindUserByIdentityUseCase) provisionUser(request *IFindUserByIdentityUseCaseRequest) (*IFindUserByIdentityUseCaseResponse, error) {
+	policy := request.JITProvisioningPolicy
+	if !policy.Enabled {
+		return nil, ErrIdentityNotLinked
+	}
+
+	status := entity.USER_ACTIVE
+	if !policy.DomainAllowed(request.Email) {
+		if policy.UnknownDomains != utils.JIT_UNKNOWN_DOMAIN_APPROVAL {
+			uc.Log.Warn("[FindUserByIdentityUseCase.provisionUser] refused to provision an email of an unknown domain")
+			return nil, ErrIdentityNotLinked
+		}
+		status = entity.USER_AWAITING_APPROVAL
+	}
+
+	roleIDs, roleNames := uc.defaultRoles(policy, request.Application)
+	if len(roleIDs) == 0 {
+		uc.Log.Warn("[FindUserByIdentityUseCase.provisionUser] refused to provision a user without roles for " + request.Application)
+		return nil, ErrNoDefaultRoles
+	}
+
+	var employeeID *uuid.UUID
+	if policy.LinkEmployee {
+		employee, err := uc.EmployeeRepository.FindByEmail(request.Email)
+		if err != nil {
+			return nil, err
+		}
+		if employee != nil && employee.User == nil {
+			employeeID = &employee.ID
+		}
+	}
+
+	hashedPassword, err := uc.PasswordHasher.Hash(utils.GenerateRandomStringToken(64))
+	if err != nil {
+		uc.Log.Error("[FindUserByIdentityUseCase.provisionUser] " + err.Error())
+		return nil, err
+	}
+
+	name := request.Name
+	if name == "" {
+		name, _, _ = strings.Cut(request.Email, "@")
+	}
+
+	user, err := uc.UserRepository.CreateUser(&entity.User{
+		Username:        request.Email,
+		Email:           request.Email,
+		Name:            name,
+		Password:        hashedPassword,
+		Status:          status,
+		EmailVerifiedAt: time.Now(),
+		EmployeeID:      employeeID,
+	}, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.UserIdentityRepository.CreateUserIdentity(&entity.UserIdentity{
+		UserID:   user.ID,
+		Provider: request.Provider,
+		Subject:  request.Subject,
+		Email:    request.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"provider":    request.Provider,
+		"subject":     request.Subject,
+		"email":       request.Email,
+		"application": request.Application,
+		"status":      status,
+		"roles":       roleNames,
+		"employee_id": employeeID,
+	})
+	if _, err := uc.AuditLogRepository.CreateAuditLog(&entity.AuditLog{
+		Action:    entity.AUDIT_USER_PROVISIONED,
+		UserID:    &user.ID,
+		Source:    string(request.Provider),
+		IPAddress: request.IPAddress,
+		Details:   string(details),
+	}); err != nil {
+		uc.Log.Error("[FindUserByIdentityUseCase.provisionUser] " + err.Error())
+	}
+
+	if status == entity.USER_AWAITING_APPROVAL {
+		return nil, ErrAwaitingApproval
+	}
+	return &IFindUserByIdentityUseCaseResponse{
+		User:        user,
+		Provisioned: true,
+	}, nil
+}
+
+// defaultRoles finds the default roles of application. Roles missing from the
+// application are logged and left out rather than failing the sign-in.
+func (uc *FindUserByIdentityUseCase) defaultRoles(policy utils.JITProvisioningPolicy, applicationName string) ([]uuid.UUID, []string) {
+	roleIDs := []uuid.UUID{}
+	roleNames := []string{}
+
+	names := policy.RolesFor(applicationName)
+	if len(names) == 0 {
+		return roleIDs, roleNames
+	}
+	application, err := uc.ApplicationRepository.FindApplicationByName(applicationName)
+	if err != nil {
+		uc.Log.Error("[FindUserByIdentityUseCase.defaultRoles] " + err.Error())
+		return roleIDs, roleNames
+	}
+
+	for _, name := range names {
+		role, err := uc.RoleRepository.FindByApplicationIDAndName(application.ID, name)
+		if err != nil || role == nil {
+			uc.Log.Error("[FindUserByIdentityUseCase.defaultRoles] role " + name + " of " + applicationName + " not found")
+			continue
+		}
+		roleIDs = append(roleIDs, role.ID)
+		roleNames = append(roleNames, role.Name)
+	}
+	return roleIDs, roleNames
+}
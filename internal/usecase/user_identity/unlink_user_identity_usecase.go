@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IUnlinkUserIdentityUseCaseRequest struct {
+	UserID   uuid.UUID                   `json:"user_id"`
+	Provider entity.UserIdentityProvider `json:"provider"`
+}
+
+type IUnlinkUserIdentityUseCase interface {
+	Execute(request *IUnlinkUserIdentityUseCaseRequest) error
+}
+
+type UnlinkUserIdentityUseCase struct {
+	Log                    *logrus.Logger
+	UserIdentityRepository repository.IUserIdentityRepository
+}
+
+func NewUnlinkUserIdentityUseCase(log *logrus.Logger, userIdentityRepository repository.IUserIdentityRepository) IUnlinkUserIdentityUseCase {
+	return &UnlinkUserIdentityUseCase{
+		Log:                    log,
+		UserIdentityRepository: userIdentityRepository,
+	}
+}
+
+func (uc *UnlinkUserIdentityUseCase) Execute(request *IUnlinkUserIdentityUseCaseRequest) error {
+	userIdentity, err := uc.UserIdentityRepository.FindByUserIDAndProvider(request.UserID, request.Provider)
+	if err != nil {
+		return err
+	}
+	if userIdentity == nil {
+		return errors.New("No account of this provider is linked")
+	}
+
+	return uc.UserIdentityRepository.DeleteUserIdentity(userIdentity.ID)
+}
+
+func UnlinkUserIdentityUseCaseFactory(log *logrus.Logger) IUnlinkUserIdentityUseCase {
+	userIdentityRepository := repository.UserIdentityRepositoryFactory(log)
+	return NewUnlinkUserIdentityUseCase(log, userIdentityRepository)
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IDeleteExpiredUserSessionsUseCaseResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+type IDeleteExpiredUserSessionsUseCase interface {
+	Execute() (*IDeleteExpiredUserSessionsUseCaseResponse, error)
+}
+
+type DeleteExpiredUserSessionsUseCase struct {
+	Log                   *logrus.Logger
+	UserSessionRepository repository.IUserSessionRepository
+}
+
+func NewDeleteExpiredUserSessionsUseCase(log *logrus.Logger, userSessionRepository repository.IUserSessionRepository) IDeleteExpiredUserSessionsUseCase {
+	return &DeleteExpiredUserSessionsUseCase{
+		Log:                   log,
+		UserSessionRepository: userSessionRepository,
+	}
+}
+
+func (uc *DeleteExpiredUserSessionsUseCase) Execute() (*IDeleteExpiredUserSessionsUseCaseResponse, error) {
+	deleted, err := uc.UserSessionRepository.DeleteExpired()
+	if err != nil {
+		return nil, err
+	}
+
+	return &IDeleteExpiredUserSessionsUseCaseResponse{
+		Deleted: deleted,
+	}, nil
+}
+
+func DeleteExpiredUserSessionsUseCaseFactory(log *logrus.Logger) IDeleteExpiredUserSessionsUseCase {
+	userSessionRepository := repository.UserSessionRepositoryFactory(log)
+	return NewDeleteExpiredUserSessionsUseCase(log, userSessionRepository)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IFindUserSessionsUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type IFindUserSessionsUseCaseResponse struct {
+	UserSessions []entity.UserSession `json:"user_sessions"`
+}
+
+type IFindUserSessionsUseCase interface {
+	Execute(request *IFindUserSessionsUseCaseRequest) (*IFindUserSessionsUseCaseResponse, error)
+}
+
+type FindUserSessionsUseCase struct {
+	Log                   *logrus.Logger
+	UserSessionRepository repository.IUserSessionRepository
+}
+
+func NewFindUserSessionsUseCase(log *logrus.Logger, userSessionRepository repository.IUserSessionRepository) IFindUserSessionsUseCase {
+	return &FindUserSessionsUseCase{
+		Log:                   log,
+		UserSessionRepository: userSessionRepository,
+	}
+}
+
+func (uc *FindUserSessionsUseCase) Execute(request *IFindUserSessionsUseCaseRequest) (*IFindUserSessionsUseCaseResponse, error) {
+	userSessions, err := uc.UserSessionRepository.FindActiveByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IFindUserSessionsUseCaseResponse{
+		UserSessions: userSessions,
+	}, nil
+}
+
+func FindUserSessionsUseCaseFactory(log *logrus.Logger) IFindUserSessionsUseCase {
+	userSessionRepository := repository.UserSessionRepositoryFactory(log)
+	return NewFindUserSessionsUseCase(log, userSessionRepository)
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ITerminateUserSessionsUseCaseRequest struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	SessionID *uuid.UUID `json:"session_id"`
+}
+
+type ITerminateUserSessionsUseCaseResponse struct {
+	UserSessions []entity.UserSession `json:"user_sessions"`
+}
+
+type ITerminateUserSessionsUseCase interface {
+	Execute(request *ITerminateUserSessionsUseCaseRequest) (*ITerminateUserSessionsUseCaseResponse, error)
+}
+
+type TerminateUserSessionsUseCase struct {
+	Log                   *logrus.Logger
+	UserSessionRepository repository.IUserSessionRepository
+}
+
+func NewTerminateUserSessionsUseCase(log *logrus.Logger, userSessionRepository repository.IUserSessionRepository) ITerminateUserSessionsUseCase {
+	return &TerminateUserSessionsUseCase{
+		Log:                   log,
+		UserSessionRepository: userSessionRepository,
+	}
+}
+
+// Execute signs the user out of one session, or of all of them when no
+// SessionID is given. The terminated sessions are returned so the caller can
+// propagate the logout to the applications.
+func (uc *TerminateUserSessionsUseCase) Execute(request *ITerminateUserSessionsUseCaseRequest) (*ITerminateUserSessionsUseCaseResponse, error) {
+	if request.SessionID != nil {
+		userSession, err := uc.UserSessionRepository.FindByID(*request.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if userSession == nil || userSession.UserID == nil || *userSession.UserID != request.UserID {
+			return nil, errors.New("Session not found")
+		}
+		if err := uc.UserSessionRepository.DeleteUserSession(userSession.ID); err != nil {
+			return nil, err
+		}
+		return &ITerminateUserSessionsUseCaseResponse{
+			UserSessions: []entity.UserSession{*userSession},
+		}, nil
+	}
+
+	userSessions, err := uc.UserSessionRepository.FindActiveByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.UserSessionRepository.DeleteByUserID(request.UserID); err != nil {
+		return nil, err
+	}
+
+	return &ITerminateUserSessionsUseCaseResponse{
+		UserSessions: userSessions,
+	}, nil
+}
+
+func TerminateUserSessionsUseCaseFactory(log *logrus.Logger) ITerminateUserSessionsUseCase {
+	userSessionRepository := repository.UserSessionRepositoryFactory(log)
+	return NewTerminateUserSessionsUseCase(log, userSessionRepository)
+}
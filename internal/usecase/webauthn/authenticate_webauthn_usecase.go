@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"bytes"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IAuthenticateWebAuthnUseCaseRequest struct {
+	RelyingParty      utils.WebAuthnRelyingParty `json:"relying_party"`
+	Challenge         string                     `json:"challenge"`
+	CredentialID      string                     `json:"credential_id"`
+	ClientDataJSON    []byte                     `json:"client_data_json"`
+	AuthenticatorData []byte                     `json:"authenticator_data"`
+	Signature         []byte                     `json:"signature"`
+	UserHandle        []byte                     `json:"user_handle"`
+	// UserID is set when the passkey is a second factor and must belong to
+	// the user who entered the password.
+	UserID                  *uuid.UUID `json:"user_id"`
+	RequireUserVerification bool       `json:"require_user_verification"`
+}
+
+type IAuthenticateWebAuthnUseCaseResponse struct {
+	UserID             uuid.UUID                  `json:"user_id"`
+	WebAuthnCredential *entity.WebAuthnCredential `json:"webauthn_credential"`
+}
+
+type IAuthenticateWebAuthnUseCase interface {
+	Execute(request *IAuthenticateWebAuthnUseCaseRequest) (*IAuthenticateWebAuthnUseCaseResponse, error)
+}
+
+type AuthenticateWebAuthnUseCase struct {
+	Log                          *logrus.Logger
+	WebAuthnCredentialRepository repository.IWebAuthnCredentialRepository
+}
+
+func NewAuthenticateWebAuthnUseCase(log *logrus.Logger, webAuthnCredentialRepository repository.IWebAuthnCredentialRepository) IAuthenticateWebAuthnUseCase {
+	return &AuthenticateWebAuthnUseCase{
+		Log:                          log,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+	}
+}
+
+// Execute verifies an authentication ceremony and returns the user the
+// passkey belongs to.
+func (uc *AuthenticateWebAuthnUseCase) Execute(request *IAuthenticateWebAuthnUseCaseRequest) (*IAuthenticateWebAuthnUseCaseResponse, error) {
+	webAuthnCredential, err := uc.WebAuthnCredentialRepository.FindByCredentialID(request.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+	if webAuthnCredential == nil {
+		return nil, errors.New("This passkey is not registered")
+	}
+	if request.UserID != nil && webAuthnCredential.UserID != *request.UserID {
+		return nil, errors.New("This passkey belongs to another account")
+	}
+	if len(request.UserHandle) > 0 && !bytes.Equal(request.UserHandle, webAuthnCredential.UserID[:]) {
+		return nil, errors.New("This passkey belongs to another account")
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(webAuthnCredential.PublicKey)
+	if err != nil {
+		uc.Log.Error("[AuthenticateWebAuthnUseCase.Execute] " + err.Error())
+		return nil, errors.New("[AuthenticateWebAuthnUseCase.Execute] " + err.Error())
+	}
+
+	signCount, err := utils.VerifyWebAuthnAssertion(request.RelyingParty, request.Challenge, request.ClientDataJSON, request.AuthenticatorData, request.Signature, publicKey, webAuthnCredential.Algorithm, webAuthnCredential.SignCount, request.RequireUserVerification)
+	if err != nil {
+		uc.Log.Error("[AuthenticateWebAuthnUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	if err := uc.WebAuthnCredentialRepository.UpdateSignCount(webAuthnCredential, signCount); err != nil {
+		return nil, err
+	}
+
+	return &IAuthenticateWebAuthnUseCaseResponse{
+		UserID:             webAuthnCredential.UserID,
+		WebAuthnCredential: webAuthnCredential,
+	}, nil
+}
+
+func AuthenticateWebAuthnUseCaseFactory(log *logrus.Logger) IAuthenticateWebAuthnUseCase {
+	webAuthnCredentialRepository := repository.WebAuthnCredentialRepositoryFactory(log)
+	return NewAuthenticateWebAuthnUseCase(log, webAuthnCredentialRepository)
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IDeleteWebAuthnCredentialUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	ID     uuid.UUID `json:"id"`
+}
+
+type IDeleteWebAuthnCredentialUseCase interface {
+	Execute(request *IDeleteWebAuthnCredentialUseCaseRequest) error
+}
+
+type DeleteWebAuthnCredentialUseCase struct {
+	Log                          *logrus.Logger
+	WebAuthnCredentialRepository repository.IWebAuthnCredentialRepository
+}
+
+func NewDeleteWebAuthnCredentialUseCase(log *logrus.Logger, webAuthnCredentialRepository repository.IWebAuthnCredentialRepository) IDeleteWebAuthnCredentialUseCase {
+	return &DeleteWebAuthnCredentialUseCase{
+		Log:                          log,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+	}
+}
+
+func (uc *DeleteWebAuthnCredentialUseCase) Execute(request *IDeleteWebAuthnCredentialUseCaseRequest) error {
+	webAuthnCredential, err := uc.WebAuthnCredentialRepository.FindByID(request.ID)
+	if err != nil {
+		return err
+	}
+	if webAuthnCredential == nil || webAuthnCredential.UserID != request.UserID {
+		return errors.New("Passkey not found")
+	}
+
+	return uc.WebAuthnCredentialRepository.DeleteWebAuthnCredential(webAuthnCredential.ID)
+}
+
+func DeleteWebAuthnCredentialUseCaseFactory(log *logrus.Logger) IDeleteWebAuthnCredentialUseCase {
+	webAuthnCredentialRepository := repository.WebAuthnCredentialRepositoryFactory(log)
+	return NewDeleteWebAuthnCredentialUseCase(log, webAuthnCredentialRepository)
+}
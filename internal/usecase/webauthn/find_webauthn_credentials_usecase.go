@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IFindWebAuthnCredentialsUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type IFindWebAuthnCredentialsUseCaseResponse struct {
+	WebAuthnCredentials []entity.WebAuthnCredential `json:"webauthn_credentials"`
+}
+
+type IFindWebAuthnCredentialsUseCase interface {
+	Execute(request *IFindWebAuthnCredentialsUseCaseRequest) (*IFindWebAuthnCredentialsUseCaseResponse, error)
+}
+
+type FindWebAuthnCredentialsUseCase struct {
+	Log                          *logrus.Logger
+	WebAuthnCredentialRepository repository.IWebAuthnCredentialRepository
+}
+
+func NewFindWebAuthnCredentialsUseCase(log *logrus.Logger, webAuthnCredentialRepository repository.IWebAuthnCredentialRepository) IFindWebAuthnCredentialsUseCase {
+	return &FindWebAuthnCredentialsUseCase{
+		Log:                          log,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+	}
+}
+
+func (uc *FindWebAuthnCredentialsUseCase) Execute(request *IFindWebAuthnCredentialsUseCaseRequest) (*IFindWebAuthnCredentialsUseCaseResponse, error) {
+	webAuthnCredentials, err := uc.WebAuthnCredentialRepository.FindByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IFindWebAuthnCredentialsUseCaseResponse{
+		WebAuthnCredentials: webAuthnCredentials,
+	}, nil
+}
+
+func FindWebAuthnCredentialsUseCaseFactory(log *logrus.Logger) IFindWebAuthnCredentialsUseCase {
+	webAuthnCredentialRepository := repository.WebAuthnCredentialRepositoryFactory(log)
+	return NewFindWebAuthnCredentialsUseCase(log, webAuthnCredentialRepository)
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"app/go-sso/utils"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IRegisterWebAuthnCredentialUseCaseRequest struct {
+	UserID            uuid.UUID                  `json:"user_id"`
+	Name              string                     `json:"name"`
+	RelyingParty      utils.WebAuthnRelyingParty `json:"relying_party"`
+	Challenge         string                     `json:"challenge"`
+	ClientDataJSON    []byte                     `json:"client_data_json"`
+	AttestationObject []byte                     `json:"attestation_object"`
+	Transports        []string                   `json:"transports"`
+}
+
+type IRegisterWebAuthnCredentialUseCaseResponse struct {
+	WebAuthnCredential *entity.WebAuthnCredential `json:"webauthn_credential"`
+}
+
+type IRegisterWebAuthnCredentialUseCase interface {
+	Execute(request *IRegisterWebAuthnCredentialUseCaseRequest) (*IRegisterWebAuthnCredentialUseCaseResponse, error)
+}
+
+type RegisterWebAuthnCredentialUseCase struct {
+	Log                          *logrus.Logger
+	WebAuthnCredentialRepository repository.IWebAuthnCredentialRepository
+}
+
+func NewRegisterWebAuthnCredentialUseCase(log *logrus.Logger, webAuthnCredentialRepository repository.IWebAuthnCredentialRepository) IRegisterWebAuthnCredentialUseCase {
+	return &RegisterWebAuthnCredentialUseCase{
+		Log:                          log,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+	}
+}
+
+// Execute verifies a registration ceremony and stores the new credential for
+// the user.
+func (uc *RegisterWebAuthnCredentialUseCase) Execute(request *IRegisterWebAuthnCredentialUseCaseRequest) (*IRegisterWebAuthnCredentialUseCaseResponse, error) {
+	attested, err := utils.VerifyWebAuthnRegistration(request.RelyingParty, request.Challenge, request.ClientDataJSON, request.AttestationObject)
+	if err != nil {
+		uc.Log.Error("[RegisterWebAuthnCredentialUseCase.Execute] " + err.Error())
+		return nil, err
+	}
+
+	credentialID := utils.EncodeWebAuthnBytes(attested.ID)
+	existing, err := uc.WebAuthnCredentialRepository.FindByCredentialID(credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("This passkey is already registered")
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	aaguid := ""
+	if id, err := uuid.FromBytes(attested.AAGUID); err == nil && id != uuid.Nil {
+		aaguid = id.String()
+	}
+
+	webAuthnCredential, err := uc.WebAuthnCredentialRepository.CreateWebAuthnCredential(&entity.WebAuthnCredential{
+		UserID:       request.UserID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    base64.StdEncoding.EncodeToString(attested.PublicKey),
+		Algorithm:    attested.Algorithm,
+		SignCount:    attested.SignCount,
+		AAGUID:       aaguid,
+		Transports:   strings.Join(request.Transports, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IRegisterWebAuthnCredentialUseCaseResponse{
+		WebAuthnCredential: webAuthnCredential,
+	}, nil
+}
+
+func RegisterWebAuthnCredentialUseCaseFactory(log *logrus.Logger) IRegisterWebAuthnCredentialUseCase {
+	webAuthnCredentialRepository := repository.WebAuthnCredentialRepositoryFactory(log)
+	return NewRegisterWebAuthnCredentialUseCase(log, webAuthnCredentialRepository)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"app/go-sso/internal/repository"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type IRenameWebAuthnCredentialUseCaseRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+}
+
+type IRenameWebAuthnCredentialUseCase interface {
+	Execute(request *IRenameWebAuthnCredentialUseCaseRequest) error
+}
+
+type RenameWebAuthnCredentialUseCase struct {
+	Log                          *logrus.Logger
+	WebAuthnCredentialRepository repository.IWebAuthnCredentialRepository
+}
+
+func NewRenameWebAuthnCredentialUseCase(log *logrus.Logger, webAuthnCredentialRepository repository.IWebAuthnCredentialRepository) IRenameWebAuthnCredentialUseCase {
+	return &RenameWebAuthnCredentialUseCase{
+		Log:                          log,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+	}
+}
+
+func (uc *RenameWebAuthnCredentialUseCase) Execute(request *IRenameWebAuthnCredentialUseCaseRequest) error {
+	webAuthnCredential, err := uc.WebAuthnCredentialRepository.FindByID(request.ID)
+	if err != nil {
+		return err
+	}
+	if webAuthnCredential == nil || webAuthnCredential.UserID != request.UserID {
+		return errors.New("Passkey not found")
+	}
+
+	return uc.WebAuthnCredentialRepository.RenameWebAuthnCredential(webAuthnCredential, strings.TrimSpace(request.Name))
+}
+
+func RenameWebAuthnCredentialUseCaseFactory(log *logrus.Logger) IRenameWebAuthnCredentialUseCase {
+	webAuthnCredentialRepository := repository.WebAuthnCredentialRepositoryFactory(log)
+	return NewRenameWebAuthnCredentialUseCase(log, webAuthnCredentialRepository)
+}
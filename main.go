@@ -9,6 +9,9 @@ import (
 	"app/go-sso/internal/http/route"
 	"app/go-sso/internal/http/scheduler"
 	"app/go-sso/internal/rabbitmq"
+	"app/go-sso/internal/repository"
+	"app/go-sso/internal/service"
+	"app/go-sso/utils"
 	"encoding/gob"
 	"net/http"
 	"strconv"
@@ -17,7 +20,6 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
 	csrf "github.com/utrack/gin-csrf"
@@ -35,18 +37,7 @@ func main() {
 	log := config.NewLogrus(viperConfig)
 	log.Printf("Starting server at port %s", viperConfig.GetString("database.host"))
 	validate := config.NewValidator(viperConfig)
-	auth, err := config.NewAuth0(viperConfig)
-	if err != nil {
-		log.Printf("Failed to initialize the auth0 authenticator: %v", err)
-	}
-	googleAuth, err := config.NewGoogleAuthenticator(viperConfig)
-	if err != nil {
-		log.Printf("Failed to initialize the google authenticator: %v", err)
-	}
-	zitadelAuth, err := config.NewZitadelAuthenticator(viperConfig)
-	if err != nil {
-		log.Printf("Failed to initialize the zitadel authenticator: %v", err)
-	}
+	upstreamProviderService := service.UpstreamProviderServiceFactory(log)
 
 	// err = rabbitmq.InitializeConnection(viperConfig.GetString("rabbitmq.url"))
 	// if err != nil {
@@ -60,6 +51,11 @@ func main() {
 	// setup gin engine
 	app := gin.Default()
 	app.MaxMultipartMemory = 50 << 20 // 10 MB
+	// client IPs key the rate limits and the IP lockout, X-Forwarded-For is
+	// only believed from the configured proxies
+	if err := app.SetTrustedProxies(viperConfig.GetStringSlice("web.trusted_proxies")); err != nil {
+		log.Fatalf("Invalid web.trusted_proxies: %v", err)
+	}
 	app.Static("/assets", "./public")
 	app.Static("/storage", "./storage")
 	app.Use(func(c *gin.Context) {
@@ -67,7 +63,18 @@ func main() {
 	})
 
 	// setup session and cookie
-	store := cookie.NewStore([]byte(viperConfig.GetString("web.cookie.secret")))
+	store := utils.NewDatabaseSessionStore(log, repository.UserSessionRepositoryFactory(log), []byte(viperConfig.GetString("web.cookie.secret")))
+	sessionMaxAge := viperConfig.GetInt("web.session.max_age")
+	if sessionMaxAge <= 0 {
+		sessionMaxAge = 30 * 24 * 60 * 60
+	}
+	store.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   sessionMaxAge,
+		Secure:   viperConfig.GetBool("web.cookie.secure"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
 	app.Use(sessions.Sessions(viperConfig.GetString("web.session.name"), store))
 
 	// Setup CORS middleware
@@ -97,11 +104,14 @@ func main() {
 	// app.Use(middleware.FlashMiddleware())
 
 	//handle handler
-	userHandler := handler.UserHandlerFactory(log, validate, auth, googleAuth, zitadelAuth)
+	userHandler := handler.UserHandlerFactory(log, validate, upstreamProviderService)
 	organizationHandler := handler.OrganizationHandlerFactory(log, validate)
 	jobHandler := handler.JobHandlerFactory(log, validate)
 	employeeHandler := handler.EmployeeHandlerFactory(log, validate)
 	gradeHandler := handler.GradeHandlerFactory(viperConfig, log, validate)
+	oidcHandler := handler.OIDCHandlerFactory(viperConfig, log, validate)
+	samlHandler := handler.SAMLHandlerFactory(viperConfig, log, validate)
+	scimHandler := handler.SCIMHandlerFactory(viperConfig, log, validate)
 
 	// handle web handler
 	dashboardHandler := web.DashboardHandlerFactory(log, validate)
@@ -110,11 +120,16 @@ func main() {
 	roleWebHandler := web.RoleHandlerFactory(log, validate)
 	permissionWebHandler := web.PermissionHandlerFactory(log, validate)
 	employeeWebHandler := web.EmployeeHandlerFactory(log, validate)
+	sessionWebHandler := web.SessionHandlerFactory(log, validate)
+	mfaWebHandler := web.MFAHandlerFactory(log, validate)
+	passkeyWebHandler := web.PasskeyHandlerFactory(log, validate)
+	identityWebHandler := web.IdentityHandlerFactory(log, validate, upstreamProviderService)
 
 	// handle middleware
 	authMiddleware := middleware.NewAuth(viperConfig)
 	authWebMiddleware := middleware.WebAuthMiddleware()
 	emailVerifiedMiddleware := middleware.EmailVerifiedMiddleware()
+	rateLimiter := middleware.NewRateLimiter(viperConfig, log)
 
 	// setup route config
 	routeConfig := route.RouteConfig{
@@ -134,6 +149,14 @@ func main() {
 		EmployeeWebHandler:      employeeWebHandler,
 		EmailVerifiedMiddleware: emailVerifiedMiddleware,
 		GradeHandler:            gradeHandler,
+		OIDCHandler:             oidcHandler,
+		SAMLHandler:             samlHandler,
+		SCIMHandler:             scimHandler,
+		SessionWebHandler:       sessionWebHandler,
+		MFAWebHandler:           mfaWebHandler,
+		PasskeyWebHandler:       passkeyWebHandler,
+		IdentityWebHandler:      identityWebHandler,
+		RateLimiter:             rateLimiter,
 	}
 	routeConfig.SetupRoutes()
 
@@ -143,7 +166,7 @@ func main() {
 		sch := cron.New(cron.WithLocation(jakartaTime))
 
 		syncScheduler := scheduler.SyncMidsuitSchedulerFactory(viperConfig, log)
-		_, err = sch.AddFunc("1 0 * * *", func() {
+		_, err := sch.AddFunc("1 0 * * *", func() {
 			authResp, err := syncScheduler.AuthOneStep()
 			if err != nil {
 				log.Fatalf("Failed to authenticate: %v", err)
@@ -216,11 +239,53 @@ func main() {
 		defer sch.Stop()
 	}
 
+	// setup signing key rotation
+	if schedule := viperConfig.GetString("jwt.key_rotation_schedule"); schedule != "" {
+		jakartaTime, _ := time.LoadLocation("Asia/Jakarta")
+		keySch := cron.New(cron.WithLocation(jakartaTime))
+
+		signingKeyScheduler := scheduler.SigningKeySchedulerFactory(viperConfig, log)
+		_, err := keySch.AddFunc(schedule, func() {
+			err := signingKeyScheduler.RotateSigningKey()
+			if err != nil {
+				log.Errorf("Failed to rotate signing key: %v", err)
+			}
+		})
+		if err != nil {
+			log.Fatalf("failed to add signing key rotation job: %v", err)
+		}
+
+		keySch.Start()
+		log.Infof("Started signing key rotation job")
+		defer keySch.Stop()
+	}
+
+	// setup expired session cleanup
+	if schedule := viperConfig.GetString("web.session.cleanup_schedule"); schedule != "" {
+		jakartaTime, _ := time.LoadLocation("Asia/Jakarta")
+		sessionSch := cron.New(cron.WithLocation(jakartaTime))
+
+		userSessionScheduler := scheduler.UserSessionSchedulerFactory(viperConfig, log)
+		_, err := sessionSch.AddFunc(schedule, func() {
+			err := userSessionScheduler.DeleteExpiredSessions()
+			if err != nil {
+				log.Errorf("Failed to delete expired sessions: %v", err)
+			}
+		})
+		if err != nil {
+			log.Fatalf("failed to add session cleanup job: %v", err)
+		}
+
+		sessionSch.Start()
+		log.Infof("Started session cleanup job")
+		defer sessionSch.Stop()
+	}
+
 	// run server
 	if viperConfig.GetString("web.mode") == "debug" {
 		webPort := strconv.Itoa(viperConfig.GetInt("web.port"))
 		log.Printf("Port configured: " + webPort)
-		err = app.Run(":" + webPort)
+		err := app.Run(":" + webPort)
 		if err != nil {
 			log.Panicf("Failed to start server: %v", err)
 		}
@@ -237,5 +302,8 @@ func main() {
 }
 
 func shouldExcludeFromCSRF(path string) bool {
-	return len(path) >= 4 && path[:4] == "/api"
+	// OAuth2 clients call the token endpoints server-to-server without a session,
+	// SAML service providers post their AuthnRequest from another site and SCIM
+	// clients provision with a bearer token
+	return strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/oauth2/") || strings.HasPrefix(path, "/saml/") || strings.HasPrefix(path, "/scim/")
 }
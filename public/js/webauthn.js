@@ -0,0 +1,86 @@
+// Passkey ceremonies. The server sends the options with binary values
+// base64url encoded; the browser answer is sent back the same way, as the
+// JSON "credential" field of a regular form post.
+(function (window) {
+  function toBuffer(value) {
+    const base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+    const padded = base64 + "===".slice((base64.length + 3) % 4);
+    return Uint8Array.from(atob(padded), (c) => c.charCodeAt(0)).buffer;
+  }
+
+  function fromBuffer(buffer) {
+    if (!buffer) {
+      return "";
+    }
+    const bytes = new Uint8Array(buffer);
+    let binary = "";
+    bytes.forEach((b) => (binary += String.fromCharCode(b)));
+    return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+  }
+
+  async function fetchOptions(url) {
+    const response = await fetch(url, { credentials: "same-origin" });
+    const body = await response.json();
+    if (!response.ok) {
+      throw new Error((body.meta && body.meta.message) || "Passkeys are not available");
+    }
+    return body.data;
+  }
+
+  function decodeCredentials(credentials) {
+    return (credentials || []).map((credential) =>
+      Object.assign({}, credential, { id: toBuffer(credential.id) })
+    );
+  }
+
+  function submit(form, credential) {
+    form.querySelector("input[name=credential]").value = JSON.stringify(credential);
+    form.submit();
+  }
+
+  window.Passkey = {
+    supported: function () {
+      return !!(window.PublicKeyCredential && navigator.credentials);
+    },
+
+    register: async function (optionsURL, form) {
+      const options = await fetchOptions(optionsURL);
+      options.challenge = toBuffer(options.challenge);
+      options.user.id = toBuffer(options.user.id);
+      options.excludeCredentials = decodeCredentials(options.excludeCredentials);
+
+      const credential = await navigator.credentials.create({ publicKey: options });
+      submit(form, {
+        id: credential.id,
+        rawId: fromBuffer(credential.rawId),
+        type: credential.type,
+        response: {
+          clientDataJSON: fromBuffer(credential.response.clientDataJSON),
+          attestationObject: fromBuffer(credential.response.attestationObject),
+          transports: credential.response.getTransports
+            ? credential.response.getTransports()
+            : [],
+        },
+      });
+    },
+
+    authenticate: async function (optionsURL, form) {
+      const options = await fetchOptions(optionsURL);
+      options.challenge = toBuffer(options.challenge);
+      options.allowCredentials = decodeCredentials(options.allowCredentials);
+
+      const credential = await navigator.credentials.get({ publicKey: options });
+      submit(form, {
+        id: credential.id,
+        rawId: fromBuffer(credential.rawId),
+        type: credential.type,
+        response: {
+          clientDataJSON: fromBuffer(credential.response.clientDataJSON),
+          authenticatorData: fromBuffer(credential.response.authenticatorData),
+          signature: fromBuffer(credential.response.signature),
+          userHandle: fromBuffer(credential.response.userHandle),
+        },
+      });
+    },
+  };
+})(window);
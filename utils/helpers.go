@@ -5,6 +5,8 @@ import (
 	"app/go-sso/internal/http/response"
 	mqResponse "app/go-sso/internal/http/response"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -138,6 +140,10 @@ func GenerateRandomIntToken(digits int) (string, error) {
 
 func GenerateRandomStringToken(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	return GenerateRandomStringTokenFrom(charset, length)
+}
+
+func GenerateRandomStringTokenFrom(charset string, length int) string {
 	b := make([]byte, length)
 	for i := range b {
 		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
@@ -145,3 +151,8 @@ func GenerateRandomStringToken(length int) string {
 	}
 	return string(b)
 }
+
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// What happens to a first sign-in with an email outside the allowed domains.
+const (
+	JIT_UNKNOWN_DOMAIN_REJECT   = "reject"
+	JIT_UNKNOWN_DOMAIN_APPROVAL = "approval"
+)
+
+// JITProvisioningPolicy decides which users signing in through an upstream
+// provider get an account on their first sign-in. Emails of AllowedDomains
+// get an active account; other domains are refused or, with UnknownDomains
+// set to approval, get an account that waits for an administrator.
+// DefaultRoles lists the roles given per application name, LinkEmployee links
+// the account to the employee with the same email.
+type JITProvisioningPolicy struct {
+	Enabled        bool
+	AllowedDomains []string
+	UnknownDomains string
+	DefaultRoles   map[string][]string
+	LinkEmployee   bool
+}
+
+// JITProvisioningPolicyFromConfig reads jit_provisioning.*. Provisioning is
+// off by default; unknown domains are refused and employees are linked unless
+// configured otherwise.
+func JITProvisioningPolicyFromConfig(config *viper.Viper) JITProvisioningPolicy {
+	allowedDomains := []string{}
+	for _, domain := range config.GetStringSlice("jit_provisioning.allowed_domains") {
+		allowedDomains = append(allowedDomains, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@")))
+	}
+
+	unknownDomains := config.GetString("jit_provisioning.unknown_domains")
+	if unknownDomains != JIT_UNKNOWN_DOMAIN_APPROVAL {
+		unknownDomains = JIT_UNKNOWN_DOMAIN_REJECT
+	}
+
+	linkEmployee := true
+	if config.IsSet("jit_provisioning.link_employee") {
+		linkEmployee = config.GetBool("jit_provisioning.link_employee")
+	}
+
+	return JITProvisioningPolicy{
+		Enabled:        config.GetBool("jit_provisioning.enabled"),
+		AllowedDomains: allowedDomains,
+		UnknownDomains: unknownDomains,
+		DefaultRoles:   config.GetStringMapStringSlice("jit_provisioning.default_roles"),
+		LinkEmployee:   linkEmployee,
+	}
+}
+
+// DomainAllowed reports whether the domain of email is one of AllowedDomains.
+func (p JITProvisioningPolicy) DomainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowedDomain := range p.AllowedDomains {
+		if domain == allowedDomain {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesFor returns the names of the roles new users of application get.
+func (p JITProvisioningPolicy) RolesFor(application string) []string {
+	return p.DefaultRoles[strings.ToLower(application)]
+}
@@ -2,29 +2,67 @@ package utils
 
 import (
 	"app/go-sso/internal/entity"
-	"fmt"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/sirupsen/logrus"
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
 )
 
+// OIDCIssuer returns the issuer URL used in tokens and the discovery document.
+func OIDCIssuer(config *viper.Viper) string {
+	issuer := config.GetString("oidc.issuer")
+	if issuer == "" {
+		issuer = config.GetString("app.url")
+	}
+	return strings.TrimRight(issuer, "/")
+}
+
+// TOKEN_USE_ACCESS marks access tokens in the token_use claim. ID, logout and
+// access tokens share the signing keys, only access tokens are accepted as
+// bearer tokens.
+const TOKEN_USE_ACCESS = "access"
+
+// IsAccessToken reports whether the claims are those of an access token
+// rather than of an ID or logout token.
+func IsAccessToken(claims jwt.MapClaims) bool {
+	tokenUse, _ := claims["token_use"].(string)
+	return tokenUse == TOKEN_USE_ACCESS
+}
+
+// AccessTokenTTL is the lifetime of access tokens, configured in seconds by
+// jwt.access_token_ttl. Without it tokens keep the historical 72 hours.
+func AccessTokenTTL() time.Duration {
+	keyring.initOnce.Do(keyring.init)
+	return keyring.accessTTL
+}
+
+func accessTokenIssuer() string {
+	keyring.initOnce.Do(keyring.init)
+	return keyring.issuer
+}
+
 func GenerateToken(user *entity.User) (string, error) {
-	viper := viper.New()
-	logger := logrus.New()
+	return generateUserToken(user, "", "")
+}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("json")
-	viper.AddConfigPath("./")
-	err := viper.ReadInConfig()
+// GenerateApplicationToken signs an access token of user issued to
+// application through OAuth 2.0. Its client_id names the application, which
+// alone may revoke it. jti lets the token be revoked along with the grant it
+// was issued from, a random one is used when it is empty.
+func GenerateApplicationToken(user *entity.User, application *entity.Application, jti string) (string, error) {
+	return generateUserToken(user, application.Name, jti)
+}
 
-	if err != nil {
-		logger.Fatalf("Fatal error config file: %v", err)
+func generateUserToken(user *entity.User, clientID string, jti string) (string, error) {
+	// the first role is the chosen one, a user without roles gets no token
+	if len(user.Roles) == 0 {
+		return "", errors.New("No role is assigned to this account")
 	}
 
 	// Prepare roles and permissions
-	fmt.Println("length of user.Roles", len(user.Roles))
 	roles := make([]map[string]interface{}, len(user.Roles))
 	for i, role := range user.Roles {
 		permissions := make([]string, len(role.Permissions))
@@ -37,22 +75,33 @@ func GenerateToken(user *entity.User) (string, error) {
 		}
 	}
 
+	if jti == "" {
+		jti = uuid.New().String()
+	}
+
 	// prepare token claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	now := TokenIssuedAt(user.ID.String())
+	claims := jwt.MapClaims{
+		"jti":          jti,
+		"iat":          now.Unix(),
+		"iss":          accessTokenIssuer(),
+		"sub":          user.ID.String(),
+		"token_use":    TOKEN_USE_ACCESS,
 		"id":           user.ID,
 		"name":         user.Name,
 		"username":     user.Username,
 		"email":        user.Email,
 		"choosed_role": roles[0]["name"],
 		"roles":        roles,
-		"exp":          time.Now().Add(time.Hour * 72).Unix(),
+		"exp":          now.Add(AccessTokenTTL()).Unix(),
 		"employee":     user.Employee,
-	})
-
-	fmt.Println("Choosed Role", roles[0]["name"])
+	}
+	if clientID != "" {
+		claims["client_id"] = clientID
+	}
 
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(viper.GetString("jwt.secret")))
+	// Sign and get the complete encoded token as a string using the active signing key
+	tokenString, err := SignToken(claims)
 	if err != nil {
 		return "", err
 	}
@@ -61,29 +110,35 @@ func GenerateToken(user *entity.User) (string, error) {
 }
 
 func GenerateTokenForOAuth2(data *map[string]interface{}) (string, error) {
-	viper := viper.New()
-	logger := logrus.New()
-
-	viper.SetConfigName("config")
-	viper.SetConfigType("json")
-	viper.AddConfigPath("./")
-	err := viper.ReadInConfig()
-
-	if err != nil {
-		logger.Fatalf("Fatal error config file: %v", err)
-	}
-
 	// prepare token claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	claims := jwt.MapClaims{
 		"data": data,
-		"exp":  time.Now().Add(time.Hour * 72).Unix(),
-	})
+		"exp":  time.Now().Add(AccessTokenTTL()).Unix(),
+	}
 
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(viper.GetString("jwt.secret")))
+	// Sign and get the complete encoded token as a string using the active signing key
+	tokenString, err := SignToken(claims)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// GenerateClientToken signs an access token for an application calling on its
+// own behalf. It has no user id; the granted permissions are in scope.
+func GenerateClientToken(application *entity.Application, scope string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti":       uuid.New().String(),
+		"iat":       now.Unix(),
+		"iss":       accessTokenIssuer(),
+		"sub":       application.Name,
+		"token_use": TOKEN_USE_ACCESS,
+		"client_id": application.Name,
+		"scope":     scope,
+		"exp":       now.Add(ttl).Unix(),
+	}
+
+	return SignToken(claims)
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// MagicLinkURL is the page magic sign-in links point to.
+func MagicLinkURL(config *viper.Viper) string {
+	return strings.TrimRight(config.GetString("app.url"), "/") + "/login/magic-link/verify"
+}
+
+// MagicLinkTTL is the lifetime of magic sign-in links, configured in seconds
+// by magic_link.token_ttl. It defaults to ten minutes.
+func MagicLinkTTL(config *viper.Viper) time.Duration {
+	if ttl := config.GetInt("magic_link.token_ttl"); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 10 * time.Minute
+}
@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes passwords and checks them against stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hashedPassword string, password string) (bool, error)
+	// NeedsRehash tells whether hashedPassword should be replaced by a new
+	// Hash of the password, because it uses another algorithm or other
+	// parameters than Hash does now.
+	NeedsRehash(hashedPassword string) bool
+	// Supports tells whether hashedPassword is in the format of the hasher.
+	Supports(hashedPassword string) bool
+}
+
+// PasswordHasherFromConfig builds the hasher selected by
+// password_hashing.algorithm, argon2id unless it is set to bcrypt. Hashes of
+// the other algorithm are still verified, so switching does not lock anyone
+// out; they are replaced on the next login.
+func PasswordHasherFromConfig(config *viper.Viper) PasswordHasher {
+	integer := func(key string, fallback int) int {
+		if !config.IsSet(key) {
+			return fallback
+		}
+		return config.GetInt(key)
+	}
+
+	argon2idHasher := &Argon2idPasswordHasher{
+		Memory:      uint32(integer("password_hashing.argon2id.memory", 64*1024)),
+		Iterations:  uint32(integer("password_hashing.argon2id.iterations", 3)),
+		Parallelism: uint8(integer("password_hashing.argon2id.parallelism", 2)),
+		SaltLength:  uint32(integer("password_hashing.argon2id.salt_length", 16)),
+		KeyLength:   uint32(integer("password_hashing.argon2id.key_length", 32)),
+	}
+	bcryptHasher := &BcryptPasswordHasher{
+		Cost: integer("password_hashing.bcrypt.cost", bcrypt.DefaultCost),
+	}
+
+	if config.GetString("password_hashing.algorithm") == "bcrypt" {
+		return &MultiPasswordHasher{Default: bcryptHasher, Hashers: []PasswordHasher{argon2idHasher}}
+	}
+	return &MultiPasswordHasher{Default: argon2idHasher, Hashers: []PasswordHasher{bcryptHasher}}
+}
+
+// MultiPasswordHasher hashes with Default and verifies with whichever of
+// Default and Hashers supports the stored hash. Hashes that Default does not
+// support always need a rehash.
+type MultiPasswordHasher struct {
+	Default PasswordHasher
+	Hashers []PasswordHasher
+}
+
+func (h *MultiPasswordHasher) Hash(password string) (string, error) {
+	return h.Default.Hash(password)
+}
+
+func (h *MultiPasswordHasher) Verify(hashedPassword string, password string) (bool, error) {
+	for _, hasher := range append([]PasswordHasher{h.Default}, h.Hashers...) {
+		if hasher.Supports(hashedPassword) {
+			return hasher.Verify(hashedPassword, password)
+		}
+	}
+	return false, nil
+}
+
+func (h *MultiPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	if !h.Default.Supports(hashedPassword) {
+		return true
+	}
+	return h.Default.NeedsRehash(hashedPassword)
+}
+
+func (h *MultiPasswordHasher) Supports(hashedPassword string) bool {
+	for _, hasher := range append([]PasswordHasher{h.Default}, h.Hashers...) {
+		if hasher.Supports(hashedPassword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Argon2idPasswordHasher stores hashes in the PHC string format,
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+// Memory is in KiB.
+type Argon2idPasswordHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *Argon2idPasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.Memory,
+		h.Iterations,
+		h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify recomputes the key with the parameters stored in the hash, not the
+// ones of the hasher, so older hashes keep verifying after a tuning change.
+func (h *Argon2idPasswordHasher) Verify(hashedPassword string, password string) (bool, error) {
+	decoded, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), decoded.salt, decoded.iterations, decoded.memory, decoded.parallelism, uint32(len(decoded.key)))
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1, nil
+}
+
+func (h *Argon2idPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	decoded, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return decoded.memory != h.Memory ||
+		decoded.iterations != h.Iterations ||
+		decoded.parallelism != h.Parallelism ||
+		uint32(len(decoded.salt)) != h.SaltLength ||
+		uint32(len(decoded.key)) != h.KeyLength
+}
+
+func (h *Argon2idPasswordHasher) Supports(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$argon2id$")
+}
+
+func decodeArgon2idHash(hashedPassword string) (*argon2idHash, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("Invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("Unsupported argon2id version")
+	}
+
+	decoded := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.iterations, &decoded.parallelism); err != nil {
+		return nil, errors.New("Invalid argon2id parameters")
+	}
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.New("Invalid argon2id salt")
+	}
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(decoded.key) == 0 {
+		return nil, errors.New("Invalid argon2id key")
+	}
+	return decoded, nil
+}
+
+// BcryptPasswordHasher is the hasher the passwords were stored with before
+// argon2id.
+type BcryptPasswordHasher struct {
+	Cost int
+}
+
+func (h *BcryptPasswordHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func (h *BcryptPasswordHasher) Verify(hashedPassword string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *BcryptPasswordHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.Cost
+}
+
+func (h *BcryptPasswordHasher) Supports(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
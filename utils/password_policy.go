@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// Rules a password can break, reported in PasswordViolation.Rule.
+const (
+	PASSWORD_RULE_MIN_LENGTH = "min_length"
+	PASSWORD_RULE_MAX_LENGTH = "max_length"
+	PASSWORD_RULE_UPPERCASE  = "uppercase"
+	PASSWORD_RULE_LOWERCASE  = "lowercase"
+	PASSWORD_RULE_DIGIT      = "digit"
+	PASSWORD_RULE_SYMBOL     = "symbol"
+	PASSWORD_RULE_BREACHED   = "breached"
+	PASSWORD_RULE_REUSED     = "reused"
+)
+
+// PasswordPolicy is what a new password has to satisfy. HistorySize is how
+// many previous passwords of the user may not be reused, EmployeeMaxAge how
+// long the password of an employee account stays valid. Zero disables either.
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	HistorySize      int
+	EmployeeMaxAge   time.Duration
+	BreachedListFile string
+}
+
+// PasswordPolicyFromConfig reads password_policy.*. Unset keys keep the
+// defaults: 8 to 64 characters with upper case, lower case and digits, no
+// reuse of the last 5 passwords and a 90 day maximum age for employees.
+func PasswordPolicyFromConfig(config *viper.Viper) PasswordPolicy {
+	integer := func(key string, fallback int) int {
+		if !config.IsSet(key) {
+			return fallback
+		}
+		return config.GetInt(key)
+	}
+	boolean := func(key string, fallback bool) bool {
+		if !config.IsSet(key) {
+			return fallback
+		}
+		return config.GetBool(key)
+	}
+
+	return PasswordPolicy{
+		MinLength:        integer("password_policy.min_length", 8),
+		MaxLength:        integer("password_policy.max_length", 64),
+		RequireUppercase: boolean("password_policy.require_uppercase", true),
+		RequireLowercase: boolean("password_policy.require_lowercase", true),
+		RequireDigit:     boolean("password_policy.require_digit", true),
+		RequireSymbol:    boolean("password_policy.require_symbol", false),
+		HistorySize:      integer("password_policy.history_size", 5),
+		EmployeeMaxAge:   time.Duration(integer("password_policy.employee_max_age_days", 90)) * 24 * time.Hour,
+		BreachedListFile: config.GetString("password_policy.breached_list_file"),
+	}
+}
+
+// PasswordViolation is one rule a password breaks.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError is returned when a password breaks the policy, with
+// every rule it breaks.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, ". ")
+}
+
+// Check returns the rules the password breaks, leaving out reuse which
+// needs the password history of the user. An error is returned only when the
+// breached password list cannot be read.
+func (p PasswordPolicy) Check(password string) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_MIN_LENGTH,
+			Message: fmt.Sprintf("The password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_MAX_LENGTH,
+			Message: fmt.Sprintf("The password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_UPPERCASE,
+			Message: "The password must contain an upper case letter",
+		})
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_LOWERCASE,
+			Message: "The password must contain a lower case letter",
+		})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_DIGIT,
+			Message: "The password must contain a digit",
+		})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordViolation{
+			Rule:    PASSWORD_RULE_SYMBOL,
+			Message: "The password must contain a symbol",
+		})
+	}
+
+	if p.BreachedListFile != "" {
+		breached, err := loadBreachedPasswords(p.BreachedListFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := breached[breachedPasswordHash(password)]; found {
+			violations = append(violations, PasswordViolation{
+				Rule:    PASSWORD_RULE_BREACHED,
+				Message: "The password appears in a list of breached passwords",
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// ReusedPasswordViolation is reported when the password is one of the last
+// HistorySize passwords of the user.
+func (p PasswordPolicy) ReusedPasswordViolation() PasswordViolation {
+	return PasswordViolation{
+		Rule:    PASSWORD_RULE_REUSED,
+		Message: fmt.Sprintf("The password must differ from your last %d passwords", p.HistorySize),
+	}
+}
+
+// PasswordExpired tells whether the password of an employee account is older
+// than EmployeeMaxAge. Accounts that never changed their password count from
+// their creation.
+func (p PasswordPolicy) PasswordExpired(user *entity.User, now time.Time) bool {
+	if p.EmployeeMaxAge <= 0 || user.EmployeeID == nil {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return now.Sub(changedAt) > p.EmployeeMaxAge
+}
+
+var (
+	breachedPasswordsMu sync.Mutex
+	breachedPasswords   = map[string]map[string]struct{}{}
+)
+
+// loadBreachedPasswords reads the breached password list once per file. The
+// file holds one entry per line, either the password itself or its SHA-1 in
+// hex as found in breach corpus dumps, optionally followed by ":count".
+// Blank lines and lines starting with # are skipped.
+func loadBreachedPasswords(path string) (map[string]struct{}, error) {
+	breachedPasswordsMu.Lock()
+	defer breachedPasswordsMu.Unlock()
+
+	if hashes, ok := breachedPasswords[path]; ok {
+		return hashes, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Breached password list could not be read: %w", err)
+	}
+	defer file.Close()
+
+	hashes := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, ok := sha1HexEntry(line); ok {
+			hashes[hash] = struct{}{}
+			continue
+		}
+		hashes[breachedPasswordHash(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Breached password list could not be read: %w", err)
+	}
+
+	breachedPasswords[path] = hashes
+	return hashes, nil
+}
+
+func sha1HexEntry(line string) (string, bool) {
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) != sha1.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return strings.ToUpper(hash), true
+}
+
+func breachedPasswordHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
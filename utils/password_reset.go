@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// PasswordResetURL is the page password reset links point to.
+func PasswordResetURL(config *viper.Viper) string {
+	return strings.TrimRight(config.GetString("app.url"), "/") + "/reset-password"
+}
+
+// PasswordResetTTL is the lifetime of password reset links, configured in
+// seconds by password_reset.token_ttl. It defaults to one hour.
+func PasswordResetTTL(config *viper.Viper) time.Duration {
+	if ttl := config.GetInt("password_reset.token_ttl"); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return time.Hour
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit is a token bucket: Burst requests at once, refilled with Rate
+// requests every Period.
+type RateLimit struct {
+	Rate   int
+	Burst  int
+	Period time.Duration
+}
+
+func (l RateLimit) refillPerSecond() float64 {
+	return float64(l.Rate) / l.Period.Seconds()
+}
+
+// idleTTL is how long a bucket takes to fill up again, after which it can be
+// forgotten.
+func (l RateLimit) idleTTL() time.Duration {
+	return time.Duration(float64(l.Burst)/l.refillPerSecond()*float64(time.Second)) + time.Second
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. The memory store only limits the
+// requests of one instance; a shared store makes the limits hold across
+// replicas.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// takeToken refills a bucket for the time passed since updatedAt and takes a
+// token from it when there is one.
+func takeToken(tokens float64, updatedAt time.Time, now time.Time, limit RateLimit) (float64, RateLimitResult) {
+	if elapsed := now.Sub(updatedAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed*limit.refillPerSecond())
+	}
+
+	if tokens < 1 {
+		wait := (1 - tokens) / limit.refillPerSecond()
+		return tokens, RateLimitResult{
+			RetryAfter: time.Duration(wait * float64(time.Second)),
+		}
+	}
+
+	tokens--
+	return tokens, RateLimitResult{
+		Allowed:   true,
+		Remaining: int(tokens),
+	}
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiredAt time.Time
+}
+
+// MemoryRateLimitStore keeps the buckets in the memory of the process.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	cleanedAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket),
+		cleanedAt: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.cleanedAt) > time.Minute {
+		for k, bucket := range s.buckets {
+			if bucket.expiredAt.Before(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.cleanedAt = now
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens, result := takeToken(bucket.tokens, bucket.updatedAt, now, limit)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	bucket.expiredAt = now.Add(limit.idleTTL())
+	return result, nil
+}
+
+// DatabaseRateLimitStore keeps the buckets in the rate_limit_buckets table so
+// every instance sharing the database shares the limits.
+type DatabaseRateLimitStore struct {
+	Log        *logrus.Logger
+	Repository repository.IRateLimitBucketRepository
+
+	mu        sync.Mutex
+	cleanedAt time.Time
+}
+
+func NewDatabaseRateLimitStore(log *logrus.Logger, repository repository.IRateLimitBucketRepository) *DatabaseRateLimitStore {
+	return &DatabaseRateLimitStore{
+		Log:        log,
+		Repository: repository,
+		cleanedAt:  time.Now(),
+	}
+}
+
+func (s *DatabaseRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+	s.cleanup(now)
+
+	var result RateLimitResult
+	err := s.Repository.UpdateBucket(key, &entity.RateLimitBucket{
+		Tokens:    float64(limit.Burst),
+		UpdatedAt: now,
+		ExpiredAt: now.Add(limit.idleTTL()),
+	}, func(bucket *entity.RateLimitBucket) {
+		bucket.Tokens, result = takeToken(bucket.Tokens, bucket.UpdatedAt, now, limit)
+		bucket.UpdatedAt = now
+		bucket.ExpiredAt = now.Add(limit.idleTTL())
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return result, nil
+}
+
+// cleanup deletes the buckets that filled up again, at most every ten minutes.
+func (s *DatabaseRateLimitStore) cleanup(now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.cleanedAt) < 10*time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.cleanedAt = now
+	s.mu.Unlock()
+
+	go func() {
+		if _, err := s.Repository.DeleteExpired(); err != nil {
+			s.Log.Warn("[DatabaseRateLimitStore.cleanup] " + err.Error())
+		}
+	}()
+}
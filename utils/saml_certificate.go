@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// LoadSAMLCertificate returns the key pair signing SAML assertions, read from
+// saml.certificate_file and saml.key_file. A self-signed pair is generated on
+// first use when the files do not exist yet; service providers pin the
+// certificate from /saml/metadata, so keep the files across deploys.
+func LoadSAMLCertificate(config *viper.Viper) (*rsa.PrivateKey, *x509.Certificate, error) {
+	certificateFile := config.GetString("saml.certificate_file")
+	if certificateFile == "" {
+		certificateFile = "cert/saml.crt"
+	}
+	keyFile := config.GetString("saml.key_file")
+	if keyFile == "" {
+		keyFile = "cert/saml.key"
+	}
+
+	if _, err := os.Stat(certificateFile); errors.Is(err, os.ErrNotExist) {
+		if err := generateSAMLCertificate(certificateFile, keyFile, OIDCIssuer(config)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certificateFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("the SAML signing key must be an RSA key")
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, certificate, nil
+}
+
+func generateSAMLCertificate(certificateFile, keyFile, commonName string) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certificateFile), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certificate,
+	}), 0o644)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func NewSession(ctx *gin.Context) sessions.Session {
@@ -15,3 +16,15 @@ func NewSession(ctx *gin.Context) sessions.Session {
 	session.Save()
 	return session
 }
+
+// LoginSessionID returns the identifier of the current login, the sid claim of
+// ID tokens and logout tokens. It is created on first use and dropped on
+// logout, so every login gets a new one. The caller saves the session.
+func LoginSessionID(session sessions.Session) string {
+	if sid, ok := session.Get("sid").(string); ok && sid != "" {
+		return sid
+	}
+	sid := uuid.New().String()
+	session.Set("sid", sid)
+	return sid
+}
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/google/uuid"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// sessionTouchInterval throttles the last seen updates of a session.
+	sessionTouchInterval = time.Minute
+	// anonymousSessionTTL keeps the sessions of visitors who never sign in
+	// (CSRF tokens, flash messages) from piling up.
+	anonymousSessionTTL = 24 * time.Hour
+)
+
+// DatabaseSessionStore keeps the web sessions in the user_sessions table. The
+// cookie only carries a signed random token, so a session stops working as
+// soon as its row is deleted.
+type DatabaseSessionStore struct {
+	Log        *logrus.Logger
+	Repository repository.IUserSessionRepository
+	Codecs     []securecookie.Codec
+	options    *gsessions.Options
+}
+
+func NewDatabaseSessionStore(log *logrus.Logger, userSessionRepository repository.IUserSessionRepository, keyPairs ...[]byte) sessions.Store {
+	store := &DatabaseSessionStore{
+		Log:        log,
+		Repository: userSessionRepository,
+		Codecs:     securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+	}
+	store.MaxAge(store.options.MaxAge)
+	return store
+}
+
+func (s *DatabaseSessionStore) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	s.MaxAge(s.options.MaxAge)
+}
+
+// MaxAge sets the lifetime of new sessions and of their cookies.
+func (s *DatabaseSessionStore) MaxAge(age int) {
+	for _, codec := range s.Codecs {
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(age)
+		}
+	}
+}
+
+func (s *DatabaseSessionStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *DatabaseSessionStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.Codecs...); err != nil {
+		// a cookie signed with an old secret or by the former cookie store
+		return session, nil
+	}
+
+	userSession, err := s.Repository.FindByTokenHash(HashToken(token))
+	if err != nil {
+		return session, err
+	}
+	if userSession == nil {
+		return session, nil
+	}
+
+	if err := decodeSessionValues(userSession.Data, &session.Values); err != nil {
+		s.Log.Warn("[DatabaseSessionStore.New] " + err.Error())
+		return session, nil
+	}
+	session.ID = userSession.ID.String()
+	session.IsNew = false
+
+	if time.Since(userSession.LastSeenAt) > sessionTouchInterval {
+		if err := s.Repository.UpdateUserSession(userSession.ID, map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip_address":   clientIP(r),
+		}); err != nil {
+			s.Log.Warn("[DatabaseSessionStore.New] " + err.Error())
+		}
+	}
+	return session, nil
+}
+
+func (s *DatabaseSessionStore) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if id, err := uuid.Parse(session.ID); err == nil {
+			if err := s.Repository.DeleteUserSession(id); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := encodeSessionValues(session.Values)
+	if err != nil {
+		return err
+	}
+	userID, loginID := sessionOwner(session.Values)
+
+	if id, err := uuid.Parse(session.ID); err == nil {
+		existing, err := s.Repository.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			// the session was terminated while this request was running
+			expired := *session.Options
+			expired.MaxAge = -1
+			http.SetCookie(w, gsessions.NewCookie(session.Name(), "", &expired))
+			return nil
+		}
+		if sameUser(existing.UserID, userID) {
+			return s.Repository.UpdateUserSession(id, map[string]interface{}{
+				"data":         data,
+				"login_id":     loginID,
+				"last_seen_at": time.Now(),
+			})
+		}
+		// signing in gets a new token, a token seen before the login is useless afterwards
+		if err := s.Repository.DeleteUserSession(id); err != nil {
+			return err
+		}
+	}
+
+	token := GenerateRandomStringToken(48)
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = s.options.MaxAge
+	}
+	lifetime := time.Duration(maxAge) * time.Second
+	if userID == nil && lifetime > anonymousSessionTTL {
+		lifetime = anonymousSessionTTL
+	}
+	userSession, err := s.Repository.CreateUserSession(&entity.UserSession{
+		TokenHash:  HashToken(token),
+		UserID:     userID,
+		LoginID:    loginID,
+		Data:       data,
+		Device:     DescribeDevice(r.UserAgent()),
+		IPAddress:  clientIP(r),
+		UserAgent:  r.UserAgent(),
+		LastSeenAt: time.Now(),
+		ExpiredAt:  time.Now().Add(lifetime),
+	})
+	if err != nil {
+		return err
+	}
+	session.ID = userSession.ID.String()
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), token, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func encodeSessionValues(values map[interface{}]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeSessionValues(data string, values *map[interface{}]interface{}) error {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(raw)).Decode(values)
+}
+
+// sessionOwner returns the signed in user and the login ID (sid) kept in the
+// session values.
+func sessionOwner(values map[interface{}]interface{}) (*uuid.UUID, string) {
+	var userID *uuid.UUID
+	if profile, ok := values["profile"].(entity.Profile); ok && profile.ID != uuid.Nil {
+		id := profile.ID
+		userID = &id
+	}
+	loginID, _ := values["sid"].(string)
+	return userID, loginID
+}
+
+func sameUser(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// DescribeDevice turns a user agent into a short label such as "Chrome on
+// Windows" for the sessions page.
+func DescribeDevice(userAgent string) string {
+	browser := "Unknown browser"
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	platform := "unknown device"
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+
+	return browser + " on " + platform
+}
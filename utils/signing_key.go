@@ -0,0 +1,372 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// signingKeyCacheTTL bounds how long a rotation done by another instance
+	// takes to be picked up.
+	signingKeyCacheTTL = time.Minute
+	// signingKeyReloadInterval throttles forced reloads caused by unknown kids.
+	signingKeyReloadInterval = 10 * time.Second
+)
+
+type loadedSigningKey struct {
+	KeyID      string
+	Algorithm  entity.SigningKeyAlgorithm
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+type signingKeyring struct {
+	mu            sync.RWMutex
+	initOnce      sync.Once
+	log           *logrus.Logger
+	repository    repository.ISigningKeyRepository
+	algorithm     entity.SigningKeyAlgorithm
+	legacy        []byte
+	accessTTL     time.Duration
+	issuer        string
+	revocationTTL time.Duration
+	active        *loadedSigningKey
+	published     map[string]*loadedSigningKey
+	loadedAt      time.Time
+}
+
+var keyring = &signingKeyring{}
+
+func (k *signingKeyring) init() {
+	viper := viper.New()
+	logger := logrus.New()
+
+	viper.SetConfigName("config")
+	viper.SetConfigType("json")
+	viper.AddConfigPath("./")
+	err := viper.ReadInConfig()
+
+	if err != nil {
+		logger.Fatalf("Fatal error config file: %v", err)
+	}
+
+	k.log = logger
+	k.repository = repository.SigningKeyRepositoryFactory(logger)
+	k.algorithm = entity.SigningKeyAlgorithm(viper.GetString("jwt.signing_algorithm"))
+	if k.algorithm == "" {
+		k.algorithm = entity.SIGNING_KEY_RS256
+	}
+	k.accessTTL = time.Duration(viper.GetInt("jwt.access_token_ttl")) * time.Second
+	if k.accessTTL <= 0 {
+		k.accessTTL = 72 * time.Hour
+	}
+	k.issuer = OIDCIssuer(viper)
+	k.revocationTTL = time.Duration(viper.GetInt("jwt.revocation_cache_ttl")) * time.Second
+	if k.revocationTTL <= 0 {
+		k.revocationTTL = 30 * time.Second
+	}
+	if viper.GetBool("jwt.accept_hs256") {
+		k.legacy = []byte(viper.GetString("jwt.secret"))
+	}
+}
+
+// load refreshes the cached keys from the database. When no active key exists
+// yet, one is generated so a fresh install can issue tokens straight away.
+func (k *signingKeyring) load(force bool) error {
+	k.initOnce.Do(k.init)
+
+	k.mu.RLock()
+	age := time.Since(k.loadedAt)
+	fresh := k.active != nil && (age < signingKeyCacheTTL || (force && age < signingKeyReloadInterval))
+	k.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	activeKey, err := k.repository.FindActiveSigningKey()
+	if err != nil {
+		return err
+	}
+	if activeKey == nil {
+		k.log.Warn("No active signing key found, generating a new " + string(k.algorithm) + " key")
+		signingKey, err := GenerateSigningKey(k.algorithm)
+		if err != nil {
+			return err
+		}
+		if _, err := k.repository.RotateSigningKey(signingKey, 0); err != nil {
+			return err
+		}
+	}
+
+	signingKeys, err := k.repository.FindPublishedSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	published := make(map[string]*loadedSigningKey, len(*signingKeys))
+	var active *loadedSigningKey
+	for _, signingKey := range *signingKeys {
+		loaded, err := parseSigningKey(&signingKey)
+		if err != nil {
+			k.log.Errorf("Skipping signing key %s: %v", signingKey.KeyID, err)
+			continue
+		}
+		published[loaded.KeyID] = loaded
+		if active == nil && signingKey.Status == entity.SIGNING_KEY_ACTIVE {
+			active = loaded
+		}
+	}
+	if active == nil {
+		return errors.New("no usable active signing key")
+	}
+
+	k.active = active
+	k.published = published
+	k.loadedAt = time.Now()
+	return nil
+}
+
+func (k *signingKeyring) activeKey() (*loadedSigningKey, error) {
+	if err := k.load(false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active, nil
+}
+
+func (k *signingKeyring) publishedKey(kid string) (*loadedSigningKey, error) {
+	if err := k.load(false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	key, ok := k.published[kid]
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	// the key may have been rotated in by another instance
+	if err := k.load(true); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, ok := k.published[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key id: %s", kid)
+}
+
+func (k *signingKeyring) publishedKeys() ([]*loadedSigningKey, error) {
+	if err := k.load(false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]*loadedSigningKey, 0, len(k.published))
+	for _, key := range k.published {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// InvalidateSigningKeys drops the cached keys so the next token operation
+// reads them again, used right after a rotation.
+func InvalidateSigningKeys() {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	keyring.loadedAt = time.Time{}
+}
+
+// GenerateSigningKey creates a new key pair for the given algorithm, PEM
+// encoded and identified by the thumbprint of its public key.
+func GenerateSigningKey(algorithm entity.SigningKeyAlgorithm) (*entity.SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case entity.SIGNING_KEY_RS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case entity.SIGNING_KEY_ES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(publicDER)
+
+	return &entity.SigningKey{
+		KeyID:      base64.RawURLEncoding.EncodeToString(hash[:])[:16],
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
+
+func parseSigningKey(signingKey *entity.SigningKey) (*loadedSigningKey, error) {
+	block, _ := pem.Decode([]byte(signingKey.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid PEM encoded signing key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &loadedSigningKey{
+		KeyID:     signingKey.KeyID,
+		Algorithm: signingKey.Algorithm,
+	}
+	switch signingKey.Algorithm {
+	case entity.SIGNING_KEY_RS256:
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("signing key is not an RSA private key")
+		}
+		loaded.Method = jwt.SigningMethodRS256
+		loaded.PrivateKey = key
+		loaded.PublicKey = &key.PublicKey
+	case entity.SIGNING_KEY_ES256:
+		key, ok := parsed.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("signing key is not an ECDSA private key")
+		}
+		loaded.Method = jwt.SigningMethodES256
+		loaded.PrivateKey = key
+		loaded.PublicKey = &key.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", signingKey.Algorithm)
+	}
+
+	return loaded, nil
+}
+
+// SignToken signs the claims with the active signing key and sets its kid header.
+func SignToken(claims jwt.MapClaims) (string, error) {
+	key, err := keyring.activeKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KeyID
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// ParseToken verifies a token against the published signing keys. HS256 tokens
+// signed with jwt.secret are only accepted while jwt.accept_hs256 is enabled.
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			keyring.initOnce.Do(keyring.init)
+			if len(keyring.legacy) == 0 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return keyring.legacy, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := keyring.publishedKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256", "ES256", "HS256"}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// HS256 tokens predate token_use and ID tokens, they are all access tokens
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if _, marked := claims["token_use"]; !marked {
+			claims["token_use"] = TOKEN_USE_ACCESS
+		}
+	}
+
+	return claims, nil
+}
+
+// GenerateIDToken signs the given claims as an OpenID Connect ID token.
+func GenerateIDToken(claims jwt.MapClaims) (string, error) {
+	return SignToken(claims)
+}
+
+// GetJSONWebKeySet returns the public part of every published signing key as a JWKS document.
+func GetJSONWebKeySet() (map[string]interface{}, error) {
+	keys, err := keyring.publishedKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]interface{}{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": string(key.Algorithm),
+				"kid": key.KeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			jwks = append(jwks, map[string]interface{}{
+				"kty": "EC",
+				"use": "sig",
+				"alg": string(key.Algorithm),
+				"kid": key.KeyID,
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"keys": jwks,
+	}, nil
+}
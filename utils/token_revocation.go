@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// revocationCache mirrors the unexpired rows of revoked_tokens so checking a
+// token does not hit the database. Revocations made on another instance are
+// picked up after jwt.revocation_cache_ttl seconds.
+type revocationCache struct {
+	mu         sync.RWMutex
+	initOnce   sync.Once
+	repository repository.IRevokedTokenRepository
+	tokens     map[string]time.Time
+	users      map[string]time.Time
+	loadedAt   time.Time
+}
+
+var revocations = &revocationCache{}
+
+func (c *revocationCache) init() {
+	c.repository = repository.RevokedTokenRepositoryFactory(logrus.New())
+}
+
+func (c *revocationCache) load() error {
+	c.initOnce.Do(c.init)
+	keyring.initOnce.Do(keyring.init)
+
+	c.mu.RLock()
+	fresh := time.Since(c.loadedAt) < keyring.revocationTTL
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	revokedTokens, err := c.repository.FindUnexpiredRevokedTokens()
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time)
+	users := make(map[string]time.Time)
+	for _, revokedToken := range *revokedTokens {
+		if revokedToken.JTI != "" {
+			tokens[revokedToken.JTI] = revokedToken.ExpiredAt
+			continue
+		}
+		userID := revokedToken.UserID.String()
+		if revokedToken.RevokedAt.After(users[userID]) {
+			users[userID] = revokedToken.RevokedAt
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = tokens
+	c.users = users
+	c.loadedAt = time.Now()
+	return nil
+}
+
+// IsTokenRevoked reports whether the access token with the given claims was
+// revoked by its jti or by a revocation of every token of its user.
+func IsTokenRevoked(claims jwt.MapClaims) (bool, error) {
+	if err := revocations.load(); err != nil {
+		return false, err
+	}
+
+	revocations.mu.RLock()
+	defer revocations.mu.RUnlock()
+
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		if _, revoked := revocations.tokens[jti]; revoked {
+			return true, nil
+		}
+	}
+
+	userID, _ := claims["id"].(string)
+	revokedAt, ok := revocations.users[userID]
+	if !ok {
+		return false, nil
+	}
+
+	// tokens issued before iat was added cannot prove they are newer
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return true, nil
+	}
+	// iat only has whole seconds, so the whole second of the revocation is
+	// revoked; TokenIssuedAt dates tokens issued after it to the next one
+	return !issuedAt.Time.After(revokedAt), nil
+}
+
+// TokenIssuedAt is the iat of a token issued now to userID. A token issued in
+// the same second as a revocation of every token of the user would count as
+// revoked, so it is dated to the second after the revocation.
+func TokenIssuedAt(userID string) time.Time {
+	now := time.Now()
+	if err := revocations.load(); err != nil {
+		return now
+	}
+
+	revocations.mu.RLock()
+	revokedAt, ok := revocations.users[userID]
+	revocations.mu.RUnlock()
+	if ok && revokedAt.Unix() >= now.Unix() {
+		return time.Unix(revokedAt.Unix()+1, 0)
+	}
+	return now
+}
+
+// MarkTokenRevoked adds a freshly stored revocation to the local cache so it
+// takes effect on this instance immediately.
+func MarkTokenRevoked(revokedToken *entity.RevokedToken) {
+	revocations.mu.Lock()
+	defer revocations.mu.Unlock()
+
+	if revocations.tokens == nil {
+		revocations.tokens = make(map[string]time.Time)
+	}
+	if revocations.users == nil {
+		revocations.users = make(map[string]time.Time)
+	}
+
+	if revokedToken.JTI != "" {
+		revocations.tokens[revokedToken.JTI] = revokedToken.ExpiredAt
+		return
+	}
+	userID := revokedToken.UserID.String()
+	if revokedToken.RevokedAt.After(revocations.users[userID]) {
+		revocations.users[userID] = revokedToken.RevokedAt
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"app/go-sso/internal/entity"
+	"app/go-sso/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type fakeRevokedTokenRepository struct {
+	repository.IRevokedTokenRepository
+	revokedTokens []entity.RevokedToken
+}
+
+func (r *fakeRevokedTokenRepository) FindUnexpiredRevokedTokens() (*[]entity.RevokedToken, error) {
+	return &r.revokedTokens, nil
+}
+
+// useRevokedTokens points the revocation cache at an in-memory repository so
+// the tests need neither a config nor a database.
+func useRevokedTokens(t *testing.T, revokedTokens ...entity.RevokedToken) {
+	t.Helper()
+
+	keyring.initOnce.Do(func() { keyring.revocationTTL = time.Hour })
+	revocations.initOnce.Do(func() {})
+
+	revocations.mu.Lock()
+	defer revocations.mu.Unlock()
+	revocations.repository = &fakeRevokedTokenRepository{revokedTokens: revokedTokens}
+	revocations.tokens = nil
+	revocations.users = nil
+	revocations.loadedAt = time.Time{}
+}
+
+// accessTokenClaims builds the claims the way ParseToken returns them, with
+// iat decoded from JSON as whole seconds.
+func accessTokenClaims(jti string, userID uuid.UUID, issuedAt time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"jti": jti,
+		"id":  userID.String(),
+		"iat": float64(issuedAt.Unix()),
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second).Add(400 * time.Millisecond)
+
+	useRevokedTokens(t,
+		entity.RevokedToken{JTI: "revoked-jti", UserID: otherUserID, RevokedAt: revokedAt, ExpiredAt: revokedAt.Add(time.Hour)},
+		entity.RevokedToken{UserID: userID, RevokedAt: revokedAt, ExpiredAt: revokedAt.Add(time.Hour)},
+	)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   bool
+	}{
+		{"revoked jti", accessTokenClaims("revoked-jti", otherUserID, time.Now()), true},
+		{"other jti of the same user", accessTokenClaims("other-jti", otherUserID, revokedAt.Add(-time.Hour)), false},
+		{"issued before the revocation", accessTokenClaims("a", userID, revokedAt.Add(-time.Second)), true},
+		// iat is truncated to the second, so a token of the same second may
+		// as well have been issued before the revocation
+		{"issued in the second of the revocation", accessTokenClaims("b", userID, revokedAt.Add(300*time.Millisecond)), true},
+		{"issued the second after the revocation", accessTokenClaims("c", userID, revokedAt.Truncate(time.Second).Add(time.Second)), false},
+		{"issued without iat", jwt.MapClaims{"jti": "d", "id": userID.String()}, true},
+		{"client token", jwt.MapClaims{"jti": "e"}, false},
+	}
+
+	for _, test := range tests {
+		got, err := IsTokenRevoked(test.claims)
+		if err != nil {
+			t.Errorf("%s: IsTokenRevoked: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: IsTokenRevoked = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMarkTokenRevoked(t *testing.T) {
+	userID := uuid.New()
+	useRevokedTokens(t)
+
+	claims := accessTokenClaims("fresh-jti", userID, time.Now().Add(-time.Minute))
+	if revoked, err := IsTokenRevoked(claims); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked = %v, %v, want false", revoked, err)
+	}
+
+	MarkTokenRevoked(&entity.RevokedToken{JTI: "fresh-jti", UserID: userID, RevokedAt: time.Now(), ExpiredAt: time.Now().Add(time.Hour)})
+	if revoked, err := IsTokenRevoked(claims); err != nil || !revoked {
+		t.Errorf("IsTokenRevoked after MarkTokenRevoked by jti = %v, %v, want true", revoked, err)
+	}
+
+	other := accessTokenClaims("other-jti", userID, time.Now().Add(-time.Minute))
+	MarkTokenRevoked(&entity.RevokedToken{UserID: userID, RevokedAt: time.Now(), ExpiredAt: time.Now().Add(time.Hour)})
+	if revoked, err := IsTokenRevoked(other); err != nil || !revoked {
+		t.Errorf("IsTokenRevoked after MarkTokenRevoked of the user = %v, %v, want true", revoked, err)
+	}
+}
+
+func TestTokenIssuedAtFollowsRevocation(t *testing.T) {
+	userID := uuid.New()
+	useRevokedTokens(t)
+
+	if issuedAt := TokenIssuedAt(userID.String()); time.Since(issuedAt) > time.Second {
+		t.Errorf("TokenIssuedAt without revocation = %v, want now", issuedAt)
+	}
+
+	revokedAt := time.Now()
+	MarkTokenRevoked(&entity.RevokedToken{UserID: userID, RevokedAt: revokedAt, ExpiredAt: revokedAt.Add(time.Hour)})
+
+	issuedAt := TokenIssuedAt(userID.String())
+	if want := time.Unix(revokedAt.Unix()+1, 0); !issuedAt.Equal(want) {
+		t.Errorf("TokenIssuedAt after revocation = %v, want %v", issuedAt, want)
+	}
+
+	// a token dated that way survives the revocation it follows
+	claims := accessTokenClaims("new-jti", userID, issuedAt)
+	if revoked, err := IsTokenRevoked(claims); err != nil || revoked {
+		t.Errorf("IsTokenRevoked for a token issued after the revocation = %v, %v, want false", revoked, err)
+	}
+}
@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithms accepted for WebAuthn credentials.
+const (
+	WEBAUTHN_ALG_ES256 = -7
+	WEBAUTHN_ALG_EDDSA = -8
+	WEBAUTHN_ALG_RS256 = -257
+)
+
+// WebAuthnAlgorithms is the pubKeyCredParams list offered to authenticators,
+// in order of preference.
+var WebAuthnAlgorithms = []int{WEBAUTHN_ALG_ES256, WEBAUTHN_ALG_EDDSA, WEBAUTHN_ALG_RS256}
+
+// authenticator data flags
+const (
+	webAuthnFlagUserPresent      = 0x01
+	webAuthnFlagUserVerified     = 0x04
+	webAuthnFlagAttestedCredData = 0x40
+)
+
+// WebAuthnRelyingParty is this server as seen by authenticators. Credentials
+// are bound to ID, a registrable domain of the origins the ceremonies run on.
+type WebAuthnRelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// WebAuthnRelyingPartyFromConfig reads webauthn.rp_id, webauthn.rp_name and
+// webauthn.origins, falling back to the host, name and origin of app.url.
+func WebAuthnRelyingPartyFromConfig(config *viper.Viper) WebAuthnRelyingParty {
+	rp := WebAuthnRelyingParty{
+		ID:   config.GetString("webauthn.rp_id"),
+		Name: config.GetString("webauthn.rp_name"),
+	}
+	for _, origin := range strings.Split(config.GetString("webauthn.origins"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			rp.Origins = append(rp.Origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+
+	appURL, _ := url.Parse(config.GetString("app.url"))
+	if rp.ID == "" && appURL != nil {
+		rp.ID = appURL.Hostname()
+	}
+	if rp.Name == "" {
+		rp.Name = config.GetString("app.name")
+	}
+	if len(rp.Origins) == 0 && appURL != nil {
+		rp.Origins = []string{appURL.Scheme + "://" + appURL.Host}
+	}
+	return rp
+}
+
+// WebAuthnAttestedCredential is a credential created by a registration
+// ceremony. PublicKey is PKIX DER encoded.
+type WebAuthnAttestedCredential struct {
+	ID        []byte
+	PublicKey []byte
+	Algorithm int
+	SignCount uint32
+	AAGUID    []byte
+}
+
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type webAuthnAttestationObject struct {
+	Format   string `codec:"fmt"`
+	AuthData []byte `codec:"authData"`
+}
+
+// NewWebAuthnChallenge returns a random challenge, base64url encoded like
+// every binary value exchanged with the browser.
+func NewWebAuthnChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return EncodeWebAuthnBytes(challenge), nil
+}
+
+func EncodeWebAuthnBytes(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeWebAuthnBytes(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// VerifyWebAuthnRegistration checks the response of navigator.credentials
+// .create() and returns the new credential. Attestation statements are not
+// verified, any authenticator is accepted.
+func VerifyWebAuthnRegistration(rp WebAuthnRelyingParty, challenge string, clientDataJSON, attestationObject []byte) (*WebAuthnAttestedCredential, error) {
+	if err := verifyWebAuthnClientData(rp, "webauthn.create", challenge, clientDataJSON); err != nil {
+		return nil, err
+	}
+
+	var attestation webAuthnAttestationObject
+	if err := codec.NewDecoderBytes(attestationObject, &codec.CborHandle{}).Decode(&attestation); err != nil {
+		return nil, errors.New("Invalid attestation object")
+	}
+
+	authData := attestation.AuthData
+	flags, signCount, err := verifyWebAuthnAuthenticatorData(rp, authData, false)
+	if err != nil {
+		return nil, err
+	}
+	if flags&webAuthnFlagAttestedCredData == 0 || len(authData) < 55 {
+		return nil, errors.New("The authenticator did not return a credential")
+	}
+
+	aaguid := authData[37:53]
+	credentialIDLength := int(binary.BigEndian.Uint16(authData[53:55]))
+	if len(authData) < 55+credentialIDLength {
+		return nil, errors.New("Invalid authenticator data")
+	}
+	credentialID := authData[55 : 55+credentialIDLength]
+
+	publicKey, algorithm, err := parseCOSEKey(authData[55+credentialIDLength:])
+	if err != nil {
+		return nil, err
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnAttestedCredential{
+		ID:        append([]byte(nil), credentialID...),
+		PublicKey: publicKeyDER,
+		Algorithm: algorithm,
+		SignCount: signCount,
+		AAGUID:    append([]byte(nil), aaguid...),
+	}, nil
+}
+
+// VerifyWebAuthnAssertion checks the response of navigator.credentials.get()
+// against a stored credential and returns the new signature counter.
+func VerifyWebAuthnAssertion(rp WebAuthnRelyingParty, challenge string, clientDataJSON, authenticatorData, signature, publicKeyDER []byte, algorithm int, storedSignCount uint32, requireUserVerification bool) (uint32, error) {
+	if err := verifyWebAuthnClientData(rp, "webauthn.get", challenge, clientDataJSON); err != nil {
+		return 0, err
+	}
+
+	_, signCount, err := verifyWebAuthnAuthenticatorData(rp, authenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(publicKeyDER)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if !verifyWebAuthnSignature(publicKey, algorithm, signed, signature) {
+		return 0, errors.New("Invalid passkey signature")
+	}
+
+	// authenticators that keep a counter must increase it, a counter going
+	// back means the credential was cloned
+	if signCount != 0 || storedSignCount != 0 {
+		if signCount <= storedSignCount {
+			return 0, errors.New("The passkey signature counter did not increase")
+		}
+	}
+	return signCount, nil
+}
+
+func verifyWebAuthnClientData(rp WebAuthnRelyingParty, ceremony, challenge string, clientDataJSON []byte) error {
+	var clientData webAuthnClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return errors.New("Invalid client data")
+	}
+	if clientData.Type != ceremony {
+		return errors.New("Invalid client data type")
+	}
+	if clientData.Challenge != strings.TrimRight(challenge, "=") {
+		return errors.New("The passkey challenge does not match")
+	}
+	for _, origin := range rp.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("Origin %s is not allowed", clientData.Origin)
+}
+
+func verifyWebAuthnAuthenticatorData(rp WebAuthnRelyingParty, authData []byte, requireUserVerification bool) (byte, uint32, error) {
+	if len(authData) < 37 {
+		return 0, 0, errors.New("Invalid authenticator data")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return 0, 0, errors.New("The passkey belongs to another site")
+	}
+
+	flags := authData[32]
+	if flags&webAuthnFlagUserPresent == 0 {
+		return 0, 0, errors.New("User presence was not confirmed")
+	}
+	if requireUserVerification && flags&webAuthnFlagUserVerified == 0 {
+		return 0, 0, errors.New("User verification is required")
+	}
+	return flags, binary.BigEndian.Uint32(authData[33:37]), nil
+}
+
+// parseCOSEKey reads a credential public key (RFC 9053) from the attested
+// credential data.
+func parseCOSEKey(data []byte) (crypto.PublicKey, int, error) {
+	var key map[int]interface{}
+	if err := codec.NewDecoderBytes(data, &codec.CborHandle{}).Decode(&key); err != nil {
+		return nil, 0, errors.New("Invalid credential public key")
+	}
+
+	keyType, _ := coseInt(key[1])
+	algorithm, _ := coseInt(key[3])
+	switch {
+	case algorithm == WEBAUTHN_ALG_ES256 && keyType == 2:
+		curve, _ := coseInt(key[-1])
+		x, _ := key[-2].([]byte)
+		y, _ := key[-3].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("Unsupported EC2 credential key")
+		}
+		publicKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, 0, errors.New("Invalid EC2 credential key")
+		}
+		return publicKey, int(algorithm), nil
+	case algorithm == WEBAUTHN_ALG_EDDSA && keyType == 1:
+		curve, _ := coseInt(key[-1])
+		x, _ := key[-2].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("Unsupported OKP credential key")
+		}
+		return ed25519.PublicKey(x), int(algorithm), nil
+	case algorithm == WEBAUTHN_ALG_RS256 && keyType == 3:
+		n, _ := key[-1].([]byte)
+		e, _ := key[-2].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("Unsupported RSA credential key")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, int(algorithm), nil
+	}
+	return nil, 0, fmt.Errorf("Unsupported credential algorithm %d", algorithm)
+}
+
+func coseInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func verifyWebAuthnSignature(publicKey crypto.PublicKey, algorithm int, signed, signature []byte) bool {
+	switch algorithm {
+	case WEBAUTHN_ALG_ES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case WEBAUTHN_ALG_EDDSA:
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(key, signed, signature)
+	case WEBAUTHN_ALG_RS256:
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}